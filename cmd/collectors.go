@@ -34,9 +34,12 @@ import (
 	"github.com/loadimpact/k6/stats/cloud"
 	"github.com/loadimpact/k6/stats/csv"
 	"github.com/loadimpact/k6/stats/datadog"
+	"github.com/loadimpact/k6/stats/httpsink"
 	"github.com/loadimpact/k6/stats/influxdb"
 	jsonc "github.com/loadimpact/k6/stats/json"
 	"github.com/loadimpact/k6/stats/kafka"
+	"github.com/loadimpact/k6/stats/openmetrics"
+	"github.com/loadimpact/k6/stats/parquet"
 	"github.com/loadimpact/k6/stats/statsd"
 	"github.com/loadimpact/k6/stats/statsd/common"
 	"github.com/pkg/errors"
@@ -44,13 +47,16 @@ import (
 )
 
 const (
-	collectorInfluxDB = "influxdb"
-	collectorJSON     = "json"
-	collectorKafka    = "kafka"
-	collectorCloud    = "cloud"
-	collectorStatsD   = "statsd"
-	collectorDatadog  = "datadog"
-	collectorCSV      = "csv"
+	collectorInfluxDB    = "influxdb"
+	collectorJSON        = "json"
+	collectorKafka       = "kafka"
+	collectorCloud       = "cloud"
+	collectorStatsD      = "statsd"
+	collectorDatadog     = "datadog"
+	collectorCSV         = "csv"
+	collectorOpenMetrics = "openmetrics"
+	collectorHTTP        = "http"
+	collectorParquet     = "parquet"
 )
 
 func parseCollector(s string) (t, arg string) {
@@ -69,7 +75,18 @@ func parseCollector(s string) (t, arg string) {
 func getCollector(collectorName, arg string, src *loader.SourceData, conf Config) (lib.Collector, error) {
 	switch collectorName {
 	case collectorJSON:
-		return jsonc.New(afero.NewOsFs(), arg)
+		config := jsonc.NewConfig().Apply(conf.Collectors.JSON)
+		if err := envconfig.Process("", &config); err != nil {
+			return nil, err
+		}
+		if arg != "" {
+			cmdConfig, err := jsonc.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			config = config.Apply(cmdConfig)
+		}
+		return jsonc.New(afero.NewOsFs(), config)
 	case collectorInfluxDB:
 		config := influxdb.NewConfig().Apply(conf.Collectors.InfluxDB)
 		if err := envconfig.Process("", &config); err != nil {
@@ -129,6 +146,45 @@ func getCollector(collectorName, arg string, src *loader.SourceData, conf Config
 			config = config.Apply(cmdConfig)
 		}
 		return csv.New(afero.NewOsFs(), conf.SystemTags.Map(), config)
+	case collectorOpenMetrics:
+		config := openmetrics.NewConfig().Apply(conf.Collectors.OpenMetrics)
+		if err := envconfig.Process("", &config); err != nil {
+			return nil, err
+		}
+		if arg != "" {
+			cmdConfig, err := openmetrics.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			config = config.Apply(cmdConfig)
+		}
+		return openmetrics.New(afero.NewOsFs(), config)
+	case collectorHTTP:
+		config := httpsink.NewConfig().Apply(conf.Collectors.HTTP)
+		if err := envconfig.Process("", &config); err != nil {
+			return nil, err
+		}
+		if arg != "" {
+			cmdConfig, err := httpsink.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			config = config.Apply(cmdConfig)
+		}
+		return httpsink.New(config)
+	case collectorParquet:
+		config := parquet.NewConfig().Apply(conf.Collectors.Parquet)
+		if err := envconfig.Process("", &config); err != nil {
+			return nil, err
+		}
+		if arg != "" {
+			cmdConfig, err := parquet.ParseArg(arg)
+			if err != nil {
+				return nil, err
+			}
+			config = config.Apply(cmdConfig)
+		}
+		return parquet.New(afero.NewOsFs(), conf.SystemTags.Map(), config)
 
 	default:
 		return nil, errors.Errorf("unknown output type: %s", collectorName)
@@ -157,5 +213,12 @@ func newCollector(collectorName, arg string, src *loader.SourceData, conf Config
 		)
 	}
 
+	if renames := conf.MetricRenames[collectorName]; len(renames) > 0 {
+		renamingCollector, err := lib.NewRenamingCollector(collector, renames)
+		if err != nil {
+			return collector, err
+		}
+		return renamingCollector, nil
+	}
 	return collector, nil
 }
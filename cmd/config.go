@@ -26,8 +26,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
+	"github.com/ghodss/yaml"
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
@@ -41,8 +43,12 @@ import (
 	"github.com/loadimpact/k6/stats/cloud"
 	"github.com/loadimpact/k6/stats/csv"
 	"github.com/loadimpact/k6/stats/datadog"
+	"github.com/loadimpact/k6/stats/httpsink"
 	"github.com/loadimpact/k6/stats/influxdb"
+	jsonc "github.com/loadimpact/k6/stats/json"
 	"github.com/loadimpact/k6/stats/kafka"
+	"github.com/loadimpact/k6/stats/openmetrics"
+	"github.com/loadimpact/k6/stats/parquet"
 	"github.com/loadimpact/k6/stats/statsd/common"
 	"github.com/loadimpact/k6/ui"
 )
@@ -61,6 +67,12 @@ func configFlagSet() *pflag.FlagSet {
 		"",
 		"output the end-of-test summary report to JSON file",
 	)
+	flags.Bool("summary-export-values", false, "also output all of the values of the trend metrics to the --summary-export file, e.g. for a http_req_duration:mannwhitney<0.05 --baseline-rule")
+	flags.Duration("summary-interval", 0, "also show a partial summary of the metrics every `interval` during the test")
+	flags.Bool("summary-stages", false, "also break the summary down by the stages of the test")
+	flags.Bool("anomaly-output", false, "only send the metrics of the threshold evaluation windows in which a threshold fails to the outputs")
+	flags.String("baseline-summary", "", "compare the end-of-test summary with a `file` written by --summary-export of a previous run")
+	flags.StringArray("baseline-rule", nil, "fail if a summary stat regressed compared to the baseline, as `[metric]:[stat]<[tolerance]`, e.g. http_req_duration:p(95)<10%")
 	return flags
 }
 
@@ -74,13 +86,35 @@ type Config struct {
 	NoSummary     null.Bool   `json:"noSummary" envconfig:"K6_NO_SUMMARY"`
 	SummaryExport null.String `json:"summaryExport" envconfig:"K6_SUMMARY_EXPORT"`
 
+	// Export all of the values of the trend metrics too, for Mann-Whitney U baseline comparisons
+	SummaryExportValues null.Bool `json:"summaryExportValues" envconfig:"K6_SUMMARY_EXPORT_VALUES"`
+
+	SummaryInterval types.NullDuration `json:"summaryInterval" envconfig:"K6_SUMMARY_INTERVAL"`
+	SummaryStages   null.Bool          `json:"summaryStages" envconfig:"K6_SUMMARY_STAGES"`
+
+	// Only send the samples of the threshold evaluation windows in which a threshold failed to the outputs.
+	AnomalyOutput null.Bool `json:"anomalyOutput" envconfig:"K6_ANOMALY_OUTPUT"`
+
+	// A summary export of a previous run, and the rules for the stats that shouldn't regress
+	// compared to it, e.g. "http_req_duration:p(95)<10%"
+	BaselineSummary null.String `json:"baselineSummary" envconfig:"K6_BASELINE_SUMMARY"`
+	BaselineRules   []string    `json:"baselineRules" envconfig:"K6_BASELINE_RULES"`
+
+	// Metric renames for specific output types, e.g. to send http_req_duration as
+	// http.request.duration only to InfluxDB: {"influxdb": {"http_req_duration": "http.request.duration"}}
+	MetricRenames map[string]map[string]string `json:"metricRenames" ignored:"true"`
+
 	Collectors struct {
-		InfluxDB influxdb.Config `json:"influxdb"`
-		Kafka    kafka.Config    `json:"kafka"`
-		Cloud    cloud.Config    `json:"cloud"`
-		StatsD   common.Config   `json:"statsd"`
-		Datadog  datadog.Config  `json:"datadog"`
-		CSV      csv.Config      `json:"csv"`
+		JSON        jsonc.Config       `json:"json"`
+		InfluxDB    influxdb.Config    `json:"influxdb"`
+		Kafka       kafka.Config       `json:"kafka"`
+		Cloud       cloud.Config       `json:"cloud"`
+		StatsD      common.Config      `json:"statsd"`
+		Datadog     datadog.Config     `json:"datadog"`
+		CSV         csv.Config         `json:"csv"`
+		OpenMetrics openmetrics.Config `json:"openmetrics"`
+		HTTP        httpsink.Config    `json:"http"`
+		Parquet     parquet.Config     `json:"parquet"`
 	} `json:"collectors"`
 }
 
@@ -104,12 +138,37 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.SummaryExport.Valid {
 		c.SummaryExport = cfg.SummaryExport
 	}
+	if cfg.SummaryExportValues.Valid {
+		c.SummaryExportValues = cfg.SummaryExportValues
+	}
+	if cfg.SummaryInterval.Valid {
+		c.SummaryInterval = cfg.SummaryInterval
+	}
+	if cfg.SummaryStages.Valid {
+		c.SummaryStages = cfg.SummaryStages
+	}
+	if cfg.AnomalyOutput.Valid {
+		c.AnomalyOutput = cfg.AnomalyOutput
+	}
+	if cfg.BaselineSummary.Valid {
+		c.BaselineSummary = cfg.BaselineSummary
+	}
+	if len(cfg.BaselineRules) > 0 {
+		c.BaselineRules = cfg.BaselineRules
+	}
+	if len(cfg.MetricRenames) > 0 {
+		c.MetricRenames = cfg.MetricRenames
+	}
+	c.Collectors.JSON = c.Collectors.JSON.Apply(cfg.Collectors.JSON)
 	c.Collectors.InfluxDB = c.Collectors.InfluxDB.Apply(cfg.Collectors.InfluxDB)
 	c.Collectors.Cloud = c.Collectors.Cloud.Apply(cfg.Collectors.Cloud)
 	c.Collectors.Kafka = c.Collectors.Kafka.Apply(cfg.Collectors.Kafka)
 	c.Collectors.StatsD = c.Collectors.StatsD.Apply(cfg.Collectors.StatsD)
 	c.Collectors.Datadog = c.Collectors.Datadog.Apply(cfg.Collectors.Datadog)
 	c.Collectors.CSV = c.Collectors.CSV.Apply(cfg.Collectors.CSV)
+	c.Collectors.OpenMetrics = c.Collectors.OpenMetrics.Apply(cfg.Collectors.OpenMetrics)
+	c.Collectors.HTTP = c.Collectors.HTTP.Apply(cfg.Collectors.HTTP)
+	c.Collectors.Parquet = c.Collectors.Parquet.Apply(cfg.Collectors.Parquet)
 	return c
 }
 
@@ -123,6 +182,10 @@ func getConfig(flags *pflag.FlagSet) (Config, error) {
 	if err != nil {
 		return Config{}, err
 	}
+	baselineRules, err := flags.GetStringArray("baseline-rule")
+	if err != nil {
+		return Config{}, err
+	}
 	return Config{
 		Options:       opts,
 		Out:           out,
@@ -131,6 +194,14 @@ func getConfig(flags *pflag.FlagSet) (Config, error) {
 		NoThresholds:  getNullBool(flags, "no-thresholds"),
 		NoSummary:     getNullBool(flags, "no-summary"),
 		SummaryExport: getNullString(flags, "summary-export"),
+
+		SummaryExportValues: getNullBool(flags, "summary-export-values"),
+
+		SummaryInterval: getNullDuration(flags, "summary-interval"),
+		SummaryStages:   getNullBool(flags, "summary-stages"),
+		AnomalyOutput:   getNullBool(flags, "anomaly-output"),
+		BaselineSummary: getNullString(flags, "baseline-summary"),
+		BaselineRules:   baselineRules,
 	}, nil
 }
 
@@ -161,6 +232,15 @@ func readDiskConfig(fs afero.Fs) (Config, string, error) {
 	if err != nil {
 		return Config{}, realConfigFilePath, err
 	}
+
+	// YAML config files are converted to JSON first, so they are parsed and validated in exactly
+	// the same way as the JSON ones
+	if ext := strings.ToLower(filepath.Ext(realConfigFilePath)); ext == ".yaml" || ext == ".yml" {
+		if data, err = yaml.YAMLToJSON(data); err != nil {
+			return Config{}, realConfigFilePath, fmt.Errorf("couldn't parse the YAML config: %s", err)
+		}
+	}
+
 	var conf Config
 	err = json.Unmarshal(data, &conf)
 	return conf, realConfigFilePath, err
@@ -181,6 +261,100 @@ func writeDiskConfig(fs afero.Fs, configPath string, conf Config) error {
 	return afero.WriteFile(fs, configPath, data, 0644)
 }
 
+// Reads a fully resolved configuration that was written with writeDiskConfig(), e.g. by
+// --export-config. Unlike readDiskConfig(), it's meant to be used as is, without being
+// consolidated with the other configuration sources or having its execution re-derived.
+func readEffectiveConfig(fs afero.Fs, configPath string) (Config, error) {
+	data, err := afero.ReadFile(fs, configPath)
+	if err != nil {
+		return Config{}, err
+	}
+	var conf Config
+	if err := json.Unmarshal(data, &conf); err != nil {
+		return Config{}, fmt.Errorf("couldn't parse the exported config %s: %s", configPath, err)
+	}
+	return conf, nil
+}
+
+// fillShadowedDefaults returns a copy of the config in which all of the null values that have a
+// default value, i.e. that aren't valid but aren't empty either, are marked as valid. Otherwise
+// the defaults would be lost when the config is serialized to JSON, since invalid null values
+// are serialized as null. Values behind pointers are shared with the original config and are
+// left as they are.
+func fillShadowedDefaults(conf Config) Config {
+	v := reflect.ValueOf(&conf).Elem()
+	fillShadowedValue(v)
+	return conf
+}
+
+//nolint:gocyclo
+func fillShadowedValue(v reflect.Value) {
+	switch val := v.Interface().(type) {
+	case null.Int:
+		if !val.Valid && val.Int64 != 0 {
+			v.Set(reflect.ValueOf(null.IntFrom(val.Int64)))
+		}
+		return
+	case null.Float:
+		if !val.Valid && val.Float64 != 0 {
+			v.Set(reflect.ValueOf(null.FloatFrom(val.Float64)))
+		}
+		return
+	case null.Bool:
+		if !val.Valid && val.Bool {
+			v.Set(reflect.ValueOf(null.BoolFrom(val.Bool)))
+		}
+		return
+	case null.String:
+		if !val.Valid && val.String != "" {
+			v.Set(reflect.ValueOf(null.StringFrom(val.String)))
+		}
+		return
+	case types.NullDuration:
+		if !val.Valid && val.Duration != 0 {
+			v.Set(reflect.ValueOf(types.NullDuration{Duration: val.Duration, Valid: true}))
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Field(i); field.CanSet() {
+				fillShadowedValue(field)
+			}
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		// Copy the slice, so the original config isn't modified
+		slice := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(slice, v)
+		for i := 0; i < slice.Len(); i++ {
+			fillShadowedValue(slice.Index(i))
+		}
+		v.Set(slice)
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		// Map values aren't addressable, so every one of them is copied and set in a new map
+		m := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for _, key := range v.MapKeys() {
+			elem := v.MapIndex(key)
+			if elem.Kind() == reflect.Interface && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			elemCopy := reflect.New(elem.Type()).Elem()
+			elemCopy.Set(elem)
+			fillShadowedValue(elemCopy)
+			m.SetMapIndex(key, elemCopy)
+		}
+		v.Set(m)
+	}
+}
+
 // Reads configuration variables from the environment.
 func readEnvConfig() (conf Config, err error) {
 	// TODO: replace envconfig and refactor the whole configuration from the groun up :/
@@ -319,6 +493,7 @@ func getConsolidatedConfig(fs afero.Fs, cliConf Config, runner lib.Runner) (conf
 	}
 	conf = conf.Apply(envConf).Apply(cliConf)
 	conf = applyDefault(conf)
+	conf.RunTags = conf.GetRunTagsWithEnv(collectTagsEnv(runner))
 
 	// TODO(imiric): Move this validation where it makes sense in the configuration
 	// refactor of #883. This repeats the trend stats validation already done
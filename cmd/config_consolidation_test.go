@@ -209,6 +209,8 @@ func resetStickyGlobalVars() {
 	runType = ""
 	runNoSetup = false
 	runNoTeardown = false
+	runExportConfig = ""
+	runImportConfig = ""
 }
 
 // Something that makes the test also be a valid io.Writer, useful for passing it
@@ -433,6 +435,36 @@ func getConfigConsolidationTestCases() []configConsolidationTestCase {
 				assert.Equal(t, []string{"avg", "p(90)", "count"}, c.Options.SummaryTrendStats)
 			},
 		},
+		// Test tags from env
+		{
+			opts{
+				cli: []string{"--tag-from-env", "commit=GIT_COMMIT", "--tag-from-env", "build=BUILD_ID"},
+				env: []string{"GIT_COMMIT=abc123"},
+			},
+			exp{},
+			func(t *testing.T, c Config) {
+				assert.Equal(t, map[string]string{"commit": "abc123"}, c.Options.RunTags.CloneTags())
+			},
+		},
+		{
+			opts{
+				runner: &lib.Options{TagsFromEnv: map[string]string{"commit": "GIT_COMMIT", "build": "BUILD_ID"}},
+				env:    []string{"GIT_COMMIT=abc123", "BUILD_ID=42"},
+				cli:    []string{"--tag", "build=manual"},
+			},
+			exp{},
+			func(t *testing.T, c Config) {
+				assert.Equal(t, map[string]string{"commit": "abc123", "build": "manual"}, c.Options.RunTags.CloneTags())
+			},
+		},
+		{
+			opts{env: []string{"K6_TAGS_FROM_ENV=commit:GIT_COMMIT", "GIT_COMMIT=abc123"}},
+			exp{},
+			func(t *testing.T, c Config) {
+				assert.Equal(t, map[string]string{"commit": "abc123"}, c.Options.RunTags.CloneTags())
+			},
+		},
+		{opts{cli: []string{"--tag-from-env", "commit"}}, exp{cliReadError: true}, nil},
 		//TODO: test for differences between flagsets
 		//TODO: more tests in general, especially ones not related to execution parameters...
 	}
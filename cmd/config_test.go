@@ -21,12 +21,20 @@
 package cmd
 
 import (
+	"encoding/json"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
+	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
 )
 
 type testCmdData struct {
@@ -95,6 +103,18 @@ func TestConfigEnv(t *testing.T) {
 			"true":  func(c Config) { assert.Equal(t, null.BoolFrom(true), c.NoUsageReport) },
 			"false": func(c Config) { assert.Equal(t, null.BoolFrom(false), c.NoUsageReport) },
 		},
+		{"SummaryInterval", "K6_SUMMARY_INTERVAL"}: {
+			"":   func(c Config) { assert.Equal(t, types.NullDuration{}, c.SummaryInterval) },
+			"5m": func(c Config) { assert.Equal(t, types.NullDurationFrom(5*time.Minute), c.SummaryInterval) },
+		},
+		{"SummaryStages", "K6_SUMMARY_STAGES"}: {
+			"":     func(c Config) { assert.Equal(t, null.Bool{}, c.SummaryStages) },
+			"true": func(c Config) { assert.Equal(t, null.BoolFrom(true), c.SummaryStages) },
+		},
+		{"AnomalyOutput", "K6_ANOMALY_OUTPUT"}: {
+			"":     func(c Config) { assert.Equal(t, null.Bool{}, c.AnomalyOutput) },
+			"true": func(c Config) { assert.Equal(t, null.BoolFrom(true), c.AnomalyOutput) },
+		},
 		{"Out", "K6_OUT"}: {
 			"":         func(c Config) { assert.Equal(t, []string{}, c.Out) },
 			"influxdb": func(c Config) { assert.Equal(t, []string{"influxdb"}, c.Out) },
@@ -131,4 +151,212 @@ func TestConfigApply(t *testing.T) {
 		conf = Config{}.Apply(Config{Out: []string{"influxdb", "json"}})
 		assert.Equal(t, []string{"influxdb", "json"}, conf.Out)
 	})
+	t.Run("SummaryInterval", func(t *testing.T) {
+		conf := Config{}.Apply(Config{SummaryInterval: types.NullDurationFrom(time.Minute)})
+		assert.Equal(t, types.NullDurationFrom(time.Minute), conf.SummaryInterval)
+	})
+	t.Run("SummaryStages", func(t *testing.T) {
+		conf := Config{}.Apply(Config{SummaryStages: null.BoolFrom(true)})
+		assert.Equal(t, null.BoolFrom(true), conf.SummaryStages)
+	})
+	t.Run("AnomalyOutput", func(t *testing.T) {
+		conf := Config{}.Apply(Config{AnomalyOutput: null.BoolFrom(true)})
+		assert.Equal(t, null.BoolFrom(true), conf.AnomalyOutput)
+	})
+	t.Run("MetricRenames", func(t *testing.T) {
+		renames := map[string]map[string]string{"influxdb": {"http_req_duration": "http.request.duration"}}
+		conf := Config{}.Apply(Config{MetricRenames: renames})
+		assert.Equal(t, renames, conf.MetricRenames)
+
+		conf = conf.Apply(Config{})
+		assert.Equal(t, renames, conf.MetricRenames)
+	})
+}
+
+func TestReadDiskConfigYAML(t *testing.T) {
+	defer func() { configFilePath = "" }()
+
+	jsonConfig := `{
+		"vus": 10,
+		"thresholds": {"http_req_duration": ["p(95)<500"]},
+		"execution": {
+			"warmup": {"type": "constant-looping-vus", "vus": 2, "duration": "10s"},
+			"main": {"type": "shared-iterations", "vus": 5, "iterations": 100, "startTime": "10s"}
+		},
+		"collectors": {"csv": {"file_name": "results.csv"}}
+	}`
+	yamlConfig := `
+vus: 10
+thresholds:
+  http_req_duration:
+    - p(95)<500
+execution:
+  warmup:
+    type: constant-looping-vus
+    vus: 2
+    duration: 10s
+  main:
+    type: shared-iterations
+    vus: 5
+    iterations: 100
+    startTime: 10s
+collectors:
+  csv:
+    file_name: results.csv
+`
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/config.json", []byte(jsonConfig), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/config.yaml", []byte(yamlConfig), 0644))
+	require.NoError(t, afero.WriteFile(fs, "/config.yml", []byte(yamlConfig), 0644))
+
+	configFilePath = "/config.json"
+	expected, path, err := readDiskConfig(fs)
+	require.NoError(t, err)
+	assert.Equal(t, "/config.json", path)
+	assert.Equal(t, null.IntFrom(10), expected.VUs)
+	require.Len(t, expected.Execution, 2)
+
+	for _, path := range []string{"/config.yaml", "/config.yml"} {
+		path := path
+		t.Run(path, func(t *testing.T) {
+			configFilePath = path
+			conf, confPath, err := readDiskConfig(fs)
+			require.NoError(t, err)
+			assert.Equal(t, path, confPath)
+			assert.Equal(t, expected.Execution, conf.Execution)
+
+			// Thresholds have their own JS runtimes, so compare the JSON representations instead
+			expectedJSON, err := json.Marshal(expected)
+			require.NoError(t, err)
+			confJSON, err := json.Marshal(conf)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(expectedJSON), string(confJSON))
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(fs, "/invalid.yaml", []byte("vus: [1"), 0644))
+		configFilePath = "/invalid.yaml"
+		_, _, err := readDiskConfig(fs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "couldn't parse the YAML config")
+
+		require.NoError(t, afero.WriteFile(fs, "/wrongtype.yaml", []byte("vus: ten"), 0644))
+		configFilePath = "/wrongtype.yaml"
+		_, _, err = readDiskConfig(fs)
+		assert.Error(t, err)
+	})
+}
+
+func TestEffectiveConfigRoundTrip(t *testing.T) {
+	defer resetStickyGlobalVars()
+	testdata := map[string][]string{
+		"default":    {},
+		"iterations": {"--vus", "5", "--iterations", "20"},
+		"duration":   {"--vus", "3", "--duration", "30s", "--tag", "foo=bar"},
+		"stages":     {"--stage", "10s:5", "--stage", "20s:10", "--system-tags", "url,status"},
+		"everything": {"--vus", "2", "--duration", "1m", "--out", "csv=results.csv", "--no-summary"},
+	}
+	for name, args := range testdata {
+		args := args
+		t.Run(name, func(t *testing.T) {
+			fs := afero.NewMemMapFs()
+			flags := runCmdFlagSet()
+			require.NoError(t, flags.Parse(args))
+
+			ths, err := stats.NewThresholds([]string{"p(95)<500"})
+			require.NoError(t, err)
+			runner := &lib.MiniRunner{Options: lib.Options{
+				Thresholds: map[string]stats.Thresholds{"http_req_duration": ths},
+			}}
+			conf, err := getRunConfig(fs, flags, runner)
+			require.NoError(t, err)
+			require.NotEmpty(t, conf.Execution)
+
+			exported := fillShadowedDefaults(conf)
+			require.NoError(t, writeDiskConfig(fs, "/exported.json", exported))
+			// Config files and env vars are ignored when a config is imported
+			require.NoError(t, afero.WriteFile(fs, defaultConfigFilePath, []byte(`{"vus": 100}`), 0644))
+			imported, err := readEffectiveConfig(fs, "/exported.json")
+			require.NoError(t, err)
+
+			assert.Equal(t, exported.Execution, imported.Execution)
+			for name, sched := range conf.Execution {
+				assert.Equal(t, sched.GetMaxVUs(), imported.Execution[name].GetMaxVUs())
+				assert.Equal(t, sched.GetMaxDuration(), imported.Execution[name].GetMaxDuration())
+			}
+			assert.Equal(t, conf.VUs.Int64, imported.VUs.Int64)
+			assert.Equal(t, conf.VUsMax.Int64, imported.VUsMax.Int64)
+			assert.Equal(t, conf.Duration.Duration, imported.Duration.Duration)
+			assert.Equal(t, conf.Iterations.Int64, imported.Iterations.Int64)
+			assert.Equal(t, conf.Stages, imported.Stages)
+
+			// Thresholds have their own JS runtimes, so compare the JSON representations instead
+			confJSON, err := json.Marshal(exported)
+			require.NoError(t, err)
+			importedJSON, err := json.Marshal(imported)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(confJSON), string(importedJSON))
+		})
+	}
+
+	t.Run("shadowed defaults", func(t *testing.T) {
+		conf := Config{Options: lib.Options{
+			VUs:          null.NewInt(1, false),
+			VUsMax:       null.IntFrom(5),
+			Paused:       null.NewBool(false, false),
+			SetupTimeout: types.NullDuration{Duration: types.Duration(10 * time.Second), Valid: false},
+			Stages:       []lib.Stage{{Duration: types.NullDuration{Duration: types.Duration(time.Second)}}},
+		}}
+		filled := fillShadowedDefaults(conf)
+		assert.Equal(t, null.IntFrom(1), filled.VUs)
+		assert.Equal(t, null.IntFrom(5), filled.VUsMax)
+		assert.Equal(t, null.NewBool(false, false), filled.Paused)
+		assert.Equal(t, types.NullDurationFrom(10*time.Second), filled.SetupTimeout)
+		assert.Equal(t, types.NullDurationFrom(time.Second), filled.Stages[0].Duration)
+
+		// The original config is left untouched
+		assert.Equal(t, null.NewInt(1, false), conf.VUs)
+		assert.False(t, conf.Stages[0].Duration.Valid)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		_, err := readEffectiveConfig(fs, "/missing.json")
+		assert.Error(t, err)
+
+		require.NoError(t, afero.WriteFile(fs, "/invalid.json", []byte(`{"vus": "ten"}`), 0644))
+		_, err = readEffectiveConfig(fs, "/invalid.json")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "couldn't parse the exported config")
+	})
+}
+
+func TestImportedRunConfig(t *testing.T) {
+	defer resetStickyGlobalVars()
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/exported.json", []byte(`{"vus": 5, "iterations": 20}`), 0644))
+
+	t.Run("valid", func(t *testing.T) {
+		flags := runCmdFlagSet()
+		require.NoError(t, flags.Parse([]string{"--import-config", "/exported.json", "--no-setup"}))
+		conf, err := getImportedRunConfig(fs, flags, "/exported.json")
+		require.NoError(t, err)
+		assert.Equal(t, null.IntFrom(5), conf.VUs)
+		assert.Equal(t, null.IntFrom(20), conf.Iterations)
+	})
+
+	t.Run("other config flags", func(t *testing.T) {
+		flags := runCmdFlagSet()
+		require.NoError(t, flags.Parse([]string{"--import-config", "/exported.json", "--out", "json", "--vus", "2"}))
+		_, err := getImportedRunConfig(fs, flags, "/exported.json")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "got --vus, --out")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.NoError(t, afero.WriteFile(fs, "/invalid.json", []byte(`{"summaryTrendStats": ["avg", "foo"]}`), 0644))
+		_, err := getImportedRunConfig(fs, runCmdFlagSet(), "/invalid.json")
+		assert.Error(t, err)
+	})
 }
@@ -48,6 +48,7 @@ func optionFlagSet() *pflag.FlagSet {
 	flags.Int64P("max", "m", 0, "max available virtual users")
 	flags.DurationP("duration", "d", 0, "test duration limit")
 	flags.Int64P("iterations", "i", 0, "script total iteration limit (among all VUs)")
+	flags.Duration("max-duration", 0, "hard limit of the whole run's duration, regardless of the other end conditions")
 	flags.StringSliceP("stage", "s", nil, "add a `stage`, as `[duration]:[target]`")
 	flags.BoolP("paused", "p", false, "start the test in a paused state")
 	flags.Int64("max-redirects", 10, "follow at most n redirects")
@@ -57,10 +58,32 @@ func optionFlagSet() *pflag.FlagSet {
 	flags.String("user-agent", fmt.Sprintf("k6/%s (https://k6.io/)", consts.Version), "user agent for http requests")
 	flags.String("http-debug", "", "log all HTTP requests and responses. Excludes body by default. To include body use '--http-debug=full'")
 	flags.Lookup("http-debug").NoOptDefVal = "headers"
+	flags.Bool("trace-context", false, "add a W3C Trace Context traceparent header with a new trace ID to every HTTP request, "+
+		"enable the trace_id system tag to also tag the request metrics with it")
+	flags.Bool("server-timing", false, "emit the durations from Server-Timing response headers as http_req_server_timing samples")
+	flags.IntSlice("expected-statuses", nil, "HTTP response `statuses` that aren't counted as failed (default 1xx, 2xx and 3xx)")
+	flags.Bool("bucket-urls", false, "replace URL path segments that look like IDs with ':id' in the url and name tags")
+	flags.StringArray("url-bucket-pattern", nil, "a `regex` for the URL path segments that should be bucketed (default numbers and UUIDs)")
 	flags.Bool("insecure-skip-tls-verify", false, "skip verification of TLS certificates")
 	flags.Bool("no-connection-reuse", false, "disable keep-alive connections")
+	flags.StringSlice("proxy", nil, "send HTTP requests through the `url` of a proxy, requests are distributed round-robin between multiple ones")
+	flags.Int64("max-conns-per-host", 0, "limit the connections per host, requests wait for a free one when it's reached, 0 means unlimited")
+	flags.String("sample-timestamps", "",
+		"the timestamps of the samples in the outputs, 'measurement' (default) or 'write' time")
+	flags.Bool("trend-extreme-tags", false, "keep the tags of the min and max samples of trend metrics")
+	flags.Int64("max-response-header-bytes", 0, "fail responses whose headers are larger than this many bytes (default 10MB)")
+	flags.Int64("max-response-headers", 0, "fail responses with more than this many headers, 0 means unlimited")
+	flags.Int64("max-requests-per-connection", 0, "close connections after this many HTTP requests, 0 means unlimited")
 	flags.Bool("no-vu-connection-reuse", false, "don't reuse connections between iterations")
 	flags.Duration("min-iteration-duration", 0, "minimum amount of time k6 will take executing a single iteration")
+	flags.Bool("runtime-stats", false, "emit goroutine and memory allocation metrics for every iteration")
+	flags.Bool("check-fails-iteration", false, "count iterations with failed checks in the iterations_failed metric")
+	flags.Duration("vu-start-jitter", 0, "delay the first iteration of every VU by a random duration below this value")
+	flags.Int64("vu-iteration-rate", 0, "maximum number of iterations that each VU starts per minute")
+	flags.Bool("connection-events", false, "emit events for the opening, reuse and closing of connections")
+	flags.Duration("heartbeat-interval", 0, "emit a k6_heartbeat metric with this interval, to detect stalled runs")
+	flags.Int64("seed", 0, "the `seed` of the random choices in the run, to replay a failed run (default random)")
+	flags.Int64("max-custom-metrics", 0, "drop the samples of custom metrics beyond this many distinct ones, 0 means unlimited")
 	flags.BoolP("throw", "w", false, "throw warnings (like failed http requests) as errors")
 	flags.StringSlice("blacklist-ip", nil, "blacklist an `ip range` from being called")
 
@@ -80,30 +103,54 @@ func optionFlagSet() *pflag.FlagSet {
 	)
 	flags.StringSlice("system-tags", nil, systemTagsCliHelpText)
 	flags.StringSlice("tag", nil, "add a `tag` to be applied to all samples, as `[name]=[value]`")
+	flags.StringSlice("tag-from-env", nil, "add a `tag` with the value of an environment variable to all samples, as `[name]=[ENV_VAR]`")
 	flags.String("console-output", "", "redirects the console logging to the provided output file")
 	flags.Bool("discard-response-bodies", false, "Read but don't process or save HTTP response bodies")
+	flags.Duration("upload-progress-interval", 0, "emit http_upload_bytes samples with this interval during uploads")
+	flags.String("json-numbers", "",
+		"how numbers in JSON response bodies are parsed: 'float' (default), 'string' or 'safe'")
 	return flags
 }
 
 func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 	opts := lib.Options{
-		VUs:                   getNullInt64(flags, "vus"),
-		VUsMax:                getNullInt64(flags, "max"),
-		Duration:              getNullDuration(flags, "duration"),
-		Iterations:            getNullInt64(flags, "iterations"),
-		Paused:                getNullBool(flags, "paused"),
-		MaxRedirects:          getNullInt64(flags, "max-redirects"),
-		Batch:                 getNullInt64(flags, "batch"),
-		BatchPerHost:          getNullInt64(flags, "batch-per-host"),
-		RPS:                   getNullInt64(flags, "rps"),
-		UserAgent:             getNullString(flags, "user-agent"),
-		HTTPDebug:             getNullString(flags, "http-debug"),
-		InsecureSkipTLSVerify: getNullBool(flags, "insecure-skip-tls-verify"),
-		NoConnectionReuse:     getNullBool(flags, "no-connection-reuse"),
-		NoVUConnectionReuse:   getNullBool(flags, "no-vu-connection-reuse"),
-		MinIterationDuration:  getNullDuration(flags, "min-iteration-duration"),
-		Throw:                 getNullBool(flags, "throw"),
-		DiscardResponseBodies: getNullBool(flags, "discard-response-bodies"),
+		VUs:                      getNullInt64(flags, "vus"),
+		VUsMax:                   getNullInt64(flags, "max"),
+		Duration:                 getNullDuration(flags, "duration"),
+		MaxDuration:              getNullDuration(flags, "max-duration"),
+		Iterations:               getNullInt64(flags, "iterations"),
+		Paused:                   getNullBool(flags, "paused"),
+		MaxRedirects:             getNullInt64(flags, "max-redirects"),
+		Batch:                    getNullInt64(flags, "batch"),
+		BatchPerHost:             getNullInt64(flags, "batch-per-host"),
+		RPS:                      getNullInt64(flags, "rps"),
+		UserAgent:                getNullString(flags, "user-agent"),
+		HTTPDebug:                getNullString(flags, "http-debug"),
+		TraceContext:             getNullBool(flags, "trace-context"),
+		ServerTiming:             getNullBool(flags, "server-timing"),
+		BucketURLs:               getNullBool(flags, "bucket-urls"),
+		InsecureSkipTLSVerify:    getNullBool(flags, "insecure-skip-tls-verify"),
+		NoConnectionReuse:        getNullBool(flags, "no-connection-reuse"),
+		NoVUConnectionReuse:      getNullBool(flags, "no-vu-connection-reuse"),
+		MaxConnsPerHost:          getNullInt64(flags, "max-conns-per-host"),
+		SampleTimestamps:         getNullString(flags, "sample-timestamps"),
+		TrendExtremeTags:         getNullBool(flags, "trend-extreme-tags"),
+		MaxResponseHeaderBytes:   getNullInt64(flags, "max-response-header-bytes"),
+		MaxResponseHeaders:       getNullInt64(flags, "max-response-headers"),
+		MaxRequestsPerConnection: getNullInt64(flags, "max-requests-per-connection"),
+		MinIterationDuration:     getNullDuration(flags, "min-iteration-duration"),
+		RuntimeStats:             getNullBool(flags, "runtime-stats"),
+		CheckFailsIteration:      getNullBool(flags, "check-fails-iteration"),
+		VUStartJitter:            getNullDuration(flags, "vu-start-jitter"),
+		VUIterationRate:          getNullInt64(flags, "vu-iteration-rate"),
+		ConnectionEvents:         getNullBool(flags, "connection-events"),
+		HeartbeatInterval:        getNullDuration(flags, "heartbeat-interval"),
+		MaxCustomMetrics:         getNullInt64(flags, "max-custom-metrics"),
+		Throw:                    getNullBool(flags, "throw"),
+		Seed:                     getNullInt64(flags, "seed"),
+		DiscardResponseBodies:    getNullBool(flags, "discard-response-bodies"),
+		UploadProgressInterval:   getNullDuration(flags, "upload-progress-interval"),
+		JSONNumbers:              getNullString(flags, "json-numbers"),
 		// Default values for options without CLI flags:
 		// TODO: find a saner and more dev-friendly and error-proof way to handle options
 		SetupTimeout:    types.NullDuration{Duration: types.Duration(10 * time.Second), Valid: false},
@@ -151,6 +198,33 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		opts.BlacklistIPs = append(opts.BlacklistIPs, net)
 	}
 
+	if flags.Changed("expected-statuses") {
+		if opts.ExpectedStatuses, err = flags.GetIntSlice("expected-statuses"); err != nil {
+			return opts, err
+		}
+	}
+
+	if flags.Changed("proxy") {
+		if opts.Proxies, err = flags.GetStringSlice("proxy"); err != nil {
+			return opts, err
+		}
+	}
+
+	if flags.Changed("url-bucket-pattern") {
+		patterns, err := flags.GetStringArray("url-bucket-pattern")
+		if err != nil {
+			return opts, err
+		}
+		opts.URLBucketPatterns = []*lib.URLBucketPattern{}
+		for _, s := range patterns {
+			pattern := &lib.URLBucketPattern{}
+			if err := pattern.UnmarshalText([]byte(s)); err != nil {
+				return opts, errors.Wrap(err, "url-bucket-pattern")
+			}
+			opts.URLBucketPatterns = append(opts.URLBucketPatterns, pattern)
+		}
+	}
+
 	if flags.Changed("summary-trend-stats") {
 		trendStats, errSts := flags.GetStringSlice("summary-trend-stats")
 		if errSts != nil {
@@ -190,6 +264,22 @@ func getOptions(flags *pflag.FlagSet) (lib.Options, error) {
 		opts.RunTags = stats.IntoSampleTags(&parsedRunTags)
 	}
 
+	tagsFromEnv, err := flags.GetStringSlice("tag-from-env")
+	if err != nil {
+		return opts, err
+	}
+
+	if len(tagsFromEnv) > 0 {
+		opts.TagsFromEnv = make(map[string]string, len(tagsFromEnv))
+		for i, s := range tagsFromEnv {
+			name, envVar, err := parseTagNameValue(s)
+			if err != nil {
+				return opts, errors.Wrapf(err, "tag from env %d", i)
+			}
+			opts.TagsFromEnv[name] = envVar
+		}
+	}
+
 	redirectConFile, err := flags.GetString("console-output")
 	if err != nil {
 		return opts, err
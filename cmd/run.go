@@ -24,8 +24,11 @@ import (
 	"archive/tar"
 	"bytes"
 	"context"
+	crand "crypto/rand"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/signal"
@@ -49,6 +52,7 @@ import (
 	"github.com/loadimpact/k6/lib/consts"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/loader"
+	"github.com/loadimpact/k6/stats"
 	"github.com/loadimpact/k6/ui"
 )
 
@@ -62,13 +66,18 @@ const (
 	genericTimeoutErrorCode      = 102
 	genericEngineErrorCode       = 103
 	invalidConfigErrorCode       = 104
+	smokeTestFailedErrorCode     = 105
+	baselineRegressedErrorCode   = 106
 )
 
 var (
 	//TODO: fix this, global variables are not very testable...
-	runType       = os.Getenv("K6_TYPE")
-	runNoSetup    = os.Getenv("K6_NO_SETUP") != ""
-	runNoTeardown = os.Getenv("K6_NO_TEARDOWN") != ""
+	runType         = os.Getenv("K6_TYPE")
+	runNoSetup      = os.Getenv("K6_NO_SETUP") != ""
+	runNoTeardown   = os.Getenv("K6_NO_TEARDOWN") != ""
+	runExportConfig = os.Getenv("K6_EXPORT_CONFIG")
+	runImportConfig = os.Getenv("K6_IMPORT_CONFIG")
+	runSmoke        = os.Getenv("K6_SMOKE") != ""
 )
 
 // runCmd represents the run command.
@@ -95,6 +104,12 @@ a commandline interface for interacting with it.`,
   # Ramp VUs from 0 to 100 over 10s, stay there for 60s, then 10s down to 0.
   k6 run -u 0 -s 10s:100 -s 60s -s 10s:0
 
+  # Check that the script works with a single iteration, ignoring the configured load.
+  k6 run --smoke script.js
+
+  # Fail if the 95th percentile request duration is more than 10% worse than in a previous run.
+  k6 run --baseline-summary previous.json --baseline-rule "http_req_duration:p(95)<10%" script.js
+
   # Send metrics to an influxdb server
   k6 run -o influxdb=http://1.2.3.4:8086/k6`[1:],
 	Args: exactArgsWithMsg(1, "arg should either be \"-\", if reading script from stdin, or a path to a script file"),
@@ -132,48 +147,36 @@ a commandline interface for interacting with it.`,
 
 		fprintf(stdout, "%s options\r", initBar.String())
 
-		cliConf, err := getConfig(cmd.Flags())
-		if err != nil {
-			return err
+		fs := afero.NewOsFs()
+		var conf Config
+		if runImportConfig != "" {
+			// An imported config is already fully resolved, so no other sources are consulted
+			conf, err = getImportedRunConfig(fs, cmd.Flags(), runImportConfig)
+		} else {
+			conf, err = getRunConfig(fs, cmd.Flags(), r)
 		}
-		conf, err := getConsolidatedConfig(afero.NewOsFs(), cliConf, r)
 		if err != nil {
 			return err
 		}
-
-		// If -m/--max isn't specified, figure out the max that should be needed.
-		if !conf.VUsMax.Valid {
-			conf.VUsMax = null.NewInt(conf.VUs.Int64, conf.VUs.Valid)
-			for _, stage := range conf.Stages {
-				if stage.Target.Valid && stage.Target.Int64 > conf.VUsMax.Int64 {
-					conf.VUsMax = stage.Target
-				}
-			}
+		// Every run gets a seed, so that a failed run can be replayed with the same random choices
+		if !conf.Seed.Valid {
+			conf.Seed = null.IntFrom(newRunSeed())
 		}
-
-		// If -d/--duration, -i/--iterations and -s/--stage are all unset, run to one iteration.
-		if !conf.Duration.Valid && !conf.Iterations.Valid && len(conf.Stages) == 0 {
-			conf.Iterations = null.IntFrom(1)
+		runFailed := func(err error) error {
+			logrus.Warnf("The seed of the failed run was %d, use --seed %d to replay it", conf.Seed.Int64, conf.Seed.Int64)
+			return err
 		}
-
-		if conf.Iterations.Valid && conf.Iterations.Int64 < conf.VUsMax.Int64 {
-			logrus.Warnf(
-				"All iterations (%d in this test run) are shared between all VUs, so some of the %d VUs will not execute even a single iteration!",
-				conf.Iterations.Int64, conf.VUsMax.Int64,
-			)
+		if runExportConfig != "" {
+			if err = writeDiskConfig(fs, runExportConfig, fillShadowedDefaults(conf)); err != nil {
+				return err
+			}
 		}
-
-		//TODO: move a bunch of the logic above to a config "constructor" and to the Validate() method
-
-		// If duration is explicitly set to 0, it means run forever.
-		//TODO: just... handle this differently, e.g. as a part of the manual executor
-		if conf.Duration.Valid && conf.Duration.Duration == 0 {
-			conf.Duration = types.NullDuration{}
+		if runSmoke {
+			conf = applySmokeConfig(conf)
 		}
-
-		conf, cerr := deriveAndValidateConfig(conf)
-		if cerr != nil {
-			return ExitCode{error: cerr, Code: invalidConfigErrorCode}
+		baselineRules, err := getBaselineRules(conf)
+		if err != nil {
+			return ExitCode{error: err, Code: invalidConfigErrorCode}
 		}
 
 		// Write options back to the runner too.
@@ -181,6 +184,13 @@ a commandline interface for interacting with it.`,
 			return err
 		}
 
+		// Let thresholds use the same environment variables as the script, so their values
+		// can be supplied externally.
+		scriptEnv := r.MakeArchive().Env
+		for _, ths := range conf.Thresholds {
+			ths.SetEnv(scriptEnv)
+		}
+
 		// Create a local executor wrapping the runner.
 		fprintf(stdout, "%s executor\r", initBar.String())
 		ex := local.New(r)
@@ -208,6 +218,29 @@ a commandline interface for interacting with it.`,
 		if conf.SummaryExport.Valid {
 			engine.SummaryExport = conf.SummaryExport.String != ""
 		}
+		if len(baselineRules) > 0 {
+			engine.SummaryExport = true // the comparison needs the same metrics as the export
+		}
+		engine.StageSummary = conf.SummaryStages.Bool
+		if conf.AnomalyOutput.Bool {
+			if conf.NoThresholds.Bool {
+				return ExitCode{error: errors.New("anomaly output mode can't be used together with no-thresholds"), Code: invalidConfigErrorCode}
+			}
+			engine.AnomalyOutput = true
+		}
+		if interval := time.Duration(conf.SummaryInterval.Duration); interval > 0 {
+			engine.PartialSummaryInterval = interval
+			engine.PartialSummary = func(metrics map[string]*stats.Metric, t time.Duration) {
+				fprintf(stdout, "\x1b[0K\n     partial summary at %s:\n\n", ui.ValueColor.Sprint(t.Truncate(time.Second)))
+				s := ui.NewSummary(conf.SummaryTrendStats)
+				s.SummarizeMetrics(stdout, "", ui.SummaryData{
+					Metrics:  metrics,
+					Time:     t,
+					TimeUnit: conf.Options.SummaryTimeUnit.String,
+				})
+				fprintf(stdout, "\n")
+			}
+		}
 
 		// Create a collector and assign it to the engine if requested.
 		fprintf(stdout, "%s   collector\r", initBar.String())
@@ -405,15 +438,17 @@ a commandline interface for interacting with it.`,
 				case lib.TimeoutError:
 					switch e.Place() {
 					case "setup":
-						return ExitCode{error: err, Code: setupTimeoutErrorCode, Hint: e.Hint()}
+						return runFailed(ExitCode{error: err, Code: setupTimeoutErrorCode, Hint: e.Hint()})
 					case "teardown":
-						return ExitCode{error: err, Code: teardownTimeoutErrorCode, Hint: e.Hint()}
+						return runFailed(ExitCode{error: err, Code: teardownTimeoutErrorCode, Hint: e.Hint()})
 					default:
-						return ExitCode{error: err, Code: genericTimeoutErrorCode}
+						return runFailed(ExitCode{error: err, Code: genericTimeoutErrorCode})
 					}
 				default:
 					//nolint:golint
-					return ExitCode{error: errors.New("Engine error"), Code: genericEngineErrorCode, Hint: err.Error()}
+					return runFailed(ExitCode{
+						error: errors.New("Engine error"), Code: genericEngineErrorCode, Hint: err.Error(),
+					})
 				}
 			case sig := <-sigC:
 				logrus.WithField("sig", sig).Debug("Exiting in response to signal")
@@ -445,6 +480,15 @@ a commandline interface for interacting with it.`,
 			RootGroup: engine.Executor.GetRunner().GetDefaultGroup(),
 			Time:      engine.Executor.GetTime(),
 			TimeUnit:  conf.Options.SummaryTimeUnit.String,
+
+			TrendValues: conf.SummaryExportValues.Bool,
+		}
+		for _, sm := range engine.StageMetrics {
+			stage := ui.SummaryStage{Index: sm.Index, Duration: sm.Duration(), Metrics: sm.Metrics}
+			if sm.Index < len(conf.Stages) {
+				stage.Stage = conf.Stages[sm.Index]
+			}
+			data.Stages = append(data.Stages, stage)
 		}
 		// Print the end-of-test summary.
 		if !conf.NoSummary.Bool {
@@ -473,18 +517,198 @@ a commandline interface for interacting with it.`,
 			}
 		}
 
+		var baselineErr error
+		if len(baselineRules) > 0 {
+			baselineErr = compareWithBaseline(fs, stdout, conf, baselineRules, data)
+		}
+
 		if conf.Linger.Bool {
 			logrus.Info("Linger set; waiting for Ctrl+C...")
 			<-sigC
 		}
 
+		if runSmoke {
+			if failed := ex.GetFailedIterations(); failed > 0 {
+				return runFailed(ExitCode{
+					error: errors.New("the smoke test failed, the script iteration returned an error"),
+					Code:  smokeTestFailedErrorCode,
+				})
+			}
+			logrus.Info("The smoke test passed")
+		}
+
 		if engine.IsTainted() {
-			return ExitCode{error: errors.New("some thresholds have failed"), Code: thresholdHaveFailedErrorCode}
+			return runFailed(ExitCode{error: errors.New("some thresholds have failed"), Code: thresholdHaveFailedErrorCode})
+		}
+		if baselineErr != nil {
+			return runFailed(baselineErr)
 		}
 		return nil
 	},
 }
 
+// getBaselineRules parses the rules for the comparison with the baseline summary
+func getBaselineRules(conf Config) ([]ui.SummaryComparisonRule, error) {
+	hasBaseline := conf.BaselineSummary.ValueOrZero() != ""
+	if hasBaseline != (len(conf.BaselineRules) > 0) {
+		return nil, errors.New("--baseline-summary and --baseline-rule have to be used together")
+	}
+	rules := make([]ui.SummaryComparisonRule, len(conf.BaselineRules))
+	for i, s := range conf.BaselineRules {
+		rule, err := ui.ParseSummaryComparisonRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules[i] = rule
+	}
+	return rules, nil
+}
+
+// compareWithBaseline compares the summary of the run with the baseline summary and prints the
+// results of the rules. If any of them has regressed, an ExitCode error is returned.
+func compareWithBaseline(
+	fs afero.Fs, w io.Writer, conf Config, rules []ui.SummaryComparisonRule, data ui.SummaryData,
+) error {
+	baseline, err := fs.Open(conf.BaselineSummary.String)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = baseline.Close() }()
+
+	for _, rule := range rules {
+		data.TrendValues = data.TrendValues || rule.UsesValues()
+	}
+	var current bytes.Buffer
+	if err = ui.NewSummary(conf.SummaryTrendStats).SummarizeMetricsJSON(&current, data); err != nil {
+		return err
+	}
+	results, regressed, err := ui.CompareSummaries(baseline, &current, rules)
+	if err != nil {
+		return err
+	}
+
+	fprintf(w, "     comparison with the baseline %s:\n\n", conf.BaselineSummary.String)
+	for _, res := range results {
+		fprintf(w, "     %s\n", res)
+	}
+	fprintf(w, "\n")
+	if regressed {
+		return ExitCode{
+			error: errors.New("some metrics have regressed compared to the baseline"),
+			Code:  baselineRegressedErrorCode,
+		}
+	}
+	return nil
+}
+
+// newRunSeed returns a random seed for a run that wasn't given one
+func newRunSeed() int64 {
+	var b [8]byte
+	if _, err := crand.Read(b[:]); err != nil {
+		return time.Now().UnixNano()
+	}
+	return int64(binary.LittleEndian.Uint64(b[:]) >> 1)
+}
+
+// getRunConfig consolidates the configuration from all of the different sources and derives
+// the execution settings from it, i.e. it returns the effective configuration for the test run.
+func getRunConfig(fs afero.Fs, flags *pflag.FlagSet, r lib.Runner) (Config, error) {
+	cliConf, err := getConfig(flags)
+	if err != nil {
+		return Config{}, err
+	}
+	conf, err := getConsolidatedConfig(fs, cliConf, r)
+	if err != nil {
+		return conf, err
+	}
+
+	// If -m/--max isn't specified, figure out the max that should be needed.
+	if !conf.VUsMax.Valid {
+		conf.VUsMax = null.NewInt(conf.VUs.Int64, conf.VUs.Valid)
+		for _, stage := range conf.Stages {
+			if stage.Target.Valid && stage.Target.Int64 > conf.VUsMax.Int64 {
+				conf.VUsMax = stage.Target
+			}
+		}
+	}
+
+	// If -d/--duration, -i/--iterations and -s/--stage are all unset, run to one iteration.
+	if !conf.Duration.Valid && !conf.Iterations.Valid && len(conf.Stages) == 0 {
+		conf.Iterations = null.IntFrom(1)
+	}
+
+	if conf.Iterations.Valid && conf.Iterations.Int64 < conf.VUsMax.Int64 {
+		logrus.Warnf(
+			"All iterations (%d in this test run) are shared between all VUs, so some of the %d VUs will not execute even a single iteration!",
+			conf.Iterations.Int64, conf.VUsMax.Int64,
+		)
+	}
+
+	//TODO: move a bunch of the logic above to a config "constructor" and to the Validate() method
+
+	// If duration is explicitly set to 0, it means run forever.
+	//TODO: just... handle this differently, e.g. as a part of the manual executor
+	if conf.Duration.Valid && conf.Duration.Duration == 0 {
+		conf.Duration = types.NullDuration{}
+	}
+
+	conf, cerr := deriveAndValidateConfig(conf)
+	if cerr != nil {
+		return conf, ExitCode{error: cerr, Code: invalidConfigErrorCode}
+	}
+	return conf, nil
+}
+
+// getImportedRunConfig reads a configuration file that was written by --export-config. Its
+// execution was already derived, so it's used as is, but it's still validated like any other
+// configuration. The other configuration flags would be silently ignored, so they're rejected.
+func getImportedRunConfig(fs afero.Fs, flags *pflag.FlagSet, configPath string) (Config, error) {
+	var conflicting []string
+	configFlags := optionFlagSet()
+	configFlags.AddFlagSet(configFlagSet())
+	configFlags.VisitAll(func(f *pflag.Flag) {
+		if flag := flags.Lookup(f.Name); flag != nil && flag.Changed {
+			conflicting = append(conflicting, "--"+f.Name)
+		}
+	})
+	if len(conflicting) > 0 {
+		return Config{}, ExitCode{error: fmt.Errorf(
+			"--import-config can't be combined with other configuration flags, got %s",
+			strings.Join(conflicting, ", "),
+		), Code: invalidConfigErrorCode}
+	}
+
+	conf, err := readEffectiveConfig(fs, configPath)
+	if err != nil {
+		return conf, err
+	}
+	if err = ui.ValidateSummary(conf.SummaryTrendStats); err != nil {
+		return conf, ExitCode{error: err, Code: invalidConfigErrorCode}
+	}
+	if err = validateConfig(conf); err != nil {
+		return conf, ExitCode{error: err, Code: invalidConfigErrorCode}
+	}
+	return conf, nil
+}
+
+// applySmokeConfig replaces the load profile of the configuration with a single VU running a
+// single iteration, so script errors can be found quickly, before the actual test run. A single
+// iteration says nothing about the thresholds and shouldn't end up in the outputs or be compared
+// with a baseline either, so all of those are disabled.
+func applySmokeConfig(conf Config) Config {
+	conf.VUs = null.IntFrom(1)
+	conf.VUsMax = null.IntFrom(1)
+	conf.Iterations = null.IntFrom(1)
+	conf.Duration = types.NullDuration{}
+	conf.Stages = nil
+	conf.NoThresholds = null.BoolFrom(true)
+	conf.AnomalyOutput = null.BoolFrom(false)
+	conf.Out = nil
+	conf.BaselineSummary = null.String{}
+	conf.BaselineRules = nil
+	return conf
+}
+
 func runCmdFlagSet() *pflag.FlagSet {
 	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
 	flags.SortFlags = false
@@ -506,6 +730,15 @@ func runCmdFlagSet() *pflag.FlagSet {
 	flags.Lookup("no-setup").DefValue = falseStr
 	flags.BoolVar(&runNoTeardown, "no-teardown", runNoTeardown, "don't run teardown()")
 	flags.Lookup("no-teardown").DefValue = falseStr
+	flags.StringVar(&runExportConfig, "export-config", runExportConfig,
+		"write the fully resolved configuration of the test run to a JSON `file`")
+	flags.Lookup("export-config").DefValue = ""
+	flags.StringVar(&runImportConfig, "import-config", runImportConfig,
+		"use a configuration `file` written by --export-config as is, without any other configuration flags")
+	flags.Lookup("import-config").DefValue = ""
+	flags.BoolVar(&runSmoke, "smoke", runSmoke,
+		"run the script once, with a single VU and without thresholds and outputs, and fail if the iteration returns an error")
+	flags.Lookup("smoke").DefValue = falseStr
 	return flags
 }
 
@@ -0,0 +1,181 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/url"
+	"testing"
+	"time"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/core"
+	"github.com/loadimpact/k6/core/local"
+	"github.com/loadimpact/k6/js"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/loader"
+	"github.com/loadimpact/k6/stats"
+	"github.com/loadimpact/k6/ui"
+)
+
+func TestApplySmokeConfig(t *testing.T) {
+	conf := applySmokeConfig(Config{Options: lib.Options{
+		VUs:        null.IntFrom(10),
+		VUsMax:     null.IntFrom(20),
+		Duration:   types.NullDurationFrom(time.Hour),
+		Iterations: null.IntFrom(1000),
+		Stages:     []lib.Stage{{Duration: types.NullDurationFrom(time.Minute), Target: null.IntFrom(20)}},
+	}, Out: []string{"json=results.json"}, AnomalyOutput: null.BoolFrom(true),
+		BaselineSummary: null.StringFrom("previous.json"), BaselineRules: []string{"http_req_duration:avg<10%"}})
+	assert.Equal(t, null.IntFrom(1), conf.VUs)
+	assert.Equal(t, null.IntFrom(1), conf.VUsMax)
+	assert.Equal(t, null.IntFrom(1), conf.Iterations)
+	assert.False(t, conf.Duration.Valid)
+	assert.Empty(t, conf.Stages)
+	assert.Equal(t, null.BoolFrom(true), conf.NoThresholds)
+	assert.Equal(t, null.BoolFrom(false), conf.AnomalyOutput)
+	assert.Empty(t, conf.Out)
+	assert.False(t, conf.BaselineSummary.Valid)
+	assert.Empty(t, conf.BaselineRules)
+}
+
+func TestSmokeRun(t *testing.T) {
+	t.Parallel()
+	smokeRun := func(t *testing.T, script string) *local.Executor {
+		r, err := js.New(
+			&loader.SourceData{URL: &url.URL{Path: "/script.js"}, Data: []byte(`
+			export let options = { vus: 10, duration: "1h" };
+			` + script)},
+			nil,
+			lib.RuntimeOptions{},
+		)
+		require.NoError(t, err)
+
+		conf := applySmokeConfig(Config{Options: r.GetOptions()})
+		require.NoError(t, r.SetOptions(conf.Options))
+
+		logger, _ := logtest.NewNullLogger()
+		ex := local.New(r)
+		ex.SetLogger(logger)
+		engine, err := core.NewEngine(ex, conf.Options)
+		require.NoError(t, err)
+		engine.SetLogger(logger)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, engine.Run(ctx))
+		require.Equal(t, int64(1), ex.GetIterations(), "the smoke test timed out")
+		return ex
+	}
+
+	t.Run("passed", func(t *testing.T) {
+		t.Parallel()
+		ex := smokeRun(t, `export default function() { JSON.parse("{}"); }`)
+		assert.Equal(t, int64(0), ex.GetFailedIterations())
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		t.Parallel()
+		ex := smokeRun(t, `export default function() { undefinedFunction(); }`)
+		assert.Equal(t, int64(1), ex.GetFailedIterations())
+	})
+}
+
+func TestCompareWithBaseline(t *testing.T) {
+	rootG, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	summaryData := func(durations ...float64) ui.SummaryData {
+		m := stats.New("http_req_duration", stats.Trend, stats.Time)
+		for _, d := range durations {
+			m.Sink.Add(stats.Sample{Value: d})
+		}
+		return ui.SummaryData{Metrics: map[string]*stats.Metric{m.Name: m}, RootGroup: rootG, Time: time.Second}
+	}
+
+	fs := afero.NewMemMapFs()
+	f, err := fs.Create("/baseline.json")
+	require.NoError(t, err)
+	require.NoError(t, ui.NewSummary(nil).SummarizeMetricsJSON(f, summaryData(100, 100, 100)))
+	require.NoError(t, f.Close())
+
+	conf := Config{
+		BaselineSummary: null.StringFrom("/baseline.json"),
+		BaselineRules:   []string{"http_req_duration:avg<10%"},
+	}
+	rules, err := getBaselineRules(conf)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	assert.NoError(t, compareWithBaseline(fs, &out, conf, rules, summaryData(105, 105, 105)))
+	assert.Contains(t, out.String(), "http_req_duration{avg}: 100 -> 105")
+
+	err = compareWithBaseline(fs, &out, conf, rules, summaryData(120, 120, 120))
+	if assert.IsType(t, ExitCode{}, err) {
+		assert.Equal(t, baselineRegressedErrorCode, err.(ExitCode).Code)
+	}
+
+	// The values of the current run are exported for the Mann-Whitney U test automatically
+	baselineData := summaryData(100, 101, 102, 103, 104, 105, 106, 107, 108, 109)
+	baselineData.TrendValues = true
+	f, err = fs.Create("/baseline-values.json")
+	require.NoError(t, err)
+	require.NoError(t, ui.NewSummary(nil).SummarizeMetricsJSON(f, baselineData))
+	require.NoError(t, f.Close())
+	conf = Config{
+		BaselineSummary: null.StringFrom("/baseline-values.json"),
+		BaselineRules:   []string{"http_req_duration:mannwhitney<0.05"},
+	}
+	rules, err = getBaselineRules(conf)
+	require.NoError(t, err)
+	assert.NoError(t, compareWithBaseline(fs, &out, conf, rules, summaryData(100, 102, 104, 106, 108)))
+	err = compareWithBaseline(fs, &out, conf, rules, summaryData(150, 151, 152, 153, 154, 155, 156, 157, 158, 159))
+	if assert.IsType(t, ExitCode{}, err) {
+		assert.Equal(t, baselineRegressedErrorCode, err.(ExitCode).Code)
+	}
+
+	conf.BaselineSummary = null.StringFrom("/nonexistent.json")
+	err = compareWithBaseline(fs, &out, conf, rules, summaryData(100))
+	assert.Error(t, err)
+	_, isExitCode := err.(ExitCode)
+	assert.False(t, isExitCode, "a missing baseline isn't a regression")
+}
+
+func TestGetBaselineRules(t *testing.T) {
+	rules, err := getBaselineRules(Config{})
+	assert.NoError(t, err)
+	assert.Empty(t, rules)
+
+	_, err = getBaselineRules(Config{BaselineSummary: null.StringFrom("previous.json")})
+	assert.EqualError(t, err, "--baseline-summary and --baseline-rule have to be used together")
+	_, err = getBaselineRules(Config{BaselineRules: []string{"http_req_duration:avg<10%"}})
+	assert.EqualError(t, err, "--baseline-summary and --baseline-rule have to be used together")
+	_, err = getBaselineRules(Config{
+		BaselineSummary: null.StringFrom("previous.json"), BaselineRules: []string{"http_req_duration"},
+	})
+	assert.Error(t, err)
+}
@@ -49,6 +49,21 @@ func collectEnv() map[string]string {
 	return env
 }
 
+// collectTagsEnv returns the environment that the values of tagsFromEnv are resolved from: the
+// system environment, overridden by the variables the script sees in __ENV (e.g. from --env).
+func collectTagsEnv(runner lib.Runner) map[string]string {
+	env := collectEnv()
+	if runner == nil {
+		return env
+	}
+	if arc := runner.MakeArchive(); arc != nil {
+		for k, v := range arc.Env {
+			env[k] = v
+		}
+	}
+	return env
+}
+
 func runtimeOptionFlagSet(includeSysEnv bool) *pflag.FlagSet {
 	flags := pflag.NewFlagSet("", 0)
 	flags.SortFlags = false
@@ -267,3 +267,34 @@ func TestEnvVars(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectTagsEnv(t *testing.T) {
+	os.Clearenv()
+	require.NoError(t, os.Setenv("GIT_COMMIT", "system"))
+	require.NoError(t, os.Setenv("BUILD_ID", "42"))
+	defer os.Clearenv()
+
+	assert.Equal(t, map[string]string{"GIT_COMMIT": "system", "BUILD_ID": "42"}, collectTagsEnv(nil))
+
+	jsCode := []byte("export default function() {}")
+	fs := afero.NewMemMapFs()
+	require.NoError(t, afero.WriteFile(fs, "/script.js", jsCode, 0644))
+	runner, err := newRunner(
+		&loader.SourceData{Data: jsCode, URL: &url.URL{Path: "/script.js", Scheme: "file"}},
+		typeJS,
+		map[string]afero.Fs{"file": fs},
+		lib.RuntimeOptions{Env: map[string]string{"GIT_COMMIT": "flag", "RELEASE": "1.0"}},
+	)
+	require.NoError(t, err)
+
+	env := collectTagsEnv(runner)
+	assert.Equal(t, "flag", env["GIT_COMMIT"])
+	assert.Equal(t, "42", env["BUILD_ID"])
+	assert.Equal(t, "1.0", env["RELEASE"])
+
+	opts := lib.Options{TagsFromEnv: map[string]string{"commit": "GIT_COMMIT", "release": "RELEASE"}}
+	assert.Equal(t,
+		map[string]string{"commit": "flag", "release": "1.0"},
+		opts.GetRunTagsWithEnv(env).CloneTags(),
+	)
+}
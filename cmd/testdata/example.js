@@ -395,6 +395,8 @@ export default function() {
 		res = http.connect("https://a-third-host.example.com:3000",
 		""
 		)
+		// Random sleep between 20s and 40s
+		sleep(Math.floor(Math.random()*20+20));
 	});
 
 }
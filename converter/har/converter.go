@@ -304,24 +304,26 @@ func Convert(h HAR, options lib.Options, minSleep, maxSleep uint, enableChecks b
 					fprintf(w, "\t\tsleep(%.2f);\n", t)
 				}
 			}
+		}
 
-			if i == len(pages)-1 {
-				// Last page; add random sleep time at the group completion
-				fprintf(w, "\t\t// Random sleep between %ds and %ds\n", minSleep, maxSleep)
-				fprintf(w, "\t\tsleep(Math.floor(Math.random()*%d+%d));\n", maxSleep-minSleep, minSleep)
-			} else {
-				// Add sleep time at the end of the group
-				nextPage := pages[i+1]
-				sleepTime := 0.5
-				if len(entries) > 0 {
-					lastEntry := entries[len(entries)-1]
-					t := nextPage.StartedDateTime.Sub(lastEntry.StartedDateTime).Seconds()
-					if t >= 0.01 {
-						sleepTime = t
-					}
+		// Think time between pages, so both batched and non-batched scripts
+		// keep the pacing of the original recording
+		if i == len(pages)-1 {
+			// Last page; add random sleep time at the group completion
+			fprintf(w, "\t\t// Random sleep between %ds and %ds\n", minSleep, maxSleep)
+			fprintf(w, "\t\tsleep(Math.floor(Math.random()*%d+%d));\n", maxSleep-minSleep, minSleep)
+		} else {
+			// Add sleep time at the end of the group
+			nextPage := pages[i+1]
+			sleepTime := 0.5
+			if len(entries) > 0 {
+				lastEntry := entries[len(entries)-1]
+				t := nextPage.StartedDateTime.Sub(lastEntry.StartedDateTime).Seconds()
+				if t >= 0.01 {
+					sleepTime = t
 				}
-				fprintf(w, "\t\tsleep(%.2f);\n", sleepTime)
 			}
+			fprintf(w, "\t\tsleep(%.2f);\n", sleepTime)
 		}
 
 		fprint(w, "\t});\n")
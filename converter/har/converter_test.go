@@ -23,12 +23,15 @@ package har
 import (
 	"fmt"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/loadimpact/k6/js"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/loader"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBuildK6Headers(t *testing.T) {
@@ -100,3 +103,63 @@ func TestBuildK6Body(t *testing.T) {
 	assert.Equal(t, len(postParams), 2, "postParams should have two items")
 	assert.Equal(t, postParams[0], expectedEmailParam, "expected unescaped value")
 }
+
+func TestConvert(t *testing.T) {
+	start := time.Date(2019, 1, 1, 10, 0, 0, 0, time.UTC)
+	h := HAR{Log: &Log{
+		Version: "1.2",
+		Creator: &Creator{Name: "test"},
+		Pages: []Page{
+			{ID: "page_1", Title: "Home", StartedDateTime: start},
+			{ID: "page_2", Title: "Checkout", StartedDateTime: start.Add(3 * time.Second)},
+		},
+		Entries: []*Entry{
+			{
+				Pageref:         "page_1",
+				StartedDateTime: start,
+				Request:         &Request{Method: "GET", URL: "https://example.com/"},
+			},
+			{
+				Pageref:         "page_1",
+				StartedDateTime: start.Add(2 * time.Second),
+				Request:         &Request{Method: "GET", URL: "https://example.com/style.css"},
+			},
+			{
+				Pageref:         "page_2",
+				StartedDateTime: start.Add(3 * time.Second),
+				Request: &Request{
+					Method:   "POST",
+					URL:      "https://example.com/checkout",
+					PostData: &PostData{MimeType: "text/plain", Text: "order=1"},
+				},
+			},
+		},
+	}}
+
+	for _, nobatch := range []bool{false, true} {
+		nobatch := nobatch
+		t.Run(fmt.Sprintf("nobatch=%t", nobatch), func(t *testing.T) {
+			script, err := Convert(h, lib.Options{}, 1, 2, false, false, 500, nobatch, false, nil, nil)
+			require.NoError(t, err)
+
+			home := strings.Index(script, `group("page_1 - Home"`)
+			checkout := strings.Index(script, `group("page_2 - Checkout"`)
+			require.True(t, home >= 0, "missing the first page group")
+			require.True(t, checkout > home, "page groups are not in order")
+
+			homeGroup, checkoutGroup := script[home:checkout], script[checkout:]
+			assert.Contains(t, homeGroup, `"https://example.com/"`)
+			assert.Contains(t, homeGroup, `"https://example.com/style.css"`)
+			assert.Contains(t, homeGroup, "sleep(1.00);")
+			assert.NotContains(t, homeGroup, "https://example.com/checkout")
+			assert.Contains(t, checkoutGroup, `"https://example.com/checkout"`)
+			assert.Contains(t, checkoutGroup, "sleep(Math.floor(Math.random()*1+1));")
+
+			_, err = js.New(&loader.SourceData{
+				URL:  &url.URL{Path: "/script.js"},
+				Data: []byte(script),
+			}, nil, lib.RuntimeOptions{})
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -57,6 +57,25 @@ type Engine struct {
 	NoSummary     bool
 	SummaryExport bool
 
+	// If both are set, PartialSummary is called every PartialSummaryInterval during the test
+	// run with the metrics aggregated so far and the current test time. It's called while the
+	// metrics are locked, so it shouldn't keep any references to them.
+	PartialSummaryInterval time.Duration
+	PartialSummary         func(metrics map[string]*stats.Metric, t time.Duration)
+
+	// If set, the metrics are also aggregated separately for every stage of the test, based on
+	// the stage markers sent by the executor, in StageMetrics.
+	StageSummary bool
+	StageMetrics []*StageMetrics
+
+	// If set, the samples are held back from the collectors until the end of each ThresholdsRate
+	// window, and are only passed on if a threshold fails for the samples of that window alone.
+	// That way a breach earlier in the run doesn't keep sending the samples after it's over.
+	// The results of the thresholds themselves are still based on the whole run.
+	AnomalyOutput bool
+	anomalyBuffer []stats.SampleContainer
+	anomalySinks  map[*stats.Metric]stats.Sink
+
 	logger *logrus.Logger
 
 	Metrics     map[string]*stats.Metric
@@ -70,6 +89,26 @@ type Engine struct {
 
 	// Are thresholds tainted?
 	thresholdsTainted bool
+
+	// The custom metrics that have been seen, and the ones over the maxCustomMetrics limit.
+	customMetrics  map[string]bool
+	droppedMetrics map[string]bool
+}
+
+// StageMetrics are the metrics aggregated for a single stage of the test
+type StageMetrics struct {
+	Index   int
+	Start   time.Time
+	End     time.Time // zero while the stage is running
+	Metrics map[string]*stats.Metric
+}
+
+// Duration returns how long the stage ran, or has been running so far
+func (sm *StageMetrics) Duration() time.Duration {
+	if sm.End.IsZero() {
+		return time.Since(sm.Start)
+	}
+	return sm.End.Sub(sm.Start)
 }
 
 func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
@@ -82,6 +121,9 @@ func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
 		Options:  o,
 		Metrics:  make(map[string]*stats.Metric),
 		Samples:  make(chan stats.SampleContainer, o.MetricSamplesBufferSize.Int64),
+
+		customMetrics:  make(map[string]bool),
+		droppedMetrics: make(map[string]bool),
 	}
 	e.SetLogger(logrus.StandardLogger())
 
@@ -96,6 +138,10 @@ func NewEngine(ex lib.Executor, o lib.Options) (*Engine, error) {
 	ex.SetEndTime(o.Duration)
 	ex.SetEndIterations(o.Iterations)
 
+	if mrs, ok := ex.GetRunner().(lib.MetricsReaderSetter); ok {
+		mrs.SetMetricsReader(e)
+	}
+
 	e.thresholds = o.Thresholds
 	e.submetrics = make(map[string][]*stats.Submetric)
 	for name := range e.thresholds {
@@ -163,6 +209,26 @@ func (e *Engine) Run(ctx context.Context) error {
 		subwg.Done()
 	}()
 
+	// Run heartbeat emission, if enabled.
+	if interval := time.Duration(e.Options.HeartbeatInterval.Duration); interval > 0 {
+		subwg.Add(1)
+		go func() {
+			e.runHeartbeat(subctx, interval)
+			e.logger.Debug("Engine: Heartbeat terminated")
+			subwg.Done()
+		}()
+	}
+
+	// Run the partial summaries, if enabled.
+	if e.PartialSummaryInterval > 0 && e.PartialSummary != nil {
+		subwg.Add(1)
+		go func() {
+			e.runPartialSummaries(subctx)
+			e.logger.Debug("Engine: Partial summaries terminated")
+			subwg.Done()
+		}()
+	}
+
 	// Run thresholds.
 	if !e.NoThresholds {
 		subwg.Add(1)
@@ -203,6 +269,7 @@ func (e *Engine) Run(ctx context.Context) error {
 		}
 
 		e.processSamples(sampleContainers)
+		e.endStage(time.Now())
 
 		// Emit final metrics.
 		e.emitMetrics()
@@ -212,11 +279,22 @@ func (e *Engine) Run(ctx context.Context) error {
 			e.processThresholds(nil)
 		}
 
-		// Finally, shut down collector.
+		// Finally, shut down collector. This has to happen only after all of the remaining samples
+		// were passed to Collect(), regardless of whether the test finished normally or was
+		// aborted by the user, an error or a threshold - collectors do their last commit when
+		// their context is done, so anything collected after that would be lost.
 		collectorcancel()
 		collectorwg.Wait()
 	}()
 
+	// A nil channel blocks forever, so this is a no-op without a maxDuration.
+	var maxDurationC <-chan time.Time
+	if maxDuration := time.Duration(e.Options.MaxDuration.Duration); maxDuration > 0 {
+		maxDurationTimer := time.NewTimer(maxDuration)
+		defer maxDurationTimer.Stop()
+		maxDurationC = maxDurationTimer.C
+	}
+
 	ticker := time.NewTicker(CollectRate)
 	for {
 		select {
@@ -236,6 +314,10 @@ func (e *Engine) Run(ctx context.Context) error {
 			}
 			e.logger.Debug("run: executor terminated")
 			return nil
+		case <-maxDurationC:
+			e.logger.Warnf("The run reached the maxDuration limit of %s and was stopped", e.Options.MaxDuration.Duration)
+			e.setRunStatus(lib.RunStatusTimedOut)
+			return nil
 		case <-ctx.Done():
 			e.logger.Debug("run: context expired; exiting...")
 			e.setRunStatus(lib.RunStatusAbortedUser)
@@ -244,6 +326,20 @@ func (e *Engine) Run(ctx context.Context) error {
 	}
 }
 
+// GetMetricSnapshot implements the lib.MetricsReader interface. It returns the current formatted
+// sink values of the metric with the given name. Metrics are only aggregated by the engine if
+// thresholds or the end-of-test summary are enabled.
+func (e *Engine) GetMetricSnapshot(name string) (map[string]float64, bool) {
+	e.MetricsLock.Lock()
+	defer e.MetricsLock.Unlock()
+
+	m, ok := e.Metrics[name]
+	if !ok {
+		return nil, false
+	}
+	return m.Sink.Format(e.Executor.GetTime()), true
+}
+
 func (e *Engine) IsTainted() bool {
 	return e.thresholdsTainted
 }
@@ -274,7 +370,7 @@ func (e *Engine) runMetricsEmission(ctx context.Context) {
 func (e *Engine) emitMetrics() {
 	t := time.Now()
 
-	e.processSamples([]stats.SampleContainer{stats.ConnectedSamples{
+	sampleContainers := []stats.SampleContainer{stats.ConnectedSamples{
 		Samples: []stats.Sample{
 			{
 				Time:   t,
@@ -290,7 +386,50 @@ func (e *Engine) emitMetrics() {
 		},
 		Tags: e.Options.RunTags,
 		Time: t,
-	}})
+	}}
+	if cs, ok := e.Executor.GetRunner().(lib.ConnectionSampler); ok {
+		if samples := cs.GetConnectionSamples(t, e.Options.RunTags); samples != nil {
+			sampleContainers = append(sampleContainers, samples)
+		}
+	}
+	e.processSamples(sampleContainers)
+}
+
+// runHeartbeat emits a heartbeat sample on every tick, independently of the executor and the
+// VUs, so that a stalled run can be detected by the lack of heartbeats. The value is the time
+// in seconds since the heartbeat started, so consecutive heartbeats can also be told apart.
+func (e *Engine) runHeartbeat(ctx context.Context, interval time.Duration) {
+	start := time.Now()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case t := <-ticker.C:
+			e.Samples <- stats.Sample{
+				Time:   t,
+				Metric: metrics.Heartbeat,
+				Value:  t.Sub(start).Seconds(),
+				Tags:   e.Options.RunTags,
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Engine) runPartialSummaries(ctx context.Context) {
+	ticker := time.NewTicker(e.PartialSummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.MetricsLock.Lock()
+			e.PartialSummary(e.Metrics, e.Executor.GetTime())
+			e.MetricsLock.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
 }
 
 func (e *Engine) runThresholds(ctx context.Context, abort func()) {
@@ -335,6 +474,10 @@ func (e *Engine) processThresholds(abort func()) {
 		}
 	}
 
+	if e.AnomalyOutput {
+		e.flushAnomalyBuffer(t)
+	}
+
 	if abortOnFail && abort != nil {
 		//TODO: When sending this status we get a 422 Unprocessable Entity
 		e.setRunStatus(lib.RunStatusAbortedThreshold)
@@ -342,6 +485,61 @@ func (e *Engine) processThresholds(abort func()) {
 	}
 }
 
+// flushAnomalyBuffer passes the samples held back since the last threshold evaluation to the
+// collectors if a threshold fails for the samples of that window, and drops them otherwise.
+// Either way, the next window starts from scratch.
+func (e *Engine) flushAnomalyBuffer(t time.Duration) {
+	buffer, sinks := e.anomalyBuffer, e.anomalySinks
+	e.anomalyBuffer, e.anomalySinks = nil, nil
+	if len(buffer) == 0 {
+		return
+	}
+
+	anomaly := false
+	for m, sink := range sinks {
+		succ, err := m.Thresholds.Check(sink, t)
+		if err != nil {
+			e.logger.WithField("m", m.Name).WithError(err).Error("Threshold error")
+			continue
+		}
+		if !succ {
+			anomaly = true
+			break
+		}
+	}
+	if !anomaly {
+		return
+	}
+	for _, collector := range e.Collectors {
+		collector.Collect(buffer)
+	}
+}
+
+// addToAnomalyWindow adds the sample to the sink of the current window of a metric with
+// thresholds, which the anomaly output evaluates them with.
+func (e *Engine) addToAnomalyWindow(m *stats.Metric, sample stats.Sample) {
+	if !e.AnomalyOutput || len(m.Thresholds.Thresholds) == 0 {
+		return
+	}
+	sink, ok := e.anomalySinks[m]
+	if !ok {
+		if e.anomalySinks == nil {
+			e.anomalySinks = make(map[*stats.Metric]stats.Sink)
+		}
+		sink = stats.New(m.Name, m.Type, m.Contains).Sink
+		e.anomalySinks[m] = sink
+	}
+	sink.Add(sample)
+}
+
+// trackExtremeTags makes the sink of trend metrics keep the tags of their min and max samples,
+// if that's enabled by the trendExtremeTags option.
+func (e *Engine) trackExtremeTags(m *stats.Metric) {
+	if sink, ok := m.Sink.(*stats.TrendSink); ok && e.Options.TrendExtremeTags.Bool {
+		sink.TrackTags = true
+	}
+}
+
 func (e *Engine) processSamplesForMetrics(sampleCointainers []stats.SampleContainer) {
 	for _, sampleCointainer := range sampleCointainers {
 		samples := sampleCointainer.GetSamples()
@@ -354,11 +552,13 @@ func (e *Engine) processSamplesForMetrics(sampleCointainers []stats.SampleContai
 			m, ok := e.Metrics[sample.Metric.Name]
 			if !ok {
 				m = stats.New(sample.Metric.Name, sample.Metric.Type, sample.Metric.Contains)
+				e.trackExtremeTags(m)
 				m.Thresholds = e.thresholds[m.Name]
 				m.Submetrics = e.submetrics[m.Name]
 				e.Metrics[m.Name] = m
 			}
 			m.Sink.Add(sample)
+			e.addToAnomalyWindow(m, sample)
 
 			for _, sm := range m.Submetrics {
 				if !sample.Tags.Contains(sm.Tags) {
@@ -367,16 +567,130 @@ func (e *Engine) processSamplesForMetrics(sampleCointainers []stats.SampleContai
 
 				if sm.Metric == nil {
 					sm.Metric = stats.New(sm.Name, sample.Metric.Type, sample.Metric.Contains)
+					e.trackExtremeTags(sm.Metric)
 					sm.Metric.Sub = *sm
 					sm.Metric.Thresholds = e.thresholds[sm.Name]
 					e.Metrics[sm.Name] = sm.Metric
 				}
 				sm.Metric.Sink.Add(sample)
+				e.addToAnomalyWindow(sm.Metric, sample)
+			}
+		}
+	}
+}
+
+// processStageMarkers removes the stage markers from the sample containers, so they aren't passed
+// to the collectors, and starts aggregating the metrics for the new stages, if enabled.
+func (e *Engine) processStageMarkers(sampleContainers []stats.SampleContainer) []stats.SampleContainer {
+	var result []stats.SampleContainer
+	for i, sc := range sampleContainers {
+		marker, ok := sc.(lib.StageMarker)
+		if !ok {
+			if result != nil {
+				result = append(result, sc)
+			}
+			continue
+		}
+		if result == nil {
+			result = append(make([]stats.SampleContainer, 0, len(sampleContainers)), sampleContainers[:i]...)
+		}
+		if e.StageSummary {
+			e.endStage(marker.Time)
+			if marker.Index < 0 {
+				continue
+			}
+			e.StageMetrics = append(e.StageMetrics, &StageMetrics{
+				Index:   marker.Index,
+				Start:   marker.Time,
+				Metrics: make(map[string]*stats.Metric),
+			})
+		}
+	}
+	if result == nil {
+		return sampleContainers
+	}
+	return result
+}
+
+// endStage sets the end time of the currently running stage, if there is one
+func (e *Engine) endStage(t time.Time) {
+	if n := len(e.StageMetrics); n > 0 && e.StageMetrics[n-1].End.IsZero() {
+		e.StageMetrics[n-1].End = t
+	}
+}
+
+// processSamplesForStages aggregates the samples in the metrics of the stages that were running
+// at their time. Samples from outside of the stages, e.g. from setup(), are ignored.
+func (e *Engine) processSamplesForStages(sampleContainers []stats.SampleContainer) {
+	for _, sc := range sampleContainers {
+		for _, sample := range sc.GetSamples() {
+			var stage *StageMetrics
+			for i := len(e.StageMetrics) - 1; i >= 0; i-- {
+				if !sample.Time.Before(e.StageMetrics[i].Start) {
+					stage = e.StageMetrics[i]
+					break
+				}
+			}
+			if stage == nil || (!stage.End.IsZero() && sample.Time.After(stage.End)) {
+				continue
+			}
+			m, ok := stage.Metrics[sample.Metric.Name]
+			if !ok {
+				m = stats.New(sample.Metric.Name, sample.Metric.Type, sample.Metric.Contains)
+				stage.Metrics[m.Name] = m
 			}
+			m.Sink.Add(sample)
 		}
 	}
 }
 
+// limitCustomMetrics drops the samples of the custom metrics over the maxCustomMetrics limit.
+// Containers without such samples, which are the vast majority, are passed through as they are.
+func (e *Engine) limitCustomMetrics(sampleContainers []stats.SampleContainer) []stats.SampleContainer {
+	result := make([]stats.SampleContainer, 0, len(sampleContainers))
+	for _, sampleContainer := range sampleContainers {
+		samples := sampleContainer.GetSamples()
+		var kept []stats.Sample
+		for i, sample := range samples {
+			switch dropped := e.isDroppedMetric(sample.Metric.Name); {
+			case dropped && kept == nil:
+				kept = append(make([]stats.Sample, 0, len(samples)), samples[:i]...)
+			case !dropped && kept != nil:
+				kept = append(kept, sample)
+			}
+		}
+
+		switch {
+		case kept == nil:
+			result = append(result, sampleContainer)
+		case len(kept) > 0:
+			result = append(result, stats.Samples(kept))
+		}
+	}
+	return result
+}
+
+// isDroppedMetric registers new custom metrics and returns whether the samples of the specified
+// metric should be dropped, because it's over the maxCustomMetrics limit.
+func (e *Engine) isDroppedMetric(name string) bool {
+	if e.customMetrics[name] || metrics.IsBuiltin(name) {
+		return false
+	}
+	if e.droppedMetrics[name] {
+		return true
+	}
+
+	limit := e.Options.MaxCustomMetrics.Int64
+	if int64(len(e.customMetrics)) < limit {
+		e.customMetrics[name] = true
+		return false
+	}
+	e.droppedMetrics[name] = true
+	e.logger.WithField("metric", name).Errorf(
+		"The number of custom metrics exceeds the maxCustomMetrics limit of %d, dropping the metric's samples", limit)
+	return true
+}
+
 func (e *Engine) processSamples(sampleContainers []stats.SampleContainer) {
 	if len(sampleContainers) == 0 {
 		return
@@ -386,12 +700,33 @@ func (e *Engine) processSamples(sampleContainers []stats.SampleContainer) {
 	e.MetricsLock.Lock()
 	defer e.MetricsLock.Unlock()
 
+	sampleContainers, events := stats.SplitEvents(sampleContainers)
+	sampleContainers = e.processStageMarkers(sampleContainers)
+	if e.Options.MaxCustomMetrics.Int64 > 0 {
+		sampleContainers = e.limitCustomMetrics(sampleContainers)
+	}
+
 	// TODO: run this and the below code in goroutines?
-	if !(e.NoSummary && e.NoThresholds && !e.SummaryExport) {
+	if !(e.NoSummary && e.NoThresholds && !e.SummaryExport && e.PartialSummary == nil) {
 		e.processSamplesForMetrics(sampleContainers)
 	}
 
+	if e.StageSummary && len(e.StageMetrics) > 0 {
+		e.processSamplesForStages(sampleContainers)
+	}
+
+	if len(e.Collectors) > 0 && e.Options.SampleTimestamps.String == stats.TimestampWrite {
+		sampleContainers = stats.Restamp(sampleContainers, time.Now())
+	}
+	if e.AnomalyOutput && len(e.Collectors) > 0 {
+		e.anomalyBuffer = append(e.anomalyBuffer, sampleContainers...)
+	}
 	for _, collector := range e.Collectors {
-		collector.Collect(sampleContainers)
+		if !e.AnomalyOutput {
+			collector.Collect(sampleContainers)
+		}
+		if ec, ok := collector.(lib.EventCollector); ok && len(events) > 0 {
+			ec.CollectEvents(events)
+		}
 	}
 }
@@ -295,6 +295,126 @@ func TestEngineRun(t *testing.T) {
 	})
 }
 
+func TestEngineMaxDuration(t *testing.T) {
+	testMetric := stats.New("test_metric", stats.Trend)
+	e, err := newTestEngine(LF(func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		out <- stats.Sample{Metric: testMetric, Time: time.Now(), Value: 1}
+		select {
+		case <-time.After(10 * time.Millisecond):
+		case <-ctx.Done():
+		}
+		return nil
+	}), lib.Options{
+		VUs:         null.IntFrom(2),
+		VUsMax:      null.IntFrom(2),
+		Duration:    types.NullDurationFrom(time.Minute),
+		MaxDuration: types.NullDurationFrom(300 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	c := &dummy.Collector{}
+	e.Collectors = []lib.Collector{c}
+
+	startTime := time.Now()
+	assert.NoError(t, e.Run(context.Background()))
+	assert.WithinDuration(t, startTime.Add(300*time.Millisecond), time.Now(), 200*time.Millisecond)
+	assert.Equal(t, lib.RunStatusTimedOut, c.RunStatus)
+
+	// The samples up to the cap were processed for the summary and passed to the collectors
+	metric := e.Metrics["test_metric"]
+	require.NotNil(t, metric)
+	count := metric.Sink.(*stats.TrendSink).Count
+	assert.True(t, count > 10, "only %d samples were processed", count)
+	assert.NotEmpty(t, c.Samples)
+}
+
+func TestEngineStageSummary(t *testing.T) {
+	t.Run("bucketing", func(t *testing.T) {
+		e, err := newTestEngine(nil, lib.Options{})
+		require.NoError(t, err)
+		e.StageSummary = true
+		c := &dummy.Collector{}
+		e.Collectors = []lib.Collector{c}
+
+		metric := stats.New("my_metric", stats.Counter)
+		start := time.Now()
+		at := func(d time.Duration, value float64) stats.Sample {
+			return stats.Sample{Metric: metric, Time: start.Add(d), Value: value}
+		}
+
+		// The late samples of a stage can arrive after the marker of the next stage
+		e.processSamples([]stats.SampleContainer{
+			at(-time.Second, 1000), // before the stages, e.g. from setup()
+			lib.StageMarker{Index: 0, Time: start},
+			at(100*time.Millisecond, 1),
+		})
+		e.processSamples([]stats.SampleContainer{
+			at(900*time.Millisecond, 2),
+			lib.StageMarker{Index: 1, Time: start.Add(time.Second)},
+			at(time.Second, 10),
+			at(1500*time.Millisecond, 20),
+		})
+		e.processSamples([]stats.SampleContainer{
+			at(999*time.Millisecond, 4),
+			lib.StageMarker{Index: -1, Time: start.Add(2 * time.Second)},
+			at(2500*time.Millisecond, 2000), // after the stages, e.g. from teardown()
+		})
+
+		require.Len(t, e.StageMetrics, 2)
+		for i, expected := range []float64{7, 30} {
+			stage := e.StageMetrics[i]
+			assert.Equal(t, i, stage.Index)
+			assert.Equal(t, start.Add(time.Duration(i)*time.Second), stage.Start)
+			assert.Equal(t, time.Second, stage.Duration())
+			assert.Equal(t, expected, stage.Metrics["my_metric"].Sink.(*stats.CounterSink).Value)
+		}
+		assert.Equal(t, 3037.0, e.Metrics["my_metric"].Sink.(*stats.CounterSink).Value)
+
+		// The markers aren't passed to the collectors
+		assert.Len(t, c.SampleContainers, 7)
+		assert.Len(t, c.Samples, 7)
+	})
+
+	t.Run("run", func(t *testing.T) {
+		metric := stats.New("my_metric", stats.Counter)
+		e, err := newTestEngine(LF(func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			out <- stats.Sample{Metric: metric, Time: time.Now(), Value: 1}
+			select {
+			case <-time.After(5 * time.Millisecond):
+			case <-ctx.Done():
+			}
+			return nil
+		}), lib.Options{
+			VUs:    null.IntFrom(1),
+			VUsMax: null.IntFrom(5),
+			Stages: []lib.Stage{
+				{Duration: types.NullDurationFrom(200 * time.Millisecond), Target: null.IntFrom(1)},
+				{Duration: types.NullDurationFrom(200 * time.Millisecond), Target: null.IntFrom(5)},
+				{Duration: types.NullDurationFrom(200 * time.Millisecond), Target: null.IntFrom(5)},
+			},
+		})
+		require.NoError(t, err)
+		e.StageSummary = true
+		require.NoError(t, e.Run(context.Background()))
+
+		require.Len(t, e.StageMetrics, 3)
+		total := 0.0
+		counts := make([]float64, 3)
+		for i, stage := range e.StageMetrics {
+			assert.Equal(t, i, stage.Index)
+			assert.False(t, stage.End.IsZero())
+			assert.InDelta(t, 200*time.Millisecond, stage.Duration(), float64(100*time.Millisecond))
+			require.NotNil(t, stage.Metrics["my_metric"], "stage %d", i)
+			counts[i] = stage.Metrics["my_metric"].Sink.(*stats.CounterSink).Value
+			total += counts[i]
+		}
+		// Only the samples of the iterations that were running when the last stage ended can be
+		// missing from the stages, since they're after the marker of the end
+		assert.InDelta(t, e.Metrics["my_metric"].Sink.(*stats.CounterSink).Value, total, 5)
+		// The plateau with 5 VUs makes a lot more iterations than the stage with a single VU
+		assert.True(t, counts[2] > 2*counts[0], "%v", counts)
+	})
+}
+
 func TestEngineAtTime(t *testing.T) {
 	e, err := newTestEngine(nil, lib.Options{})
 	assert.NoError(t, err)
@@ -335,6 +455,149 @@ func TestEngineCollector(t *testing.T) {
 	}
 }
 
+func TestEngineRenamingCollector(t *testing.T) {
+	metric := stats.New("my_metric", stats.Trend)
+	ths, err := stats.NewThresholds([]string{"max<10"})
+	require.NoError(t, err)
+	e, err := newTestEngine(nil, lib.Options{Thresholds: map[string]stats.Thresholds{"my_metric": ths}})
+	require.NoError(t, err)
+
+	c := &dummy.Collector{}
+	rc, err := lib.NewRenamingCollector(c, map[string]string{"my_metric": "my.metric"})
+	require.NoError(t, err)
+	e.Collectors = []lib.Collector{rc}
+
+	e.processSamples([]stats.SampleContainer{stats.Sample{Metric: metric, Value: 20}})
+	e.processThresholds(nil)
+
+	// The output gets the renamed metric, but the thresholds are evaluated on the original one
+	require.Len(t, c.Samples, 1)
+	assert.Equal(t, "my.metric", c.Samples[0].Metric.Name)
+	assert.Equal(t, "my_metric", metric.Name)
+	assert.Contains(t, e.Metrics, "my_metric")
+	assert.NotContains(t, e.Metrics, "my.metric")
+	assert.True(t, e.IsTainted())
+}
+
+func TestEnginePartialSummaries(t *testing.T) {
+	testMetric := stats.New("test_metric", stats.Counter)
+	e, err := newTestEngine(LF(func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		out <- stats.Sample{Time: time.Now(), Metric: testMetric, Value: 1}
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}), lib.Options{
+		VUs:      null.IntFrom(1),
+		VUsMax:   null.IntFrom(1),
+		Duration: types.NullDurationFrom(1 * time.Second),
+	})
+	require.NoError(t, err)
+	e.NoSummary = true
+	e.NoThresholds = true
+
+	var times []time.Duration
+	var counts []float64
+	e.PartialSummaryInterval = 200 * time.Millisecond
+	e.PartialSummary = func(metrics map[string]*stats.Metric, t time.Duration) {
+		times = append(times, t)
+		if m, ok := metrics["test_metric"]; ok {
+			counts = append(counts, m.Sink.(*stats.CounterSink).Value)
+		} else {
+			counts = append(counts, 0)
+		}
+	}
+	require.NoError(t, e.Run(context.Background()))
+
+	// The partial summaries are produced at the interval, with the aggregates only growing
+	require.True(t, len(times) >= 3 && len(times) <= 5, "%d partial summaries", len(times))
+	for i := 1; i < len(times); i++ {
+		assert.True(t, times[i] > times[i-1], "times %v", times)
+		assert.True(t, counts[i] >= counts[i-1], "counts %v", counts)
+	}
+	assert.True(t, counts[len(counts)-1] > counts[0], "counts %v", counts)
+	assert.True(t, counts[len(counts)-1] <= e.Metrics["test_metric"].Sink.(*stats.CounterSink).Value)
+}
+
+func TestEngineTrendExtremeTags(t *testing.T) {
+	metric := stats.New("my_trend", stats.Trend)
+	tags := func(url string) *stats.SampleTags {
+		return stats.IntoSampleTags(&map[string]string{"url": url, "method": "GET"})
+	}
+	ths, err := stats.NewThresholds([]string{"max<100"})
+	require.NoError(t, err)
+
+	e, err := newTestEngine(nil, lib.Options{
+		TrendExtremeTags: null.BoolFrom(true),
+		Thresholds:       map[string]stats.Thresholds{"my_trend{method:GET}": ths},
+	})
+	require.NoError(t, err)
+	e.processSamples([]stats.SampleContainer{
+		stats.Sample{Metric: metric, Value: 20, Tags: tags("/a")},
+		stats.Sample{Metric: metric, Value: 50, Tags: tags("/slow")},
+		stats.Sample{Metric: metric, Value: 5, Tags: tags("/fast")},
+	})
+
+	for _, name := range []string{"my_trend", "my_trend{method:GET}"} {
+		sink := e.Metrics[name].Sink.(*stats.TrendSink)
+		assert.Equal(t, 50.0, sink.Max, name)
+		assert.Equal(t, tags("/slow"), sink.MaxTags, name)
+		assert.Equal(t, 5.0, sink.Min, name)
+		assert.Equal(t, tags("/fast"), sink.MinTags, name)
+	}
+}
+
+func TestEngineSampleTimestamps(t *testing.T) {
+	metric := stats.New("my_metric", stats.Counter)
+	measured := time.Now().Add(-time.Hour)
+
+	for _, mode := range []string{"", stats.TimestampMeasurement, stats.TimestampWrite} {
+		mode := mode
+		t.Run(mode, func(t *testing.T) {
+			e, err := newTestEngine(nil, lib.Options{SampleTimestamps: null.NewString(mode, mode != "")})
+			require.NoError(t, err)
+			c := &dummy.Collector{}
+			e.Collectors = []lib.Collector{c}
+
+			original := stats.Sample{Metric: metric, Time: measured, Value: 1}
+			before := time.Now()
+			e.processSamples([]stats.SampleContainer{original})
+			after := time.Now()
+
+			require.Len(t, c.Samples, 1)
+			if mode == stats.TimestampWrite {
+				assert.False(t, c.Samples[0].Time.Before(before), "%s", c.Samples[0].Time)
+				assert.False(t, c.Samples[0].Time.After(after), "%s", c.Samples[0].Time)
+			} else {
+				assert.Equal(t, measured, c.Samples[0].Time)
+			}
+			assert.Equal(t, measured, original.Time)
+			assert.Equal(t, 1.0, e.Metrics["my_metric"].Sink.(*stats.CounterSink).Value)
+		})
+	}
+}
+
+func TestEngineEvents(t *testing.T) {
+	e, err := newTestEngine(nil, lib.Options{})
+	require.NoError(t, err)
+	c := &dummy.Collector{}
+	e.Collectors = []lib.Collector{c}
+
+	metric := stats.New("my_metric", stats.Counter)
+	event := &stats.Event{
+		Time: time.Now(),
+		Name: "deployment",
+		Tags: stats.IntoSampleTags(&map[string]string{"env": "staging"}),
+		Data: map[string]interface{}{"version": "1.2"},
+	}
+	sample := stats.Sample{Metric: metric, Time: time.Now(), Value: 1}
+	e.processSamples([]stats.SampleContainer{sample, event})
+
+	require.Len(t, c.Events, 1)
+	assert.Equal(t, event, c.Events[0])
+	assert.Equal(t, map[string]interface{}{"version": "1.2"}, c.Events[0].Data)
+	assert.Equal(t, []stats.SampleContainer{sample}, c.SampleContainers)
+	assert.Equal(t, 1.0, e.Metrics["my_metric"].Sink.(*stats.CounterSink).Value)
+}
+
 func TestEngine_processSamples(t *testing.T) {
 	metric := stats.New("my_metric", stats.Gauge)
 
@@ -371,6 +634,173 @@ func TestEngine_processSamples(t *testing.T) {
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric"].Sink)
 		assert.IsType(t, &stats.GaugeSink{}, e.Metrics["my_metric{a:1}"].Sink)
 	})
+	t.Run("maxCustomMetrics", func(t *testing.T) {
+		e, err := newTestEngine(nil, lib.Options{MaxCustomMetrics: null.IntFrom(2)})
+		require.NoError(t, err)
+		hook := applyNullLogger(e)
+		c := &dummy.Collector{}
+		e.Collectors = []lib.Collector{c}
+
+		custom := make([]*stats.Metric, 4)
+		for i := range custom {
+			custom[i] = stats.New(fmt.Sprintf("custom_%d", i), stats.Counter)
+		}
+		e.processSamples([]stats.SampleContainer{
+			stats.Sample{Metric: custom[0], Value: 1},
+			stats.Samples{{Metric: metrics.Iterations, Value: 1}, {Metric: custom[1], Value: 1}},
+			stats.Samples{{Metric: custom[2], Value: 1}, {Metric: metrics.DataSent, Value: 1}, {Metric: custom[0], Value: 1}},
+		})
+		e.processSamples([]stats.SampleContainer{
+			stats.Sample{Metric: custom[3], Value: 1},
+			stats.Sample{Metric: custom[2], Value: 1},
+			stats.Sample{Metric: custom[1], Value: 1},
+		})
+
+		names := map[string]int{}
+		for _, s := range c.Samples {
+			names[s.Metric.Name]++
+		}
+		expected := map[string]int{"custom_0": 2, "custom_1": 2, "iterations": 1, "data_sent": 1}
+		assert.Equal(t, expected, names)
+		assert.Contains(t, e.Metrics, "custom_1")
+		assert.NotContains(t, e.Metrics, "custom_2")
+		assert.NotContains(t, e.Metrics, "custom_3")
+
+		// Every dropped metric is logged only once
+		entries := hook.AllEntries()
+		require.Len(t, entries, 2)
+		assert.Equal(t, "custom_2", entries[0].Data["metric"])
+		assert.Equal(t, "custom_3", entries[1].Data["metric"])
+		assert.Contains(t, entries[0].Message, "maxCustomMetrics limit of 2")
+	})
+}
+
+func TestEngine_processThresholdsOnTaggedSubmetric(t *testing.T) {
+	metric := stats.New("my_trend", stats.Trend)
+	checkoutTags := stats.IntoSampleTags(&map[string]string{"group": "::checkout"})
+	browseTags := stats.IntoSampleTags(&map[string]string{"group": "::browse"})
+
+	testdata := map[string]struct {
+		checkoutValue float64
+		pass          bool
+	}{
+		"passing": {100, true},
+		"failing": {1000, false},
+	}
+
+	for name, data := range testdata {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			ths, err := stats.NewThresholds([]string{"p(95)<800"})
+			require.NoError(t, err)
+
+			e, err := newTestEngine(nil, lib.Options{
+				Thresholds: map[string]stats.Thresholds{"my_trend{group:::checkout}": ths},
+			})
+			require.NoError(t, err)
+
+			samples := make([]stats.SampleContainer, 0, 20)
+			for i := 0; i < 10; i++ {
+				samples = append(samples,
+					stats.Sample{Metric: metric, Value: data.checkoutValue, Tags: checkoutTags},
+					stats.Sample{Metric: metric, Value: 5000, Tags: browseTags},
+				)
+			}
+			e.processSamples(samples)
+
+			sub := e.Metrics["my_trend{group:::checkout}"]
+			require.NotNil(t, sub)
+			sink := sub.Sink.(*stats.TrendSink)
+			assert.Equal(t, uint64(10), sink.Count)
+			assert.Equal(t, data.checkoutValue, sink.Max)
+			assert.Equal(t, uint64(20), e.Metrics["my_trend"].Sink.(*stats.TrendSink).Count)
+
+			e.processThresholds(nil)
+			assert.Equal(t, data.pass, !e.IsTainted())
+			assert.Equal(t, data.pass, !sub.Tainted.Bool)
+		})
+	}
+}
+
+func TestEngine_processThresholdsOnErrorCategories(t *testing.T) {
+	tagged := func(category string) *stats.SampleTags {
+		return stats.IntoSampleTags(&map[string]string{"error_category": category})
+	}
+	connectThs, err := stats.NewThresholds([]string{"count<1"})
+	require.NoError(t, err)
+	tcpThs, err := stats.NewThresholds([]string{"count<10"})
+	require.NoError(t, err)
+
+	e, err := newTestEngine(nil, lib.Options{
+		Thresholds: map[string]stats.Thresholds{
+			"http_reqs{error_category:connect}": connectThs,
+			"http_reqs{error_category:tcp}":     tcpThs,
+		},
+	})
+	require.NoError(t, err)
+
+	samples := []stats.SampleContainer{}
+	for i := 0; i < 5; i++ {
+		samples = append(samples, stats.Sample{Metric: metrics.HTTPReqs, Value: 1, Tags: tagged("tcp")})
+	}
+	e.processSamples(samples)
+	e.processThresholds(nil)
+	assert.False(t, e.IsTainted())
+
+	// A single connection error fails its own threshold, but not the one of the TCP errors
+	e.processSamples([]stats.SampleContainer{stats.Sample{Metric: metrics.HTTPReqs, Value: 1, Tags: tagged("connect")}})
+	e.processThresholds(nil)
+	assert.True(t, e.IsTainted())
+	assert.True(t, e.Metrics["http_reqs{error_category:connect}"].Tainted.Bool)
+	assert.False(t, e.Metrics["http_reqs{error_category:tcp}"].Tainted.Bool)
+}
+
+func TestEngine_processThresholdsOnErrorCategoryRates(t *testing.T) {
+	connectThs, err := stats.NewThresholds([]string{"rate<0.001"})
+	require.NoError(t, err)
+	readThs, err := stats.NewThresholds([]string{"rate<0.01"})
+	require.NoError(t, err)
+
+	e, err := newTestEngine(nil, lib.Options{
+		Thresholds: map[string]stats.Thresholds{
+			"http_req_failed_connect": connectThs,
+			"http_req_failed_tcp":     readThs,
+		},
+	})
+	require.NoError(t, err)
+
+	// Every request has a sample of every category, so each rate is independent of the others
+	request := func(category string) stats.SampleContainer {
+		samples := stats.Samples{}
+		for c, metric := range metrics.HTTPReqFailedCategories {
+			value := 0.0
+			if c == category {
+				value = 1
+			}
+			samples = append(samples, stats.Sample{Metric: metric, Value: value})
+		}
+		return samples
+	}
+
+	samples := []stats.SampleContainer{}
+	for i := 0; i < 1000; i++ {
+		category := ""
+		if i%200 == 0 {
+			category = "tcp" // 0.5% of the requests
+		}
+		samples = append(samples, request(category))
+	}
+	e.processSamples(samples)
+	e.processThresholds(nil)
+	assert.False(t, e.IsTainted())
+
+	// Two connection errors in 1002 requests are more than 0.1% of them, while the TCP errors
+	// are still below 1% of all requests
+	e.processSamples([]stats.SampleContainer{request("connect"), request("connect")})
+	e.processThresholds(nil)
+	assert.True(t, e.IsTainted())
+	assert.True(t, e.Metrics["http_req_failed_connect"].Tainted.Bool)
+	assert.False(t, e.Metrics["http_req_failed_tcp"].Tainted.Bool)
 }
 
 func TestEngine_runThresholds(t *testing.T) {
@@ -482,6 +912,46 @@ func TestEngine_processThresholds(t *testing.T) {
 	}
 }
 
+func TestEngineAnomalyOutput(t *testing.T) {
+	metric := stats.New("my_metric", stats.Trend)
+	ths, err := stats.NewThresholds([]string{"max<10"})
+	require.NoError(t, err)
+
+	e, err := newTestEngine(nil, lib.Options{Thresholds: map[string]stats.Thresholds{"my_metric": ths}})
+	require.NoError(t, err)
+	c := &dummy.Collector{}
+	e.Collectors = []lib.Collector{c}
+	e.AnomalyOutput = true
+
+	window := func(value float64) {
+		e.processSamples([]stats.SampleContainer{stats.Sample{Metric: metric, Value: value}})
+		assert.Empty(t, c.Samples, "samples should be held back until the thresholds are evaluated")
+		e.processThresholds(nil)
+	}
+
+	window(1)
+	assert.False(t, e.IsTainted())
+	assert.Empty(t, c.Samples)
+
+	window(20)
+	assert.True(t, e.IsTainted())
+	if assert.Len(t, c.Samples, 1) {
+		assert.Equal(t, 20.0, c.Samples[0].Value)
+	}
+
+	// The threshold is still failing for the whole run, but not for the samples of the window
+	c.Samples = nil
+	window(5)
+	assert.True(t, e.IsTainted())
+	assert.Empty(t, c.Samples)
+	assert.Empty(t, e.anomalyBuffer)
+
+	window(30)
+	if assert.Len(t, c.Samples, 1) {
+		assert.Equal(t, 30.0, c.Samples[0].Value)
+	}
+}
+
 func getMetricSum(collector *dummy.Collector, name string) (result float64) {
 	for _, sc := range collector.SampleContainers {
 		for _, s := range sc.GetSamples() {
@@ -739,7 +1209,7 @@ func TestRunTags(t *testing.T) {
 
 	systemMetrics := []*stats.Metric{
 		metrics.VUs, metrics.VUsMax, metrics.Iterations, metrics.IterationDuration,
-		metrics.GroupDuration, metrics.DataSent, metrics.DataReceived,
+		metrics.GroupDuration, metrics.DataSent, metrics.DataReceived, metrics.ActiveConnections,
 	}
 
 	getExpectedOverVal := func(metricName string) string {
@@ -1061,3 +1531,174 @@ func TestMinIterationDurationInSetupTeardownStage(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricSnapshotFromScript(t *testing.T) {
+	t.Parallel()
+	script := []byte(`
+		import { Counter, Gauge } from "k6/metrics";
+		import metrics from "k6/metrics";
+		import { sleep } from "k6";
+
+		let iterations = new Counter("my_iterations");
+		let seen = new Gauge("seen_iterations");
+
+		export default function() {
+			iterations.add(1);
+			sleep(0.1);
+			let snapshot = metrics.snapshot("my_iterations");
+			seen.add(snapshot ? snapshot.count : 0);
+		}
+	`)
+
+	r, err := js.New(
+		&loader.SourceData{URL: &url.URL{Path: "/script.js"}, Data: script},
+		nil,
+		lib.RuntimeOptions{},
+	)
+	require.NoError(t, err)
+
+	options := lib.Options{
+		Iterations: null.IntFrom(8),
+		VUs:        null.IntFrom(1),
+		VUsMax:     null.IntFrom(1),
+	}
+	require.NoError(t, r.SetOptions(options))
+	engine, err := NewEngine(local.New(r), options)
+	require.NoError(t, err)
+
+	collector := &dummy.Collector{}
+	engine.Collectors = []lib.Collector{collector}
+
+	errC := make(chan error)
+	go func() { errC <- engine.Run(context.Background()) }()
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("Test timed out")
+	case err := <-errC:
+		require.NoError(t, err)
+	}
+
+	var seen []float64
+	for _, sc := range collector.SampleContainers {
+		for _, s := range sc.GetSamples() {
+			if s.Metric.Name == "seen_iterations" {
+				seen = append(seen, s.Value)
+			}
+		}
+	}
+	require.Len(t, seen, 8)
+	for i := 1; i < len(seen); i++ {
+		assert.True(t, seen[i] >= seen[i-1], "snapshot values decreased: %v", seen)
+	}
+	assert.True(t, seen[len(seen)-1] > seen[0], "snapshot values didn't increase: %v", seen)
+	assert.True(t, seen[len(seen)-1] <= 8, "snapshot values too high: %v", seen)
+}
+
+// flushCheckCollector records how many samples it had received when its Run() context was done,
+// i.e. at the point where a real output would make its final commit.
+type flushCheckCollector struct {
+	dummy.Collector
+	samplesAtStop int
+}
+
+func (c *flushCheckCollector) Run(ctx context.Context) {
+	<-ctx.Done()
+	c.samplesAtStop = len(c.Samples)
+}
+
+func TestEngineFlushesCollectorsOnThresholdAbort(t *testing.T) {
+	t.Parallel()
+	testMetric := stats.New("my_counter", stats.Counter)
+
+	ths, err := stats.NewThresholds([]string{"count<5"})
+	require.NoError(t, err)
+	ths.Thresholds[0].AbortOnFail = true
+
+	e, err := newTestEngine(LF(func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		out <- stats.Sample{Metric: testMetric, Time: time.Now(), Value: 1}
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}), lib.Options{
+		VUs:        null.IntFrom(1),
+		VUsMax:     null.IntFrom(1),
+		Duration:   types.NullDurationFrom(20 * time.Second),
+		Thresholds: map[string]stats.Thresholds{testMetric.Name: ths},
+	})
+	require.NoError(t, err)
+
+	c := &flushCheckCollector{}
+	e.Collectors = []lib.Collector{c}
+
+	errC := make(chan error)
+	go func() { errC <- e.Run(context.Background()) }()
+	select {
+	case <-time.After(10 * time.Second):
+		t.Fatal("Test should have been aborted by the threshold")
+	case err := <-errC:
+		require.NoError(t, err)
+	}
+
+	assert.True(t, e.IsTainted())
+	assert.Equal(t, lib.RunStatusAbortedThreshold, c.RunStatus)
+
+	collected := 0
+	for _, s := range c.Samples {
+		if s.Metric.Name == testMetric.Name {
+			collected++
+		}
+	}
+	sink, ok := e.Metrics[testMetric.Name].Sink.(*stats.CounterSink)
+	require.True(t, ok)
+	assert.True(t, collected > 5, "too few samples: %d", collected)
+	assert.Equal(t, sink.Value, float64(collected))
+	assert.Equal(t, len(c.Samples), c.samplesAtStop, "samples were collected after the output was stopped")
+}
+
+func TestEngineHeartbeat(t *testing.T) {
+	t.Parallel()
+	runEngine := func(t *testing.T, opts lib.Options) []stats.Sample {
+		// The nil executor doesn't run any VUs, so there's no traffic at all
+		e, err := newTestEngine(nil, opts)
+		require.NoError(t, err)
+		c := &dummy.Collector{}
+		e.Collectors = []lib.Collector{c}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		require.NoError(t, e.Run(ctx))
+
+		heartbeats := []stats.Sample{}
+		for _, s := range c.Samples {
+			if s.Metric == metrics.Heartbeat {
+				heartbeats = append(heartbeats, s)
+			}
+		}
+		return heartbeats
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		assert.Empty(t, runEngine(t, lib.Options{}))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		interval := 50 * time.Millisecond
+		heartbeats := runEngine(t, lib.Options{
+			HeartbeatInterval: types.NullDurationFrom(interval),
+			RunTags:           stats.IntoSampleTags(&map[string]string{"foo": "bar"}),
+		})
+
+		require.True(t, len(heartbeats) >= 5, "too few heartbeats: %d", len(heartbeats))
+		assert.True(t, len(heartbeats) <= 10, "too many heartbeats: %d", len(heartbeats))
+		for i, s := range heartbeats {
+			assert.Equal(t, map[string]string{"foo": "bar"}, s.Tags.CloneTags())
+			if i == 0 {
+				continue
+			}
+			prev := heartbeats[i-1]
+			assert.True(t, s.Value > prev.Value, "heartbeat values should increase")
+			assert.InDelta(t, interval, s.Time.Sub(prev.Time), float64(interval)/2)
+		}
+	})
+}
@@ -23,12 +23,14 @@ package local
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 	null "gopkg.in/guregu/null.v3"
 
 	"github.com/loadimpact/k6/lib"
@@ -49,14 +51,39 @@ type vuHandle struct {
 	vu     lib.VU
 	ctx    context.Context
 	cancel context.CancelFunc
+
+	// How long to wait before the first iteration, and the per-VU iteration rate limit, if any
+	startDelay time.Duration
+	limiter    *rate.Limiter
 }
 
-func (h *vuHandle) run(logger *logrus.Logger, flow <-chan int64, iterDone chan<- struct{}) {
+func (h *vuHandle) run(logger *logrus.Logger, flow <-chan int64, iterDone chan<- struct{}, failedIters *int64) {
 	h.RLock()
 	ctx := h.ctx
+	startDelay := h.startDelay
+	limiter := h.limiter
 	h.RUnlock()
 
+	if startDelay > 0 {
+		timer := time.NewTimer(startDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+
 	for {
+		// Waiting here, before an iteration is taken from the flow, leaves the iterations to the
+		// other VUs while this one is held back by its rate limit. The token is only taken once
+		// the VU has an iteration though, since it could be blocked on the flow for a long time.
+		if limiter != nil {
+			if err := waitForToken(ctx, limiter); err != nil {
+				return
+			}
+		}
+
 		select {
 		case _, ok := <-flow:
 			if !ok {
@@ -66,6 +93,12 @@ func (h *vuHandle) run(logger *logrus.Logger, flow <-chan int64, iterDone chan<-
 			return
 		}
 
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				return
+			}
+		}
+
 		if h.vu != nil {
 			err := h.vu.RunOnce(ctx)
 			select {
@@ -73,6 +106,7 @@ func (h *vuHandle) run(logger *logrus.Logger, flow <-chan int64, iterDone chan<-
 			// Don't log errors or emit iterations metrics from cancelled iterations
 			default:
 				if err != nil {
+					atomic.AddInt64(failedIters, 1)
 					if s, ok := err.(fmt.Stringer); ok {
 						logger.Error(s.String())
 					} else {
@@ -87,6 +121,26 @@ func (h *vuHandle) run(logger *logrus.Logger, flow <-chan int64, iterDone chan<-
 	}
 }
 
+// waitForToken waits until the limiter has a token available, without taking it.
+func waitForToken(ctx context.Context, limiter *rate.Limiter) error {
+	now := time.Now()
+	r := limiter.ReserveN(now, 1)
+	delay := r.DelayFrom(now)
+	r.CancelAt(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 type Executor struct {
 	Runner lib.Runner
 	Logger *logrus.Logger
@@ -103,9 +157,18 @@ type Executor struct {
 	numVUsMax int64
 	nextVUID  int64
 
-	iters     int64 // Completed iterations
-	partIters int64 // Partial, incomplete iterations
-	endIters  int64 // End test at this many iterations
+	// Newly started VUs wait for a random duration below startJitter before their first
+	// iteration; startRand is only used while holding vusLock.
+	startJitter time.Duration
+	startRand   *rand.Rand
+
+	// The maximum number of iterations that every VU starts per minute; 0 means unlimited.
+	vuIterationRate int64
+
+	iters       int64 // Completed iterations
+	failedIters int64 // Completed iterations that returned an error
+	partIters   int64 // Partial, incomplete iterations
+	endIters    int64 // End test at this many iterations
 
 	time    int64 // Current time
 	endTime int64 // End test at this timestamp
@@ -133,8 +196,17 @@ type Executor struct {
 
 func New(r lib.Runner) *Executor {
 	var bufferSize int64
+	var startJitter time.Duration
+	var vuIterationRate int64
+	seed := time.Now().UnixNano()
 	if r != nil {
-		bufferSize = r.GetOptions().MetricSamplesBufferSize.Int64
+		opts := r.GetOptions()
+		bufferSize = opts.MetricSamplesBufferSize.Int64
+		startJitter = time.Duration(opts.VUStartJitter.Duration)
+		vuIterationRate = opts.VUIterationRate.Int64
+		if opts.Seed.Valid {
+			seed = opts.Seed.Int64
+		}
 	}
 
 	return &Executor{
@@ -146,6 +218,10 @@ func New(r lib.Runner) *Executor {
 		endTime:     -1,
 		vuOut:       make(chan stats.SampleContainer, bufferSize),
 		iterDone:    make(chan struct{}),
+		startJitter: startJitter,
+		startRand:   rand.New(rand.NewSource(seed)),
+
+		vuIterationRate: vuIterationRate,
 	}
 }
 
@@ -230,6 +306,13 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 	defer ticker.Stop()
 
 	lastTick := time.Now()
+
+	// Mark the start of every stage in the samples stream, e.g. for per-stage summaries
+	stageIndex := -1
+	if len(e.stages) > 0 {
+		stageIndex = 0
+		engineOut <- lib.StageMarker{Index: 0, Time: lastTick}
+	}
 	for {
 		// If the test is paused, sleep until either the pause or the test ends.
 		// Also shift the last tick to omit time spent paused, but not partial ticks.
@@ -284,6 +367,7 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 				if !keepRunning {
 					e.Logger.WithField("at", at).Debug("Local: Ran out of stages")
 					cutoff = time.Now()
+					engineOut <- lib.StageMarker{Index: -1, Time: t}
 					return nil
 				}
 				if vus.Valid {
@@ -291,6 +375,10 @@ func (e *Executor) Run(parent context.Context, engineOut chan<- stats.SampleCont
 						return err
 					}
 				}
+				if i := StageIndex(stages, at); i != stageIndex {
+					stageIndex = i
+					engineOut <- lib.StageMarker{Index: i, Time: t}
+				}
 			}
 		case sampleContainer := <-vuOut:
 			engineOut <- sampleContainer
@@ -332,10 +420,21 @@ func (e *Executor) scale(ctx context.Context, num int64) error {
 
 		if i < int(num) {
 			if cancel == nil {
+				var startDelay time.Duration
+				if e.startJitter > 0 {
+					startDelay = time.Duration(e.startRand.Int63n(int64(e.startJitter)))
+				}
+				var limiter *rate.Limiter
+				if e.vuIterationRate > 0 {
+					limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(e.vuIterationRate)), 1)
+				}
+
 				vuctx, cancel := context.WithCancel(ctx)
 				handle.Lock()
 				handle.ctx = vuctx
 				handle.cancel = cancel
+				handle.startDelay = startDelay
+				handle.limiter = limiter
 				handle.Unlock()
 
 				if handle.vu != nil {
@@ -346,7 +445,7 @@ func (e *Executor) scale(ctx context.Context, num int64) error {
 
 				e.wg.Add(1)
 				go func() {
-					handle.run(e.Logger, flow, iterDone)
+					handle.run(e.Logger, flow, iterDone, &e.failedIters)
 					e.wg.Done()
 				}()
 			}
@@ -394,6 +493,12 @@ func (e *Executor) GetIterations() int64 {
 	return atomic.LoadInt64(&e.iters)
 }
 
+// GetFailedIterations returns the number of completed iterations that returned an error,
+// e.g. because of an exception in the script.
+func (e *Executor) GetFailedIterations() int64 {
+	return atomic.LoadInt64(&e.failedIters)
+}
+
 func (e *Executor) GetEndIterations() null.Int {
 	v := atomic.LoadInt64(&e.endIters)
 	if v < 0 {
@@ -22,9 +22,11 @@ package local
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/url"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -41,6 +43,7 @@ import (
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
 	null "gopkg.in/guregu/null.v3"
 )
 
@@ -291,6 +294,206 @@ func TestExecutorEndIterations(t *testing.T) {
 	}
 }
 
+func TestExecutorFailedIterations(t *testing.T) {
+	var i int64
+	e := New(&lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+		if atomic.AddInt64(&i, 1)%2 == 0 {
+			return errors.New("iteration error")
+		}
+		return nil
+	}})
+	logger, _ := logtest.NewNullLogger()
+	e.SetLogger(logger)
+	assert.NoError(t, e.SetVUsMax(1))
+	assert.NoError(t, e.SetVUs(1))
+	e.SetEndIterations(null.IntFrom(10))
+
+	assert.NoError(t, e.Run(context.Background(), make(chan stats.SampleContainer, 100)))
+	assert.Equal(t, int64(10), e.GetIterations())
+	assert.Equal(t, int64(5), e.GetFailedIterations())
+}
+
+func TestExecutorVUStartJitter(t *testing.T) {
+	t.Parallel()
+	const numVUs = 10
+	jitter := 500 * time.Millisecond
+
+	var mu sync.Mutex
+	var starts []time.Duration
+	var begin time.Time
+	e := New(&lib.MiniRunner{
+		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			mu.Lock()
+			starts = append(starts, time.Since(begin))
+			mu.Unlock()
+			<-ctx.Done()
+			return nil
+		},
+		Options: lib.Options{VUStartJitter: types.NullDurationFrom(jitter), Seed: null.IntFrom(1)},
+	})
+	e.SetEndTime(types.NullDurationFrom(jitter + 200*time.Millisecond))
+	assert.NoError(t, e.SetVUsMax(numVUs))
+	assert.NoError(t, e.SetVUs(numVUs))
+
+	begin = time.Now()
+	assert.NoError(t, e.Run(context.Background(), make(chan stats.SampleContainer, 100)))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, starts, numVUs)
+	minStart, maxStart := starts[0], starts[0]
+	for _, start := range starts {
+		if start < minStart {
+			minStart = start
+		}
+		if start > maxStart {
+			maxStart = start
+		}
+	}
+	assert.True(t, maxStart-minStart > jitter/5, "VU starts aren't spread out: %v", starts)
+	assert.True(t, maxStart < jitter+100*time.Millisecond, "a VU started too late: %v", starts)
+}
+
+func TestExecutorVUIterationRate(t *testing.T) {
+	t.Parallel()
+	const numVUs = 4
+	interval := 100 * time.Millisecond
+
+	// Every VU runs its iterations with its own context, so the iterations can be told apart
+	var mu sync.Mutex
+	starts := make(map[context.Context][]time.Time)
+	e := New(&lib.MiniRunner{
+		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			mu.Lock()
+			starts[ctx] = append(starts[ctx], time.Now())
+			mu.Unlock()
+			return nil
+		},
+		Options: lib.Options{VUIterationRate: null.IntFrom(int64(time.Minute / interval))},
+	})
+	e.SetEndTime(types.NullDurationFrom(5*interval + interval/2))
+	assert.NoError(t, e.SetVUsMax(numVUs))
+	assert.NoError(t, e.SetVUs(numVUs))
+	assert.NoError(t, e.Run(context.Background(), make(chan stats.SampleContainer, 100)))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, starts, numVUs)
+	var total, maxPerVU int
+	for _, vuStarts := range starts {
+		for i := 1; i < len(vuStarts); i++ {
+			gap := vuStarts[i].Sub(vuStarts[i-1])
+			assert.True(t, gap >= interval-10*time.Millisecond, "iterations of a VU started %s apart", gap)
+		}
+		assert.True(t, len(vuStarts) <= 6, "a VU ran %d iterations", len(vuStarts))
+		total += len(vuStarts)
+		if len(vuStarts) > maxPerVU {
+			maxPerVU = len(vuStarts)
+		}
+	}
+	assert.True(t, total >= 3*maxPerVU, "the VUs ran %d iterations in total, at most %d each", total, maxPerVU)
+}
+
+func TestVUHandleIterationRateThrottledFlow(t *testing.T) {
+	t.Parallel()
+	interval := 100 * time.Millisecond
+
+	var mu sync.Mutex
+	var starts []time.Time
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handle := &vuHandle{
+		vu: &lib.MiniRunnerVU{R: lib.MiniRunner{Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			mu.Lock()
+			starts = append(starts, time.Now())
+			mu.Unlock()
+			return nil
+		}}},
+		ctx:     ctx,
+		cancel:  cancel,
+		limiter: rate.NewLimiter(rate.Every(interval), 1),
+	}
+
+	flow := make(chan int64)
+	iterDone := make(chan struct{}, 10)
+	done := make(chan struct{})
+	logger, _ := logtest.NewNullLogger()
+	go func() {
+		handle.run(logger, flow, iterDone, new(int64))
+		close(done)
+	}()
+
+	// The VU is blocked on the flow for a few rate limit intervals after its first iteration, so
+	// a token taken while it waited would let the next two iterations run back to back
+	flow <- 1
+	time.Sleep(3 * interval)
+	for i := 0; i < 3; i++ {
+		flow <- 1
+	}
+	for i := 0; i < 4; i++ {
+		<-iterDone
+	}
+	close(flow)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, starts, 4)
+	for i := 1; i < len(starts); i++ {
+		gap := starts[i].Sub(starts[i-1])
+		assert.True(t, gap >= interval-10*time.Millisecond, "iterations %d and %d started %s apart", i, i+1, gap)
+	}
+}
+
+func TestExecutorReplayRunner(t *testing.T) {
+	t.Parallel()
+	durations := []time.Duration{
+		100 * time.Millisecond, 300 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond,
+	}
+	testCases := []struct {
+		vus                      int64
+		minDuration, maxDuration time.Duration
+	}{
+		// The first VU runs the 1st, 3rd and 4th iterations while the second one runs the 2nd
+		{2, 300 * time.Millisecond, 450 * time.Millisecond},
+		// All iterations run sequentially
+		{1, 600 * time.Millisecond, 750 * time.Millisecond},
+		// The 4th iteration has to wait for one of the first three to finish
+		{3, 200 * time.Millisecond, 350 * time.Millisecond},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%d VUs", tc.vus), func(t *testing.T) {
+			t.Parallel()
+			e := New(lib.NewReplayRunner(durations))
+			require.NoError(t, e.SetVUsMax(tc.vus))
+			require.NoError(t, e.SetVUs(tc.vus))
+			e.SetEndIterations(null.IntFrom(int64(len(durations))))
+
+			startTime := time.Now()
+			require.NoError(t, e.Run(context.Background(), make(chan stats.SampleContainer, 100)))
+			took := time.Since(startTime)
+
+			assert.Equal(t, int64(len(durations)), e.GetIterations())
+			assert.True(t, took >= tc.minDuration, "took %s, expected at least %s", took, tc.minDuration)
+			assert.True(t, took <= tc.maxDuration, "took %s, expected at most %s", took, tc.maxDuration)
+		})
+	}
+
+	t.Run("end time", func(t *testing.T) {
+		t.Parallel()
+		// The durations are repeated, so the 5th iteration should be the first one again and get
+		// interrupted in the middle, without being counted
+		e := New(lib.NewReplayRunner(durations))
+		require.NoError(t, e.SetVUsMax(1))
+		require.NoError(t, e.SetVUs(1))
+		e.SetEndTime(types.NullDurationFrom(650 * time.Millisecond))
+
+		require.NoError(t, e.Run(context.Background(), make(chan stats.SampleContainer, 100)))
+		assert.Equal(t, int64(len(durations)), e.GetIterations())
+	})
+}
+
 func TestExecutorIsRunning(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	e := New(nil)
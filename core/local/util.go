@@ -62,3 +62,19 @@ func ProcessStages(startVUs int64, stages []lib.Stage, t time.Duration) (null.In
 	}
 	return vus, false
 }
+
+// StageIndex returns the index of the stage that is running at the specified time, or -1 if all
+// of the stages have ended. Infinite stages never end.
+func StageIndex(stages []lib.Stage, t time.Duration) int {
+	var start time.Duration
+	for i, stage := range stages {
+		if !stage.Duration.Valid {
+			return i
+		}
+		start += time.Duration(stage.Duration.Duration)
+		if t <= start {
+			return i
+		}
+	}
+	return -1
+}
@@ -291,3 +291,27 @@ func TestProcessStages(t *testing.T) {
 		})
 	}
 }
+
+func TestStageIndex(t *testing.T) {
+	stages := []lib.Stage{
+		{Duration: types.NullDurationFrom(10 * time.Second), Target: null.IntFrom(10)},
+		{Duration: types.NullDurationFrom(20 * time.Second)},
+		{Duration: types.NullDurationFrom(10 * time.Second), Target: null.IntFrom(0)},
+	}
+	testdata := map[time.Duration]int{
+		0:                0,
+		10 * time.Second: 0,
+		11 * time.Second: 1,
+		30 * time.Second: 1,
+		31 * time.Second: 2,
+		40 * time.Second: 2,
+		41 * time.Second: -1,
+	}
+	for at, index := range testdata {
+		assert.Equal(t, index, StageIndex(stages, at), at.String())
+	}
+
+	infinite := append(stages[:1:1], lib.Stage{Target: null.IntFrom(5)})
+	assert.Equal(t, 1, StageIndex(infinite, time.Hour))
+	assert.Equal(t, -1, StageIndex(nil, 0))
+}
@@ -33,7 +33,9 @@ import (
 	"github.com/loadimpact/k6/js/compiler"
 	jslib "github.com/loadimpact/k6/js/lib"
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/loader"
+	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
 	"github.com/spf13/afero"
 )
@@ -48,6 +50,10 @@ type Bundle struct {
 
 	BaseInitContext *InitContext
 
+	// The custom metrics defined by all of the instances of the bundle, so conflicting
+	// definitions of the same metric are detected
+	MetricRegistry *stats.Registry
+
 	Env               map[string]string
 	CompatibilityMode compiler.CompatibilityMode
 }
@@ -81,6 +87,7 @@ func NewBundle(src *loader.SourceData, filesystems map[string]afero.Fs, rtOpts l
 		Program:  pgm,
 		BaseInitContext: NewInitContext(rt, c, compatMode, new(context.Context),
 			filesystems, loader.Dir(src.URL)),
+		MetricRegistry:    metrics.NewRegistry(),
 		Env:               rtOpts.Env,
 		CompatibilityMode: compatMode,
 	}
@@ -166,6 +173,7 @@ func NewBundleFromArchive(arc *lib.Archive, rtOpts lib.RuntimeOptions) (*Bundle,
 		Program:           pgm,
 		Options:           arc.Options,
 		BaseInitContext:   initctx,
+		MetricRegistry:    metrics.NewRegistry(),
 		Env:               env,
 		CompatibilityMode: compatMode,
 	}
@@ -237,11 +245,20 @@ func (b *Bundle) Instantiate() (bi *BundleInstance, instErr error) {
 	}, instErr
 }
 
+// newRandSource returns the source of Math.random(). If the run has a seed, it's combined with
+// the given offset, e.g. the VU ID, so every VU gets its own reproducible sequence.
+func (b *Bundle) newRandSource(offset int64) goja.RandSource {
+	if !b.Options.Seed.Valid {
+		return common.NewRandSource()
+	}
+	return common.NewSeededRandSource(b.Options.Seed.Int64 + offset)
+}
+
 // Instantiates the bundle into an existing runtime. Not public because it also messes with a bunch
 // of other things, will potentially thrash data and makes a mess in it if the operation fails.
 func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) error {
 	rt.SetFieldNameMapper(common.FieldNameMapper{})
-	rt.SetRandSource(common.NewRandSource())
+	rt.SetRandSource(b.newRandSource(0))
 
 	if init.compatibilityMode == compiler.CompatibilityModeExtended {
 		if _, err := rt.RunProgram(jslib.GetCoreJS()); err != nil {
@@ -262,7 +279,10 @@ func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) error {
 	rt.Set("__ENV", env)
 	rt.Set("console", common.Bind(rt, newConsole(), init.ctxPtr))
 
-	*init.ctxPtr = common.WithRuntime(context.Background(), rt)
+	*init.ctxPtr = lib.WithMetricRegistry(common.WithRuntime(context.Background(), rt), b.MetricRegistry)
+	if seed := b.Options.Seed; seed.Valid {
+		*init.ctxPtr = lib.WithRunSeed(*init.ctxPtr, seed.Int64)
+	}
 	unbindInit := common.BindToGlobal(rt, common.Bind(rt, init, init.ctxPtr))
 	if _, err := rt.RunProgram(b.Program); err != nil {
 		return err
@@ -270,7 +290,7 @@ func (b *Bundle) instantiate(rt *goja.Runtime, init *InitContext) error {
 	unbindInit()
 	*init.ctxPtr = nil
 
-	rt.SetRandSource(common.NewRandSource())
+	rt.SetRandSource(b.newRandSource(0))
 
 	return nil
 }
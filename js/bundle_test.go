@@ -84,6 +84,34 @@ func TestNewBundle(t *testing.T) {
 		_, err := getSimpleBundle("/script.js", `throw new Error("aaaa");`)
 		assert.EqualError(t, err, "Error: aaaa at file:///script.js:1:7(3)")
 	})
+	t.Run("MetricConflict", func(t *testing.T) {
+		fs := afero.NewMemMapFs()
+		require.NoError(t, afero.WriteFile(fs, "/module.js", []byte(`
+			import { Trend } from "k6/metrics";
+			export let latency = new Trend("latency", true);
+		`), 0644))
+		_, err := getSimpleBundle("/script.js", `
+			import { Counter } from "k6/metrics";
+			import { latency } from "./module.js";
+			let latencyCount = new Counter("latency");
+			export default function() {};
+		`, fs)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "metric 'latency' is already defined as a trend with time values, "+
+			"it can't be redefined as a counter with default values")
+
+		// The same definition in another module is fine
+		require.NoError(t, afero.WriteFile(fs, "/module2.js", []byte(`
+			import { Trend } from "k6/metrics";
+			export let latency = new Trend("latency", true);
+		`), 0644))
+		_, err = getSimpleBundle("/script.js", `
+			import { latency } from "./module.js";
+			import { latency as latency2 } from "./module2.js";
+			export default function() {};
+		`, fs)
+		require.NoError(t, err)
+	})
 	t.Run("InvalidExports", func(t *testing.T) {
 		_, err := getSimpleBundle("/script.js", `exports = null`)
 		assert.EqualError(t, err, "exports must be an object")
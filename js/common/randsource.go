@@ -38,5 +38,11 @@ func NewRandSource() goja.RandSource {
 	if err := binary.Read(crand.Reader, binary.LittleEndian, &seed); err != nil {
 		panic(fmt.Errorf("could not read random bytes: %v", err))
 	}
+	return NewSeededRandSource(seed)
+}
+
+// NewSeededRandSource returns a RandSource with the given seed, for reproducible random values.
+// Like the one from NewRandSource(), it's NOT safe for concurrent use.
+func NewSeededRandSource(seed int64) goja.RandSource {
 	return rand.New(rand.NewSource(seed)).Float64
 }
@@ -27,6 +27,8 @@ import (
 
 	"github.com/dop251/goja"
 	"github.com/sirupsen/logrus"
+
+	"github.com/loadimpact/k6/lib"
 )
 
 // console represents a JS console implemented as a logrus.Logger.
@@ -56,15 +58,23 @@ func newFileConsole(filepath string) (*console, error) {
 }
 
 func (c console) log(ctx *context.Context, level logrus.Level, msgobj goja.Value, args ...goja.Value) {
+	fields := make(logrus.Fields)
 	if ctx != nil && *ctx != nil {
 		select {
 		case <-(*ctx).Done():
 			return
 		default:
 		}
+
+		// Attach the identity of the VU that logged the message, so that output from
+		// many VUs can be told apart in structured (e.g. --logformat=json) logs. The
+		// temporary VU used for setup() and teardown() has no meaningful ID.
+		if state := lib.GetState(*ctx); state != nil && state.Vu != 0 {
+			fields["vu"] = state.Vu
+			fields["iter"] = state.Iteration
+		}
 	}
 
-	fields := make(logrus.Fields)
 	for i, arg := range args {
 		fields[strconv.Itoa(i)] = arg.String()
 	}
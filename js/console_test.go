@@ -21,11 +21,14 @@
 package js
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/url"
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/dop251/goja"
@@ -33,6 +36,7 @@ import (
 	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	null "gopkg.in/guregu/null.v3"
 
 	"github.com/loadimpact/k6/js/common"
@@ -150,6 +154,40 @@ func TestConsole(t *testing.T) {
 	}
 }
 
+func TestConsoleVUFields(t *testing.T) {
+	r, err := getSimpleRunner("/script.js", `export default function() { console.log("msg", __ITER); }`)
+	require.NoError(t, err)
+
+	samples := make(chan stats.SampleContainer, 100)
+	vu, err := r.newVU(samples)
+	require.NoError(t, err)
+	require.NoError(t, vu.Reconfigure(5))
+
+	buf := &bytes.Buffer{}
+	logger, hook := logtest.NewNullLogger()
+	logger.SetOutput(buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	vu.Console.Logger = logger
+
+	for i := int64(0); i < 3; i++ {
+		buf.Reset()
+		require.NoError(t, vu.RunOnce(context.Background()))
+
+		entry := hook.LastEntry()
+		require.NotNil(t, entry)
+		assert.Equal(t, logrus.Fields{"vu": int64(5), "iter": i, "0": strconv.FormatInt(i, 10)}, entry.Data)
+		assert.False(t, entry.Time.IsZero())
+
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		assert.Equal(t, "msg", record["msg"])
+		assert.Equal(t, "info", record["level"])
+		assert.EqualValues(t, 5, record["vu"])
+		assert.EqualValues(t, i, record["iter"])
+		assert.Contains(t, record, "time")
+	}
+}
+
 func TestFileConsole(t *testing.T) {
 	var (
 		levels = map[string]logrus.Level{
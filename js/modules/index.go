@@ -25,6 +25,7 @@ import (
 	"github.com/loadimpact/k6/js/modules/k6/crypto"
 	"github.com/loadimpact/k6/js/modules/k6/crypto/x509"
 	"github.com/loadimpact/k6/js/modules/k6/encoding"
+	"github.com/loadimpact/k6/js/modules/k6/fuzz"
 	"github.com/loadimpact/k6/js/modules/k6/html"
 	"github.com/loadimpact/k6/js/modules/k6/http"
 	"github.com/loadimpact/k6/js/modules/k6/metrics"
@@ -37,6 +38,7 @@ var Index = map[string]interface{}{
 	"k6/crypto":      crypto.New(),
 	"k6/crypto/x509": x509.New(),
 	"k6/encoding":    encoding.New(),
+	"k6/fuzz":        fuzz.New(),
 	"k6/http":        http.New(),
 	"k6/metrics":     metrics.New(),
 	"k6/html":        html.New(),
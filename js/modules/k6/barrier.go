@@ -0,0 +1,119 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package k6
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+)
+
+// ErrBarrierWaitInInitContext is returned when a barrier is waited on in the init context
+var ErrBarrierWaitInInitContext = common.NewInitContextError(
+	"Waiting on a barrier in the init context is not supported")
+
+// Barrier blocks the VUs that wait on it until it's released, so they all continue at the same
+// time, e.g. for a coordinated burst of requests. Barriers are shared by name between the VUs.
+type Barrier struct {
+	mu       sync.Mutex
+	parties  int64
+	waiting  int64
+	released chan struct{}
+}
+
+func newBarrier(parties int64) *Barrier {
+	return &Barrier{parties: parties, released: make(chan struct{})}
+}
+
+// XBarrier returns the barrier with the given name, creating it if it doesn't exist yet. If
+// parties is specified, the barrier is also released when that many VUs are waiting on it.
+func (k6 *K6) XBarrier(ctxPtr *context.Context, name string, parties ...int64) (interface{}, error) {
+	if lib.GetState(*ctxPtr) != nil {
+		return nil, errors.New("barriers must be created in the init context")
+	}
+	if name == "" {
+		return nil, common.NewInitContextError("a barrier needs a name")
+	}
+	var n int64
+	if len(parties) > 0 {
+		if parties[0] < 0 {
+			return nil, common.NewInitContextError("the parties of a barrier can't be negative")
+		}
+		n = parties[0]
+	}
+
+	k6.barriersMu.Lock()
+	defer k6.barriersMu.Unlock()
+	b, ok := k6.barriers[name]
+	if !ok {
+		b = newBarrier(n)
+		k6.barriers[name] = b
+	}
+	return common.Bind(common.GetRuntime(*ctxPtr), b, ctxPtr), nil
+}
+
+// Wait blocks until the barrier is released. It returns false if the VU was stopped before that.
+func (b *Barrier) Wait(ctx context.Context) (bool, error) {
+	if lib.GetState(ctx) == nil {
+		return false, ErrBarrierWaitInInitContext
+	}
+
+	b.mu.Lock()
+	released := b.released
+	b.waiting++
+	if b.parties > 0 && b.waiting >= b.parties {
+		b.release()
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-released:
+		return true, nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		if released == b.released { // it wasn't released in the meantime
+			b.waiting--
+		}
+		b.mu.Unlock()
+		return false, nil
+	}
+}
+
+// Release releases all of the VUs waiting on the barrier and returns their number. The barrier
+// can be waited on again afterwards.
+func (b *Barrier) Release() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.release()
+}
+
+// release wakes up all of the waiting VUs at once by closing the channel they wait on, so it
+// doesn't depend on how many of them there are; it has to be called with the lock held.
+func (b *Barrier) release() int64 {
+	waiting := b.waiting
+	close(b.released)
+	b.released = make(chan struct{})
+	b.waiting = 0
+	return waiting
+}
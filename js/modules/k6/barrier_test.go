@@ -0,0 +1,163 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package k6
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+)
+
+// newBarrierVU returns a runtime that has created the barrier with the given constructor
+// arguments in its init context, and a context pointer that is already in the VU context
+func newBarrierVU(t *testing.T, module *K6, args string) (*goja.Runtime, *context.Context) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctxPtr := new(context.Context)
+	*ctxPtr = common.WithRuntime(context.Background(), rt)
+	rt.Set("k6", common.Bind(rt, module, ctxPtr))
+	_, err := common.RunString(rt, `let barrier = new k6.Barrier(`+args+`);`)
+	require.NoError(t, err)
+	*ctxPtr = lib.WithState(*ctxPtr, &lib.State{})
+	return rt, ctxPtr
+}
+
+func waitForWaiting(t *testing.T, b *Barrier, n int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b.mu.Lock()
+		waiting := b.waiting
+		b.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		require.True(t, time.Now().Before(deadline), "only %d of %d VUs are waiting", waiting, n)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBarrier(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Release", func(t *testing.T) {
+		t.Parallel()
+		module := New()
+		const vus = 50
+		var released int32
+		var wg sync.WaitGroup
+		results := make(chan bool, vus)
+		for i := 0; i < vus; i++ {
+			rt, _ := newBarrierVU(t, module, `"burst"`)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := common.RunString(rt, `barrier.wait()`)
+				assert.NoError(t, err)
+				assert.Equal(t, int32(1), atomic.LoadInt32(&released), "a VU continued before the release")
+				results <- v.ToBoolean()
+			}()
+		}
+		barrier := module.barriers["burst"]
+		waitForWaiting(t, barrier, vus)
+
+		rt, _ := newBarrierVU(t, module, `"burst"`)
+		atomic.StoreInt32(&released, 1)
+		v, err := common.RunString(rt, `barrier.release()`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(vus), v.ToInteger())
+		wg.Wait()
+		close(results)
+		for result := range results {
+			assert.True(t, result)
+		}
+
+		// The barrier can be reused
+		v, err = common.RunString(rt, `barrier.release()`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), v.ToInteger())
+	})
+
+	t.Run("Parties", func(t *testing.T) {
+		t.Parallel()
+		module := New()
+		const vus = 10
+		var wg sync.WaitGroup
+		var done int32
+		for i := 0; i < vus; i++ {
+			rt, _ := newBarrierVU(t, module, `"parties", 10`)
+			if i == vus-1 {
+				waitForWaiting(t, module.barriers["parties"], vus-1)
+				assert.Equal(t, int32(0), atomic.LoadInt32(&done))
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				v, err := common.RunString(rt, `barrier.wait()`)
+				assert.NoError(t, err)
+				assert.True(t, v.ToBoolean())
+				atomic.AddInt32(&done, 1)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int32(vus), atomic.LoadInt32(&done))
+	})
+
+	t.Run("Cancel", func(t *testing.T) {
+		t.Parallel()
+		module := New()
+		rt, ctxPtr := newBarrierVU(t, module, `"cancel"`)
+		ctx, cancel := context.WithCancel(*ctxPtr)
+		*ctxPtr = ctx
+		go func() {
+			waitForWaiting(t, module.barriers["cancel"], 1)
+			cancel()
+		}()
+		v, err := common.RunString(rt, `barrier.wait()`)
+		require.NoError(t, err)
+		assert.False(t, v.ToBoolean())
+		assert.Equal(t, int64(0), module.barriers["cancel"].waiting)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		t.Parallel()
+		module := New()
+		rt, ctxPtr := newBarrierVU(t, module, `"errors"`)
+		_, err := common.RunString(rt, `new k6.Barrier("other")`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "barriers must be created in the init context")
+
+		*ctxPtr = common.WithRuntime(context.Background(), rt)
+		_, err = common.RunString(rt, `barrier.wait()`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), ErrBarrierWaitInInitContext.Error())
+		_, err = common.RunString(rt, `new k6.Barrier("negative", -1)`)
+		assert.Error(t, err)
+	})
+}
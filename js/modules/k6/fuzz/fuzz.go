@@ -0,0 +1,140 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package fuzz
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"gopkg.in/guregu/null.v3"
+)
+
+// Fuzz is the k6/fuzz module, which generates random payloads for robustness testing
+type Fuzz struct{}
+
+// New returns a new instance of the k6/fuzz module
+func New() *Fuzz {
+	return &Fuzz{}
+}
+
+// Generator produces random values conforming to a schema. Every VU has its own copy of the
+// generators declared in the init context, so VUs using the same seed generate the same values.
+type Generator struct {
+	schema *Schema
+	rand   *rand.Rand
+	seed   int64
+
+	// If the generator uses the seed of the run, it's combined with the ID of the VU that the
+	// generator is used in, like the seed of Math.random(), so the VUs don't generate the same values.
+	runSeed null.Int
+	vu      int64
+}
+
+// XGenerator is the JS constructor of Generator, e.g. `new Generator(schema, seed)`. If the
+// seed is omitted, the seed of the run combined with the VU ID is used, or a time-based one if the
+// run doesn't have one.
+func (*Fuzz) XGenerator(ctx *context.Context, descriptor goja.Value, seed ...int64) (interface{}, error) {
+	if descriptor == nil || goja.IsUndefined(descriptor) || goja.IsNull(descriptor) {
+		return nil, errors.New("a schema is required")
+	}
+	schema, err := ParseSchema(descriptor.Export())
+	if err != nil {
+		return nil, err
+	}
+
+	var runSeed null.Int
+	s, ok := lib.GetRunSeed(*ctx)
+	switch {
+	case len(seed) > 0:
+		s = seed[0]
+	case ok:
+		runSeed = null.IntFrom(s)
+	default:
+		s = time.Now().UnixNano()
+	}
+	g := NewGenerator(schema, s)
+	g.runSeed = runSeed
+	return common.Bind(common.GetRuntime(*ctx), g, ctx), nil
+}
+
+// NewGenerator returns a generator for an already validated schema
+func NewGenerator(schema *Schema, seed int64) *Generator {
+	return &Generator{schema: schema, rand: rand.New(rand.NewSource(seed)), seed: seed}
+}
+
+// Next returns the next random value
+func (g *Generator) Next(ctx context.Context) goja.Value {
+	g.reseed(ctx)
+	return toValue(common.GetRuntime(ctx), g.schema.Generate(g.rand))
+}
+
+// reseed derives the seed of a generator that uses the seed of the run from the ID of the VU it's
+// used in, when it's first used there
+func (g *Generator) reseed(ctx context.Context) {
+	state := lib.GetState(ctx)
+	if !g.runSeed.Valid || state == nil || state.Vu == g.vu {
+		return
+	}
+	g.vu = state.Vu
+	g.seed = g.runSeed.Int64 + state.Vu
+	g.rand = rand.New(rand.NewSource(g.seed))
+}
+
+// Seed returns the seed the generator is using, so a run can be reproduced
+func (g *Generator) Seed() int64 {
+	return g.seed
+}
+
+// toValue converts a generated value to a JS one, setting the object properties in a sorted
+// order, so e.g. JSON.stringify() of the same generated value always returns the same string.
+func toValue(rt *goja.Runtime, v interface{}) goja.Value {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		obj := rt.NewObject()
+		for _, k := range keys {
+			_ = obj.Set(k, toValue(rt, v[k]))
+		}
+		return obj
+	case []interface{}:
+		arr, err := rt.New(rt.Get("Array"))
+		if err != nil {
+			common.Throw(rt, err)
+		}
+		for i := range v {
+			_ = arr.Set(strconv.Itoa(i), toValue(rt, v[i]))
+		}
+		return arr
+	default:
+		return rt.ToValue(v)
+	}
+}
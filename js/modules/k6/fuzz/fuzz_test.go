@@ -0,0 +1,204 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package fuzz
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/js/common"
+)
+
+const testSchema = `{
+	type: "object",
+	properties: {
+		id: { type: "integer", minimum: 1, maximum: 10 },
+		score: { type: "number", minimum: -1.5, maximum: 1.5 },
+		name: { type: "string", minLength: 3, maxLength: 5 },
+		active: { type: "boolean" },
+		status: { enum: ["new", "paid", "shipped"] },
+		nothing: { type: "null" },
+		tags: { type: "array", items: { type: "string", maxLength: 2 }, minItems: 1, maxItems: 3 },
+		address: {
+			type: "object",
+			properties: { zip: { type: "integer", minimum: 10000, maximum: 99999 } },
+		},
+	},
+}`
+
+func newTestRuntime() (*goja.Runtime, *context.Context) {
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	ctx := common.WithRuntime(context.Background(), rt)
+	rt.Set("fuzz", common.Bind(rt, New(), &ctx))
+	return rt, &ctx
+}
+
+// assertConforms checks that a value, as exported from JS, matches the schema
+func assertConforms(t *testing.T, s *Schema, v interface{}, path string) {
+	if len(s.Enum) > 0 {
+		assert.Contains(t, s.Enum, v, path)
+		return
+	}
+	switch s.Type {
+	case "null":
+		assert.Nil(t, v, path)
+	case "boolean":
+		assert.IsType(t, true, v, path)
+	case "integer":
+		i, ok := v.(int64)
+		if assert.True(t, ok, "%s: %#v isn't an integer", path, v) {
+			assert.True(t, float64(i) >= *s.Minimum && float64(i) <= *s.Maximum, "%s: %d", path, i)
+		}
+	case "number":
+		f, ok := v.(float64)
+		if assert.True(t, ok, "%s: %#v isn't a number", path, v) {
+			assert.True(t, f >= *s.Minimum && f <= *s.Maximum, "%s: %f", path, f)
+		}
+	case "string":
+		str, ok := v.(string)
+		if assert.True(t, ok, "%s: %#v isn't a string", path, v) {
+			min, max := intBounds(s.MinLength, s.MaxLength, DefaultMinLength, DefaultMaxLength)
+			assert.True(t, len(str) >= min && len(str) <= max, "%s: %q", path, str)
+		}
+	case "array":
+		items, ok := v.([]interface{})
+		if assert.True(t, ok, "%s: %#v isn't an array", path, v) {
+			min, max := intBounds(s.MinItems, s.MaxItems, DefaultMinItems, DefaultMaxItems)
+			assert.True(t, len(items) >= min && len(items) <= max, "%s: %d items", path, len(items))
+			for _, item := range items {
+				assertConforms(t, s.Items, item, path+"[]")
+			}
+		}
+	case "object":
+		obj, ok := v.(map[string]interface{})
+		if assert.True(t, ok, "%s: %#v isn't an object", path, v) {
+			assert.Len(t, obj, len(s.Properties), path)
+			for name, prop := range s.Properties {
+				assertConforms(t, prop, obj[name], path+"."+name)
+			}
+		}
+	default:
+		t.Errorf("%s: unexpected type %s", path, s.Type)
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	t.Run("Conforms", func(t *testing.T) {
+		rt, _ := newTestRuntime()
+		v, err := common.RunString(rt, `
+		let schema = `+testSchema+`;
+		let gen = new fuzz.Generator(schema, 42);
+		let values = [];
+		for (let i = 0; i < 200; i++) {
+			values.push(gen.next());
+		}
+		[schema, values]`)
+		require.NoError(t, err)
+
+		exported := v.Export().([]interface{})
+		schema, err := ParseSchema(exported[0])
+		require.NoError(t, err)
+		for _, value := range exported[1].([]interface{}) {
+			assertConforms(t, schema, value, "value")
+		}
+	})
+
+	t.Run("Reproducible", func(t *testing.T) {
+		generate := func(seed string) string {
+			rt, _ := newTestRuntime()
+			v, err := common.RunString(rt, `
+			let gen = new fuzz.Generator(`+testSchema+`, `+seed+`);
+			let values = [];
+			for (let i = 0; i < 10; i++) {
+				values.push(gen.next());
+			}
+			JSON.stringify(values)`)
+			require.NoError(t, err)
+			return v.String()
+		}
+		assert.Equal(t, generate("1"), generate("1"))
+		assert.NotEqual(t, generate("1"), generate("2"))
+	})
+
+	t.Run("Seed", func(t *testing.T) {
+		rt, _ := newTestRuntime()
+		v, err := common.RunString(rt, `new fuzz.Generator({ type: "boolean" }, 1234).seed()`)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1234), v.Export())
+	})
+
+	t.Run("InvalidSchema", func(t *testing.T) {
+		testdata := map[string]string{
+			`undefined`:        "a schema is required",
+			`{}`:               "schema: either a type or enum values are required",
+			`{ type: "date" }`: "schema: unsupported type 'date'",
+			`{ type: "integer", minimum: 5, maximum: 1 }`:                                    "schema: no integer between 5 and 1",
+			`{ type: "integer", minimum: 1.2, maximum: 1.8 }`:                                "schema: no integer between 2 and 1",
+			`{ type: "integer", minimum: 1e19 }`:                                             "schema: the integer bounds 1e+19 and 1e+19 don't fit into 64 bits",
+			`{ type: "integer", minimum: -1e19, maximum: 0 }`:                                "schema: the integer bounds -1e+19 and 0 don't fit into 64 bits",
+			`{ type: "integer", minimum: -5e18, maximum: 5e18 }`:                             "schema: too many integers between -5e+18 and 5e+18",
+			`{ type: "string", minLength: -1 }`:                                              "schema: invalid length bounds [-1, 16]",
+			`{ type: "array" }`:                                                              "schema: arrays need an items schema",
+			`{ type: "object", properties: { a: { type: "array", items: { type: "x" } } } }`: "schema.a[]: unsupported type 'x'",
+		}
+		for schema, errMsg := range testdata {
+			schema, errMsg := schema, errMsg
+			t.Run(schema, func(t *testing.T) {
+				rt, _ := newTestRuntime()
+				_, err := common.RunString(rt, `new fuzz.Generator(`+schema+`, 1)`)
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), errMsg)
+			})
+		}
+	})
+}
+
+func TestSchemaBounds(t *testing.T) {
+	min, max := 5000.0, -5000.0
+	assert.Equal(t, []float64{5000, 6000}, boundsOf(&Schema{Type: "integer", Minimum: &min}))
+	assert.Equal(t, []float64{-6000, -5000}, boundsOf(&Schema{Type: "integer", Maximum: &max}))
+	assert.Equal(t, []float64{DefaultMinimum, DefaultMaximum}, boundsOf(&Schema{Type: "number"}))
+
+	bigMin, bigMax := -4e18, 4e18
+	big := &Schema{Type: "integer", Minimum: &bigMin, Maximum: &bigMax}
+	require.NoError(t, big.Validate())
+	for i := int64(0); i < 100; i++ {
+		v, ok := big.Generate(rand.New(rand.NewSource(i))).(int64)
+		if assert.True(t, ok) {
+			assert.True(t, v >= -4e18 && v <= 4e18, "%d is out of bounds", v)
+		}
+	}
+
+	minLength := 20
+	lmin, lmax := intBounds(&minLength, nil, DefaultMinLength, DefaultMaxLength)
+	assert.Equal(t, []int{20, 20}, []int{lmin, lmax})
+}
+
+func boundsOf(s *Schema) []float64 {
+	min, max := s.bounds()
+	return []float64{min, max}
+}
@@ -0,0 +1,231 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// The defaults used when a schema doesn't specify the corresponding bounds
+const (
+	DefaultMinimum   = 0
+	DefaultMaximum   = 1000
+	DefaultMinLength = 0
+	DefaultMaxLength = 16
+	DefaultMinItems  = 0
+	DefaultMaxItems  = 10
+)
+
+const stringChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Schema describes the values a Generator produces. It's a small subset of JSON Schema:
+// a value either has one of the supported types or is picked from a list of enum values.
+type Schema struct {
+	Type string        `json:"type"`
+	Enum []interface{} `json:"enum"`
+
+	// Bounds for "integer" and "number", inclusive
+	Minimum *float64 `json:"minimum"`
+	Maximum *float64 `json:"maximum"`
+
+	// Bounds for the length of "string"
+	MinLength *int `json:"minLength"`
+	MaxLength *int `json:"maxLength"`
+
+	// Element schema and length bounds for "array"
+	Items    *Schema `json:"items"`
+	MinItems *int    `json:"minItems"`
+	MaxItems *int    `json:"maxItems"`
+
+	// Property schemas for "object"
+	Properties map[string]*Schema `json:"properties"`
+}
+
+// ParseSchema converts an exported JS schema descriptor into a validated Schema.
+func ParseSchema(descriptor interface{}) (*Schema, error) {
+	data, err := json.Marshal(descriptor)
+	if err != nil {
+		return nil, err
+	}
+	var s Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %s", err)
+	}
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Validate checks that the schema and all of its nested schemas can be generated.
+func (s *Schema) Validate() error {
+	return s.validate("schema")
+}
+
+func (s *Schema) validate(path string) error {
+	if len(s.Enum) > 0 {
+		return nil
+	}
+	switch s.Type {
+	case "boolean", "null":
+	case "integer":
+		if _, _, err := s.integerBounds(); err != nil {
+			return fmt.Errorf("%s: %s", path, err)
+		}
+	case "number":
+		if min, max := s.bounds(); min > max {
+			return fmt.Errorf("%s: no number between %g and %g", path, min, max)
+		}
+	case "string":
+		min, max := intBounds(s.MinLength, s.MaxLength, DefaultMinLength, DefaultMaxLength)
+		if min < 0 || min > max {
+			return fmt.Errorf("%s: invalid length bounds [%d, %d]", path, min, max)
+		}
+	case "array":
+		min, max := intBounds(s.MinItems, s.MaxItems, DefaultMinItems, DefaultMaxItems)
+		if min < 0 || min > max {
+			return fmt.Errorf("%s: invalid item bounds [%d, %d]", path, min, max)
+		}
+		if s.Items == nil {
+			return fmt.Errorf("%s: arrays need an items schema", path)
+		}
+		return s.Items.validate(path + "[]")
+	case "object":
+		for name, prop := range s.Properties {
+			if prop == nil {
+				return fmt.Errorf("%s.%s: empty schema", path, name)
+			}
+			if err := prop.validate(path + "." + name); err != nil {
+				return err
+			}
+		}
+	case "":
+		return fmt.Errorf("%s: either a type or enum values are required", path)
+	default:
+		return fmt.Errorf("%s: unsupported type '%s'", path, s.Type)
+	}
+	return nil
+}
+
+// Generate returns a random value conforming to the schema. The same sequence of values is
+// generated for the same schema and state of r.
+func (s *Schema) Generate(r *rand.Rand) interface{} {
+	if len(s.Enum) > 0 {
+		return s.Enum[r.Intn(len(s.Enum))]
+	}
+	switch s.Type {
+	case "boolean":
+		return r.Intn(2) == 1
+	case "integer":
+		min, max, _ := s.integerBounds()
+		return min + r.Int63n(max-min+1)
+	case "number":
+		min, max := s.bounds()
+		return min + r.Float64()*(max-min)
+	case "string":
+		min, max := intBounds(s.MinLength, s.MaxLength, DefaultMinLength, DefaultMaxLength)
+		b := make([]byte, min+r.Intn(max-min+1))
+		for i := range b {
+			b[i] = stringChars[r.Intn(len(stringChars))]
+		}
+		return string(b)
+	case "array":
+		min, max := intBounds(s.MinItems, s.MaxItems, DefaultMinItems, DefaultMaxItems)
+		items := make([]interface{}, min+r.Intn(max-min+1))
+		for i := range items {
+			items[i] = s.Items.Generate(r)
+		}
+		return items
+	case "object":
+		// Map iteration order is random, so the properties are sorted to keep the output seeded
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		obj := make(map[string]interface{}, len(names))
+		for _, name := range names {
+			obj[name] = s.Properties[name].Generate(r)
+		}
+		return obj
+	default:
+		return nil
+	}
+}
+
+// bounds returns the range of numbers. If only one of the bounds is set and it's outside of
+// the default range, the range is shifted so that it still has the default width.
+func (s *Schema) bounds() (min, max float64) {
+	min, max = DefaultMinimum, DefaultMaximum
+	switch {
+	case s.Minimum != nil && s.Maximum != nil:
+		min, max = *s.Minimum, *s.Maximum
+	case s.Minimum != nil:
+		min = *s.Minimum
+		if min > max {
+			max = min + DefaultMaximum - DefaultMinimum
+		}
+	case s.Maximum != nil:
+		max = *s.Maximum
+		if max < min {
+			min = max - DefaultMaximum + DefaultMinimum
+		}
+	}
+	return min, max
+}
+
+// integerBounds returns the range of integers, which has to fit into an int64, as does the
+// number of integers in it.
+func (s *Schema) integerBounds() (int64, int64, error) {
+	min, max := s.bounds()
+	min, max = math.Ceil(min), math.Floor(max)
+	if min > max {
+		return 0, 0, fmt.Errorf("no integer between %g and %g", min, max)
+	}
+	// float64(math.MaxInt64) is rounded up to 2^63, which is already out of range
+	if min < math.MinInt64 || max >= math.MaxInt64 {
+		return 0, 0, fmt.Errorf("the integer bounds %g and %g don't fit into 64 bits", min, max)
+	}
+	lo, hi := int64(min), int64(max)
+	if lo <= 0 && hi >= math.MaxInt64+lo {
+		return 0, 0, fmt.Errorf("too many integers between %g and %g", min, max)
+	}
+	return lo, hi, nil
+}
+
+// intBounds returns the range of lengths, where a minimum that's larger than the default
+// maximum is also used as the maximum, if the latter isn't set.
+func intBounds(minPtr, maxPtr *int, min, max int) (int, int) {
+	if minPtr != nil {
+		min = *minPtr
+		if maxPtr == nil && min > max {
+			max = min
+		}
+	}
+	if maxPtr != nil {
+		max = *maxPtr
+	}
+	return min, max
+}
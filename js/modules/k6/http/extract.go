@@ -0,0 +1,115 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package http
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/dop251/goja"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/netext/httpext"
+)
+
+// ErrGetVarInInitContext is returned when http.getVar() is used in the init context
+var ErrGetVarInInitContext = common.NewInitContextError("Using http.getVar() in the init context is not supported")
+
+// GetVar returns the value that was captured by the extract param of an earlier request in the
+// current iteration, or undefined if there isn't one.
+func (*HTTP) GetVar(ctx context.Context, name string) (goja.Value, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrGetVarInInitContext
+	}
+	value, ok := state.Vars[name]
+	if !ok {
+		return goja.Undefined(), nil
+	}
+	return common.GetRuntime(ctx).ToValue(value), nil
+}
+
+// parseExtractors parses the extract param, an object with the names of the variables as keys
+// and {json: path}, {regex: pattern} or {header: name} objects as values.
+func parseExtractors(rt *goja.Runtime, v goja.Value) ([]httpext.Extractor, error) {
+	obj := v.ToObject(rt)
+	extractors := make([]httpext.Extractor, 0, len(obj.Keys()))
+	for _, name := range obj.Keys() {
+		invalid := fmt.Errorf(
+			"invalid extract value for '%s', expected an object with one of the json, regex or header properties", name,
+		)
+		spec, ok := obj.Get(name).Export().(map[string]interface{})
+		if !ok || len(spec) != 1 {
+			return nil, invalid
+		}
+		e := httpext.Extractor{Name: name}
+		for kind, value := range spec {
+			str, ok := value.(string)
+			if !ok || str == "" {
+				return nil, invalid
+			}
+			switch kind {
+			case "json":
+				e.JSON = str
+			case "header":
+				e.Header = str
+			case "regex":
+				re, err := regexp.Compile(str)
+				if err != nil {
+					return nil, fmt.Errorf("invalid extract regex for '%s': %s", name, err)
+				}
+				e.Regex = re
+			default:
+				return nil, invalid
+			}
+		}
+		extractors = append(extractors, e)
+	}
+	return extractors, nil
+}
+
+// storeExtractedVars saves the values that the extractors of the request captured from its
+// response in the iteration variables. A value that isn't found is an error if the request
+// throws, and a warning otherwise, and the variable is unset either way.
+func storeExtractedVars(state *lib.State, req *httpext.ParsedHTTPRequest, resp *httpext.Response) error {
+	if req.Skip {
+		return nil
+	}
+	for _, e := range req.Extractors {
+		value, ok := e.Extract(resp)
+		if !ok {
+			delete(state.Vars, e.Name)
+			err := fmt.Errorf("couldn't extract '%s' from the response of %s", e.Name, req.URL.URL)
+			if req.Throw {
+				return err
+			}
+			state.Logger.Warn(err)
+			continue
+		}
+		if state.Vars == nil {
+			state.Vars = make(map[string]string)
+		}
+		state.Vars[e.Name] = value
+	}
+	return nil
+}
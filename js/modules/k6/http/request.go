@@ -23,6 +23,8 @@ package http
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
@@ -111,6 +113,9 @@ func (h *HTTP) Request(ctx context.Context, method string, url goja.Value, args
 	if err != nil {
 		return nil, err
 	}
+	if err := storeExtractedVars(lib.GetState(ctx), req, resp); err != nil {
+		return nil, err
+	}
 	return responseFromHttpext(resp), nil
 }
 
@@ -241,6 +246,8 @@ func (h *HTTP) parseRequest(
 		result.ActiveJar = state.CookieJar
 	}
 
+	var tagger goja.Callable
+
 	// TODO: ditch goja.Value, reflections and Object and use a simple go map and type assertions?
 	if params != nil && !goja.IsUndefined(params) && !goja.IsNull(params) {
 		params := params.ToObject(rt)
@@ -345,10 +352,76 @@ func (h *HTTP) parseRequest(
 					return nil, err
 				}
 				result.ResponseType = responseType
+			case "dependsOn":
+				failed, err := dependencyFailed(params.Get(k).Export())
+				if err != nil {
+					return nil, err
+				}
+				result.Skip = failed
+			case "expectedStatuses":
+				var statuses []int
+				if err := rt.ExportTo(params.Get(k), &statuses); err != nil {
+					return nil, fmt.Errorf("invalid expectedStatuses value, expected an array of statuses: %s", err)
+				}
+				result.ExpectedStatuses = statuses
+			case "onRecord":
+				onRecordV := params.Get(k)
+				if goja.IsUndefined(onRecordV) || goja.IsNull(onRecordV) {
+					continue
+				}
+				onRecord, ok := goja.AssertFunction(onRecordV)
+				if !ok {
+					return nil, fmt.Errorf("invalid onRecord value, expected a function")
+				}
+				result.OnRecord = func(record json.RawMessage) error {
+					var value interface{}
+					if err := json.Unmarshal(record, &value); err != nil {
+						return err
+					}
+					_, err := onRecord(goja.Undefined(), rt.ToValue(value))
+					return err
+				}
+			case "proxy":
+				proxyV := params.Get(k)
+				if goja.IsUndefined(proxyV) || goja.IsNull(proxyV) {
+					continue
+				}
+				proxy, err := httpext.ParseProxyURL(proxyV.String())
+				if err != nil {
+					return nil, err
+				}
+				result.Proxy = proxy
+			case "extract":
+				extractV := params.Get(k)
+				if goja.IsUndefined(extractV) || goja.IsNull(extractV) {
+					continue
+				}
+				extractors, err := parseExtractors(rt, extractV)
+				if err != nil {
+					return nil, err
+				}
+				result.Extractors = extractors
+			case "tagger":
+				taggerV := params.Get(k)
+				if goja.IsUndefined(taggerV) || goja.IsNull(taggerV) {
+					continue
+				}
+				var ok bool
+				if tagger, ok = goja.AssertFunction(taggerV); !ok {
+					return nil, fmt.Errorf("invalid tagger value, expected a function")
+				}
 			}
 		}
 	}
 
+	// The tagger is called after all other params are parsed, so it can see the final request
+	// URL and tags, and the tags it returns take precedence over the static ones.
+	if tagger != nil {
+		if err := applyTagger(rt, tagger, result); err != nil {
+			return nil, err
+		}
+	}
+
 	if result.ActiveJar != nil {
 		httpext.SetRequestCookies(result.Req, result.ActiveJar, result.Cookies)
 	}
@@ -356,6 +429,33 @@ func (h *HTTP) parseRequest(
 	return result, nil
 }
 
+// applyTagger calls a user-supplied JS function with the request details and merges the
+// properties of the object it returns into the request tags.
+func applyTagger(rt *goja.Runtime, tagger goja.Callable, req *httpext.ParsedHTTPRequest) error {
+	tags := make(map[string]string, len(req.Tags))
+	for k, v := range req.Tags {
+		tags[k] = v
+	}
+	info := map[string]interface{}{
+		"method": req.Req.Method,
+		"url":    req.URL.URL,
+		"name":   req.URL.Name,
+		"tags":   tags,
+	}
+	resV, err := tagger(goja.Undefined(), rt.ToValue(info))
+	if err != nil {
+		return err
+	}
+	if goja.IsUndefined(resV) || goja.IsNull(resV) {
+		return nil
+	}
+	resObj := resV.ToObject(rt)
+	for _, key := range resObj.Keys() {
+		req.Tags[key] = resObj.Get(key).String()
+	}
+	return nil
+}
+
 func (h *HTTP) prepareBatchArray(
 	ctx context.Context, requests []interface{},
 ) ([]httpext.BatchParsedHTTPRequest, []*Response, error) {
@@ -442,6 +542,12 @@ func (h *HTTP) Batch(ctx context.Context, reqsV goja.Value) (goja.Value, error)
 			err = e
 		}
 	}
+	// The values are stored once all requests are done, in the order of the requests
+	for _, req := range batchReqs {
+		if e := storeExtractedVars(state, req.ParsedHTTPRequest, req.Response); e != nil && err == nil {
+			err = e
+		}
+	}
 	return common.GetRuntime(ctx).ToValue(results), err
 }
 
@@ -512,7 +618,44 @@ func (h *HTTP) parseBatchRequest(
 		}
 	}
 
-	return h.parseRequest(ctx, method, reqURL, body, params)
+	preq, err := h.parseRequest(ctx, method, reqURL, body, params)
+	if err != nil {
+		return nil, err
+	}
+	// The batched requests are made concurrently, while JS callbacks can only run on the VU's goroutine
+	if preq.OnRecord != nil {
+		return nil, errors.New("the onRecord param isn't supported in http.batch()")
+	}
+	return preq, nil
+}
+
+// dependencyFailed checks whether any of the responses passed as the `dependsOn` request
+// parameter, either a single response or an array of them, has failed. That's decided the same
+// way as for http_req_failed, based on the expected statuses of the dependency's request.
+func dependencyFailed(deps interface{}) (bool, error) {
+	switch d := deps.(type) {
+	case nil:
+		return false, nil
+	case *Response:
+		return d.Error != "" || d.Status == 0 || d.Failed, nil
+	case []*Response: // returned by http.batch()
+		for _, dep := range d {
+			if failed, _ := dependencyFailed(dep); failed {
+				return true, nil
+			}
+		}
+		return false, nil
+	case []interface{}:
+		for _, dep := range d {
+			failed, err := dependencyFailed(dep)
+			if err != nil || failed {
+				return failed, err
+			}
+		}
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid dependsOn value of type %T, expected a response or an array of them", deps)
+	}
 }
 
 func requestContainsFile(data map[string]interface{}) bool {
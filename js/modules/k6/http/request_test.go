@@ -30,8 +30,10 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/cookiejar"
+	"net/http/httptest"
 	"net/url"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -43,6 +45,7 @@ import (
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext/httpext"
 	"github.com/loadimpact/k6/lib/testutils"
 	"github.com/loadimpact/k6/lib/testutils/httpmultibin"
 	"github.com/loadimpact/k6/stats"
@@ -1976,3 +1979,660 @@ func TestDigestAuthWithBody(t *testing.T) {
 	assertRequestMetricsEmitted(t, sampleContainers[0:1], "POST", expectedURL, expectedName, 401, "")
 	assertRequestMetricsEmitted(t, sampleContainers[1:2], "POST", expectedURL, expectedName, 200, "")
 }
+
+func TestRequestDependsOn(t *testing.T) {
+	t.Parallel()
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	countMetrics := func(url string) map[string]int {
+		counts := map[string]int{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				if s.Tags.CloneTags()["url"] == url {
+					counts[s.Metric.Name]++
+				}
+			}
+		}
+		return counts
+	}
+
+	t.Run("failed", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let failed = http.get("HTTPBIN_URL/status/500");
+		let res = http.get("HTTPBIN_URL/get?dep=failed", { dependsOn: failed });
+		if (res.status !== 0) { throw new Error("wrong status: " + res.status); }
+		if (res.error.indexOf("skipped") === -1) { throw new Error("wrong error: " + res.error); }
+		`))
+		require.NoError(t, err)
+		counts := countMetrics(sr("HTTPBIN_URL/get?dep=failed"))
+		assert.Equal(t, 0, counts["http_reqs"])
+		assert.Equal(t, 1, counts["http_reqs_skipped"])
+	})
+
+	t.Run("succeeded", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let ok = http.get("HTTPBIN_URL/status/200");
+		let res = http.get("HTTPBIN_URL/get?dep=succeeded", { dependsOn: ok });
+		if (res.status !== 200) { throw new Error("wrong status: " + res.status); }
+		`))
+		require.NoError(t, err)
+		counts := countMetrics(sr("HTTPBIN_URL/get?dep=succeeded"))
+		assert.Equal(t, 1, counts["http_reqs"])
+		assert.Equal(t, 0, counts["http_reqs_skipped"])
+	})
+
+	t.Run("expected statuses", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let notFound = http.get("HTTPBIN_URL/status/404", { expectedStatuses: [404] });
+		let res = http.get("HTTPBIN_URL/get?dep=expected", { dependsOn: notFound });
+		if (res.status !== 200) { throw new Error("wrong status: " + res.status); }
+		`))
+		require.NoError(t, err)
+		counts := countMetrics(sr("HTTPBIN_URL/get?dep=expected"))
+		assert.Equal(t, 1, counts["http_reqs"])
+
+		_, err = common.RunString(rt, sr(`
+		let created = http.get("HTTPBIN_URL/status/201", { expectedStatuses: [200] });
+		let res = http.get("HTTPBIN_URL/get?dep=unexpected", { dependsOn: created });
+		if (res.status !== 0) { throw new Error("wrong status: " + res.status); }
+		`))
+		require.NoError(t, err)
+		counts = countMetrics(sr("HTTPBIN_URL/get?dep=unexpected"))
+		assert.Equal(t, 0, counts["http_reqs"])
+		assert.Equal(t, 1, counts["http_reqs_skipped"])
+	})
+
+	t.Run("array", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let deps = http.batch(["HTTPBIN_URL/status/200", "HTTPBIN_URL/status/404"]);
+		let res = http.post("HTTPBIN_URL/post", "data", { dependsOn: deps });
+		if (res.status !== 0) { throw new Error("wrong status: " + res.status); }
+		`))
+		require.NoError(t, err)
+		counts := countMetrics(sr("HTTPBIN_URL/post"))
+		assert.Equal(t, 0, counts["http_reqs"])
+		assert.Equal(t, 1, counts["http_reqs_skipped"])
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let failed = http.get("HTTPBIN_URL/status/503");
+		let responses = http.batch([
+			["GET", "HTTPBIN_URL/get?dep=batch1", null, { dependsOn: failed }],
+			["GET", "HTTPBIN_URL/get?dep=batch2"],
+		]);
+		if (responses[0].status !== 0) { throw new Error("wrong status: " + responses[0].status); }
+		if (responses[1].status !== 200) { throw new Error("wrong status: " + responses[1].status); }
+		`))
+		require.NoError(t, err)
+		counts := countMetrics(sr("HTTPBIN_URL/get?dep=batch1"))
+		assert.Equal(t, 0, counts["http_reqs"])
+		assert.Equal(t, 1, counts["http_reqs_skipped"])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/get", { dependsOn: 42 });
+		`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid dependsOn value")
+	})
+}
+
+func TestRequestExpectedStatuses(t *testing.T) {
+	t.Parallel()
+	tb, state, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	getFailed := func(t *testing.T) map[string]float64 {
+		failed := map[string]float64{}
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				if s.Metric.Name == "http_req_failed" {
+					failed[s.Tags.CloneTags()["status"]] = s.Value
+				}
+			}
+		}
+		return failed
+	}
+
+	t.Run("default", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/status/200");
+		http.get("HTTPBIN_URL/status/404");
+		http.get("HTTPBIN_URL/status/500");
+		`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"200": 0, "404": 1, "500": 1}, getFailed(t))
+	})
+
+	t.Run("per request", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/status/404", { expectedStatuses: [200, 404] });
+		http.get("HTTPBIN_URL/status/500", { expectedStatuses: [200, 404] });
+		`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"404": 0, "500": 1}, getFailed(t))
+	})
+
+	t.Run("global", func(t *testing.T) {
+		oldOpts := state.Options
+		defer func() { state.Options = oldOpts }()
+		state.Options.ExpectedStatuses = []int{200, 404}
+
+		_, err := common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/status/404");
+		http.get("HTTPBIN_URL/status/500");
+		http.get("HTTPBIN_URL/status/201", { expectedStatuses: [201] });
+		`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]float64{"201": 0, "404": 0, "500": 1}, getFailed(t))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`http.get("HTTPBIN_URL/status/200", { expectedStatuses: "nope" });`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid expectedStatuses value")
+	})
+}
+
+func TestRequestTagger(t *testing.T) {
+	t.Parallel()
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	getTags := func(t *testing.T) []map[string]string {
+		var result []map[string]string
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				if s.Metric.Name == "http_reqs" {
+					result = append(result, s.Tags.CloneTags())
+				}
+			}
+		}
+		return result
+	}
+
+	t.Run("dynamic tags", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		function tagger(req) {
+			return {
+				endpoint_group: req.url.indexOf("/status/") !== -1 ? "status" : "other",
+				method_lower: req.method.toLowerCase(),
+				my_tag: req.tags.my_tag + "-overridden",
+			};
+		}
+		http.get("HTTPBIN_URL/status/200", { tagger: tagger, tags: { my_tag: "static", other: "yes" } });
+		http.post("HTTPBIN_URL/post", null, { tagger: tagger });
+		`))
+		require.NoError(t, err)
+		tags := getTags(t)
+		require.Len(t, tags, 2)
+		assert.Equal(t, "status", tags[0]["endpoint_group"])
+		assert.Equal(t, "get", tags[0]["method_lower"])
+		assert.Equal(t, "static-overridden", tags[0]["my_tag"])
+		assert.Equal(t, "yes", tags[0]["other"])
+		assert.Equal(t, "other", tags[1]["endpoint_group"])
+		assert.Equal(t, "post", tags[1]["method_lower"])
+		assert.Equal(t, "undefined-overridden", tags[1]["my_tag"])
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let tagger = function(req) { return { region: req.name.indexOf("/get") !== -1 ? "eu" : "us" }; };
+		http.batch([
+			["GET", "HTTPBIN_URL/get", null, { tagger: tagger }],
+			["GET", "HTTPBIN_URL/status/200", null, { tagger: tagger }],
+		]);
+		`))
+		require.NoError(t, err)
+		regions := map[string]string{}
+		for _, tags := range getTags(t) {
+			regions[tags["name"]] = tags["region"]
+		}
+		assert.Equal(t, map[string]string{
+			sr("HTTPBIN_URL/get"):        "eu",
+			sr("HTTPBIN_URL/status/200"): "us",
+		}, regions)
+	})
+
+	t.Run("no tags", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/status/200", { tagger: function() {} });
+		`))
+		require.NoError(t, err)
+		tags := getTags(t)
+		require.Len(t, tags, 1)
+		assert.NotContains(t, tags[0], "region")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`http.get("HTTPBIN_URL/status/200", { tagger: "nope" });`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid tagger value")
+
+		_, err = common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/status/200", { tagger: function() { throw new Error("tagger error"); } });
+		`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "tagger error")
+	})
+}
+
+func TestRequestsPerConnection(t *testing.T) {
+	t.Parallel()
+	tb, state, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	getConnRequests := func(t *testing.T) []float64 {
+		var result []float64
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				if s.Metric.Name == "http_conn_requests" {
+					result = append(result, s.Value)
+				}
+			}
+		}
+		return result
+	}
+	script := sr(`for (let i = 0; i < 5; i++) { http.get("HTTPBIN_URL/get"); }`)
+
+	t.Run("limited", func(t *testing.T) {
+		oldOpts := state.Options
+		defer func() { state.Options = oldOpts }()
+		state.Options.MaxRequestsPerConnection = null.IntFrom(2)
+
+		_, err := common.RunString(rt, script)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{1, 2, 1, 2, 1}, getConnRequests(t))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		// The last connection from the previous test only had a single request
+		_, err := common.RunString(rt, script)
+		require.NoError(t, err)
+		assert.Equal(t, []float64{2, 3, 4, 5, 6}, getConnRequests(t))
+	})
+
+	t.Run("tls", func(t *testing.T) {
+		oldOpts := state.Options
+		defer func() { state.Options = oldOpts }()
+		state.Options.MaxRequestsPerConnection = null.IntFrom(2)
+		// The connections under the TLS ones are looked up in the dialer of the VU
+		state.Dialer = tb.Dialer
+
+		_, err := common.RunString(rt, sr(`for (let i = 0; i < 5; i++) { http.get("HTTPSBIN_URL/get"); }`))
+		require.NoError(t, err)
+		assert.Equal(t, []float64{1, 2, 1, 2, 1}, getConnRequests(t))
+	})
+}
+
+func TestRequestProxies(t *testing.T) {
+	t.Parallel()
+	tb, state, _, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+
+	// The mock proxies respond to every request themselves, with their name and the proxied URL
+	proxyURLs := make([]string, 3)
+	for i := range proxyURLs {
+		name := fmt.Sprintf("proxy%d", i+1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = fmt.Fprintf(w, "%s %s", name, r.URL)
+		}))
+		defer srv.Close()
+		proxyURLs[i] = srv.URL
+	}
+
+	selector, err := httpext.NewProxySelector(proxyURLs[:2])
+	require.NoError(t, err)
+	state.Transport = &http.Transport{Proxy: selector.Proxy}
+	rt.Set("proxy3", proxyURLs[2])
+
+	v, err := common.RunString(rt, `
+	let bodies = [];
+	for (let i = 0; i < 3; i++) {
+		bodies.push(http.get("http://example.invalid/get?i=" + i).body);
+	}
+	bodies.push(http.get("http://example.invalid/explicit", { proxy: proxy3 }).body);
+	bodies.push(http.get("http://example.invalid/get?i=3").body);
+	bodies`)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		"proxy1 http://example.invalid/get?i=0",
+		"proxy2 http://example.invalid/get?i=1",
+		"proxy1 http://example.invalid/get?i=2",
+		"proxy3 http://example.invalid/explicit",
+		"proxy2 http://example.invalid/get?i=3",
+	}, v.Export())
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := common.RunString(rt, `http.get("http://example.invalid/", { proxy: "ftp://proxy:21" })`)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid proxy URL "ftp://proxy:21": the scheme should be http, https or socks5`)
+	})
+}
+
+func TestRequestQueuedTime(t *testing.T) {
+	t.Parallel()
+	tb, state, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	tb.Mux.HandleFunc("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	script := sr(`http.batch(["HTTPBIN_URL/slow", "HTTPBIN_URL/slow", "HTTPBIN_URL/slow"]);`)
+
+	getQueued := func(t *testing.T) []time.Duration {
+		var result []time.Duration
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				if s.Metric == metrics.HTTPReqQueued {
+					result = append(result, time.Duration(s.Value*float64(time.Millisecond)))
+				}
+			}
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+		return result
+	}
+
+	t.Run("unlimited", func(t *testing.T) {
+		_, err := common.RunString(rt, script)
+		require.NoError(t, err)
+		assert.Empty(t, getQueued(t))
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		oldOpts, oldTransport := state.Options, state.Transport
+		defer func() { state.Options, state.Transport = oldOpts, oldTransport }()
+		state.Options.MaxConnsPerHost = null.IntFrom(1)
+		state.Transport = &http.Transport{DialContext: tb.HTTPTransport.DialContext, MaxConnsPerHost: 1}
+
+		_, err := common.RunString(rt, script)
+		require.NoError(t, err)
+
+		// The requests go through the single connection one after the other
+		queued := getQueued(t)
+		require.Len(t, queued, 3)
+		assert.True(t, queued[0] < 100*time.Millisecond, "first request queued for %s", queued[0])
+		assert.True(t, queued[1] >= 150*time.Millisecond, "second request queued for %s", queued[1])
+		assert.True(t, queued[2] >= 350*time.Millisecond, "third request queued for %s", queued[2])
+	})
+}
+
+func TestRequestOnRecord(t *testing.T) {
+	t.Parallel()
+	tb, _, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	// Every record is only sent after the previous one has been processed by the script
+	next := make(chan struct{}, 10)
+	rt.Set("processed", func() { next <- struct{}{} })
+	tb.Mux.HandleFunc("/ndjson", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			if i > 0 {
+				select {
+				case <-next:
+				case <-time.After(2 * time.Second):
+					return
+				}
+			}
+			_, _ = fmt.Fprintf(w, `{"id": %d, "tags": ["a", "b"]}`+"\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}))
+	tb.Mux.HandleFunc("/endless", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; ; i++ {
+			if _, err := fmt.Fprintf(w, `{"id": %d}`, i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}))
+	tb.Mux.HandleFunc("/invalid", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"id": 0} {"id": `)
+	}))
+
+	t.Run("incremental", func(t *testing.T) {
+		v, err := common.RunString(rt, sr(`
+		let records = [];
+		let res = http.get("HTTPBIN_URL/ndjson", { onRecord: function(r) { records.push(r); processed(); } });
+		if (res.status !== 200) { throw new Error("wrong status: " + res.status); }
+		if (res.body !== null) { throw new Error("the body was buffered: " + res.body); }
+		JSON.stringify(records)`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `[{"id":0,"tags":["a","b"]},{"id":1,"tags":["a","b"]},{"id":2,"tags":["a","b"]}]`, v.String())
+		assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/ndjson"), "", 200, "")
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		start := time.Now()
+		_, err := common.RunString(rt, sr(`
+		let count = 0;
+		http.get("HTTPBIN_URL/endless", { onRecord: function(r) {
+			if (++count === 3) { throw new Error("enough records"); }
+		}});`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "enough records")
+		assert.True(t, time.Since(start) < time.Second, "the endless body was read for %s", time.Since(start))
+		assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/endless"), "", 200, "")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let ids = [];
+		http.get("HTTPBIN_URL/invalid", { onRecord: function(r) { ids.push(r.id); } });`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unexpected EOF")
+
+		_, err = common.RunString(rt, sr(`
+		http.batch([["GET", "HTTPBIN_URL/ndjson", null, { onRecord: function(r) {} }]]);`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "the onRecord param isn't supported in http.batch()")
+	})
+}
+
+func TestRequestExtract(t *testing.T) {
+	t.Parallel()
+	tb, state, _, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	tb.Mux.HandleFunc("/login", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Session", "session-1")
+		_, _ = fmt.Fprint(w, `{"auth": {"token": "token-1"}, "form": "<input name=csrf value=csrf-1>"}`)
+	}))
+	tb.Mux.HandleFunc("/orders", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" || r.Header.Get("X-Session") != "session-1" ||
+			r.URL.Query().Get("csrf") != "csrf-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"orders": [{"id": 42}]}`)
+	}))
+
+	t.Run("subsequent requests", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/login", { extract: {
+			token: { json: "auth.token" },
+			session: { header: "x-session" },
+			csrf: { regex: "value=([\\w-]+)" },
+		}});
+		let res = http.get("HTTPBIN_URL/orders?csrf=" + http.getVar("csrf"), {
+			headers: { "Authorization": "Bearer " + http.getVar("token"), "X-Session": http.getVar("session") },
+			extract: { orderId: { json: "orders.0.id" } },
+		});
+		if (res.status !== 200) { throw new Error("wrong status: " + res.status); }
+		if (http.getVar("orderId") !== "42") { throw new Error("wrong order ID: " + http.getVar("orderId")); }
+		if (http.getVar("missing") !== undefined) { throw new Error("unexpected variable"); }
+		`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"token": "token-1", "session": "session-1", "csrf": "csrf-1", "orderId": "42",
+		}, state.Vars)
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		state.Vars = nil
+		_, err := common.RunString(rt, sr(`
+		http.batch([
+			["GET", "HTTPBIN_URL/login", null, { extract: { token: { json: "auth.token" } } }],
+			["GET", "HTTPBIN_URL/get", null, { extract: { url: { json: "url" } } }],
+		]);
+		if (http.getVar("token") !== "token-1") { throw new Error("wrong token: " + http.getVar("token")); }
+		if (http.getVar("url") !== "HTTPBIN_URL/get") { throw new Error("wrong url: " + http.getVar("url")); }
+		`))
+		require.NoError(t, err)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		state.Vars = map[string]string{"token": "stale"}
+		hook := logtest.NewLocal(state.Logger)
+		defer hook.Reset()
+		_, err := common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/get", { throw: false, extract: { token: { json: "auth.token" } } });
+		if (http.getVar("token") !== undefined) { throw new Error("stale token: " + http.getVar("token")); }
+		`))
+		require.NoError(t, err)
+		require.NotNil(t, hook.LastEntry())
+		assert.Contains(t, hook.LastEntry().Message, "couldn't extract 'token'")
+
+		_, err = common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/get", { throw: true, extract: { token: { json: "auth.token" } } });
+		`))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), sr("couldn't extract 'token' from the response of HTTPBIN_URL/get"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for _, extract := range []string{`{ token: "auth.token" }`, `{ token: { json: "a", header: "b" } }`,
+			`{ token: { xpath: "//a" } }`, `{ token: { regex: "(" } }`} {
+			_, err := common.RunString(rt, sr(`http.get("HTTPBIN_URL/get", { extract: `+extract+` });`))
+			require.Error(t, err, extract)
+			assert.Contains(t, err.Error(), "invalid extract", extract)
+		}
+	})
+}
+
+func TestResponseHeaderLimits(t *testing.T) {
+	t.Parallel()
+	tb, state, samples, rt, _ := newRuntime(t)
+	state.Options.Throw = null.BoolFrom(false)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	tb.Mux.HandleFunc("/many-headers", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 20; i++ {
+			w.Header().Add("X-Many", strconv.Itoa(i))
+		}
+	}))
+	tb.Mux.HandleFunc("/large-header", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Large", strings.Repeat("a", 8192))
+	}))
+
+	checkError := func(t *testing.T, url string, code int, msg string) {
+		stats.GetBufferedSamples(samples)
+		_, err := common.RunString(rt, sr(fmt.Sprintf(`
+		let res = http.get(%q);
+		if (res.status != 0) { throw new Error("wrong status: " + res.status); }
+		if (res.error_code != %d) { throw new Error("wrong error_code: " + res.error_code); }
+		if (res.error.indexOf(%q) === -1) { throw new Error("wrong error: " + res.error); }`, url, code, msg)))
+		require.NoError(t, err)
+		for _, c := range stats.GetBufferedSamples(samples) {
+			for _, sample := range c.GetSamples() {
+				checkErrorCode(t, sample.GetTags(), code, msg)
+			}
+		}
+	}
+
+	t.Run("unlimited", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = http.get("HTTPBIN_URL/many-headers");
+		if (res.status != 200) { throw new Error("wrong status: " + res.status); }
+		res = http.get("HTTPBIN_URL/large-header");
+		if (res.status != 200) { throw new Error("wrong status: " + res.status); }`))
+		require.NoError(t, err)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		oldOpts := state.Options
+		defer func() { state.Options = oldOpts }()
+		state.Options.MaxResponseHeaders = null.IntFrom(10)
+
+		checkError(t, "HTTPBIN_URL/many-headers", 1703,
+			"the response has 22 headers, more than the maxResponseHeaders limit of 10")
+	})
+
+	t.Run("size", func(t *testing.T) {
+		oldOpts, oldTransport := state.Options, state.Transport
+		defer func() { state.Options, state.Transport = oldOpts, oldTransport }()
+		state.Options.MaxResponseHeaderBytes = null.IntFrom(4096)
+		state.Transport = &http.Transport{DialContext: tb.HTTPTransport.DialContext, MaxResponseHeaderBytes: 4096}
+
+		checkError(t, "HTTPBIN_URL/large-header", 1702,
+			"the response headers are larger than the maxResponseHeaderBytes limit of 4096 bytes")
+	})
+}
+
+func TestRequestConnPoolExhausted(t *testing.T) {
+	t.Parallel()
+	tb, state, samples, rt, _ := newRuntime(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	tb.Mux.HandleFunc("/slow", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	script := sr(`http.batch(["HTTPBIN_URL/slow", "HTTPBIN_URL/slow", "HTTPBIN_URL/slow"]);`)
+
+	getExhausted := func(t *testing.T) []float64 {
+		var result []float64
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, s := range sc.GetSamples() {
+				if s.Metric == metrics.ConnPoolExhausted {
+					result = append(result, s.Value)
+				}
+			}
+		}
+		return result
+	}
+
+	t.Run("unlimited", func(t *testing.T) {
+		_, err := common.RunString(rt, script)
+		require.NoError(t, err)
+		assert.Empty(t, getExhausted(t))
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		oldOpts, oldTransport := state.Options, state.Transport
+		defer func() { state.Options, state.Transport, state.ConnPool = oldOpts, oldTransport, nil }()
+		state.Options.MaxConnsPerHost = null.IntFrom(1)
+		state.Transport = &http.Transport{DialContext: tb.HTTPTransport.DialContext, MaxConnsPerHost: 1}
+		state.ConnPool = lib.NewConnPoolTracker(1)
+
+		// Only the first request gets the single connection right away
+		_, err := common.RunString(rt, script)
+		require.NoError(t, err)
+		exhausted := getExhausted(t)
+		sort.Float64s(exhausted)
+		assert.Equal(t, []float64{0, 1, 1}, exhausted)
+
+		// Sequential requests never have to wait
+		_, err = common.RunString(rt, sr(`
+		http.get("HTTPBIN_URL/slow");
+		http.get("HTTPBIN_URL/slow");`))
+		require.NoError(t, err)
+		assert.Equal(t, []float64{0, 0}, getExhausted(t))
+	})
+}
@@ -28,9 +28,11 @@ import (
 	"testing"
 
 	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext/httpext"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
+	null "gopkg.in/guregu/null.v3"
 )
 
 const testGetFormHTML = `
@@ -245,6 +247,55 @@ func TestResponse(t *testing.T) {
 		assertRequestMetricsEmitted(t, stats.GetBufferedSamples(samples), "GET", sr("HTTPBIN_URL/json"), "", 200, "")
 	})
 
+	t.Run("JSONNumbers", func(t *testing.T) {
+		tb.Mux.HandleFunc("/bigint", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"id": 9007199254740993, "small": 42, "pi": 3.14, "ids": [-12345678901234567890]}`))
+		})
+		defer func() { state.Options.JSONNumbers = null.String{} }()
+
+		testCases := []struct {
+			mode, script string
+		}{
+			{"", `
+				if (data.id !== 9007199254740992) { throw new Error("wrong id: " + data.id); }
+				if (data.small !== 42) { throw new Error("wrong small: " + data.small); }
+			`},
+			{lib.JSONNumbersFloat, `
+				if (data.id !== 9007199254740992) { throw new Error("wrong id: " + data.id); }
+			`},
+			{lib.JSONNumbersString, `
+				if (data.id !== "9007199254740993") { throw new Error("wrong id: " + data.id); }
+				if (data.small !== "42") { throw new Error("wrong small: " + data.small); }
+				if (data.pi !== "3.14") { throw new Error("wrong pi: " + data.pi); }
+				if (data.ids[0] !== "-12345678901234567890") { throw new Error("wrong ids: " + data.ids); }
+				if (res.json("id") !== "9007199254740993") { throw new Error("wrong selected id: " + res.json("id")); }
+			`},
+			{lib.JSONNumbersSafe, `
+				if (data.id !== "9007199254740993") { throw new Error("wrong id: " + data.id); }
+				if (data.small !== 42) { throw new Error("wrong small: " + data.small); }
+				if (data.pi !== 3.14) { throw new Error("wrong pi: " + data.pi); }
+				if (data.ids[0] !== "-12345678901234567890") { throw new Error("wrong ids: " + data.ids); }
+				if (res.json("id") !== "9007199254740993") { throw new Error("wrong selected id: " + res.json("id")); }
+				if (res.json("small") !== 42) { throw new Error("wrong selected small: " + res.json("small")); }
+			`},
+		}
+		for _, tc := range testCases {
+			tc := tc
+			name := tc.mode
+			if name == "" {
+				name = "default"
+			}
+			t.Run(name, func(t *testing.T) {
+				state.Options.JSONNumbers = null.NewString(tc.mode, tc.mode != "")
+				_, err := common.RunString(rt, sr(`
+					let res = http.request("GET", "HTTPBIN_URL/bigint");
+					let data = res.json();
+				`+tc.script))
+				assert.NoError(t, err)
+			})
+		}
+	})
 	t.Run("SubmitForm", func(t *testing.T) {
 		t.Run("withoutArgs", func(t *testing.T) {
 			_, err := common.RunString(rt, sr(`
@@ -24,6 +24,7 @@ import (
 	"context"
 	"math/rand"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -33,9 +34,13 @@ import (
 	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
 )
 
-type K6 struct{}
+type K6 struct {
+	barriers   map[string]*Barrier
+	barriersMu sync.Mutex
+}
 
 // ErrGroupInInitContext is returned when group() are using in the init context
 var ErrGroupInInitContext = common.NewInitContextError("Using group() in the init context is not supported")
@@ -43,8 +48,19 @@ var ErrGroupInInitContext = common.NewInitContextError("Using group() in the ini
 // ErrCheckInInitContext is returned when check() are using in the init context
 var ErrCheckInInitContext = common.NewInitContextError("Using check() in the init context is not supported")
 
+// ErrSetIterationTagInInitContext is returned when setIterationTag() is used in the init context
+var ErrSetIterationTagInInitContext = common.NewInitContextError(
+	"Using setIterationTag() in the init context is not supported")
+
+// ErrMarkIterationInInitContext is returned when markIteration() is used in the init context
+var ErrMarkIterationInInitContext = common.NewInitContextError(
+	"Using markIteration() in the init context is not supported")
+
+// ErrEmitEventInInitContext is returned when emitEvent() is used in the init context
+var ErrEmitEventInInitContext = common.NewInitContextError("Using emitEvent() in the init context is not supported")
+
 func New() *K6 {
-	return &K6{}
+	return &K6{barriers: make(map[string]*Barrier)}
 }
 
 func (*K6) Fail(msg string) (goja.Value, error) {
@@ -67,6 +83,67 @@ func (*K6) RandomSeed(ctx context.Context, seed int64) {
 	rt.SetRandSource(randSource)
 }
 
+// SetIterationTag sets a tag that is applied to all metrics emitted after it for the rest of the
+// current iteration, e.g. to tag them with the kind of user that the iteration simulates.
+func (*K6) SetIterationTag(ctx context.Context, name, value string) (goja.Value, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrSetIterationTagInInitContext
+	}
+	if name == "" {
+		return nil, errors.New("setIterationTag() requires a non-empty tag name")
+	}
+	if state.Tags == nil {
+		state.Tags = make(map[string]string)
+	}
+	state.Tags[name] = value
+	return goja.Undefined(), nil
+}
+
+// MarkIteration records whether the current iteration succeeded, by whatever definition of
+// success the script has. It's emitted as the iteration_success rate when the iteration ends,
+// so if it's called multiple times during an iteration, only the last call counts.
+func (*K6) MarkIteration(ctx context.Context, success bool) (goja.Value, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrMarkIterationInInitContext
+	}
+	state.IterationOutcome = null.BoolFrom(success)
+	return goja.Undefined(), nil
+}
+
+// EmitEvent sends an event with the given name and optional data and tags to the outputs that
+// support events, e.g. to annotate the deployment of a new version on a dashboard.
+func (*K6) EmitEvent(
+	ctx context.Context, name string, data map[string]interface{}, extraTags ...map[string]string,
+) (goja.Value, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrEmitEventInInitContext
+	}
+	if name == "" {
+		return nil, errors.New("emitEvent() requires a non-empty event name")
+	}
+
+	tags := state.CloneTags()
+	if state.Options.SystemTags.Has(stats.TagGroup) {
+		tags["group"] = state.Group.Path
+	}
+	for _, ts := range extraTags {
+		for k, v := range ts {
+			tags[k] = v
+		}
+	}
+
+	stats.PushIfNotDone(ctx, state.Samples, &stats.Event{
+		Time: time.Now(),
+		Name: name,
+		Tags: stats.IntoSampleTags(&tags),
+		Data: data,
+	})
+	return goja.Undefined(), nil
+}
+
 func (*K6) Group(ctx context.Context, name string, fn goja.Callable) (goja.Value, error) {
 	state := lib.GetState(ctx)
 	if state == nil {
@@ -82,6 +159,10 @@ func (*K6) Group(ctx context.Context, name string, fn goja.Callable) (goja.Value
 		return goja.Undefined(), err
 	}
 
+	// The data exchanged before the group is attributed to the parent group, and the data
+	// exchanged inside of it to the group itself, so bandwidth can be tracked per group.
+	pushDataSamples(ctx, state, time.Now(), getGroupTags(state, state.Group))
+
 	old := state.Group
 	state.Group = g
 	defer func() { state.Group = old }()
@@ -90,7 +171,20 @@ func (*K6) Group(ctx context.Context, name string, fn goja.Callable) (goja.Value
 	ret, err := fn(goja.Undefined())
 	t := time.Now()
 
-	tags := state.Options.RunTags.CloneTags()
+	tags := getGroupTags(state, g)
+	pushDataSamples(ctx, state, t, tags)
+	stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+		Time:   t,
+		Metric: metrics.GroupDuration,
+		Tags:   tags,
+		Value:  stats.D(t.Sub(startTime)),
+	})
+
+	return ret, err
+}
+
+func getGroupTags(state *lib.State, g *lib.Group) *stats.SampleTags {
+	tags := state.CloneTags()
 	if state.Options.SystemTags.Has(stats.TagGroup) {
 		tags["group"] = g.Path
 	}
@@ -100,15 +194,29 @@ func (*K6) Group(ctx context.Context, name string, fn goja.Callable) (goja.Value
 	if state.Options.SystemTags.Has(stats.TagIter) {
 		tags["iter"] = strconv.FormatInt(state.Iteration, 10)
 	}
+	return stats.IntoSampleTags(&tags)
+}
 
-	stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
-		Time:   t,
-		Metric: metrics.GroupDuration,
-		Tags:   stats.IntoSampleTags(&tags),
-		Value:  stats.D(t.Sub(startTime)),
-	})
+// dataSampler is implemented by dialers that keep track of the exchanged data,
+// like netext.Dialer
+type dataSampler interface {
+	GetDataSamples(t time.Time, tags *stats.SampleTags) stats.SampleContainer
+}
 
-	return ret, err
+// pushDataSamples emits the data_sent and data_received samples for the data that was
+// exchanged since they were last emitted. Without the group tag, the samples couldn't be
+// told apart, so they're left to be emitted once, at the end of the iteration.
+func pushDataSamples(ctx context.Context, state *lib.State, t time.Time, tags *stats.SampleTags) {
+	if !state.Options.SystemTags.Has(stats.TagGroup) {
+		return
+	}
+	sampler, ok := state.Dialer.(dataSampler)
+	if !ok {
+		return
+	}
+	if samples := sampler.GetDataSamples(t, tags); samples != nil {
+		stats.PushIfNotDone(ctx, state.Samples, samples)
+	}
 }
 
 func (*K6) Check(ctx context.Context, arg0, checks goja.Value, extras ...goja.Value) (bool, error) {
@@ -120,7 +228,7 @@ func (*K6) Check(ctx context.Context, arg0, checks goja.Value, extras ...goja.Va
 	t := time.Now()
 
 	// Prepare tags, make sure the `group` tag can't be overwritten.
-	commonTags := state.Options.RunTags.CloneTags()
+	commonTags := state.CloneTags()
 	if state.Options.SystemTags.Has(stats.TagGroup) {
 		commonTags["group"] = state.Group.Path
 	}
@@ -179,6 +287,7 @@ func (*K6) Check(ctx context.Context, arg0, checks goja.Value, extras ...goja.Va
 				stats.PushIfNotDone(ctx, state.Samples, stats.Sample{Time: t, Metric: metrics.Checks, Tags: sampleTags, Value: 1})
 			} else {
 				atomic.AddInt64(&check.Fails, 1)
+				state.FailedChecks++
 				stats.PushIfNotDone(ctx, state.Samples, stats.Sample{Time: t, Metric: metrics.Checks, Tags: sampleTags, Value: 0})
 				// A single failure makes the return value false.
 				succ = false
@@ -23,7 +23,9 @@ package k6
 import (
 	"context"
 	"fmt"
+	"net"
 	"runtime"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -33,6 +35,7 @@ import (
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -135,6 +138,108 @@ func TestGroup(t *testing.T) {
 		assert.EqualError(t, err, "GoError: group and check names may not contain '::'")
 	})
 }
+func TestGroupDataSamples(t *testing.T) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	rt := goja.New()
+	dialer := netext.NewDialer(net.Dialer{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:   root,
+		Dialer:  dialer,
+		Samples: samples,
+		Options: lib.Options{SystemTags: &stats.DefaultSystemTagSet},
+	}
+
+	ctx := context.Background()
+	ctx = lib.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+	rt.Set("k6", common.Bind(rt, New(), &ctx))
+
+	// Simulates the traffic of a request, without actually making one
+	rt.Set("transfer", func(sent, received int64) {
+		atomic.AddInt64(&dialer.BytesWritten, sent)
+		atomic.AddInt64(&dialer.BytesRead, received)
+	})
+
+	_, err = common.RunString(rt, `
+		transfer(1, 10);
+		k6.group("outer", function() {
+			transfer(2, 20);
+			k6.group("inner", function() { transfer(3, 30); });
+			k6.group("empty", function() {});
+			transfer(4, 40);
+		});
+		transfer(5, 50);
+	`)
+	require.NoError(t, err)
+
+	type dataKey struct{ metric, group string }
+	data := map[dataKey]float64{}
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, s := range sc.GetSamples() {
+			if s.Metric != metrics.DataSent && s.Metric != metrics.DataReceived {
+				continue
+			}
+			group, _ := s.Tags.Get("group")
+			data[dataKey{s.Metric.Name, group}] += s.Value
+		}
+	}
+	assert.Equal(t, map[dataKey]float64{
+		{"data_sent", ""}:                   1,
+		{"data_received", ""}:               10,
+		{"data_sent", "::outer"}:            6,
+		{"data_received", "::outer"}:        60,
+		{"data_sent", "::outer::inner"}:     3,
+		{"data_received", "::outer::inner"}: 30,
+	}, data)
+
+	// The data exchanged after the last group is left for the end of the iteration
+	assert.Equal(t, int64(5), atomic.LoadInt64(&dialer.BytesWritten))
+	assert.Equal(t, int64(50), atomic.LoadInt64(&dialer.BytesRead))
+}
+
+func TestEmitEvent(t *testing.T) {
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+	g, err := root.Group("deploys")
+	require.NoError(t, err)
+
+	rt := goja.New()
+	baseCtx := common.WithRuntime(context.Background(), rt)
+	ctx := new(context.Context)
+	*ctx = baseCtx
+	rt.Set("k6", common.Bind(rt, New(), ctx))
+
+	_, err = common.RunString(rt, `k6.emitEvent("deployment")`)
+	assert.Contains(t, err.Error(), ErrEmitEventInInitContext.Error())
+
+	samples := make(chan stats.SampleContainer, 10)
+	*ctx = lib.WithState(baseCtx, &lib.State{
+		Group:   g,
+		Options: lib.Options{SystemTags: &stats.DefaultSystemTagSet},
+		Tags:    map[string]string{"vu": "1"},
+		Samples: samples,
+	})
+
+	before := time.Now()
+	_, err = common.RunString(rt, `k6.emitEvent("deployment", { version: "1.2", canary: true }, { env: "staging" })`)
+	require.NoError(t, err)
+	bufSamples := stats.GetBufferedSamples(samples)
+	require.Len(t, bufSamples, 1)
+	event, ok := bufSamples[0].(*stats.Event)
+	require.True(t, ok)
+	assert.Equal(t, "deployment", event.Name)
+	assert.Equal(t, map[string]interface{}{"version": "1.2", "canary": true}, event.Data)
+	assert.Equal(t, map[string]string{"vu": "1", "group": "::deploys", "env": "staging"}, event.Tags.CloneTags())
+	assert.False(t, event.Time.Before(before))
+	assert.Empty(t, event.GetSamples())
+
+	_, err = common.RunString(rt, `k6.emitEvent("")`)
+	assert.Contains(t, err.Error(), "emitEvent() requires a non-empty event name")
+}
+
 func TestCheck(t *testing.T) {
 	rt := goja.New()
 
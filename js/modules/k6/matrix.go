@@ -0,0 +1,193 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package k6
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/js/common"
+	"github.com/loadimpact/k6/lib"
+)
+
+// MatrixOperation is one of the operations of a Matrix, with its relative weight and optional
+// parameters, e.g. the URLs it should request.
+type MatrixOperation struct {
+	Name   string      `js:"name"`
+	Weight float64     `js:"weight"`
+	Params interface{} `js:"params"`
+}
+
+// Matrix picks operations at random, according to their weights, e.g. to mix browsing, searching
+// and checking out in a 70/20/10 ratio. Every VU has its own copy of the matrices declared in the
+// init context, so VUs using the same seed pick the same sequence of operations.
+type Matrix struct {
+	operations []MatrixOperation
+	cumWeights []float64
+	rand       *rand.Rand
+	seed       int64
+
+	// If the matrix uses the seed of the run, it's combined with the ID of the VU that the matrix
+	// is used in, like the seed of Math.random(), so the VUs don't pick the same operations.
+	runSeed null.Int
+	vu      int64
+}
+
+// XMatrix is the JS constructor of Matrix, e.g. `new Matrix(operations, seed)`. The operations
+// are either an array of {name, weight, params} objects, or an object with the weights by
+// operation name. If the seed is omitted, the seed of the run combined with the VU ID is used, or
+// a time-based one if the run doesn't have one.
+func (*K6) XMatrix(ctx *context.Context, operations goja.Value, seed ...int64) (interface{}, error) {
+	if operations == nil || goja.IsUndefined(operations) || goja.IsNull(operations) {
+		return nil, errors.New("a matrix requires operations")
+	}
+	ops, err := parseMatrixOperations(operations.Export())
+	if err != nil {
+		return nil, err
+	}
+
+	var runSeed null.Int
+	s, ok := lib.GetRunSeed(*ctx)
+	switch {
+	case len(seed) > 0:
+		s = seed[0]
+	case ok:
+		runSeed = null.IntFrom(s)
+	default:
+		s = time.Now().UnixNano()
+	}
+	m, err := NewMatrix(ops, s)
+	if err != nil {
+		return nil, err
+	}
+	m.runSeed = runSeed
+	return common.Bind(common.GetRuntime(*ctx), m, ctx), nil
+}
+
+// NewMatrix returns a new matrix for the given operations, which need positive weights
+func NewMatrix(operations []MatrixOperation, seed int64) (*Matrix, error) {
+	if len(operations) == 0 {
+		return nil, errors.New("a matrix requires at least one operation")
+	}
+	cumWeights := make([]float64, len(operations))
+	total := 0.0
+	for i, op := range operations {
+		if op.Name == "" {
+			return nil, fmt.Errorf("the operation #%d of the matrix has no name", i)
+		}
+		if !(op.Weight > 0) {
+			return nil, fmt.Errorf("the weight of the operation '%s' should be positive", op.Name)
+		}
+		total += op.Weight
+		cumWeights[i] = total
+	}
+	return &Matrix{
+		operations: operations,
+		cumWeights: cumWeights,
+		rand:       rand.New(rand.NewSource(seed)),
+		seed:       seed,
+	}, nil
+}
+
+// parseMatrixOperations parses the exported JS operations of a matrix. The operations of the
+// object form are sorted by name, so the same seed always picks the same operations.
+func parseMatrixOperations(v interface{}) ([]MatrixOperation, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		ops := make([]MatrixOperation, len(v))
+		for i, o := range v {
+			obj, ok := o.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("the operation #%d of the matrix should be an object", i)
+			}
+			name, _ := obj["name"].(string)
+			weight, err := matrixWeight(name, obj["weight"])
+			if err != nil {
+				return nil, err
+			}
+			ops[i] = MatrixOperation{Name: name, Weight: weight, Params: obj["params"]}
+		}
+		return ops, nil
+	case map[string]interface{}:
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		ops := make([]MatrixOperation, len(names))
+		for i, name := range names {
+			weight, err := matrixWeight(name, v[name])
+			if err != nil {
+				return nil, err
+			}
+			ops[i] = MatrixOperation{Name: name, Weight: weight}
+		}
+		return ops, nil
+	default:
+		return nil, errors.New("the matrix operations should be an array or an object")
+	}
+}
+
+func matrixWeight(name string, v interface{}) (float64, error) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	default:
+		return 0, fmt.Errorf("the weight of the operation '%s' should be a number", name)
+	}
+}
+
+// Pick returns a random operation, with a probability proportional to its weight
+func (m *Matrix) Pick(ctx context.Context) MatrixOperation {
+	m.reseed(ctx)
+	r := m.rand.Float64() * m.cumWeights[len(m.cumWeights)-1]
+	i := sort.Search(len(m.cumWeights), func(i int) bool { return m.cumWeights[i] > r })
+	if i == len(m.cumWeights) { // only possible because of float rounding
+		i--
+	}
+	return m.operations[i]
+}
+
+// reseed derives the seed of a matrix that uses the seed of the run from the ID of the VU it's
+// used in, when it's first used there
+func (m *Matrix) reseed(ctx context.Context) {
+	state := lib.GetState(ctx)
+	if !m.runSeed.Valid || state == nil || state.Vu == m.vu {
+		return
+	}
+	m.vu = state.Vu
+	m.seed = m.runSeed.Int64 + state.Vu
+	m.rand = rand.New(rand.NewSource(m.seed))
+}
+
+// Seed returns the seed the matrix is using, so a run can be reproduced
+func (m *Matrix) Seed() int64 {
+	return m.seed
+}
@@ -0,0 +1,108 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package k6
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/dop251/goja"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/js/common"
+)
+
+func TestMatrix(t *testing.T) {
+	t.Parallel()
+
+	newRuntime := func() *goja.Runtime {
+		rt := goja.New()
+		rt.SetFieldNameMapper(common.FieldNameMapper{})
+		ctx := common.WithRuntime(context.Background(), rt)
+		rt.Set("k6", common.Bind(rt, New(), &ctx))
+		return rt
+	}
+
+	t.Run("Weights", func(t *testing.T) {
+		t.Parallel()
+		rt := newRuntime()
+		v, err := common.RunString(rt, `
+			let matrix = new k6.Matrix([
+				{ name: "browse", weight: 70, params: { url: "/products" } },
+				{ name: "search", weight: 20 },
+				{ name: "checkout", weight: 10 },
+			], 42);
+			let counts = { browse: 0, search: 0, checkout: 0 };
+			for (let i = 0; i < 100000; i++) {
+				let op = matrix.pick();
+				counts[op.name]++;
+				if (op.name === "browse" && op.params.url !== "/products") {
+					throw new Error("wrong params: " + JSON.stringify(op.params));
+				}
+			}
+			counts;
+		`)
+		require.NoError(t, err)
+		counts := v.Export().(map[string]interface{})
+		for name, weight := range map[string]float64{"browse": 0.7, "search": 0.2, "checkout": 0.1} {
+			share := float64(counts[name].(int64)) / 100000
+			assert.True(t, math.Abs(share-weight) < 0.01, "%s was picked %.3f of the time", name, share)
+		}
+	})
+
+	t.Run("Seeded", func(t *testing.T) {
+		t.Parallel()
+		script := `
+			let matrix = new k6.Matrix({ browse: 7, search: 2, checkout: 1 }, 1234);
+			let picks = [];
+			for (let i = 0; i < 50; i++) { picks.push(matrix.pick().name); }
+			[matrix.seed(), picks.join(",")];
+		`
+		v1, err := common.RunString(newRuntime(), script)
+		require.NoError(t, err)
+		v2, err := common.RunString(newRuntime(), script)
+		require.NoError(t, err)
+		assert.Equal(t, v1.Export(), v2.Export())
+		assert.Equal(t, int64(1234), v1.Export().([]interface{})[0])
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Parallel()
+		testdata := map[string]string{
+			`new k6.Matrix()`:                                   "a matrix requires operations",
+			`new k6.Matrix([])`:                                 "a matrix requires at least one operation",
+			`new k6.Matrix("browse")`:                           "the matrix operations should be an array or an object",
+			`new k6.Matrix([1])`:                                "the operation #0 of the matrix should be an object",
+			`new k6.Matrix([{ weight: 1 }])`:                    "the operation #0 of the matrix has no name",
+			`new k6.Matrix([{ name: "browse" }])`:               "the weight of the operation 'browse' should be a number",
+			`new k6.Matrix({ browse: 1, search: 0 })`:           "the weight of the operation 'search' should be positive",
+			`new k6.Matrix([{ name: "browse", weight: -1.5 }])`: "the weight of the operation 'browse' should be positive",
+		}
+		for script, errMsg := range testdata {
+			_, err := common.RunString(newRuntime(), script)
+			if assert.Error(t, err, script) {
+				assert.Contains(t, err.Error(), errMsg)
+			}
+		}
+	})
+}
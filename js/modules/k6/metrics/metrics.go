@@ -63,8 +63,16 @@ func newMetric(ctxPtr *context.Context, name string, t stats.MetricType, isTime
 		valueType = stats.Time
 	}
 
+	metric := stats.New(name, t, valueType)
+	if registry := lib.GetMetricRegistry(*ctxPtr); registry != nil {
+		var err error
+		if metric, err = registry.NewMetric(name, t, valueType); err != nil {
+			return nil, common.NewInitContextError(err.Error())
+		}
+	}
+
 	rt := common.GetRuntime(*ctxPtr)
-	return common.Bind(rt, Metric{stats.New(name, t, valueType)}, ctxPtr), nil
+	return common.Bind(rt, Metric{metric}, ctxPtr), nil
 }
 
 func (m Metric) Add(ctx context.Context, v goja.Value, addTags ...map[string]string) (bool, error) {
@@ -73,7 +81,7 @@ func (m Metric) Add(ctx context.Context, v goja.Value, addTags ...map[string]str
 		return false, ErrMetricsAddInInitContext
 	}
 
-	tags := state.Options.RunTags.CloneTags()
+	tags := state.CloneTags()
 	if state.Options.SystemTags.Has(stats.TagGroup) {
 		tags["group"] = state.Group.Path
 	}
@@ -94,6 +102,9 @@ func (m Metric) Add(ctx context.Context, v goja.Value, addTags ...map[string]str
 	return true, nil
 }
 
+// ErrSnapshotInInitContext is returned when a metric snapshot is requested in the init context
+var ErrSnapshotInInitContext = common.NewInitContextError("Getting metric snapshots in the init context is not supported")
+
 type Metrics struct{}
 
 func New() *Metrics {
@@ -115,3 +126,20 @@ func (*Metrics) XTrend(ctx *context.Context, name string, isTime ...bool) (inter
 func (*Metrics) XRate(ctx *context.Context, name string, isTime ...bool) (interface{}, error) {
 	return newMetric(ctx, name, stats.Rate, isTime)
 }
+
+// Snapshot returns the current aggregated values of the metric with the supplied name, e.g.
+// `{count: 10, rate: 5}` for a counter, or null if no samples for it have been processed yet.
+// The values lag slightly behind what the VUs emitted, since samples are aggregated periodically.
+func (*Metrics) Snapshot(ctx context.Context, name string) (interface{}, error) {
+	state := lib.GetState(ctx)
+	if state == nil {
+		return nil, ErrSnapshotInInitContext
+	}
+	if state.Metrics == nil {
+		return nil, nil
+	}
+	if snapshot, ok := state.Metrics.GetMetricSnapshot(name); ok {
+		return snapshot, nil
+	}
+	return nil, nil
+}
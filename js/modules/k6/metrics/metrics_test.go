@@ -168,3 +168,82 @@ func TestMetricNames(t *testing.T) {
 		})
 	}
 }
+
+type mapMetricsReader map[string]map[string]float64
+
+func (mr mapMetricsReader) GetMetricSnapshot(name string) (map[string]float64, bool) {
+	snapshot, ok := mr[name]
+	return snapshot, ok
+}
+
+func TestMetricConflicts(t *testing.T) {
+	t.Parallel()
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+
+	registry := stats.NewRegistry()
+	builtin := stats.New("http_reqs", stats.Counter)
+	require.NoError(t, registry.Register(builtin))
+	ctxPtr := new(context.Context)
+	*ctxPtr = lib.WithMetricRegistry(common.WithRuntime(context.Background(), rt), registry)
+	rt.Set("metrics", common.Bind(rt, New(), ctxPtr))
+
+	_, err := common.RunString(rt, `
+		let counter = new metrics.Counter("my_metric");
+		let sameCounter = new metrics.Counter("my_metric");
+		let reqs = new metrics.Counter("http_reqs");
+	`)
+	require.NoError(t, err)
+
+	_, err = common.RunString(rt, `new metrics.Trend("my_metric")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(),
+		"metric 'my_metric' is already defined as a counter with default values, "+
+			"it can't be redefined as a trend with default values")
+
+	_, err = common.RunString(rt, `new metrics.Rate("http_reqs")`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "metric 'http_reqs' is already defined as a counter")
+
+	_, err = common.RunString(rt, `new metrics.Counter("my_metric", true)`)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "it can't be redefined as a counter with time values")
+}
+
+func TestMetricSnapshot(t *testing.T) {
+	t.Parallel()
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+
+	ctxPtr := new(context.Context)
+	*ctxPtr = common.WithRuntime(context.Background(), rt)
+	rt.Set("metrics", common.Bind(rt, New(), ctxPtr))
+
+	t.Run("InitContext", func(t *testing.T) {
+		_, err := common.RunString(rt, `metrics.snapshot("my_counter")`)
+		assert.Contains(t, err.Error(), ErrSnapshotInInitContext.Error())
+	})
+
+	reader := mapMetricsReader{"my_counter": {"count": 3, "rate": 1.5}}
+	state := &lib.State{Metrics: reader}
+	*ctxPtr = lib.WithState(*ctxPtr, state)
+
+	t.Run("Existing", func(t *testing.T) {
+		v, err := common.RunString(rt, `
+			let s = metrics.snapshot("my_counter");
+			if (s.count !== 3 || s.rate !== 1.5) { throw new Error("unexpected snapshot " + JSON.stringify(s)); }
+		`)
+		require.NoError(t, err, v)
+	})
+	t.Run("Missing", func(t *testing.T) {
+		v, err := common.RunString(rt, `metrics.snapshot("nonexistent")`)
+		require.NoError(t, err)
+		assert.True(t, goja.IsNull(v))
+	})
+	t.Run("NoReader", func(t *testing.T) {
+		state.Metrics = nil
+		v, err := common.RunString(rt, `metrics.snapshot("my_counter")`)
+		require.NoError(t, err)
+		assert.True(t, goja.IsNull(v))
+	})
+}
@@ -58,6 +58,15 @@ type Socket struct {
 
 	sampleTags    *stats.SampleTags
 	samplesOutput chan<- stats.SampleContainer
+
+	// The tags for the message samples, which include the msg_type tag if it's enabled
+	textMsgTags, binaryMsgTags *stats.SampleTags
+}
+
+// message is a single data frame read from the connection
+type message struct {
+	mtype int // websocket.TextMessage or websocket.BinaryMessage
+	data  []byte
 }
 
 type WSHTTPResponse struct {
@@ -103,7 +112,7 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 	// Leave header to nil by default so we can pass it directly to the Dialer
 	var header http.Header
 
-	tags := state.Options.RunTags.CloneTags()
+	tags := state.CloneTags()
 
 	// Parse the optional second argument (params)
 	if !goja.IsUndefined(paramsV) && !goja.IsNull(paramsV) {
@@ -171,7 +180,7 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 	connectionEnd := time.Now()
 	connectionDuration := stats.D(connectionEnd.Sub(start))
 
-	if state.Options.SystemTags.Has(stats.TagIP) && conn.RemoteAddr() != nil {
+	if state.Options.SystemTags.Has(stats.TagIP) && conn != nil && conn.RemoteAddr() != nil {
 		if ip, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
 			tags["ip"] = ip
 		}
@@ -185,6 +194,9 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 		if state.Options.SystemTags.Has(stats.TagSubproto) {
 			tags["subproto"] = httpResponse.Header.Get("Sec-WebSocket-Protocol")
 		}
+	} else if connErr != nil && state.Options.SystemTags.Has(stats.TagStatus) {
+		// There was no handshake response at all, e.g. because the connection was refused
+		tags["status"] = "0"
 	}
 
 	socket := Socket{
@@ -197,14 +209,25 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 		samplesOutput:      state.Samples,
 		sampleTags:         stats.IntoSampleTags(&tags),
 	}
+	socket.textMsgTags, socket.binaryMsgTags = socket.sampleTags, socket.sampleTags
+	if state.Options.SystemTags.Has(stats.TagMsgType) {
+		// tags was consumed by IntoSampleTags() above, so a copy is needed
+		socket.textMsgTags = getMsgTags(socket.sampleTags.CloneTags(), "text")
+		socket.binaryMsgTags = getMsgTags(socket.sampleTags.CloneTags(), "binary")
+	}
 
+	connectSamples := []stats.Sample{
+		{Metric: metrics.WSSessions, Time: start, Tags: socket.sampleTags, Value: 1},
+		{Metric: metrics.WSConnecting, Time: start, Tags: socket.sampleTags, Value: connectionDuration},
+	}
+	if connErr != nil {
+		connectSamples = append(connectSamples,
+			stats.Sample{Metric: metrics.WSConnectErrors, Time: start, Tags: socket.sampleTags, Value: 1})
+	}
 	stats.PushIfNotDone(ctx, state.Samples, stats.ConnectedSamples{
-		Samples: []stats.Sample{
-			{Metric: metrics.WSSessions, Time: start, Tags: socket.sampleTags, Value: 1},
-			{Metric: metrics.WSConnecting, Time: start, Tags: socket.sampleTags, Value: connectionDuration},
-		},
-		Tags: socket.sampleTags,
-		Time: start,
+		Samples: connectSamples,
+		Tags:    socket.sampleTags,
+		Time:    start,
 	})
 
 	if connErr != nil {
@@ -244,7 +267,7 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 	conn.SetPingHandler(func(msg string) error { pingChan <- msg; return nil })
 	conn.SetPongHandler(func(pingID string) error { pongChan <- pingID; return nil })
 
-	readDataChan := make(chan []byte)
+	readDataChan := make(chan *message)
 	readCloseChan := make(chan int)
 	readErrChan := make(chan error)
 
@@ -270,14 +293,20 @@ func (*WS) Connect(ctx context.Context, url string, args ...goja.Value) (*WSHTTP
 			socket.trackPong(pingID)
 			socket.handleEvent("pong")
 
-		case readData := <-readDataChan:
-			stats.PushIfNotDone(ctx, socket.samplesOutput, stats.Sample{
-				Metric: metrics.WSMessagesReceived,
-				Time:   time.Now(),
-				Tags:   socket.sampleTags,
-				Value:  1,
-			})
-			socket.handleEvent("message", rt.ToValue(string(readData)))
+		case msg := <-readDataChan:
+			msgTags := socket.textMsgTags
+			if msg.mtype == websocket.BinaryMessage {
+				msgTags = socket.binaryMsgTags
+			}
+			socket.pushMessageSamples(metrics.WSMessagesReceived, metrics.WSMessagesReceivedSize, msgTags, len(msg.data))
+
+			// Binary frames are passed to the binaryMessage handlers as an array of bytes, but if
+			// there are none, they are passed to the message handlers as a string, as before
+			if _, ok := socket.eventHandlers["binaryMessage"]; ok && msg.mtype == websocket.BinaryMessage {
+				socket.handleEvent("binaryMessage", rt.ToValue(msg.data))
+			} else {
+				socket.handleEvent("message", rt.ToValue(string(msg.data)))
+			}
 
 		case readErr := <-readErrChan:
 			socket.handleEvent("error", rt.ToValue(readErr))
@@ -328,21 +357,34 @@ func (s *Socket) handleEvent(event string, args ...goja.Value) {
 	}
 }
 
+// Send sends a text frame with the given message
 func (s *Socket) Send(message string) {
-	// NOTE: No binary message support for the time being since goja doesn't
-	// support typed arrays.
-	rt := common.GetRuntime(s.ctx)
+	s.send(websocket.TextMessage, []byte(message), s.textMsgTags)
+}
+
+// SendBinary sends a binary frame with the given data. Since goja doesn't support typed arrays,
+// the data is passed as a plain array of bytes.
+func (s *Socket) SendBinary(data []byte) {
+	s.send(websocket.BinaryMessage, data, s.binaryMsgTags)
+}
 
-	writeData := []byte(message)
-	if err := s.conn.WriteMessage(websocket.TextMessage, writeData); err != nil {
+func (s *Socket) send(mtype int, data []byte, tags *stats.SampleTags) {
+	rt := common.GetRuntime(s.ctx)
+	if err := s.conn.WriteMessage(mtype, data); err != nil {
 		s.handleEvent("error", rt.ToValue(err))
 	}
+	s.pushMessageSamples(metrics.WSMessagesSent, metrics.WSMessagesSentSize, tags, len(data))
+}
 
-	stats.PushIfNotDone(s.ctx, s.samplesOutput, stats.Sample{
-		Metric: metrics.WSMessagesSent,
-		Time:   time.Now(),
-		Tags:   s.sampleTags,
-		Value:  1,
+func (s *Socket) pushMessageSamples(countMetric, sizeMetric *stats.Metric, tags *stats.SampleTags, size int) {
+	now := time.Now()
+	stats.PushIfNotDone(s.ctx, s.samplesOutput, stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Metric: countMetric, Time: now, Tags: tags, Value: 1},
+			{Metric: sizeMetric, Time: now, Tags: tags, Value: float64(size)},
+		},
+		Tags: tags,
+		Time: now,
 	})
 }
 
@@ -452,9 +494,9 @@ func (s *Socket) closeConnection(code int) error {
 }
 
 // Wraps conn.ReadMessage in a channel
-func readPump(conn *websocket.Conn, readChan chan []byte, errorChan chan error, closeChan chan int) {
+func readPump(conn *websocket.Conn, readChan chan *message, errorChan chan error, closeChan chan int) {
 	for {
-		_, message, err := conn.ReadMessage()
+		mtype, data, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(
 				err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
@@ -469,10 +511,16 @@ func readPump(conn *websocket.Conn, readChan chan []byte, errorChan chan error,
 			return
 		}
 
-		readChan <- message
+		readChan <- &message{mtype: mtype, data: data}
 	}
 }
 
+// getMsgTags adds the given msg_type to the socket tags
+func getMsgTags(tags map[string]string, msgType string) *stats.SampleTags {
+	tags["msg_type"] = msgType
+	return stats.IntoSampleTags(&tags)
+}
+
 // Wrap the raw HTTPResponse we received to a WSHTTPResponse we can pass to the user
 func wrapHTTPResponse(httpResponse *http.Response) (*WSHTTPResponse, error) {
 	wsResponse := WSHTTPResponse{
@@ -37,6 +37,7 @@ import (
 	"github.com/loadimpact/k6/lib/testutils/httpmultibin"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func assertSessionMetricsEmitted(t *testing.T, sampleContainers []stats.SampleContainer, subprotocol, url string, status int, group string) {
@@ -425,6 +426,198 @@ func TestErrors(t *testing.T) {
 	})
 }
 
+func TestConnectErrors(t *testing.T) {
+	t.Parallel()
+	tb := httpmultibin.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	root, err := lib.NewGroup("", nil)
+	assert.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:  root,
+		Dialer: tb.Dialer,
+		Options: lib.Options{
+			SystemTags: &stats.DefaultSystemTagSet,
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = lib.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+
+	rt.Set("ws", common.Bind(rt, New(), &ctx))
+
+	// Returns the status tags of the ws_connecting and ws_connect_errors samples for the url
+	getConnectSamples := func(url string) (connecting, connectErrors []string) {
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, sample := range sc.GetSamples() {
+				tags := sample.Tags.CloneTags()
+				if tags["url"] != url {
+					continue
+				}
+				switch sample.Metric {
+				case metrics.WSConnecting:
+					connecting = append(connecting, tags["status"])
+				case metrics.WSConnectErrors:
+					assert.Equal(t, 1.0, sample.Value)
+					connectErrors = append(connectErrors, tags["status"])
+				}
+			}
+		}
+		return connecting, connectErrors
+	}
+
+	t.Run("bad handshake", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = ws.connect("WSBIN_URL/status/404", function(socket){});
+		`))
+		assert.Error(t, err)
+		connecting, connectErrors := getConnectSamples(sr("WSBIN_URL/status/404"))
+		assert.Equal(t, []string{"404"}, connecting)
+		assert.Equal(t, []string{"404"}, connectErrors)
+	})
+
+	t.Run("connection refused", func(t *testing.T) {
+		_, err := common.RunString(rt, `
+		let res = ws.connect("ws://127.0.0.1:1/ws-echo", function(socket){});
+		`)
+		assert.Error(t, err)
+		connecting, connectErrors := getConnectSamples("ws://127.0.0.1:1/ws-echo")
+		assert.Equal(t, []string{"0"}, connecting)
+		assert.Equal(t, []string{"0"}, connectErrors)
+	})
+
+	t.Run("healthy", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = ws.connect("WSBIN_URL/ws-echo", function(socket){
+			socket.close();
+		});
+		`))
+		assert.NoError(t, err)
+		connecting, connectErrors := getConnectSamples(sr("WSBIN_URL/ws-echo"))
+		assert.Equal(t, []string{"101"}, connecting)
+		assert.Empty(t, connectErrors)
+	})
+}
+
+func TestMessageTypes(t *testing.T) {
+	t.Parallel()
+	tb := httpmultibin.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+	sr := tb.Replacer.Replace
+
+	root, err := lib.NewGroup("", nil)
+	assert.NoError(t, err)
+
+	rt := goja.New()
+	rt.SetFieldNameMapper(common.FieldNameMapper{})
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Group:  root,
+		Dialer: tb.Dialer,
+		Options: lib.Options{
+			SystemTags: &stats.DefaultSystemTagSet,
+		},
+		Samples: samples,
+	}
+
+	ctx := context.Background()
+	ctx = lib.WithState(ctx, state)
+	ctx = common.WithRuntime(ctx, rt)
+
+	rt.Set("ws", common.Bind(rt, New(), &ctx))
+
+	// Returns the msg_type tag and value of all of the samples of the given metric
+	getMsgSamples := func(sampleContainers []stats.SampleContainer, metric *stats.Metric) map[string][]float64 {
+		result := map[string][]float64{}
+		for _, sc := range sampleContainers {
+			for _, sample := range sc.GetSamples() {
+				if sample.Metric == metric {
+					msgType, _ := sample.Tags.Get("msg_type")
+					result[msgType] = append(result[msgType], sample.Value)
+				}
+			}
+		}
+		return result
+	}
+
+	t.Run("binary", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let received = null;
+		let res = ws.connect("WSBIN_URL/ws-echo", function(socket){
+			socket.on("open", function() {
+				socket.sendBinary([1, 2, 3, 255]);
+			});
+			socket.on("binaryMessage", function(data) {
+				received = data;
+				socket.close();
+			});
+			socket.on("message", function(data) {
+				throw new Error("unexpected text message: " + data);
+			});
+		});
+		if (received === null || received.length !== 4 || received[0] !== 1 || received[3] !== 255) {
+			throw new Error("wrong binary message: " + JSON.stringify(received));
+		}
+		`))
+		require.NoError(t, err)
+		samplesBuf := stats.GetBufferedSamples(samples)
+		assert.Equal(t, map[string][]float64{"binary": {1}}, getMsgSamples(samplesBuf, metrics.WSMessagesSent))
+		assert.Equal(t, map[string][]float64{"binary": {4}}, getMsgSamples(samplesBuf, metrics.WSMessagesSentSize))
+		assert.Equal(t, map[string][]float64{"binary": {1}}, getMsgSamples(samplesBuf, metrics.WSMessagesReceived))
+		assert.Equal(t, map[string][]float64{"binary": {4}}, getMsgSamples(samplesBuf, metrics.WSMessagesReceivedSize))
+	})
+
+	t.Run("binary without handler", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let received = null;
+		let res = ws.connect("WSBIN_URL/ws-echo", function(socket){
+			socket.on("open", function() {
+				socket.sendBinary([104, 105]);
+			});
+			socket.on("message", function(data) {
+				received = data;
+				socket.close();
+			});
+		});
+		if (received !== "hi") {
+			throw new Error("wrong message: " + received);
+		}
+		`))
+		require.NoError(t, err)
+		samplesBuf := stats.GetBufferedSamples(samples)
+		assert.Equal(t, map[string][]float64{"binary": {2}}, getMsgSamples(samplesBuf, metrics.WSMessagesReceivedSize))
+	})
+
+	t.Run("text", func(t *testing.T) {
+		_, err := common.RunString(rt, sr(`
+		let res = ws.connect("WSBIN_URL/ws-echo", function(socket){
+			socket.on("open", function() {
+				socket.send("hello");
+			});
+			socket.on("message", function(data) {
+				if (data !== "hello") {
+					throw new Error("wrong message: " + data);
+				}
+				socket.close();
+			});
+		});
+		`))
+		require.NoError(t, err)
+		samplesBuf := stats.GetBufferedSamples(samples)
+		assert.Equal(t, map[string][]float64{"text": {1}}, getMsgSamples(samplesBuf, metrics.WSMessagesSent))
+		assert.Equal(t, map[string][]float64{"text": {5}}, getMsgSamples(samplesBuf, metrics.WSMessagesSentSize))
+		assert.Equal(t, map[string][]float64{"text": {1}}, getMsgSamples(samplesBuf, metrics.WSMessagesReceived))
+		assert.Equal(t, map[string][]float64{"text": {5}}, getMsgSamples(samplesBuf, metrics.WSMessagesReceivedSize))
+	})
+}
+
 func TestSystemTags(t *testing.T) {
 	tb := httpmultibin.NewHTTPMultiBin(t)
 	defer tb.Cleanup()
@@ -577,7 +770,7 @@ func TestReadPump(t *testing.T) {
 				_ = conn.Close()
 			}()
 
-			msgChan := make(chan []byte)
+			msgChan := make(chan *message)
 			errChan := make(chan error)
 			closeChan := make(chan int)
 			go readPump(conn, msgChan, errChan, closeChan)
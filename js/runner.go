@@ -27,6 +27,7 @@ import (
 	"net"
 	"net/http"
 	"net/http/cookiejar"
+	"runtime"
 	"strconv"
 	"sync"
 	"time"
@@ -42,7 +43,9 @@ import (
 
 	"github.com/loadimpact/k6/js/common"
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/lib/netext/httpext"
 	"github.com/loadimpact/k6/loader"
 	"github.com/loadimpact/k6/stats"
 )
@@ -66,8 +69,10 @@ type Runner struct {
 	Resolver   *dnscache.Resolver
 	RPSLimit   *rate.Limiter
 
-	console   *console
-	setupData []byte
+	console       *console
+	setupData     []byte
+	metricsReader lib.MetricsReader
+	conns         *netext.ConnTracker
 }
 
 // New returns a new Runner for the provide source
@@ -104,6 +109,7 @@ func NewFromBundle(b *Bundle) (*Runner, error) {
 		},
 		console:  newConsole(),
 		Resolver: dnscache.New(0),
+		conns:    netext.NewConnTracker(),
 	}
 
 	err = r.SetOptions(r.Bundle.Options)
@@ -158,6 +164,13 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		Resolver:  r.Resolver,
 		Blacklist: r.Bundle.Options.BlacklistIPs,
 		Hosts:     r.Bundle.Options.Hosts,
+		Conns:     r.conns,
+
+		RecordConnEvents: r.Bundle.Options.ConnectionEvents.Bool,
+	}
+	if faults := r.Bundle.Options.Faults; faults != nil {
+		// This is reseeded with the VU ID in Reconfigure()
+		dialer.Faults = netext.NewFaultInjector(*faults, 0)
 	}
 	tlsConfig := &tls.Config{
 		InsecureSkipVerify: r.Bundle.Options.InsecureSkipTLSVerify.Bool,
@@ -168,16 +181,38 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		NameToCertificate:  nameToCert,
 		Renegotiation:      tls.RenegotiateFreelyAsClient,
 	}
+	if caCerts := r.Bundle.Options.TLSCACerts; caCerts != nil {
+		tlsConfig.RootCAs = caCerts.CertPool()
+	}
+	proxySelector, err := httpext.NewProxySelector(r.Bundle.Options.Proxies)
+	if err != nil {
+		return nil, err
+	}
 	transport := &http.Transport{
-		Proxy:               http.ProxyFromEnvironment,
+		Proxy:               proxySelector.Proxy,
 		TLSClientConfig:     tlsConfig,
 		DialContext:         dialer.DialContext,
 		DisableCompression:  true,
 		DisableKeepAlives:   r.Bundle.Options.NoConnectionReuse.Bool,
 		MaxIdleConns:        int(r.Bundle.Options.Batch.Int64),
 		MaxIdleConnsPerHost: int(r.Bundle.Options.BatchPerHost.Int64),
+		MaxConnsPerHost:     int(r.Bundle.Options.MaxConnsPerHost.Int64),
+
+		MaxResponseHeaderBytes: r.Bundle.Options.MaxResponseHeaderBytes.Int64,
+	}
+	// ConfigureTransport() always offers h2 and http/1.1, so a custom ALPN list replaces them
+	// afterwards, and HTTP/2 isn't configured at all if it isn't in the list
+	alpn := r.Bundle.Options.TLSALPN
+	offersH2 := len(alpn) == 0
+	for _, proto := range alpn {
+		offersH2 = offersH2 || proto == http2.NextProtoTLS
+	}
+	if offersH2 {
+		_ = http2.ConfigureTransport(transport)
+	}
+	if len(alpn) > 0 {
+		tlsConfig.NextProtos = alpn
 	}
-	_ = http2.ConfigureTransport(transport)
 
 	cookieJar, err := cookiejar.New(nil)
 	if err != nil {
@@ -196,6 +231,16 @@ func (r *Runner) newVU(samplesOut chan<- stats.SampleContainer) (*VU, error) {
 		Samples:        samplesOut,
 		m:              &sync.Mutex{},
 	}
+	if maxConns := r.Bundle.Options.MaxConnsPerHost.Int64; maxConns > 0 {
+		vu.ConnPool = lib.NewConnPoolTracker(maxConns)
+	}
+	if stickyBackends := r.Bundle.Options.StickyBackends; len(stickyBackends) > 0 {
+		// The backends of this VU are picked in Reconfigure(), once its ID is known
+		vu.stickyRings = make(map[string]*netext.HashRing, len(stickyBackends))
+		for host, backends := range stickyBackends {
+			vu.stickyRings[host] = netext.NewHashRing(backends)
+		}
+	}
 	vu.Runtime.Set("console", common.Bind(vu.Runtime, vu.Console, vu.Context))
 	common.BindToGlobal(vu.Runtime, map[string]interface{}{
 		"open": func() {
@@ -268,6 +313,18 @@ func (r *Runner) Teardown(ctx context.Context, out chan<- stats.SampleContainer)
 	return err
 }
 
+// SetMetricsReader implements the lib.MetricsReaderSetter interface, so that VUs can query the
+// current values of the test metrics.
+func (r *Runner) SetMetricsReader(mr lib.MetricsReader) {
+	r.metricsReader = mr
+}
+
+// GetConnectionSamples implements the lib.ConnectionSampler interface, returning the number of
+// active connections of all VUs per host.
+func (r *Runner) GetConnectionSamples(t time.Time, tags *stats.SampleTags) stats.SampleContainer {
+	return r.conns.GetSamples(t, tags)
+}
+
 func (r *Runner) GetDefaultGroup() *lib.Group {
 	return r.defaultGroup
 }
@@ -356,6 +413,7 @@ type VU struct {
 	Runner    *Runner
 	Transport *http.Transport
 	Dialer    *netext.Dialer
+	ConnPool  *lib.ConnPoolTracker
 	CookieJar *cookiejar.Jar
 	TLSConfig *tls.Config
 	ID        int64
@@ -368,6 +426,9 @@ type VU struct {
 
 	setupData goja.Value
 
+	// The consistent hashing rings of the stickyBackends option, by host
+	stickyRings map[string]*netext.HashRing
+
 	// A VU will track the last context it was called with for cancellation.
 	// Note that interruptTrackedCtx is the context that is currently being tracked, while
 	// interruptCancel cancels an unrelated context that terminates the tracking goroutine
@@ -388,6 +449,22 @@ func (u *VU) Reconfigure(id int64) error {
 	u.ID = id
 	u.Iteration = 0
 	u.Runtime.Set("__VU", u.ID)
+	if u.Runner.Bundle.Options.Seed.Valid {
+		u.Runtime.SetRandSource(u.Runner.Bundle.newRandSource(id))
+	}
+	if u.Dialer.Faults != nil {
+		u.Dialer.Faults.Reseed(id)
+	}
+	if len(u.stickyRings) > 0 {
+		key := strconv.FormatInt(id, 10)
+		stickyBackends := make(map[string]string, len(u.stickyRings))
+		for host, ring := range u.stickyRings {
+			stickyBackends[host] = ring.Get(key)
+		}
+		u.Dialer.StickyBackends = stickyBackends
+		// Don't reuse the connections to the backends of the previous ID
+		u.Transport.CloseIdleConnections()
+	}
 	return nil
 }
 
@@ -430,11 +507,18 @@ func (u *VU) RunOnce(ctx context.Context) error {
 	_, isFullIteration, totalTime, err := u.runFn(ctx, u.Runner.defaultGroup, true, u.Default, u.setupData)
 
 	// If MinIterationDuration is specified and the iteration wasn't cancelled
-	// and was less than it, sleep for the remainder
+	// and was less than it, sleep for the remainder, so that the iterations of
+	// each VU are started at least MinIterationDuration apart. The sleep is
+	// interrupted if the context is cancelled, so it doesn't delay the test end.
 	if isFullIteration && u.Runner.Bundle.Options.MinIterationDuration.Valid {
 		durationDiff := time.Duration(u.Runner.Bundle.Options.MinIterationDuration.Duration) - totalTime
 		if durationDiff > 0 {
-			time.Sleep(durationDiff)
+			timer := time.NewTimer(durationDiff)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+			}
 		}
 	}
 
@@ -459,12 +543,14 @@ func (u *VU) runFn(
 		Group:     group,
 		Transport: u.Transport,
 		Dialer:    u.Dialer,
+		ConnPool:  u.ConnPool,
 		TLSConfig: u.TLSConfig,
 		CookieJar: cookieJar,
 		RPSLimit:  u.Runner.RPSLimit,
 		BPool:     u.BPool,
 		Vu:        u.ID,
 		Samples:   u.Samples,
+		Metrics:   u.Runner.metricsReader,
 		Iteration: u.Iteration,
 	}
 
@@ -476,10 +562,20 @@ func (u *VU) runFn(
 	iter := u.Iteration
 	u.Iteration++
 
+	var memStatsBefore, memStatsAfter runtime.MemStats
+	emitRuntimeStats := isDefault && state.Options.RuntimeStats.Bool
+	if emitRuntimeStats {
+		runtime.ReadMemStats(&memStatsBefore)
+	}
+
 	startTime := time.Now()
 	v, err := fn(goja.Undefined(), args...) // Actually run the JS script
 	endTime := time.Now()
 
+	if emitRuntimeStats {
+		runtime.ReadMemStats(&memStatsAfter)
+	}
+
 	var isFullIteration bool
 	select {
 	case <-ctx.Done():
@@ -488,7 +584,7 @@ func (u *VU) runFn(
 		isFullIteration = true
 	}
 
-	tags := state.Options.RunTags.CloneTags()
+	tags := state.CloneTags()
 	if state.Options.SystemTags.Has(stats.TagVU) {
 		tags["vu"] = strconv.FormatInt(u.ID, 10)
 	}
@@ -503,7 +599,42 @@ func (u *VU) runFn(
 		u.Transport.CloseIdleConnections()
 	}
 
-	state.Samples <- u.Dialer.GetTrail(startTime, endTime, isFullIteration, isDefault, stats.IntoSampleTags(&tags))
+	sampleTags := stats.IntoSampleTags(&tags)
+	state.Samples <- u.Dialer.GetTrail(startTime, endTime, isFullIteration, isDefault, sampleTags)
+	for _, event := range u.Dialer.GetConnEvents(sampleTags) {
+		state.Samples <- event
+	}
+
+	if isDefault && state.Options.CheckFailsIteration.Bool && state.FailedChecks > 0 {
+		state.Samples <- stats.Sample{Time: endTime, Metric: metrics.IterationsFailed, Tags: sampleTags, Value: 1}
+	}
+
+	if isDefault && state.IterationOutcome.Valid {
+		var value float64
+		if state.IterationOutcome.Bool {
+			value = 1
+		}
+		state.Samples <- stats.Sample{Time: endTime, Metric: metrics.IterationSuccess, Tags: sampleTags, Value: value}
+	}
+
+	if emitRuntimeStats {
+		state.Samples <- getRuntimeSamples(&memStatsBefore, &memStatsAfter, endTime, sampleTags)
+	}
 
 	return v, isFullIteration, endTime.Sub(startTime), err
 }
+
+// getRuntimeSamples returns the Go runtime metrics for a single iteration, based on the memory
+// stats read right before and right after it. Since the memory stats are process-wide, the
+// allocations of any concurrently running VUs are included as well.
+func getRuntimeSamples(before, after *runtime.MemStats, t time.Time, tags *stats.SampleTags) stats.SampleContainer {
+	return stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Time: t, Metric: metrics.Goroutines, Value: float64(runtime.NumGoroutine()), Tags: tags},
+			{Time: t, Metric: metrics.IterationAllocs, Value: float64(after.Mallocs - before.Mallocs), Tags: tags},
+			{Time: t, Metric: metrics.IterationAllocBytes, Value: float64(after.TotalAlloc - before.TotalAlloc), Tags: tags},
+		},
+		Tags: tags,
+		Time: t,
+	}
+}
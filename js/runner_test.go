@@ -23,14 +23,21 @@ package js
 import (
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"go/build"
 	"io/ioutil"
 	stdlog "log"
+	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"sync"
@@ -46,6 +53,7 @@ import (
 	"github.com/loadimpact/k6/js/modules/k6/ws"
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/lib/testutils/httpmultibin"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
@@ -587,6 +595,49 @@ func TestVURunInterruptDoesntPanic(t *testing.T) {
 	}
 }
 
+func TestVUMinIterationDuration(t *testing.T) {
+	r1, err := getSimpleRunner("/script.js", `
+		export let options = { minIterationDuration: "200ms" };
+		export default function() { }
+		`)
+	require.NoError(t, err)
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			vu, err := r.newVU(make(chan stats.SampleContainer, 100))
+			require.NoError(t, err)
+
+			t.Run("paced", func(t *testing.T) {
+				starts := []time.Time{}
+				for i := 0; i < 4; i++ {
+					starts = append(starts, time.Now())
+					require.NoError(t, vu.RunOnce(context.Background()))
+				}
+				for i := 1; i < len(starts); i++ {
+					assert.True(t, starts[i].Sub(starts[i-1]) >= 200*time.Millisecond,
+						"iteration %d started only %s after the previous one", i, starts[i].Sub(starts[i-1]))
+				}
+			})
+
+			t.Run("cancelled", func(t *testing.T) {
+				r.Bundle.Options.MinIterationDuration = types.NullDurationFrom(time.Minute)
+				defer func() { r.Bundle.Options.MinIterationDuration = types.NullDurationFrom(200 * time.Millisecond) }()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				start := time.Now()
+				require.NoError(t, vu.RunOnce(ctx))
+				assert.True(t, time.Since(start) < 10*time.Second, "the pacing sleep wasn't interrupted")
+			})
+		})
+	}
+}
+
 func TestVUIntegrationGroups(t *testing.T) {
 	r1, err := getSimpleRunner("/script.js", `
 		import { group } from "k6";
@@ -694,6 +745,277 @@ func TestVUIntegrationMetrics(t *testing.T) {
 	}
 }
 
+func TestVUIntegrationRuntimeStats(t *testing.T) {
+	r1, err := getSimpleRunner("/script.js", `
+		export let options = { runtimeStats: true };
+		export default function() {
+			let data = [];
+			for (let i = 0; i < 1000; i++) {
+				data.push({ i: i, s: "item " + i });
+			}
+		}
+		`)
+	require.NoError(t, err)
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			samples := make(chan stats.SampleContainer, 100)
+			vu, err := r.newVU(samples)
+			require.NoError(t, err)
+
+			require.NoError(t, vu.RunOnce(context.Background()))
+
+			found := map[string]float64{}
+			for _, sampleC := range stats.GetBufferedSamples(samples) {
+				for _, s := range sampleC.GetSamples() {
+					switch s.Metric {
+					case metrics.Goroutines, metrics.IterationAllocs, metrics.IterationAllocBytes:
+						found[s.Metric.Name] = s.Value
+					}
+				}
+			}
+			require.Len(t, found, 3)
+			assert.True(t, found["goroutines"] > 0)
+			assert.True(t, found["iteration_allocs"] > 0)
+			assert.True(t, found["iteration_alloc_bytes"] > 0)
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		require.NoError(t, r1.SetOptions(r1.GetOptions().Apply(lib.Options{RuntimeStats: null.BoolFrom(false)})))
+		samples := make(chan stats.SampleContainer, 100)
+		vu, err := r1.newVU(samples)
+		require.NoError(t, err)
+
+		require.NoError(t, vu.RunOnce(context.Background()))
+		for _, sampleC := range stats.GetBufferedSamples(samples) {
+			for _, s := range sampleC.GetSamples() {
+				assert.NotEqual(t, metrics.Goroutines, s.Metric)
+				assert.NotEqual(t, metrics.IterationAllocs, s.Metric)
+				assert.NotEqual(t, metrics.IterationAllocBytes, s.Metric)
+			}
+		}
+	})
+}
+
+func TestVUIntegrationCheckFailsIteration(t *testing.T) {
+	r1, err := getSimpleRunner("/script.js", `
+		import { check } from "k6";
+		export let options = { checkFailsIteration: true };
+		export default function() {
+			check(null, { "passes": true });
+			check(null, { "fails": false, "fails too": false }, { tag: "value" });
+		}
+		`)
+	require.NoError(t, err)
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	getFailed := func(t *testing.T, r *Runner) []stats.Sample {
+		samples := make(chan stats.SampleContainer, 100)
+		vu, err := r.newVU(samples)
+		require.NoError(t, err)
+		require.NoError(t, vu.RunOnce(context.Background()))
+
+		failed := []stats.Sample{}
+		for _, sampleC := range stats.GetBufferedSamples(samples) {
+			for _, s := range sampleC.GetSamples() {
+				if s.Metric == metrics.IterationsFailed {
+					failed = append(failed, s)
+				}
+			}
+		}
+		return failed
+	}
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			// Multiple failed checks still count as a single failed iteration
+			failed := getFailed(t, r)
+			require.Len(t, failed, 1)
+			assert.Equal(t, 1.0, failed[0].Value)
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		require.NoError(t, r1.SetOptions(r1.GetOptions().Apply(lib.Options{CheckFailsIteration: null.BoolFrom(false)})))
+		assert.Empty(t, getFailed(t, r1))
+	})
+}
+
+func TestVUIntegrationMarkIteration(t *testing.T) {
+	r1, err := getSimpleRunner("/script.js", `
+		import { markIteration } from "k6";
+		export default function() {
+			switch (__ITER % 3) {
+			case 0:
+				markIteration(true);
+				break;
+			case 1:
+				// Only the last call counts
+				markIteration(true);
+				markIteration(false);
+				break;
+			}
+		}
+		`)
+	require.NoError(t, err)
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			samples := make(chan stats.SampleContainer, 100)
+			vu, err := r.newVU(samples)
+			require.NoError(t, err)
+
+			sink := &stats.RateSink{}
+			for i := 0; i < 6; i++ {
+				require.NoError(t, vu.RunOnce(context.Background()))
+			}
+			for _, sampleC := range stats.GetBufferedSamples(samples) {
+				for _, s := range sampleC.GetSamples() {
+					if s.Metric == metrics.IterationSuccess {
+						sink.Add(s)
+					}
+				}
+			}
+			// Two successful and two failed iterations, the unmarked ones aren't counted
+			assert.Equal(t, int64(4), sink.Total)
+			assert.Equal(t, int64(2), sink.Trues)
+		})
+	}
+}
+
+func TestVUIntegrationTagsFromEnv(t *testing.T) {
+	tb := httpmultibin.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+
+	r, err := getSimpleRunner("/script.js", tb.Replacer.Replace(`
+		import http from "k6/http";
+		import { check, group } from "k6";
+		export default function() {
+			group("my group", function() {
+				let res = http.get("HTTPBIN_URL/get");
+				check(res, { "is 200": (r) => r.status === 200 });
+			});
+		}
+		`))
+	require.NoError(t, err)
+
+	opts := lib.Options{TagsFromEnv: map[string]string{"commit": "GIT_COMMIT", "build": "BUILD_ID"}}
+	opts.RunTags = opts.GetRunTagsWithEnv(map[string]string{"GIT_COMMIT": "abc123", "BUILD_ID": "42"})
+	require.NoError(t, r.SetOptions(r.GetOptions().Apply(opts)))
+
+	samples := make(chan stats.SampleContainer, 100)
+	vu, err := r.newVU(samples)
+	require.NoError(t, err)
+	require.NoError(t, vu.RunOnce(context.Background()))
+
+	seen := map[string]bool{}
+	for _, sampleC := range stats.GetBufferedSamples(samples) {
+		for _, s := range sampleC.GetSamples() {
+			seen[s.Metric.Name] = true
+			commit, _ := s.Tags.Get("commit")
+			build, _ := s.Tags.Get("build")
+			assert.Equal(t, "abc123", commit, s.Metric.Name)
+			assert.Equal(t, "42", build, s.Metric.Name)
+		}
+	}
+	for _, name := range []string{"http_reqs", "checks", "group_duration", "data_sent", "iterations"} {
+		assert.True(t, seen[name], "no %s samples", name)
+	}
+}
+
+func TestVUIntegrationIterationTags(t *testing.T) {
+	tb := httpmultibin.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+
+	r1, err := getSimpleRunner("/script.js", tb.Replacer.Replace(`
+		import http from "k6/http";
+		import { setIterationTag } from "k6";
+		import { Counter } from "k6/metrics";
+		let counter = new Counter("my_counter");
+		export default function() {
+			http.get("HTTPBIN_IP_URL/get?step=before");
+			setIterationTag("segment", __ITER === 0 ? "premium" : "free");
+			http.get("HTTPBIN_IP_URL/get?step=after");
+			http.get("HTTPBIN_IP_URL/get?step=explicit", { tags: { segment: "explicit" } });
+			counter.add(1);
+			if (__ITER === 0) {
+				setIterationTag("segment", "changed");
+				counter.add(1);
+			}
+		}
+		`))
+	require.NoError(t, err)
+	require.NoError(t, r1.SetOptions(lib.Options{SystemTags: &stats.DefaultSystemTagSet}))
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	testdata := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range testdata {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			samples := make(chan stats.SampleContainer, 100)
+			vu, err := r.newVU(samples)
+			require.NoError(t, err)
+
+			getSegments := func() map[string][]string {
+				segments := map[string][]string{}
+				for _, sampleC := range stats.GetBufferedSamples(samples) {
+					for _, s := range sampleC.GetSamples() {
+						var key string
+						switch s.Metric.Name {
+						case "http_reqs":
+							key, _ = s.Tags.Get("url")
+							key = key[strings.Index(key, "step="):]
+						case "my_counter", "iterations":
+							key = s.Metric.Name
+						default:
+							continue
+						}
+						segment, _ := s.Tags.Get("segment")
+						segments[key] = append(segments[key], segment)
+					}
+				}
+				return segments
+			}
+
+			require.NoError(t, vu.RunOnce(context.Background()))
+			assert.Equal(t, map[string][]string{
+				"step=before":   {""},
+				"step=after":    {"premium"},
+				"step=explicit": {"explicit"},
+				"my_counter":    {"premium", "changed"},
+				"iterations":    {"changed"},
+			}, getSegments())
+
+			// The tags of the previous iteration aren't carried over
+			require.NoError(t, vu.RunOnce(context.Background()))
+			assert.Equal(t, map[string][]string{
+				"step=before":   {""},
+				"step=after":    {"free"},
+				"step=explicit": {"explicit"},
+				"my_counter":    {"free"},
+				"iterations":    {"free"},
+			}, getSegments())
+		})
+	}
+}
+
 func TestVUIntegrationInsecureRequests(t *testing.T) {
 	testdata := map[string]struct {
 		opts   lib.Options
@@ -818,6 +1140,38 @@ func TestVUIntegrationBlacklistScript(t *testing.T) {
 	}
 }
 
+func TestVUIntegrationFaults(t *testing.T) {
+	tb := httpmultibin.NewHTTPMultiBin(t)
+	defer tb.Cleanup()
+
+	r1, err := getSimpleRunner("/script.js", tb.Replacer.Replace(`
+					import http from "k6/http";
+
+					export let options = {
+						throw: true,
+						faults: { connectErrorRate: 1 },
+					};
+
+					export default function() { http.get("HTTPBIN_IP_URL/get"); }
+				`))
+	require.NoError(t, err)
+
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+
+	runners := map[string]*Runner{"Source": r1, "Archive": r2}
+	for name, r := range runners {
+		r := r
+		t.Run(name, func(t *testing.T) {
+			vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+			require.NoError(t, err)
+			err = vu.RunOnce(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "injected connection fault")
+		})
+	}
+}
+
 func TestVUIntegrationHosts(t *testing.T) {
 	tb := httpmultibin.NewHTTPMultiBin(t)
 	defer tb.Cleanup()
@@ -865,6 +1219,60 @@ func TestVUIntegrationHosts(t *testing.T) {
 	}
 }
 
+func TestVUIntegrationStickyBackends(t *testing.T) {
+	var m sync.Mutex
+	vuBackends := map[string]map[string]bool{}
+	newBackend := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			m.Lock()
+			defer m.Unlock()
+			vu := r.Header.Get("X-VU")
+			if vuBackends[vu] == nil {
+				vuBackends[vu] = map[string]bool{}
+			}
+			vuBackends[vu][name] = true
+		}))
+	}
+	backendA, backendB := newBackend("A"), newBackend("B")
+	defer backendA.Close()
+	defer backendB.Close()
+
+	r, err := getSimpleRunner("/script.js", `
+			import http from "k6/http";
+			export default function() {
+				http.get("http://sticky.test/", { headers: { "X-VU": String(__VU) } });
+			}
+		`)
+	require.NoError(t, err)
+	require.NoError(t, r.SetOptions(lib.Options{
+		Throw:             null.BoolFrom(true),
+		NoConnectionReuse: null.BoolFrom(true),
+		StickyBackends: map[string][]string{
+			"sticky.test": {backendA.Listener.Addr().String(), backendB.Listener.Addr().String()},
+		},
+	}))
+
+	const vus, iterations = 10, 5
+	for id := int64(1); id <= vus; id++ {
+		vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+		require.NoError(t, err)
+		require.NoError(t, vu.Reconfigure(id))
+		for i := 0; i < iterations; i++ {
+			require.NoError(t, vu.RunOnce(context.Background()))
+		}
+	}
+
+	require.Len(t, vuBackends, vus)
+	usedBackends := map[string]bool{}
+	for vu, backends := range vuBackends {
+		assert.Len(t, backends, 1, "VU %s hit more than one backend", vu)
+		for backend := range backends {
+			usedBackends[backend] = true
+		}
+	}
+	assert.Len(t, usedBackends, 2)
+}
+
 func TestVUIntegrationTLSConfig(t *testing.T) {
 	unsupportedVersionErrorMsg := "remote error: tls: handshake failure"
 	for _, tag := range build.Default.ReleaseTags {
@@ -1279,6 +1687,337 @@ func TestVUIntegrationClientCerts(t *testing.T) {
 	})
 }
 
+// generateTestCert creates a short-lived certificate for 127.0.0.1, signed by the given parent,
+// or a self-signed CA certificate if parent is nil, and returns it along with its PEM encodings.
+func generateTestCert(
+	t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	if parent == nil {
+		template.Subject = pkix.Name{CommonName: "Test CA"}
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return cert, key, string(certPEM), string(keyPEM)
+}
+
+func TestVUIntegrationInlinePEMCerts(t *testing.T) {
+	caCert, caKey, caPEM, _ := generateTestCert(t, nil, nil)
+	_, _, serverCertPEM, serverKeyPEM := generateTestCert(t, caCert, caKey)
+	_, _, clientCertPEM, clientKeyPEM := generateTestCert(t, caCert, caKey)
+
+	serverCert, err := tls.X509KeyPair([]byte(serverCertPEM), []byte(serverKeyPEM))
+	require.NoError(t, err)
+	clientCAPool := x509.NewCertPool()
+	clientCAPool.AddCert(caCert)
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAPool,
+	})
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = fmt.Fprintf(w, "ok")
+		}),
+		ErrorLog: stdlog.New(ioutil.Discard, "", 0),
+	}
+	go func() { _ = srv.Serve(listener) }()
+
+	r1, err := getSimpleRunner("/script.js", fmt.Sprintf(`
+			import http from "k6/http";
+			export default function() { http.get("https://%s")}
+		`, listener.Addr().String()))
+	require.NoError(t, err)
+
+	var caCerts lib.TLSCACerts
+	require.NoError(t, caCerts.UnmarshalText([]byte(caPEM)))
+	clientAuth := &lib.TLSAuth{TLSAuthFields: lib.TLSAuthFields{
+		Cert: clientCertPEM, Key: clientKeyPEM, Domains: []string{"127.0.0.1"},
+	}}
+
+	testCases := map[string]struct {
+		opts        lib.Options
+		expectedErr string
+	}{
+		"untrusted CA": {
+			opts:        lib.Options{TLSAuth: []*lib.TLSAuth{clientAuth}},
+			expectedErr: "certificate signed by unknown authority",
+		},
+		"no client cert": {
+			opts:        lib.Options{TLSCACerts: &caCerts},
+			expectedErr: "remote error",
+		},
+		"inline CA and client cert": {
+			opts: lib.Options{TLSCACerts: &caCerts, TLSAuth: []*lib.TLSAuth{clientAuth}},
+		},
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, r1.SetOptions(lib.Options{Throw: null.BoolFrom(true)}.Apply(tc.opts)))
+
+			// Make sure that the certificates survive a round trip through an archive
+			r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+			require.NoError(t, err)
+
+			for _, r := range []*Runner{r1, r2} {
+				vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+				require.NoError(t, err)
+				err = vu.RunOnce(context.Background())
+				if tc.expectedErr == "" {
+					assert.NoError(t, err)
+				} else {
+					require.Error(t, err)
+					assert.Contains(t, err.Error(), tc.expectedErr)
+				}
+			}
+		})
+	}
+}
+
+func TestVUIntegrationTLSALPN(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	srv.EnableHTTP2 = true
+	srv.Config.ErrorLog = stdlog.New(ioutil.Discard, "", 0)
+	srv.StartTLS()
+	defer srv.Close()
+
+	testCases := map[string]struct {
+		alpn  []string
+		proto string
+	}{
+		"default":        {nil, "HTTP/2.0"},
+		"http/1.1 only":  {[]string{"http/1.1"}, "HTTP/1.1"},
+		"h2 only":        {[]string{"h2"}, "HTTP/2.0"},
+		"http/1.1 first": {[]string{"http/1.1", "h2"}, "HTTP/2.0"}, // the server's preference wins
+	}
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			r1, err := getSimpleRunner("/script.js", fmt.Sprintf(`
+					import http from "k6/http";
+					export default function() {
+						let res = http.get("%s");
+						if (res.proto != "%s") { throw new Error("wrong proto: " + res.proto) }
+						if (res.body != res.proto) { throw new Error("wrong server proto: " + res.body) }
+					}
+				`, srv.URL, tc.proto))
+			require.NoError(t, err)
+			require.NoError(t, r1.SetOptions(lib.Options{
+				Throw:                 null.BoolFrom(true),
+				InsecureSkipTLSVerify: null.BoolFrom(true),
+				TLSALPN:               tc.alpn,
+			}))
+
+			r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+			require.NoError(t, err)
+
+			for _, r := range []*Runner{r1, r2} {
+				vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+				require.NoError(t, err)
+				assert.NoError(t, vu.RunOnce(context.Background()))
+			}
+		})
+	}
+}
+
+func TestVUIntegrationActiveConnections(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+	srvA := httptest.NewServer(handler)
+	defer srvA.Close()
+	srvB := httptest.NewServer(handler)
+	defer srvB.Close()
+
+	r, err := getSimpleRunner("/script.js", fmt.Sprintf(`
+			import http from "k6/http";
+			export default function() {
+				http.get("%s");
+				if (__VU == 1) {
+					http.get("%s");
+				}
+			}
+		`, srvA.URL, srvB.URL))
+	require.NoError(t, err)
+	require.NoError(t, r.SetOptions(lib.Options{Throw: null.BoolFrom(true)}))
+
+	assert.Nil(t, r.GetConnectionSamples(time.Now(), nil))
+
+	vus := make([]*VU, 2)
+	for i := range vus {
+		vu, err := r.newVU(make(chan stats.SampleContainer, 100))
+		require.NoError(t, err)
+		require.NoError(t, vu.Reconfigure(int64(i+1)))
+		require.NoError(t, vu.RunOnce(context.Background()))
+		vus[i] = vu
+	}
+
+	getActive := func() map[string]float64 {
+		active := make(map[string]float64)
+		for _, s := range r.GetConnectionSamples(time.Now(), nil).GetSamples() {
+			assert.Equal(t, metrics.ActiveConnections, s.Metric)
+			host, _ := s.Tags.Get("host")
+			active[host] = s.Value
+		}
+		return active
+	}
+
+	hostA, hostB := srvA.Listener.Addr().String(), srvB.Listener.Addr().String()
+	assert.Equal(t, map[string]float64{hostA: 2, hostB: 1}, getActive())
+
+	vus[0].Transport.CloseIdleConnections()
+	assert.Equal(t, map[string]float64{hostA: 1, hostB: 0}, getActive())
+}
+
+func TestVUIntegrationConnectionEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	r, err := getSimpleRunner("/script.js", fmt.Sprintf(`
+			import http from "k6/http";
+			export default function() {
+				http.get("%[1]s");
+				http.get("%[1]s");
+				http.get("%[1]s");
+			}
+		`, srv.URL))
+	require.NoError(t, err)
+
+	getEvents := func(samples chan stats.SampleContainer) []*stats.Event {
+		close(samples)
+		var events []*stats.Event
+		for sc := range samples {
+			if event, ok := sc.(*stats.Event); ok {
+				events = append(events, event)
+			}
+		}
+		return events
+	}
+	host := srv.Listener.Addr().String()
+
+	t.Run("Disabled", func(t *testing.T) {
+		require.NoError(t, r.SetOptions(lib.Options{Throw: null.BoolFrom(true)}))
+		samples := make(chan stats.SampleContainer, 100)
+		vu, err := r.NewVU(samples)
+		require.NoError(t, err)
+		require.NoError(t, vu.RunOnce(context.Background()))
+		assert.Empty(t, getEvents(samples))
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		require.NoError(t, r.SetOptions(lib.Options{
+			Throw:               null.BoolFrom(true),
+			ConnectionEvents:    null.BoolFrom(true),
+			NoVUConnectionReuse: null.BoolFrom(true),
+			SystemTags:          stats.NewSystemTagSet(stats.TagVU),
+		}))
+		samples := make(chan stats.SampleContainer, 100)
+		vu, err := r.NewVU(samples)
+		require.NoError(t, err)
+		require.NoError(t, vu.Reconfigure(5))
+		require.NoError(t, vu.RunOnce(context.Background()))
+
+		events := getEvents(samples)
+		names := make([]string, len(events))
+		for i, event := range events {
+			names[i] = event.Name
+			assert.Equal(t, map[string]string{"host": host, "vu": "5"}, event.Tags.CloneTags())
+			assert.Equal(t, host, event.Data["remote_addr"])
+		}
+		require.Equal(t, []string{
+			netext.ConnOpenEvent, netext.ConnReuseEvent, netext.ConnReuseEvent, netext.ConnCloseEvent,
+		}, names)
+
+		assert.Contains(t, events[0].Data, "connect_duration")
+		assert.Equal(t, int64(2), events[1].Data["requests"])
+		assert.Equal(t, int64(3), events[2].Data["requests"])
+		assert.Contains(t, events[2].Data, "idle_duration")
+		assert.Equal(t, int64(3), events[3].Data["requests"])
+		assert.True(t, events[3].Data["lifetime"].(float64) > 0)
+	})
+}
+
+func TestVUIntegrationSeed(t *testing.T) {
+	r1, err := getSimpleRunner("/script.js", `
+			import { Matrix } from "k6";
+			import { Generator } from "k6/fuzz";
+			let matrix = new Matrix({ browse: 3, search: 2, buy: 1 });
+			let gen = new Generator({ type: "integer", minimum: 0, maximum: 1000000 });
+			let initRandom = Math.random();
+			let choices = [];
+			export default function() {
+				let picks = [];
+				for (let i = 0; i < 10; i++) {
+					picks.push(matrix.pick().name);
+				}
+				choices = [initRandom, Math.random(), Math.random(), picks.join(","), gen.next(), gen.next()];
+			}
+		`)
+	require.NoError(t, err)
+
+	runChoices := func(r *Runner, id int64) []interface{} {
+		vu, err := r.NewVU(make(chan stats.SampleContainer, 100))
+		require.NoError(t, err)
+		require.NoError(t, vu.Reconfigure(id))
+		require.NoError(t, vu.RunOnce(context.Background()))
+		return vu.(*VU).Runtime.Get("choices").Export().([]interface{})
+	}
+
+	require.NoError(t, r1.SetOptions(lib.Options{Seed: null.IntFrom(1234)}))
+	choices := runChoices(r1, 1)
+	require.Len(t, choices, 6)
+
+	// A replayed run makes exactly the same random choices, even from an archive
+	r2, err := NewFromArchive(r1.MakeArchive(), lib.RuntimeOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, choices, runChoices(r1, 1))
+	assert.Equal(t, choices, runChoices(r2, 1))
+
+	// Every VU gets its own Math.random(), matrix and generator sequences, which are replayable too
+	otherVU := runChoices(r1, 2)
+	assert.Equal(t, choices[0], otherVU[0])
+	assert.NotEqual(t, choices[1:3], otherVU[1:3])
+	assert.NotEqual(t, choices[3], otherVU[3])
+	assert.NotEqual(t, choices[4:], otherVU[4:])
+	assert.Equal(t, otherVU, runChoices(r1, 2))
+	assert.Equal(t, otherVU, runChoices(r2, 2))
+
+	require.NoError(t, r1.SetOptions(lib.Options{Seed: null.IntFrom(4321)}))
+	assert.NotEqual(t, choices, runChoices(r1, 1))
+}
+
 func TestHTTPRequestInInitContext(t *testing.T) {
 	tb := httpmultibin.NewHTTPMultiBin(t)
 	defer tb.Cleanup()
@@ -1336,6 +2075,20 @@ func TestInitContextForbidden(t *testing.T) {
 			 export default function() { console.log("p"); }`,
 			k6.ErrGroupInInitContext.Error(),
 		},
+		{
+			"setIterationTag",
+			`import { setIterationTag } from "k6";
+			 setIterationTag("segment", "premium");
+			 export default function() { console.log("p"); }`,
+			k6.ErrSetIterationTagInInitContext.Error(),
+		},
+		{
+			"markIteration",
+			`import { markIteration } from "k6";
+			 markIteration(true);
+			 export default function() { console.log("p"); }`,
+			k6.ErrMarkIterationInInitContext.Error(),
+		},
 		{
 			"ws",
 			`import ws from "k6/ws";
@@ -70,3 +70,11 @@ type Collector interface {
 	// Set run status
 	SetRunStatus(status RunStatus)
 }
+
+// An EventCollector is a Collector that also receives the events that are emitted by the scripts.
+type EventCollector interface {
+	Collector
+
+	// CollectEvents receives a set of events, under the same conditions as Collect().
+	CollectEvents(events []*stats.Event)
+}
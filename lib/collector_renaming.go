@@ -0,0 +1,119 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"fmt"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// RenamingCollector wraps a Collector and renames the metrics of the samples it receives, so
+// the names can follow the conventions of a specific backend. Only the samples passed to the
+// wrapped collector are changed, everything else, e.g. the thresholds, still sees the original
+// metric names.
+type RenamingCollector struct {
+	Collector
+
+	renames map[string]string
+	metrics map[*stats.Metric]*stats.Metric
+}
+
+// NewRenamingCollector returns a collector that renames the metrics with names in the keys of
+// renames to the corresponding values before passing their samples to the wrapped collector.
+func NewRenamingCollector(c Collector, renames map[string]string) (*RenamingCollector, error) {
+	for name, newName := range renames {
+		if newName == "" {
+			return nil, fmt.Errorf("the new name of the metric '%s' can't be empty", name)
+		}
+	}
+	return &RenamingCollector{
+		Collector: c,
+		renames:   renames,
+		metrics:   make(map[*stats.Metric]*stats.Metric),
+	}, nil
+}
+
+// Collect renames the metrics of the samples and passes them to the wrapped collector. Sample
+// containers with renamed metrics are replaced with plain ones, since e.g. HTTP trails keep
+// references to the original metrics.
+func (c *RenamingCollector) Collect(sampleContainers []stats.SampleContainer) {
+	renamed := make([]stats.SampleContainer, len(sampleContainers))
+	for i, sc := range sampleContainers {
+		renamed[i] = c.renameContainer(sc)
+	}
+	c.Collector.Collect(renamed)
+}
+
+// CollectEvents passes the events as they are to the wrapped collector, if it supports them
+func (c *RenamingCollector) CollectEvents(events []*stats.Event) {
+	if ec, ok := c.Collector.(EventCollector); ok {
+		ec.CollectEvents(events)
+	}
+}
+
+func (c *RenamingCollector) renameContainer(sc stats.SampleContainer) stats.SampleContainer {
+	samples := sc.GetSamples()
+	var newSamples []stats.Sample
+	for i, sample := range samples {
+		metric := c.renameMetric(sample.Metric)
+		if metric == sample.Metric {
+			if newSamples != nil {
+				newSamples = append(newSamples, sample)
+			}
+			continue
+		}
+		if newSamples == nil {
+			newSamples = make([]stats.Sample, i, len(samples))
+			copy(newSamples, samples[:i])
+		}
+		sample.Metric = metric
+		newSamples = append(newSamples, sample)
+	}
+
+	if newSamples == nil {
+		return sc
+	}
+	switch sc := sc.(type) {
+	case stats.Sample:
+		return newSamples[0]
+	case stats.ConnectedSampleContainer:
+		return stats.ConnectedSamples{Samples: newSamples, Tags: sc.GetTags(), Time: sc.GetTime()}
+	default:
+		return stats.Samples(newSamples)
+	}
+}
+
+// renameMetric returns the renamed copy of the metric, or the metric itself if it isn't renamed.
+// The copies are cached, since Collect() is never called concurrently.
+func (c *RenamingCollector) renameMetric(m *stats.Metric) *stats.Metric {
+	if renamed, ok := c.metrics[m]; ok {
+		return renamed
+	}
+	renamed := m
+	if newName, ok := c.renames[m.Name]; ok {
+		metricCopy := *m
+		metricCopy.Name = newName
+		renamed = &metricCopy
+	}
+	c.metrics[m] = renamed
+	return renamed
+}
@@ -0,0 +1,112 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// collectingCollector only implements Collect(), the rest of the methods panic
+type collectingCollector struct {
+	Collector
+	sampleContainers []stats.SampleContainer
+}
+
+func (c *collectingCollector) Collect(scs []stats.SampleContainer) {
+	c.sampleContainers = append(c.sampleContainers, scs...)
+}
+
+// eventCollector is a collectingCollector that also collects events
+type eventCollector struct {
+	collectingCollector
+	events []*stats.Event
+}
+
+func (c *eventCollector) CollectEvents(events []*stats.Event) {
+	c.events = append(c.events, events...)
+}
+
+func TestRenamingCollector(t *testing.T) {
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	reqs := stats.New("http_reqs", stats.Counter)
+	tags := stats.IntoSampleTags(&map[string]string{"url": "http://example.com"})
+	now := time.Now()
+
+	c := &collectingCollector{}
+	rc, err := NewRenamingCollector(c, map[string]string{"http_req_duration": "http.request.duration"})
+	require.NoError(t, err)
+
+	original := []stats.SampleContainer{
+		stats.Sample{Metric: duration, Value: 1},
+		stats.Sample{Metric: reqs, Value: 1},
+		stats.ConnectedSamples{
+			Samples: []stats.Sample{{Metric: reqs, Value: 1}, {Metric: duration, Value: 2}},
+			Tags:    tags,
+			Time:    now,
+		},
+		stats.Samples{{Metric: reqs, Value: 1}},
+	}
+	rc.Collect(original)
+	rc.Collect([]stats.SampleContainer{stats.Sample{Metric: duration, Value: 3}})
+
+	require.Len(t, c.sampleContainers, 5)
+	assert.Equal(t, "http.request.duration", c.sampleContainers[0].(stats.Sample).Metric.Name)
+	assert.Equal(t, original[1], c.sampleContainers[1])
+	connected := c.sampleContainers[2].(stats.ConnectedSamples)
+	assert.Equal(t, tags, connected.Tags)
+	assert.Equal(t, now, connected.Time)
+	require.Len(t, connected.Samples, 2)
+	assert.Equal(t, reqs, connected.Samples[0].Metric)
+	assert.Equal(t, "http.request.duration", connected.Samples[1].Metric.Name)
+	assert.Equal(t, 2.0, connected.Samples[1].Value)
+	assert.Equal(t, original[3], c.sampleContainers[3])
+
+	// The renamed metric is reused and the original samples aren't changed
+	assert.Equal(t, c.sampleContainers[0].(stats.Sample).Metric, c.sampleContainers[4].(stats.Sample).Metric)
+	assert.Equal(t, stats.Time, connected.Samples[1].Metric.Contains)
+	assert.Equal(t, "http_req_duration", duration.Name)
+	assert.Equal(t, duration, original[0].(stats.Sample).Metric)
+	assert.Equal(t, duration, original[2].(stats.ConnectedSamples).Samples[1].Metric)
+
+	_, err = NewRenamingCollector(c, map[string]string{"http_reqs": ""})
+	assert.EqualError(t, err, "the new name of the metric 'http_reqs' can't be empty")
+}
+
+func TestRenamingCollectorEvents(t *testing.T) {
+	events := []*stats.Event{{Name: "deployment"}}
+
+	c := &eventCollector{}
+	rc, err := NewRenamingCollector(c, map[string]string{"http_reqs": "http.requests"})
+	require.NoError(t, err)
+	rc.CollectEvents(events)
+	assert.Equal(t, events, c.events)
+
+	// Events are dropped for the collectors that don't support them
+	rc, err = NewRenamingCollector(&collectingCollector{}, nil)
+	require.NoError(t, err)
+	assert.NotPanics(t, func() { rc.CollectEvents(events) })
+}
@@ -0,0 +1,63 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import "sync"
+
+// ConnPoolTracker counts the HTTP requests of a VU that are using or waiting for a connection to
+// every host, so it can tell when a request has to wait for a connection because the
+// maxConnsPerHost limit was reached, i.e. the connection pool for the host was exhausted.
+type ConnPoolTracker struct {
+	maxConnsPerHost int64
+
+	mu       sync.Mutex
+	requests map[string]int64
+}
+
+// NewConnPoolTracker returns a new tracker for the given maximum number of connections per host
+func NewConnPoolTracker(maxConnsPerHost int64) *ConnPoolTracker {
+	return &ConnPoolTracker{maxConnsPerHost: maxConnsPerHost, requests: make(map[string]int64)}
+}
+
+// Acquire registers a new request to the host and returns whether all of the connections to it
+// were already in use. It's safe to call it on a nil tracker, which never reports exhaustion.
+func (t *ConnPoolTracker) Acquire(host string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	exhausted := t.requests[host] >= t.maxConnsPerHost
+	t.requests[host]++
+	return exhausted
+}
+
+// Release unregisters a finished request to the host
+func (t *ConnPoolTracker) Release(host string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.requests[host]--; t.requests[host] <= 0 {
+		delete(t.requests, host)
+	}
+}
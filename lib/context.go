@@ -1,11 +1,17 @@
 package lib
 
-import "context"
+import (
+	"context"
+
+	"github.com/loadimpact/k6/stats"
+)
 
 type ctxKey int
 
 const (
 	ctxKeyState ctxKey = iota
+	ctxKeyMetricRegistry
+	ctxKeyRunSeed
 )
 
 func WithState(ctx context.Context, state *State) context.Context {
@@ -19,3 +25,32 @@ func GetState(ctx context.Context) *State {
 	}
 	return v.(*State)
 }
+
+// WithMetricRegistry returns a context with the registry that the custom metrics are defined in
+func WithMetricRegistry(ctx context.Context, registry *stats.Registry) context.Context {
+	return context.WithValue(ctx, ctxKeyMetricRegistry, registry)
+}
+
+// GetMetricRegistry returns the metric registry of the context, or nil if there isn't one
+func GetMetricRegistry(ctx context.Context) *stats.Registry {
+	v := ctx.Value(ctxKeyMetricRegistry)
+	if v == nil {
+		return nil
+	}
+	return v.(*stats.Registry)
+}
+
+// WithRunSeed returns a context with the seed of the run, for the init context
+func WithRunSeed(ctx context.Context, seed int64) context.Context {
+	return context.WithValue(ctx, ctxKeyRunSeed, seed)
+}
+
+// GetRunSeed returns the seed of the run from the VU state or the init context, and whether
+// the run has one
+func GetRunSeed(ctx context.Context) (int64, bool) {
+	if state := GetState(ctx); state != nil {
+		return state.Options.Seed.Int64, state.Options.Seed.Valid
+	}
+	seed, ok := ctx.Value(ctxKeyRunSeed).(int64)
+	return seed, ok
+}
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	null "gopkg.in/guregu/null.v3"
 )
 
 func TestContextState(t *testing.T) {
@@ -15,3 +16,18 @@ func TestContextState(t *testing.T) {
 func TestContextStateNil(t *testing.T) {
 	assert.Nil(t, GetState(context.Background()))
 }
+
+func TestContextRunSeed(t *testing.T) {
+	_, ok := GetRunSeed(context.Background())
+	assert.False(t, ok)
+
+	seed, ok := GetRunSeed(WithRunSeed(context.Background(), 1234))
+	assert.True(t, ok)
+	assert.Equal(t, int64(1234), seed)
+
+	// The seed of the VU state takes precedence over the init context one
+	st := &State{Options: Options{Seed: null.IntFrom(4321)}}
+	seed, ok = GetRunSeed(WithState(WithRunSeed(context.Background(), 1234), st))
+	assert.True(t, ok)
+	assert.Equal(t, int64(4321), seed)
+}
@@ -0,0 +1,96 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"fmt"
+
+	"github.com/loadimpact/k6/lib/types"
+)
+
+// FaultDelay configures a delay that's injected with the given probability. The length of every
+// injected delay is chosen uniformly between Min and Max, or, if there are Buckets, a bucket is
+// picked according to the weights first and the delay is chosen uniformly between its Min and
+// Max. The buckets can describe a long tail, e.g. 99% of short delays and 1% of long ones.
+type FaultDelay struct {
+	Rate    float64            `json:"rate"`
+	Min     types.Duration     `json:"min"`
+	Max     types.Duration     `json:"max"`
+	Buckets []FaultDelayBucket `json:"buckets"`
+}
+
+// FaultDelayBucket is a range of delays that's picked with the given weight, relative to the
+// weights of the other buckets of the FaultDelay.
+type FaultDelayBucket struct {
+	Weight float64        `json:"weight"`
+	Min    types.Duration `json:"min"`
+	Max    types.Duration `json:"max"`
+}
+
+// FaultInjection configures the faults that are deliberately injected in the network connections
+// of VUs, for testing how scripts and client-side timeout or retry logic cope with a slow or
+// unreliable network. The random choices are seeded, so the faults are reproducible between runs.
+type FaultInjection struct {
+	Seed int64 `json:"seed"`
+
+	// The probability that a new connection fails with an error
+	ConnectErrorRate float64 `json:"connectErrorRate"`
+
+	// Delays before a new connection is established and before a read from a connection
+	ConnectDelay FaultDelay `json:"connectDelay"`
+	ReadDelay    FaultDelay `json:"readDelay"`
+}
+
+func (d FaultDelay) validate(name string) error {
+	if d.Rate < 0 || d.Rate > 1 {
+		return fmt.Errorf("the %s rate should be between 0 and 1", name)
+	}
+	if d.Min < 0 || d.Max < d.Min {
+		return fmt.Errorf("the %s min should be non-negative and not greater than the max", name)
+	}
+	if len(d.Buckets) > 0 && (d.Min != 0 || d.Max != 0) {
+		return fmt.Errorf("the %s min and max can't be used together with buckets", name)
+	}
+	for i, b := range d.Buckets {
+		if b.Weight <= 0 {
+			return fmt.Errorf("the weight of %s bucket %d should be positive", name, i)
+		}
+		if b.Min < 0 || b.Max < b.Min {
+			return fmt.Errorf("the min of %s bucket %d should be non-negative and not greater than the max", name, i)
+		}
+	}
+	return nil
+}
+
+// Validate checks that all of the probabilities and delay ranges are valid
+func (f FaultInjection) Validate() []error {
+	var errs []error
+	if f.ConnectErrorRate < 0 || f.ConnectErrorRate > 1 {
+		errs = append(errs, fmt.Errorf("the connectErrorRate should be between 0 and 1"))
+	}
+	if err := f.ConnectDelay.validate("connectDelay"); err != nil {
+		errs = append(errs, err)
+	}
+	if err := f.ReadDelay.validate("readDelay"); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,99 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package lib
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/lib/types"
+)
+
+func TestFaultInjectionJSON(t *testing.T) {
+	var opts Options
+	require.NoError(t, json.Unmarshal([]byte(`{"faults": {
+		"seed": 42,
+		"connectErrorRate": 0.1,
+		"connectDelay": {"rate": 0.5, "min": "100ms", "max": "1s"},
+		"readDelay": {"rate": 0.2, "buckets": [
+			{"weight": 99, "min": "10ms", "max": "20ms"},
+			{"weight": 1, "min": "1s", "max": "2s"}
+		]}
+	}}`), &opts))
+	require.NotNil(t, opts.Faults)
+	assert.Equal(t, FaultInjection{
+		Seed:             42,
+		ConnectErrorRate: 0.1,
+		ConnectDelay:     FaultDelay{Rate: 0.5, Min: types.Duration(100 * time.Millisecond), Max: types.Duration(time.Second)},
+		ReadDelay: FaultDelay{Rate: 0.2, Buckets: []FaultDelayBucket{
+			{Weight: 99, Min: types.Duration(10 * time.Millisecond), Max: types.Duration(20 * time.Millisecond)},
+			{Weight: 1, Min: types.Duration(time.Second), Max: types.Duration(2 * time.Second)},
+		}},
+	}, *opts.Faults)
+	assert.Empty(t, opts.Validate())
+
+	applied := Options{}.Apply(opts)
+	assert.Equal(t, opts.Faults, applied.Faults)
+}
+
+func TestFaultInjectionValidate(t *testing.T) {
+	testCases := []struct {
+		faults FaultInjection
+		errs   []string
+	}{
+		{FaultInjection{}, nil},
+		{FaultInjection{ConnectErrorRate: 1}, nil},
+		{FaultInjection{ConnectErrorRate: 1.5}, []string{"the connectErrorRate should be between 0 and 1"}},
+		{
+			FaultInjection{ConnectDelay: FaultDelay{Rate: -1}},
+			[]string{"the connectDelay rate should be between 0 and 1"},
+		},
+		{
+			FaultInjection{ReadDelay: FaultDelay{Rate: 1, Min: types.Duration(time.Second)}},
+			[]string{"the readDelay min should be non-negative and not greater than the max"},
+		},
+		{
+			FaultInjection{ConnectDelay: FaultDelay{
+				Rate: 1, Max: types.Duration(time.Second), Buckets: []FaultDelayBucket{{Weight: 1}},
+			}},
+			[]string{"the connectDelay min and max can't be used together with buckets"},
+		},
+		{
+			FaultInjection{ReadDelay: FaultDelay{Rate: 1, Buckets: []FaultDelayBucket{{Weight: 1}, {Weight: 0}}}},
+			[]string{"the weight of readDelay bucket 1 should be positive"},
+		},
+		{
+			FaultInjection{ReadDelay: FaultDelay{Rate: 1, Buckets: []FaultDelayBucket{{Weight: 1, Min: types.Duration(time.Second)}}}},
+			[]string{"the min of readDelay bucket 0 should be non-negative and not greater than the max"},
+		},
+	}
+	for _, tc := range testCases {
+		var errs []string
+		for _, err := range tc.faults.Validate() {
+			errs = append(errs, err.Error())
+		}
+		assert.Equal(t, tc.errs, errs)
+	}
+}
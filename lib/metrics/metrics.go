@@ -28,35 +28,96 @@ import (
 
 var (
 	// Engine-emitted.
-	VUs               = stats.New("vus", stats.Gauge)
-	VUsMax            = stats.New("vus_max", stats.Gauge)
-	Iterations        = stats.New("iterations", stats.Counter)
-	IterationDuration = stats.New("iteration_duration", stats.Trend, stats.Time)
-	Errors            = stats.New("errors", stats.Counter)
+	VUs               = newBuiltin("vus", stats.Gauge)
+	VUsMax            = newBuiltin("vus_max", stats.Gauge)
+	Iterations        = newBuiltin("iterations", stats.Counter)
+	IterationsFailed  = newBuiltin("iterations_failed", stats.Counter)
+	IterationSuccess  = newBuiltin("iteration_success", stats.Rate)
+	IterationDuration = newBuiltin("iteration_duration", stats.Trend, stats.Time)
+	Errors            = newBuiltin("errors", stats.Counter)
+	Heartbeat         = newBuiltin("k6_heartbeat", stats.Gauge)
 
 	// Runner-emitted.
-	Checks        = stats.New("checks", stats.Rate)
-	GroupDuration = stats.New("group_duration", stats.Trend, stats.Time)
+	Checks        = newBuiltin("checks", stats.Rate)
+	GroupDuration = newBuiltin("group_duration", stats.Trend, stats.Time)
 
 	// HTTP-related.
-	HTTPReqs              = stats.New("http_reqs", stats.Counter)
-	HTTPReqDuration       = stats.New("http_req_duration", stats.Trend, stats.Time)
-	HTTPReqBlocked        = stats.New("http_req_blocked", stats.Trend, stats.Time)
-	HTTPReqConnecting     = stats.New("http_req_connecting", stats.Trend, stats.Time)
-	HTTPReqTLSHandshaking = stats.New("http_req_tls_handshaking", stats.Trend, stats.Time)
-	HTTPReqSending        = stats.New("http_req_sending", stats.Trend, stats.Time)
-	HTTPReqWaiting        = stats.New("http_req_waiting", stats.Trend, stats.Time)
-	HTTPReqReceiving      = stats.New("http_req_receiving", stats.Trend, stats.Time)
+	HTTPReqs              = newBuiltin("http_reqs", stats.Counter)
+	HTTPReqsSkipped       = newBuiltin("http_reqs_skipped", stats.Counter)
+	HTTPReqFailed         = newBuiltin("http_req_failed", stats.Rate)
+	HTTPReqDuration       = newBuiltin("http_req_duration", stats.Trend, stats.Time)
+	HTTPReqBlocked        = newBuiltin("http_req_blocked", stats.Trend, stats.Time)
+	HTTPReqQueued         = newBuiltin("http_req_queued", stats.Trend, stats.Time)
+	HTTPReqConnecting     = newBuiltin("http_req_connecting", stats.Trend, stats.Time)
+	HTTPReqTLSHandshaking = newBuiltin("http_req_tls_handshaking", stats.Trend, stats.Time)
+	HTTPReqSending        = newBuiltin("http_req_sending", stats.Trend, stats.Time)
+	HTTPReqWaiting        = newBuiltin("http_req_waiting", stats.Trend, stats.Time)
+	HTTPReqReceiving      = newBuiltin("http_req_receiving", stats.Trend, stats.Time)
+	HTTPReqServerTiming   = newBuiltin("http_req_server_timing", stats.Trend, stats.Time)
+	HTTPConnRequests      = newBuiltin("http_conn_requests", stats.Trend)
+	HTTPUploadBytes       = newBuiltin("http_upload_bytes", stats.Counter, stats.Data)
+	ConnPoolExhausted     = newBuiltin("conn_pool_exhausted", stats.Rate)
+
+	// The rates of the requests that failed with an error of every category, by category, so
+	// thresholds like http_req_failed_connect: ["rate<0.001"] don't depend on the other errors.
+	HTTPReqFailedCategories = map[string]*stats.Metric{
+		"dns":     newBuiltin("http_req_failed_dns", stats.Rate),
+		"connect": newBuiltin("http_req_failed_connect", stats.Rate),
+		"tcp":     newBuiltin("http_req_failed_tcp", stats.Rate),
+		"tls":     newBuiltin("http_req_failed_tls", stats.Rate),
+		"http4xx": newBuiltin("http_req_failed_http4xx", stats.Rate),
+		"http5xx": newBuiltin("http_req_failed_http5xx", stats.Rate),
+		"http2":   newBuiltin("http_req_failed_http2", stats.Rate),
+		"content": newBuiltin("http_req_failed_content", stats.Rate),
+		"general": newBuiltin("http_req_failed_general", stats.Rate),
+	}
 
 	// Websocket-related
-	WSSessions         = stats.New("ws_sessions", stats.Counter)
-	WSMessagesSent     = stats.New("ws_msgs_sent", stats.Counter)
-	WSMessagesReceived = stats.New("ws_msgs_received", stats.Counter)
-	WSPing             = stats.New("ws_ping", stats.Trend)
-	WSSessionDuration  = stats.New("ws_session_duration", stats.Trend, stats.Time)
-	WSConnecting       = stats.New("ws_connecting", stats.Trend, stats.Time)
+	WSSessions             = newBuiltin("ws_sessions", stats.Counter)
+	WSMessagesSent         = newBuiltin("ws_msgs_sent", stats.Counter)
+	WSMessagesReceived     = newBuiltin("ws_msgs_received", stats.Counter)
+	WSMessagesSentSize     = newBuiltin("ws_msgs_sent_size", stats.Trend, stats.Data)
+	WSMessagesReceivedSize = newBuiltin("ws_msgs_received_size", stats.Trend, stats.Data)
+	WSPing                 = newBuiltin("ws_ping", stats.Trend)
+	WSSessionDuration      = newBuiltin("ws_session_duration", stats.Trend, stats.Time)
+	WSConnecting           = newBuiltin("ws_connecting", stats.Trend, stats.Time)
+	WSConnectErrors        = newBuiltin("ws_connect_errors", stats.Counter)
 
 	// Network-related; used for future protocols as well.
-	DataSent     = stats.New("data_sent", stats.Counter, stats.Data)
-	DataReceived = stats.New("data_received", stats.Counter, stats.Data)
+	DataSent     = newBuiltin("data_sent", stats.Counter, stats.Data)
+	DataReceived = newBuiltin("data_received", stats.Counter, stats.Data)
+
+	// Periodically emitted by the engine for every host, if the runner keeps track of connections.
+	ActiveConnections = newBuiltin("active_connections", stats.Gauge)
+
+	// Go runtime-related; only emitted when the runtimeStats option is enabled.
+	// The allocations are process-wide, so they're only a rough per-VU approximation.
+	Goroutines          = newBuiltin("goroutines", stats.Gauge)
+	IterationAllocs     = newBuiltin("iteration_allocs", stats.Trend)
+	IterationAllocBytes = newBuiltin("iteration_alloc_bytes", stats.Trend, stats.Data)
 )
+
+var builtins = make(map[string]*stats.Metric)
+
+func newBuiltin(name string, typ stats.MetricType, t ...stats.ValueType) *stats.Metric {
+	m := stats.New(name, typ, t...)
+	builtins[name] = m
+	return m
+}
+
+// IsBuiltin returns whether a metric with the specified name is emitted by k6 itself,
+// as opposed to a custom metric defined by a script.
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
+
+// NewRegistry returns a metric registry that already contains the built-in metrics, so custom
+// metrics can't be defined with the same name as a built-in one, but a different type.
+func NewRegistry() *stats.Registry {
+	registry := stats.NewRegistry()
+	for _, m := range builtins {
+		_ = registry.Register(m) // the names of the built-in metrics are unique
+	}
+	return registry
+}
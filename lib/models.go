@@ -30,6 +30,7 @@ import (
 	"time"
 
 	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
 	"gopkg.in/guregu/null.v3"
 )
@@ -53,6 +54,23 @@ type StageFields struct {
 // A Stage defines a step in a test's timeline.
 type Stage StageFields
 
+// StageMarker marks the start of a stage in the samples that the executor sends to the engine,
+// so the samples can be attributed to the stages by their time; an Index of -1 marks the end of
+// the last stage. It's a sample container without any samples, so it can be sent through the
+// same channel.
+type StageMarker struct {
+	Index int
+	Time  time.Time
+}
+
+// Ensure that StageMarker implements stats.SampleContainer
+var _ stats.SampleContainer = StageMarker{}
+
+// GetSamples implements the stats.SampleContainer interface, stage markers don't have any samples
+func (m StageMarker) GetSamples() []stats.Sample {
+	return nil
+}
+
 // For some reason, implementing UnmarshalText makes encoding/json treat the type as a string.
 func (s *Stage) UnmarshalJSON(b []byte) error {
 	var fields StageFields
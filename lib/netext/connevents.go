@@ -0,0 +1,92 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// The names of the connection lifecycle events
+const (
+	ConnOpenEvent  = "connection_open"
+	ConnReuseEvent = "connection_reuse"
+	ConnCloseEvent = "connection_close"
+)
+
+// connEvent is a connection lifecycle event that hasn't been emitted yet
+type connEvent struct {
+	time time.Time
+	name string
+	host string
+	data map[string]interface{}
+}
+
+// connEventRecorder buffers the lifecycle events of the connections made by a dialer, until
+// they're emitted with the rest of the VU's samples at the end of its iteration. Connections
+// can be closed by the HTTP transport at any time, so it's safe for concurrent use.
+type connEventRecorder struct {
+	mu     sync.Mutex
+	events []connEvent
+}
+
+func (r *connEventRecorder) record(name, host string, data map[string]interface{}) {
+	r.mu.Lock()
+	r.events = append(r.events, connEvent{time: time.Now(), name: name, host: host, data: data})
+	r.mu.Unlock()
+}
+
+// GetConnEvents returns the connection lifecycle events that were recorded since the last call,
+// with the supplied tags and the host tag, which is the host:port pair that was dialed. It
+// returns nil if RecordConnEvents isn't enabled or nothing happened in the meantime.
+func (d *Dialer) GetConnEvents(tags *stats.SampleTags) []*stats.Event {
+	d.connEvents.mu.Lock()
+	pending := d.connEvents.events
+	d.connEvents.events = nil
+	d.connEvents.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	events := make([]*stats.Event, len(pending))
+	for i, e := range pending {
+		eventTags := tags.CloneTags()
+		eventTags["host"] = e.host
+		events[i] = &stats.Event{Time: e.time, Name: e.name, Tags: stats.IntoSampleTags(&eventTags), Data: e.data}
+	}
+	return events
+}
+
+// Reused records a connection_reuse event for the connection, if its dialer records connection
+// events. It's meant to be called by the HTTP tracer, with the time the connection was idle.
+func (c *Conn) Reused(idle time.Duration) {
+	if c.events == nil {
+		return
+	}
+	c.events.record(ConnReuseEvent, c.addr, map[string]interface{}{
+		"remote_addr":   c.RemoteAddr().String(),
+		"idle_duration": stats.D(idle),
+		"requests":      atomic.LoadInt64(&c.requests),
+	})
+}
@@ -0,0 +1,87 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+func TestConnEvents(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = l.Close() }()
+	go func() {
+		for {
+			if _, err := l.Accept(); err != nil {
+				return
+			}
+		}
+	}()
+	host := l.Addr().String()
+
+	t.Run("Disabled", func(t *testing.T) {
+		dialer := NewDialer(net.Dialer{})
+		conn, err := dialer.DialContext(context.Background(), "tcp", host)
+		require.NoError(t, err)
+		conn.(*Conn).Reused(time.Second)
+		require.NoError(t, conn.Close())
+		assert.Nil(t, dialer.GetConnEvents(nil))
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		dialer := NewDialer(net.Dialer{})
+		dialer.RecordConnEvents = true
+		conn, err := dialer.DialContext(context.Background(), "tcp", host)
+		require.NoError(t, err)
+		c := conn.(*Conn)
+		c.AddRequest()
+		c.AddRequest()
+		c.Reused(1500 * time.Microsecond)
+		require.NoError(t, conn.Close())
+		_ = conn.Close() // closing the same connection twice shouldn't record a second event
+
+		tags := stats.IntoSampleTags(&map[string]string{"foo": "bar"})
+		events := dialer.GetConnEvents(tags)
+		require.Len(t, events, 3)
+		for _, e := range events {
+			assert.Equal(t, map[string]string{"foo": "bar", "host": host}, e.Tags.CloneTags())
+			assert.Equal(t, host, e.Data["remote_addr"])
+		}
+		assert.Equal(t, ConnOpenEvent, events[0].Name)
+		assert.Contains(t, events[0].Data, "connect_duration")
+		assert.Equal(t, ConnReuseEvent, events[1].Name)
+		assert.Equal(t, 1.5, events[1].Data["idle_duration"])
+		assert.Equal(t, int64(2), events[1].Data["requests"])
+		assert.Equal(t, ConnCloseEvent, events[2].Name)
+		assert.Equal(t, int64(2), events[2].Data["requests"])
+		assert.False(t, events[2].Time.Before(events[0].Time))
+
+		assert.Nil(t, dialer.GetConnEvents(tags), "the events should only be returned once")
+	})
+}
@@ -0,0 +1,99 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+)
+
+// ConnTracker keeps track of the number of active connections per host, i.e. per host:port
+// pair, since that's what the connection limits of the HTTP transport apply to. It's safe for
+// concurrent use, so it can be shared by the dialers of all VUs.
+type ConnTracker struct {
+	mu    sync.Mutex
+	conns map[string]int64
+}
+
+// NewConnTracker returns a new, empty ConnTracker.
+func NewConnTracker() *ConnTracker {
+	return &ConnTracker{conns: make(map[string]int64)}
+}
+
+// track registers a new connection to the given host and returns a function that unregisters
+// it, which is safe to call multiple times.
+func (ct *ConnTracker) track(host string) func() {
+	ct.mu.Lock()
+	ct.conns[host]++
+	ct.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ct.mu.Lock()
+			ct.conns[host]--
+			ct.mu.Unlock()
+		})
+	}
+}
+
+// Active returns the number of active connections per host. Hosts whose connections have all
+// been closed are still included, with 0 active connections.
+func (ct *ConnTracker) Active() map[string]int64 {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	active := make(map[string]int64, len(ct.conns))
+	for host, n := range ct.conns {
+		active[host] = n
+	}
+	return active
+}
+
+// GetSamples returns an active_connections sample for every host that a connection was made to
+// so far, with the supplied tags and the host tag. It returns nil if there weren't any yet.
+func (ct *ConnTracker) GetSamples(t time.Time, tags *stats.SampleTags) stats.SampleContainer {
+	active := ct.Active()
+	if len(active) == 0 {
+		return nil
+	}
+	hosts := make([]string, 0, len(active))
+	for host := range active {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	samples := make([]stats.Sample, len(hosts))
+	for i, host := range hosts {
+		hostTags := tags.CloneTags()
+		hostTags["host"] = host
+		samples[i] = stats.Sample{
+			Time:   t,
+			Metric: metrics.ActiveConnections,
+			Value:  float64(active[host]),
+			Tags:   stats.IntoSampleTags(&hostTags),
+		}
+	}
+	return stats.Samples(samples)
+}
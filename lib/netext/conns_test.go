@@ -0,0 +1,84 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+)
+
+func TestConnTracker(t *testing.T) {
+	listen := func() net.Listener {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go func() {
+			for {
+				if _, err := l.Accept(); err != nil {
+					return
+				}
+			}
+		}()
+		return l
+	}
+	lA, lB := listen(), listen()
+	defer func() { _ = lA.Close() }()
+	defer func() { _ = lB.Close() }()
+	hostA, hostB := lA.Addr().String(), lB.Addr().String()
+
+	tracker := NewConnTracker()
+	dialer := NewDialer(net.Dialer{})
+	dialer.Conns = tracker
+	assert.Nil(t, tracker.GetSamples(time.Now(), nil))
+
+	dial := func(addr string) net.Conn {
+		conn, err := dialer.DialContext(context.Background(), "tcp", addr)
+		require.NoError(t, err)
+		return conn
+	}
+	connA1, connA2, connB := dial(hostA), dial(hostA), dial(hostB)
+	assert.Equal(t, map[string]int64{hostA: 2, hostB: 1}, tracker.Active())
+
+	require.NoError(t, connA1.Close())
+	_ = connA1.Close() // closing the same connection twice shouldn't count twice
+	require.NoError(t, connB.Close())
+	assert.Equal(t, map[string]int64{hostA: 1, hostB: 0}, tracker.Active())
+
+	tags := stats.IntoSampleTags(&map[string]string{"foo": "bar"})
+	samples := tracker.GetSamples(time.Now(), tags).GetSamples()
+	require.Len(t, samples, 2)
+	for _, s := range samples {
+		assert.Equal(t, metrics.ActiveConnections, s.Metric)
+		assert.Equal(t, "bar", s.Tags.CloneTags()["foo"])
+		host, _ := s.Tags.Get("host")
+		assert.Equal(t, float64(tracker.Active()[host]), s.Value)
+	}
+
+	require.NoError(t, connA2.Close())
+	assert.Equal(t, map[string]int64{hostA: 0, hostB: 0}, tracker.Active())
+}
@@ -25,6 +25,7 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -36,13 +37,28 @@ import (
 )
 
 // Dialer wraps net.Dialer and provides k6 specific functionality -
-// tracing, blacklists and DNS cache and aliases.
+// tracing, blacklists, DNS cache and aliases and fault injection.
 type Dialer struct {
 	net.Dialer
 
 	Resolver  *dnscache.Resolver
 	Blacklist []*lib.IPNet
 	Hosts     map[string]net.IP
+	Faults    *FaultInjector
+
+	// StickyBackends maps hosts to the backend this VU sticks to, an IP with an optional port
+	StickyBackends map[string]string
+
+	// Conns, if set, keeps track of the active connections made by the dialer
+	Conns *ConnTracker
+
+	// RecordConnEvents enables the recording of the open, reuse and close events of the
+	// connections made by the dialer, which can be retrieved with GetConnEvents()
+	RecordConnEvents bool
+	connEvents       connEventRecorder
+
+	// The open connections made by the dialer, by their local and remote addresses; see GetConn()
+	openConns sync.Map
 
 	BytesRead    int64
 	BytesWritten int64
@@ -69,11 +85,21 @@ func (b BlackListedIPError) Error() string {
 // DialContext wraps the net.Dialer.DialContext and handles the k6 specifics
 func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn, error) {
 	delimiter := strings.LastIndex(addr, ":")
-	host := addr[:delimiter]
+	host, port := addr[:delimiter], addr[delimiter+1:]
 
-	// lookup for domain defined in Hosts option before trying to resolve DNS.
-	ip, ok := d.Hosts[host]
-	if !ok {
+	// the sticky backends take precedence over the Hosts option and the DNS resolution
+	var ip net.IP
+	if backend, ok := d.StickyBackends[host]; ok {
+		var backendPort string
+		var err error
+		if ip, backendPort, err = lib.ParseBackend(backend); err != nil {
+			return nil, err
+		}
+		if backendPort != "" {
+			port = backendPort
+		}
+	} else if ip, ok = d.Hosts[host]; !ok {
+		// lookup for domain defined in Hosts option before trying to resolve DNS.
 		var err error
 		ip, err = d.Resolver.FetchOne(host)
 		if err != nil {
@@ -86,16 +112,55 @@ func (d *Dialer) DialContext(ctx context.Context, proto, addr string) (net.Conn,
 			return nil, BlackListedIPError{ip: ip, net: ipnet}
 		}
 	}
+	if d.Faults != nil {
+		if err := d.Faults.injectConnectFault(ctx); err != nil {
+			return nil, err
+		}
+	}
 	ipStr := ip.String()
 	if strings.ContainsRune(ipStr, ':') {
 		ipStr = "[" + ipStr + "]"
 	}
-	conn, err := d.Dialer.DialContext(ctx, proto, ipStr+":"+addr[delimiter+1:])
+	dialStart := time.Now()
+	conn, err := d.Dialer.DialContext(ctx, proto, ipStr+":"+port)
 	if err != nil {
 		return nil, err
 	}
-	conn = &Conn{conn, &d.BytesRead, &d.BytesWritten}
-	return conn, err
+	if d.Faults != nil {
+		conn = d.Faults.wrapConn(conn)
+	}
+	c := &Conn{Conn: conn, BytesRead: &d.BytesRead, BytesWritten: &d.BytesWritten, addr: addr}
+	c.openConns, c.key = &d.openConns, connKey(conn)
+	d.openConns.Store(c.key, c)
+	if d.Conns != nil {
+		c.onClose = d.Conns.track(addr)
+	}
+	if d.RecordConnEvents {
+		c.events, c.opened = &d.connEvents, time.Now()
+		c.events.record(ConnOpenEvent, addr, map[string]interface{}{
+			"remote_addr":      conn.RemoteAddr().String(),
+			"connect_duration": stats.D(c.opened.Sub(dialStart)),
+		})
+	}
+	return c, err
+}
+
+// GetConn returns the Conn made by the dialer that conn is, or is layered on top of, e.g. a TLS
+// connection made by the HTTP transport. crypto/tls doesn't expose the connection it wraps, so
+// that's looked up by the local and remote addresses, which it passes through. It returns nil
+// if conn wasn't made by the dialer or if it was already closed.
+func (d *Dialer) GetConn(conn net.Conn) *Conn {
+	if c, ok := conn.(*Conn); ok {
+		return c
+	}
+	if c, ok := d.openConns.Load(connKey(conn)); ok {
+		return c.(*Conn)
+	}
+	return nil
+}
+
+func connKey(conn net.Conn) string {
+	return conn.LocalAddr().String() + "-" + conn.RemoteAddr().String()
 }
 
 // GetTrail creates a new NetTrail instance with the Dialer
@@ -149,6 +214,25 @@ func (d *Dialer) GetTrail(
 	}
 }
 
+// GetDataSamples returns data_sent and data_received samples with the supplied tags for the
+// data that was exchanged since the last call, or since the last GetTrail() call, and resets
+// the counters. It returns nil if no data was exchanged in the meantime.
+func (d *Dialer) GetDataSamples(t time.Time, tags *stats.SampleTags) stats.SampleContainer {
+	bytesWritten := atomic.SwapInt64(&d.BytesWritten, 0)
+	bytesRead := atomic.SwapInt64(&d.BytesRead, 0)
+	if bytesWritten == 0 && bytesRead == 0 {
+		return nil
+	}
+	return stats.ConnectedSamples{
+		Samples: []stats.Sample{
+			{Time: t, Metric: metrics.DataSent, Value: float64(bytesWritten), Tags: tags},
+			{Time: t, Metric: metrics.DataReceived, Value: float64(bytesRead), Tags: tags},
+		},
+		Tags: tags,
+		Time: t,
+	}
+}
+
 // NetTrail contains information about the exchanged data size and length of a
 // series of connections from a particular netext.Dialer
 type NetTrail struct {
@@ -179,11 +263,30 @@ func (ntr *NetTrail) GetTime() time.Time {
 	return ntr.EndTime
 }
 
-// Conn wraps net.Conn and keeps track of sent and received data size
+// Conn wraps net.Conn and keeps track of sent and received data size, as well as of the number
+// of HTTP requests that were made over the connection
 type Conn struct {
 	net.Conn
 
 	BytesRead, BytesWritten *int64
+
+	requests int64
+	onClose  func()
+
+	// The open connections of the dialer and the key of this one in them
+	openConns *sync.Map
+	key       string
+
+	// The lifecycle events of the connection are recorded in events, if it's set
+	addr      string
+	opened    time.Time
+	events    *connEventRecorder
+	closeOnce sync.Once
+}
+
+// AddRequest increments the number of requests made over the connection and returns the new total
+func (c *Conn) AddRequest() int64 {
+	return atomic.AddInt64(&c.requests, 1)
 }
 
 func (c *Conn) Read(b []byte) (int, error) {
@@ -194,6 +297,27 @@ func (c *Conn) Read(b []byte) (int, error) {
 	return n, err
 }
 
+// Close closes the connection, unregisters it from the ConnTracker of the dialer, if any, and
+// records its close event, if the dialer records connection events
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		if c.openConns != nil {
+			c.openConns.Delete(c.key)
+		}
+		if c.onClose != nil {
+			c.onClose()
+		}
+		if c.events != nil {
+			c.events.record(ConnCloseEvent, c.addr, map[string]interface{}{
+				"remote_addr": c.RemoteAddr().String(),
+				"lifetime":    stats.D(time.Since(c.opened)),
+				"requests":    atomic.LoadInt64(&c.requests),
+			})
+		}
+	})
+	return c.Conn.Close()
+}
+
 func (c *Conn) Write(b []byte) (int, error) {
 	n, err := c.Conn.Write(b)
 	if n > 0 {
@@ -0,0 +1,149 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib"
+)
+
+// ErrInjectedFault is returned for connections that were made to fail by a FaultInjector
+var ErrInjectedFault = errors.New("injected connection fault")
+
+// FaultInjector randomly decides which connections and reads should be delayed or should fail,
+// according to the probabilities in its config. It's safe for concurrent use.
+type FaultInjector struct {
+	config lib.FaultInjection
+
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+// NewFaultInjector returns a new FaultInjector. The seed from the config is combined with the
+// given seed offset (e.g. the VU ID), so that every VU gets its own reproducible sequence.
+func NewFaultInjector(config lib.FaultInjection, seedOffset int64) *FaultInjector {
+	return &FaultInjector{
+		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed + seedOffset)), //nolint:gosec
+	}
+}
+
+// Reseed restarts the random sequence, with the config seed combined with the given offset.
+func (f *FaultInjector) Reseed(seedOffset int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rand.Seed(f.config.Seed + seedOffset)
+}
+
+func (f *FaultInjector) delay(d lib.FaultDelay) time.Duration {
+	if d.Rate <= 0 || f.rand.Float64() >= d.Rate {
+		return 0
+	}
+	min, max := d.Min, d.Max
+	if len(d.Buckets) > 0 {
+		b := f.pickBucket(d.Buckets)
+		min, max = b.Min, b.Max
+	}
+	delay := time.Duration(min)
+	if max > min {
+		delay += time.Duration(f.rand.Int63n(int64(max - min + 1)))
+	}
+	return delay
+}
+
+// pickBucket randomly picks one of the buckets, with probabilities proportional to their weights
+func (f *FaultInjector) pickBucket(buckets []lib.FaultDelayBucket) lib.FaultDelayBucket {
+	var total float64
+	for _, b := range buckets {
+		total += b.Weight
+	}
+	r := f.rand.Float64() * total
+	for _, b := range buckets {
+		if r < b.Weight {
+			return b
+		}
+		r -= b.Weight
+	}
+	return buckets[len(buckets)-1] // only reachable because of rounding errors
+}
+
+// ConnectFault returns the delay before establishing a new connection and whether the
+// connection attempt should fail.
+func (f *FaultInjector) ConnectFault() (delay time.Duration, fail bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delay = f.delay(f.config.ConnectDelay)
+	fail = f.config.ConnectErrorRate > 0 && f.rand.Float64() < f.config.ConnectErrorRate
+	return delay, fail
+}
+
+// ReadDelay returns the delay before a single read from a connection.
+func (f *FaultInjector) ReadDelay() time.Duration {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.delay(f.config.ReadDelay)
+}
+
+// injectConnectFault waits for the connect delay, if any, and returns ErrInjectedFault if the
+// connection should fail.
+func (f *FaultInjector) injectConnectFault(ctx context.Context) error {
+	delay, fail := f.ConnectFault()
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	if fail {
+		return ErrInjectedFault
+	}
+	return nil
+}
+
+// wrapConn adds read delays to the connection, if they're enabled.
+func (f *FaultInjector) wrapConn(conn net.Conn) net.Conn {
+	if f.config.ReadDelay.Rate <= 0 {
+		return conn
+	}
+	return &faultConn{Conn: conn, faults: f}
+}
+
+// faultConn delays reads, which looks like a slow server or network to the reader
+type faultConn struct {
+	net.Conn
+	faults *FaultInjector
+}
+
+func (c *faultConn) Read(b []byte) (int, error) {
+	if delay := c.faults.ReadDelay(); delay > 0 {
+		time.Sleep(delay)
+	}
+	return c.Conn.Read(b)
+}
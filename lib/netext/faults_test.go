@@ -0,0 +1,189 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viki-org/dnscache"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
+)
+
+func TestFaultInjectorRates(t *testing.T) {
+	config := lib.FaultInjection{
+		Seed:             1337,
+		ConnectErrorRate: 0.2,
+		ConnectDelay: lib.FaultDelay{
+			Rate: 0.3, Min: types.Duration(10 * time.Millisecond), Max: types.Duration(20 * time.Millisecond),
+		},
+		ReadDelay: lib.FaultDelay{
+			Rate: 0.5, Min: types.Duration(5 * time.Millisecond), Max: types.Duration(5 * time.Millisecond),
+		},
+	}
+	const draws = 10000
+
+	f := NewFaultInjector(config, 0)
+	var connectErrors, connectDelays, readDelays int
+	for i := 0; i < draws; i++ {
+		delay, fail := f.ConnectFault()
+		if fail {
+			connectErrors++
+		}
+		if delay > 0 {
+			connectDelays++
+			assert.True(t, delay >= 10*time.Millisecond && delay <= 20*time.Millisecond, delay)
+		}
+		if delay := f.ReadDelay(); delay > 0 {
+			readDelays++
+			assert.Equal(t, 5*time.Millisecond, delay)
+		}
+	}
+	assert.InDelta(t, 0.2, float64(connectErrors)/draws, 0.02)
+	assert.InDelta(t, 0.3, float64(connectDelays)/draws, 0.02)
+	assert.InDelta(t, 0.5, float64(readDelays)/draws, 0.02)
+
+	t.Run("reproducible", func(t *testing.T) {
+		sequence := func(f *FaultInjector) []time.Duration {
+			result := make([]time.Duration, 100)
+			for i := range result {
+				result[i], _ = f.ConnectFault()
+			}
+			return result
+		}
+		first := sequence(NewFaultInjector(config, 1))
+		assert.Equal(t, first, sequence(NewFaultInjector(config, 1)))
+		assert.NotEqual(t, first, sequence(NewFaultInjector(config, 2)))
+
+		f := NewFaultInjector(config, 2)
+		f.Reseed(1)
+		assert.Equal(t, first, sequence(f))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		f := NewFaultInjector(lib.FaultInjection{}, 0)
+		for i := 0; i < 100; i++ {
+			delay, fail := f.ConnectFault()
+			assert.Zero(t, delay)
+			assert.False(t, fail)
+			assert.Zero(t, f.ReadDelay())
+		}
+	})
+}
+
+func TestFaultInjectorDelayBuckets(t *testing.T) {
+	ms := func(n int) types.Duration { return types.Duration(time.Duration(n) * time.Millisecond) }
+	config := lib.FaultInjection{
+		Seed: 1337,
+		ConnectDelay: lib.FaultDelay{Rate: 0.5, Buckets: []lib.FaultDelayBucket{
+			{Weight: 90, Min: ms(1), Max: ms(10)},
+			{Weight: 9, Min: ms(100), Max: ms(200)},
+			{Weight: 1, Min: ms(1000), Max: ms(1000)},
+		}},
+	}
+	const draws = 100000
+
+	f := NewFaultInjector(config, 0)
+	var short, medium, long, none int
+	for i := 0; i < draws; i++ {
+		delay, _ := f.ConnectFault()
+		switch {
+		case delay == 0:
+			none++
+		case delay >= time.Millisecond && delay <= 10*time.Millisecond:
+			short++
+		case delay >= 100*time.Millisecond && delay <= 200*time.Millisecond:
+			medium++
+		case delay == time.Second:
+			long++
+		default:
+			t.Fatalf("delay %s isn't in any of the buckets", delay)
+		}
+	}
+	delayed := float64(draws - none)
+	assert.InDelta(t, 0.5, delayed/draws, 0.01)
+	assert.InDelta(t, 0.90, float64(short)/delayed, 0.005)
+	assert.InDelta(t, 0.09, float64(medium)/delayed, 0.005)
+	assert.InDelta(t, 0.01, float64(long)/delayed, 0.002)
+}
+
+func TestDialerFaults(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer func() { _ = listener.Close() }()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte("hi"))
+			_ = conn.Close()
+		}
+	}()
+	addr := listener.Addr().String()
+
+	newDialer := func(config lib.FaultInjection) *Dialer {
+		return &Dialer{Resolver: dnscache.New(0), Faults: NewFaultInjector(config, 0)}
+	}
+
+	t.Run("connect error", func(t *testing.T) {
+		d := newDialer(lib.FaultInjection{ConnectErrorRate: 1})
+		_, err := d.DialContext(context.Background(), "tcp", addr)
+		assert.Equal(t, ErrInjectedFault, err)
+	})
+
+	t.Run("connect delay", func(t *testing.T) {
+		delay := lib.FaultDelay{Rate: 1, Min: types.Duration(50 * time.Millisecond), Max: types.Duration(50 * time.Millisecond)}
+		d := newDialer(lib.FaultInjection{ConnectDelay: delay})
+		start := time.Now()
+		conn, err := d.DialContext(context.Background(), "tcp", addr)
+		require.NoError(t, err)
+		_ = conn.Close()
+		assert.True(t, time.Since(start) >= 50*time.Millisecond)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = d.DialContext(ctx, "tcp", addr)
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+
+	t.Run("read delay", func(t *testing.T) {
+		delay := lib.FaultDelay{Rate: 1, Min: types.Duration(50 * time.Millisecond), Max: types.Duration(50 * time.Millisecond)}
+		d := newDialer(lib.FaultInjection{ReadDelay: delay})
+		conn, err := d.DialContext(context.Background(), "tcp", addr)
+		require.NoError(t, err)
+		defer func() { _ = conn.Close() }()
+		start := time.Now()
+		buf := make([]byte, 2)
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		assert.Equal(t, "hi", string(buf[:n]))
+		assert.True(t, time.Since(start) >= 50*time.Millisecond)
+		assert.Equal(t, int64(2), d.BytesRead)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"compress/gzip"
 	"compress/zlib"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -118,6 +119,7 @@ func wrapDecompressionError(err error) error {
 func readResponseBody(
 	state *lib.State,
 	respType ResponseType,
+	onRecord func(json.RawMessage) error,
 	resp *http.Response,
 	respErr error,
 ) (interface{}, error) {
@@ -125,7 +127,7 @@ func readResponseBody(
 		return nil, respErr
 	}
 
-	if respType == ResponseTypeNone {
+	if respType == ResponseTypeNone && onRecord == nil {
 		_, err := io.Copy(ioutil.Discard, resp.Body)
 		_ = resp.Body.Close()
 		if err != nil {
@@ -135,9 +137,13 @@ func readResponseBody(
 	}
 
 	rc := &readCloser{resp.Body}
-	// Ensure that the entire response body is read and closed, e.g. in case of decoding errors
+	// Ensure that the entire response body is read and closed, e.g. in case of decoding errors,
+	// unless the streaming of the records was stopped, since the stream could be endless
+	drain := true
 	defer func(respBody io.ReadCloser) {
-		_, _ = io.Copy(ioutil.Discard, respBody)
+		if drain {
+			_, _ = io.Copy(ioutil.Discard, respBody)
+		}
 		_ = respBody.Close()
 	}(resp.Body)
 
@@ -171,6 +177,19 @@ func readResponseBody(
 			rc = &readCloser{decoder}
 		}
 	}
+
+	if onRecord != nil {
+		err := streamRecords(rc.Reader, onRecord)
+		if _, isRecordErr := err.(recordError); isRecordErr {
+			drain = false
+			return nil, err
+		}
+		if closeErr := rc.Close(); err == nil {
+			err = wrapDecompressionError(closeErr)
+		}
+		return nil, err
+	}
+
 	buf := state.BPool.Get()
 	defer state.BPool.Put(buf)
 	buf.Reset()
@@ -201,3 +220,30 @@ func readResponseBody(
 
 	return result, respErr
 }
+
+// recordError wraps the errors returned by the onRecord callback of streamRecords()
+type recordError struct {
+	error
+}
+
+// streamRecords decodes the JSON values in the body one by one, e.g. NDJSON records, and passes
+// each of them to onRecord as soon as it's read, so the whole body is never buffered.
+func streamRecords(body io.Reader, onRecord func(json.RawMessage) error) error {
+	dec := json.NewDecoder(body)
+	for {
+		var record json.RawMessage
+		err := dec.Decode(&record)
+		if err == io.EOF {
+			return nil
+		}
+		if _, ok := err.(*json.SyntaxError); ok {
+			return fmt.Errorf("couldn't parse a streamed JSON record: %s", err)
+		}
+		if err != nil {
+			return wrapDecompressionError(err)
+		}
+		if err := onRecord(record); err != nil {
+			return recordError{err}
+		}
+	}
+}
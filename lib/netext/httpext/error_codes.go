@@ -79,7 +79,9 @@ const (
 
 	// Custom k6 content errors, i.e. when the magic fails
 	//defaultContentError errCode = 1700 // reserved for future use
-	responseDecompressionErrorCode errCode = 1701
+	responseDecompressionErrorCode   errCode = 1701
+	responseHeadersTooLargeErrorCode errCode = 1702
+	tooManyResponseHeadersErrorCode  errCode = 1703
 )
 
 const (
@@ -104,6 +106,32 @@ func http2ErrCodeOffset(code http2.ErrCode) errCode {
 	return 1 + errCode(code)
 }
 
+// errorCategory returns the category of the given error code, which can be used for thresholds
+// on whole groups of errors, e.g. http_req_failed{error_category:connect}. Connection errors
+// are told apart from the other TCP errors, which happen on already established connections.
+func errorCategory(code errCode) string {
+	switch {
+	case code >= 1100 && code < 1200:
+		return "dns"
+	case code >= tcpDialErrorCode && code < tcpResetByPeerErrorCode:
+		return "connect"
+	case code >= 1200 && code < 1300:
+		return "tcp"
+	case code >= 1300 && code < 1400:
+		return "tls"
+	case code >= 1400 && code < 1500:
+		return "http4xx"
+	case code >= 1500 && code < 1600:
+		return "http5xx"
+	case code >= 1600 && code < 1700:
+		return "http2"
+	case code >= 1700 && code < 1800:
+		return "content"
+	default:
+		return "general"
+	}
+}
+
 // errorCodeForError returns the errorCode and a specific error message for given error.
 func errorCodeForError(err error) (errCode, string) {
 	switch e := errors.Cause(err).(type) {
@@ -154,3 +154,28 @@ func testMapOfErrorCodes(t *testing.T, testTable map[errCode]error) {
 		testErrorCode(t, code, err)
 	}
 }
+
+func TestErrorCategory(t *testing.T) {
+	testTable := map[errCode]string{
+		defaultErrorCode:                "general",
+		defaultNetNonTCPErrorCode:       "general",
+		dnsNoSuchHostErrorCode:          "dns",
+		blackListedIPErrorCode:          "dns",
+		tcpDialErrorCode:                "connect",
+		tcpDialTimeoutErrorCode:         "connect",
+		tcpDialRefusedErrorCode:         "connect",
+		tcpDialUnknownErrnoCode:         "connect",
+		defaultTCPErrorCode:             "tcp",
+		tcpBrokenPipeErrorCode:          "tcp",
+		tcpResetByPeerErrorCode:         "tcp",
+		x509HostnameErrorCode:           "tls",
+		1404:                            "http4xx",
+		1503:                            "http5xx",
+		unknownHTTP2StreamErrorCode + 1: "http2",
+		responseDecompressionErrorCode:  "content",
+		tooManyResponseHeadersErrorCode: "content",
+	}
+	for code, category := range testTable {
+		require.Equalf(t, category, errorCategory(code), "Wrong category for error code %d", code)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"net/http"
+	"regexp"
+
+	"github.com/tidwall/gjson"
+)
+
+// Extractor captures a value from a response, e.g. a token that the following requests need.
+// Only one of JSON, Regex and Header is set.
+type Extractor struct {
+	Name string
+
+	// A path in the JSON body, with the same syntax as the selectors of Response.JSON()
+	JSON string
+	// Matched against the body; the first group is captured if there is one, the whole match otherwise
+	Regex *regexp.Regexp
+	// The name of a response header
+	Header string
+}
+
+// Extract returns the value that was captured from the response and whether there was one
+func (e Extractor) Extract(resp *Response) (string, bool) {
+	if e.Header != "" {
+		value, ok := resp.Headers[http.CanonicalHeaderKey(e.Header)]
+		return value, ok
+	}
+
+	var body []byte
+	switch b := resp.Body.(type) {
+	case []byte:
+		body = b
+	case string:
+		body = []byte(b)
+	default:
+		return "", false
+	}
+
+	if e.Regex != nil {
+		match := e.Regex.FindSubmatch(body)
+		switch {
+		case match == nil:
+			return "", false
+		case len(match) > 1:
+			return string(match[1]), true
+		default:
+			return string(match[0]), true
+		}
+	}
+
+	result := gjson.GetBytes(body, e.JSON)
+	return result.String(), result.Exists()
+}
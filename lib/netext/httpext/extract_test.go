@@ -0,0 +1,67 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractor(t *testing.T) {
+	resp := &Response{
+		Headers: map[string]string{"X-Session-Id": "abc123"},
+		Body:    []byte(`{"data": {"token": "secret", "ids": [4, 2]}, "csrf": "<input name=csrf value=xyz>"}`),
+	}
+
+	testCases := []struct {
+		extractor Extractor
+		value     string
+		found     bool
+	}{
+		{Extractor{JSON: "data.token"}, "secret", true},
+		{Extractor{JSON: "data.ids.1"}, "2", true},
+		{Extractor{JSON: "data.ids"}, "[4, 2]", true},
+		{Extractor{JSON: "data.missing"}, "", false},
+		{Extractor{Regex: regexp.MustCompile(`value=(\w+)`)}, "xyz", true},
+		{Extractor{Regex: regexp.MustCompile(`name=\w+`)}, "name=csrf", true},
+		{Extractor{Regex: regexp.MustCompile(`missing=(\w+)`)}, "", false},
+		{Extractor{Header: "x-session-id"}, "abc123", true},
+		{Extractor{Header: "X-Missing"}, "", false},
+	}
+	for _, tc := range testCases {
+		value, found := tc.extractor.Extract(resp)
+		assert.Equal(t, tc.found, found, "%#v", tc.extractor)
+		assert.Equal(t, tc.value, value, "%#v", tc.extractor)
+	}
+
+	t.Run("string body", func(t *testing.T) {
+		value, found := Extractor{JSON: "data.token"}.Extract(&Response{Body: `{"data": {"token": "secret"}}`})
+		assert.True(t, found)
+		assert.Equal(t, "secret", value)
+	})
+
+	t.Run("no body", func(t *testing.T) {
+		_, found := Extractor{JSON: "data.token"}.Extract(&Response{})
+		assert.False(t, found)
+	})
+}
@@ -0,0 +1,88 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+)
+
+type ctxKeyProxy int
+
+const proxyKey ctxKeyProxy = iota
+
+// withProxy returns a context that makes ProxySelector route the request through the proxy
+func withProxy(ctx context.Context, proxy *url.URL) context.Context {
+	return context.WithValue(ctx, proxyKey, proxy)
+}
+
+// ParseProxyURL parses the URL of a proxy, which needs an http, https or socks5 scheme
+func ParseProxyURL(rawurl string) (*url.URL, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %s", rawurl, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: the scheme should be http, https or socks5", rawurl)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: the host is missing", rawurl)
+	}
+	return u, nil
+}
+
+// ProxySelector picks the proxy for every request made through an http.Transport. Requests
+// with an explicit proxy param use it, the rest are distributed round-robin between the proxies
+// from the proxies option. Without any configured proxies, the proxy environment variables apply.
+type ProxySelector struct {
+	proxies []*url.URL
+	next    uint64
+}
+
+// NewProxySelector returns a new ProxySelector for the specified proxy URLs
+func NewProxySelector(proxies []string) (*ProxySelector, error) {
+	s := &ProxySelector{proxies: make([]*url.URL, len(proxies))}
+	for i, proxy := range proxies {
+		u, err := ParseProxyURL(proxy)
+		if err != nil {
+			return nil, err
+		}
+		s.proxies[i] = u
+	}
+	return s, nil
+}
+
+// Proxy can be used as the Proxy function of an http.Transport
+func (s *ProxySelector) Proxy(req *http.Request) (*url.URL, error) {
+	if proxy, ok := req.Context().Value(proxyKey).(*url.URL); ok {
+		return proxy, nil
+	}
+	if len(s.proxies) == 0 {
+		return http.ProxyFromEnvironment(req)
+	}
+	next := atomic.AddUint64(&s.next, 1) - 1
+	return s.proxies[next%uint64(len(s.proxies))], nil
+}
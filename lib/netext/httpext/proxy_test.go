@@ -0,0 +1,56 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxySelector(t *testing.T) {
+	_, err := NewProxySelector([]string{"http://proxy1:3128", "proxy2"})
+	assert.EqualError(t, err, `invalid proxy URL "proxy2": the scheme should be http, https or socks5`)
+	_, err = NewProxySelector([]string{"socks5://"})
+	assert.EqualError(t, err, `invalid proxy URL "socks5://": the host is missing`)
+
+	s, err := NewProxySelector([]string{"http://proxy1:3128", "socks5://proxy2:1080"})
+	require.NoError(t, err)
+	req, err := http.NewRequest("GET", "http://example.com/", nil)
+	require.NoError(t, err)
+
+	proxies := []string{}
+	for i := 0; i < 3; i++ {
+		proxy, err := s.Proxy(req)
+		require.NoError(t, err)
+		proxies = append(proxies, proxy.String())
+	}
+	assert.Equal(t, []string{"http://proxy1:3128", "socks5://proxy2:1080", "http://proxy1:3128"}, proxies)
+
+	explicit, err := ParseProxyURL("https://proxy3")
+	require.NoError(t, err)
+	proxy, err := s.Proxy(req.WithContext(withProxy(context.Background(), explicit)))
+	require.NoError(t, err)
+	assert.Equal(t, explicit, proxy)
+}
@@ -23,6 +23,7 @@ package httpext
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -39,6 +40,7 @@ import (
 	null "gopkg.in/guregu/null.v3"
 
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
 	"github.com/loadimpact/k6/stats"
 )
 
@@ -117,6 +119,17 @@ type ParsedHTTPRequest struct {
 	ActiveJar    *cookiejar.Jar
 	Cookies      map[string]*HTTPRequestCookie
 	Tags         map[string]string
+	// Skip is set when a response this request depends on has failed
+	Skip bool
+	// ExpectedStatuses overrides the expectedStatuses option for this request
+	ExpectedStatuses []int
+	// Proxy overrides the proxy that the request would otherwise be sent through
+	Proxy *url.URL
+	// OnRecord is called with every JSON value in the response body, e.g. NDJSON records, as
+	// soon as it's received. The body isn't buffered then, and an error stops the streaming.
+	OnRecord func(record json.RawMessage) error
+	// Extractors capture values from the response into the iteration variables
+	Extractors []Extractor
 }
 
 // Matches non-compliant io.Closer implementations (e.g. zstd.Decoder)
@@ -154,6 +167,7 @@ func updateK6Response(k6Response *Response, finishedReq *finishedRequest) {
 	k6Response.ErrorCode = int(finishedReq.errorCode)
 	k6Response.Error = finishedReq.errorMsg
 	trail := finishedReq.trail
+	k6Response.Failed = trail.Failed.Bool
 
 	if trail.ConnRemoteAddr != nil {
 		remoteHost, remotePortStr, _ := net.SplitHostPort(trail.ConnRemoteAddr.String())
@@ -231,7 +245,7 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		}
 	}
 
-	tags := state.Options.RunTags.CloneTags()
+	tags := state.CloneTags()
 	for k, v := range preq.Tags {
 		tags[k] = v
 	}
@@ -239,13 +253,20 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 	if state.Options.SystemTags.Has(stats.TagMethod) {
 		tags["method"] = preq.Req.Method
 	}
+	bucketURLs := state.Options.BucketURLs.Bool
 	if state.Options.SystemTags.Has(stats.TagURL) {
 		tags["url"] = preq.URL.Clean()
+		if bucketURLs {
+			tags["url"] = bucketURL(tags["url"], state.Options.URLBucketPatterns)
+		}
 	}
 
 	// Only set the name system tag if the user didn't explicitly set it beforehand
 	if _, ok := tags["name"]; !ok && state.Options.SystemTags.Has(stats.TagName) {
 		tags["name"] = preq.URL.Name
+		if bucketURLs {
+			tags["name"] = bucketURL(tags["name"], state.Options.URLBucketPatterns)
+		}
 	}
 	if state.Options.SystemTags.Has(stats.TagGroup) {
 		tags["group"] = state.Group.Path
@@ -257,6 +278,51 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		tags["iter"] = strconv.FormatInt(state.Iteration, 10)
 	}
 
+	// Start a new trace for every request, unless the user propagates one explicitly
+	if state.Options.TraceContext.Bool && preq.Req.Header.Get(traceParentHeader) == "" {
+		traceID, traceParent, err := newTraceParent()
+		if err != nil {
+			return nil, err
+		}
+		preq.Req.Header.Set(traceParentHeader, traceParent)
+		if state.Options.SystemTags.Has(stats.TagTraceID) {
+			tags["trace_id"] = traceID
+		}
+	}
+
+	// Don't make requests whose dependencies have failed, just count them as skipped.
+	if preq.Skip {
+		stats.PushIfNotDone(ctx, state.Samples, stats.Sample{
+			Metric: metrics.HTTPReqsSkipped,
+			Time:   time.Now(),
+			Tags:   stats.IntoSampleTags(&tags),
+			Value:  1,
+		})
+		return &Response{
+			ctx:     ctx,
+			URL:     preq.URL.URL,
+			Request: *respReq,
+			Error:   "request skipped because a response it depends on has failed",
+		}, nil
+	}
+
+	if interval := time.Duration(state.Options.UploadProgressInterval.Duration); interval > 0 && preq.Body != nil {
+		progressTags := make(map[string]string, len(tags))
+		for k, v := range tags {
+			progressTags[k] = v
+		}
+		sampleTags := stats.IntoSampleTags(&progressTags)
+		getBody := preq.Req.GetBody
+		preq.Req.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+			return newUploadProgressReader(ctx, state.Samples, body, interval, sampleTags), nil
+		}
+		preq.Req.Body, _ = preq.Req.GetBody()
+	}
+
 	// Check rate limit *after* we've prepared a request; no need to wait with that part.
 	if rpsLimit := state.RPSLimit; rpsLimit != nil {
 		if err := rpsLimit.Wait(ctx); err != nil {
@@ -264,7 +330,11 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		}
 	}
 
-	tracerTransport := newTransport(ctx, state, tags)
+	expectedStatuses := preq.ExpectedStatuses
+	if expectedStatuses == nil {
+		expectedStatuses = state.Options.ExpectedStatuses
+	}
+	tracerTransport := newTransport(ctx, state, tags, expectedStatuses)
 	var transport http.RoundTripper = tracerTransport
 
 	if state.Options.HTTPDebug.String != "" {
@@ -280,7 +350,7 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		transport = ntlmssp.Negotiator{RoundTripper: transport}
 	}
 
-	resp := &Response{ctx: ctx, URL: preq.URL.URL, Request: *respReq}
+	resp := &Response{ctx: ctx, URL: preq.URL.URL, Request: *respReq, jsonNumbers: state.Options.JSONNumbers.String}
 	client := http.Client{
 		Transport: transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
@@ -313,6 +383,9 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 
 	reqCtx, cancelFunc := context.WithTimeout(ctx, preq.Timeout)
 	defer cancelFunc()
+	if preq.Proxy != nil {
+		reqCtx = withProxy(reqCtx, preq.Proxy)
+	}
 	mreq := preq.Req.WithContext(reqCtx)
 	res, resErr := client.Do(mreq)
 
@@ -325,11 +398,19 @@ func MakeRequest(ctx context.Context, preq *ParsedHTTPRequest) (*Response, error
 		return nil, fmt.Errorf("unsupported response status: %s", res.Status)
 	}
 
-	resp.Body, resErr = readResponseBody(state, preq.ResponseType, res, resErr)
+	resp.Body, resErr = readResponseBody(state, preq.ResponseType, preq.OnRecord, res, resErr)
+	// An error from the OnRecord callback doesn't mean that the request itself has failed
+	var recordErr recordError
+	if err, ok := resErr.(recordError); ok {
+		recordErr, resErr = err, nil
+	}
 	finishedReq := tracerTransport.processLastSavedRequest(wrapDecompressionError(resErr))
 	if finishedReq != nil {
 		updateK6Response(resp, finishedReq)
 	}
+	if recordErr.error != nil {
+		return nil, recordErr.error
+	}
 
 	if resErr == nil {
 		if preq.ActiveJar != nil {
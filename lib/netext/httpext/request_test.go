@@ -3,20 +3,28 @@ package httpext
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
 )
 
 type reader func([]byte) (int, error)
@@ -180,6 +188,275 @@ func TestMakeRequestTimeout(t *testing.T) {
 	assert.Len(t, samples, 1)
 }
 
+func TestStatusClassTag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		require.NoError(t, err)
+		w.WriteHeader(code)
+	}))
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	testCases := map[int]string{
+		200: "2xx", 204: "2xx", 304: "3xx", 404: "4xx", 418: "4xx", 500: "5xx", 503: "5xx",
+	}
+	for code, class := range testCases {
+		code, class := code, class
+		t.Run(strconv.Itoa(code), func(t *testing.T) {
+			samples := make(chan stats.SampleContainer, 10)
+			state := &lib.State{
+				Options:   lib.Options{RunTags: &stats.SampleTags{}, SystemTags: &stats.DefaultSystemTagSet},
+				Transport: srv.Client().Transport,
+				Samples:   samples,
+				Logger:    logrus.New(),
+				Group:     root,
+			}
+			ctx := lib.WithState(context.Background(), state)
+			req, _ := http.NewRequest("GET", fmt.Sprintf("%s/%d", srv.URL, code), nil)
+			preq := &ParsedHTTPRequest{
+				Req: req, URL: &URL{u: req.URL}, Body: new(bytes.Buffer), Timeout: 10 * time.Second,
+				ResponseType: ResponseTypeNone,
+			}
+
+			res, err := MakeRequest(ctx, preq)
+			require.NoError(t, err)
+			assert.Equal(t, code, res.Status)
+
+			bufSamples := stats.GetBufferedSamples(samples)
+			require.Len(t, bufSamples, 1)
+			for _, sample := range bufSamples[0].GetSamples() {
+				tag, ok := sample.Tags.Get("status_class")
+				assert.True(t, ok)
+				assert.Equal(t, class, tag)
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		samples := make(chan stats.SampleContainer, 10)
+		state := &lib.State{
+			Options:   lib.Options{RunTags: &stats.SampleTags{}, SystemTags: stats.NewSystemTagSet(stats.TagStatus)},
+			Transport: srv.Client().Transport,
+			Samples:   samples,
+			Logger:    logrus.New(),
+			Group:     root,
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req, _ := http.NewRequest("GET", srv.URL+"/200", nil)
+		preq := &ParsedHTTPRequest{
+			Req: req, URL: &URL{u: req.URL}, Body: new(bytes.Buffer), Timeout: 10 * time.Second,
+			ResponseType: ResponseTypeNone,
+		}
+
+		_, err := MakeRequest(ctx, preq)
+		require.NoError(t, err)
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.Len(t, bufSamples, 1)
+		_, ok := bufSamples[0].(stats.ConnectedSampleContainer).GetTags().Get("status_class")
+		assert.False(t, ok)
+	})
+}
+
+func TestErrorCategoryTag(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	// Nothing listens on the port of a closed listener, so connections to it are refused
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	refusedURL := "http://" + listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	testCases := map[string]string{srv.URL: "http5xx", refusedURL: "connect"}
+	for u, category := range testCases {
+		u, category := u, category
+		t.Run(category, func(t *testing.T) {
+			samples := make(chan stats.SampleContainer, 10)
+			state := &lib.State{
+				Options:   lib.Options{RunTags: &stats.SampleTags{}, SystemTags: &stats.DefaultSystemTagSet},
+				Transport: srv.Client().Transport,
+				Samples:   samples,
+				Logger:    logrus.New(),
+				Group:     root,
+			}
+			ctx := lib.WithState(context.Background(), state)
+			req, _ := http.NewRequest("GET", u, nil)
+			preq := &ParsedHTTPRequest{
+				Req: req, URL: &URL{u: req.URL}, Body: new(bytes.Buffer), Timeout: 10 * time.Second,
+				ResponseType: ResponseTypeNone,
+			}
+
+			_, _ = MakeRequest(ctx, preq)
+			bufSamples := stats.GetBufferedSamples(samples)
+			require.Len(t, bufSamples, 1)
+			rates := map[string]float64{}
+			for _, sample := range bufSamples[0].GetSamples() {
+				tag, ok := sample.Tags.Get("error_category")
+				assert.True(t, ok)
+				assert.Equal(t, category, tag)
+				if strings.HasPrefix(sample.Metric.Name, "http_req_failed_") {
+					rates[strings.TrimPrefix(sample.Metric.Name, "http_req_failed_")] = sample.Value
+				}
+			}
+			// Every category gets a sample, but only the one of the error is a failure
+			assert.Len(t, rates, len(metrics.HTTPReqFailedCategories))
+			for c, rate := range rates {
+				assert.Equal(t, c == category, rate == 1, c)
+			}
+		})
+	}
+}
+
+func TestUploadProgress(t *testing.T) {
+	// The server reads the body slowly, so the upload is spread over a few hundred milliseconds
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		buf := make([]byte, 64*1024)
+		for {
+			if _, err := r.Body.Read(buf); err != nil {
+				break
+			}
+			time.Sleep(2 * time.Millisecond)
+		}
+	}))
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	const bodySize = 16 * 1024 * 1024
+	interval := 20 * time.Millisecond
+	samples := make(chan stats.SampleContainer, 1000)
+	state := &lib.State{
+		Options: lib.Options{
+			RunTags:                &stats.SampleTags{},
+			SystemTags:             &stats.DefaultSystemTagSet,
+			UploadProgressInterval: types.NullDurationFrom(interval),
+		},
+		Transport: srv.Client().Transport,
+		Samples:   samples,
+		Logger:    logrus.New(),
+		Group:     root,
+	}
+	ctx := lib.WithState(context.Background(), state)
+	req, _ := http.NewRequest("POST", srv.URL, nil)
+	preq := &ParsedHTTPRequest{
+		Req: req, URL: &URL{u: req.URL, URL: srv.URL}, Body: bytes.NewBuffer(make([]byte, bodySize)),
+		Timeout: 10 * time.Second, ResponseType: ResponseTypeNone,
+	}
+	_, err = MakeRequest(ctx, preq)
+	require.NoError(t, err)
+
+	var progress []stats.Sample
+	for _, sc := range stats.GetBufferedSamples(samples) {
+		for _, sample := range sc.GetSamples() {
+			if sample.Metric == metrics.HTTPUploadBytes {
+				progress = append(progress, sample)
+			}
+		}
+	}
+	require.True(t, len(progress) > 2, "expected incremental progress samples, got %d", len(progress))
+
+	var total float64
+	for i, sample := range progress {
+		assert.True(t, sample.Value > 0)
+		total += sample.Value
+		method, _ := sample.Tags.Get("method")
+		assert.Equal(t, "POST", method)
+		if i > 0 && i < len(progress)-1 {
+			assert.False(t, sample.Time.Sub(progress[i-1].Time) < interval, "progress samples emitted too often")
+		}
+	}
+	assert.Equal(t, float64(bodySize), total)
+}
+
+func TestTraceContext(t *testing.T) {
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("traceparent")
+	}))
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	// makeRequest returns the emitted samples and the traceparent header the server received
+	makeRequest := func(t *testing.T, opts lib.Options, header string) (stats.ConnectedSampleContainer, string) {
+		samples := make(chan stats.SampleContainer, 10)
+		opts.RunTags = &stats.SampleTags{}
+		state := &lib.State{
+			Options:   opts,
+			Transport: srv.Client().Transport,
+			Samples:   samples,
+			Logger:    logrus.New(),
+			Group:     root,
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		if header != "" {
+			req.Header.Set("traceparent", header)
+		}
+		preq := &ParsedHTTPRequest{
+			Req: req, URL: &URL{u: req.URL}, Body: new(bytes.Buffer), Timeout: 10 * time.Second,
+			ResponseType: ResponseTypeNone,
+		}
+		_, err := MakeRequest(ctx, preq)
+		require.NoError(t, err)
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.Len(t, bufSamples, 1)
+		return bufSamples[0].(stats.ConnectedSampleContainer), <-received
+	}
+
+	traceParentRegexp := regexp.MustCompile(`^00-([0-9a-f]{32})-[0-9a-f]{16}-01$`)
+	withTraceID := stats.DefaultSystemTagSet | stats.TagTraceID
+	enabled := lib.Options{TraceContext: null.BoolFrom(true), SystemTags: &withTraceID}
+
+	t.Run("enabled", func(t *testing.T) {
+		var traceIDs []string
+		for i := 0; i < 2; i++ {
+			sc, header := makeRequest(t, enabled, "")
+			match := traceParentRegexp.FindStringSubmatch(header)
+			require.Len(t, match, 2, "invalid traceparent header %q", header)
+			for _, sample := range sc.GetSamples() {
+				tag, ok := sample.Tags.Get("trace_id")
+				assert.True(t, ok)
+				assert.Equal(t, match[1], tag)
+			}
+			traceIDs = append(traceIDs, match[1])
+		}
+		assert.NotEqual(t, traceIDs[0], traceIDs[1])
+	})
+
+	t.Run("user header", func(t *testing.T) {
+		userHeader := "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+		sc, header := makeRequest(t, enabled, userHeader)
+		assert.Equal(t, userHeader, header)
+		_, ok := sc.GetTags().Get("trace_id")
+		assert.False(t, ok)
+	})
+
+	t.Run("tag not enabled by default", func(t *testing.T) {
+		opts := lib.Options{TraceContext: null.BoolFrom(true), SystemTags: &stats.DefaultSystemTagSet}
+		sc, header := makeRequest(t, opts, "")
+		assert.Regexp(t, traceParentRegexp, header)
+		_, ok := sc.GetTags().Get("trace_id")
+		assert.False(t, ok)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		sc, header := makeRequest(t, lib.Options{SystemTags: &stats.DefaultSystemTagSet}, "")
+		assert.Empty(t, header)
+		_, ok := sc.GetTags().Get("trace_id")
+		assert.False(t, ok)
+	})
+}
+
 func BenchmarkWrapDecompressionError(b *testing.B) {
 	err := errors.New("error")
 	b.ResetTimer()
@@ -188,3 +465,66 @@ func BenchmarkWrapDecompressionError(b *testing.B) {
 		_ = wrapDecompressionError(err)
 	}
 }
+
+func TestBucketURLTags(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	getTags := func(t *testing.T, opts lib.Options, path string, tags map[string]string) map[string]string {
+		samples := make(chan stats.SampleContainer, 10)
+		opts.RunTags = &stats.SampleTags{}
+		opts.SystemTags = &stats.DefaultSystemTagSet
+		state := &lib.State{
+			Options:   opts,
+			Transport: srv.Client().Transport,
+			Samples:   samples,
+			Logger:    logrus.New(),
+			Group:     root,
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req, _ := http.NewRequest("GET", srv.URL+path, nil)
+		preq := &ParsedHTTPRequest{
+			Req: req, URL: &URL{u: req.URL, URL: srv.URL + path, Name: srv.URL + path},
+			Body: new(bytes.Buffer), Timeout: 10 * time.Second, ResponseType: ResponseTypeNone, Tags: tags,
+		}
+		_, err := MakeRequest(ctx, preq)
+		require.NoError(t, err)
+		bufSamples := stats.GetBufferedSamples(samples)
+		require.Len(t, bufSamples, 1)
+		return bufSamples[0].(stats.ConnectedSampleContainer).GetTags().CloneTags()
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		tags := getTags(t, lib.Options{}, "/users/123", nil)
+		assert.Equal(t, srv.URL+"/users/123", tags["url"])
+		assert.Equal(t, srv.URL+"/users/123", tags["name"])
+	})
+
+	t.Run("default patterns", func(t *testing.T) {
+		tags := getTags(t, lib.Options{BucketURLs: null.BoolFrom(true)}, "/users/123/orders/abc", nil)
+		assert.Equal(t, srv.URL+"/users/:id/orders/abc", tags["url"])
+		assert.Equal(t, srv.URL+"/users/:id/orders/abc", tags["name"])
+	})
+
+	t.Run("custom patterns", func(t *testing.T) {
+		opts := lib.Options{
+			BucketURLs: null.BoolFrom(true),
+			URLBucketPatterns: []*lib.URLBucketPattern{
+				{Regexp: regexp.MustCompile(`^\d+$`)},
+				{Regexp: regexp.MustCompile(`^[a-c]+$`)},
+			},
+		}
+		tags := getTags(t, opts, "/users/123/orders/abc", nil)
+		assert.Equal(t, srv.URL+"/users/:id/orders/:id", tags["url"])
+		assert.Equal(t, srv.URL+"/users/:id/orders/:id", tags["name"])
+	})
+
+	t.Run("explicit name", func(t *testing.T) {
+		tags := getTags(t, lib.Options{BucketURLs: null.BoolFrom(true)}, "/users/123", map[string]string{"name": "user 123"})
+		assert.Equal(t, srv.URL+"/users/:id", tags["url"])
+		assert.Equal(t, "user 123", tags["name"])
+	})
+}
@@ -21,11 +21,15 @@
 package httpext
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strings"
 
+	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext"
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
@@ -107,8 +111,13 @@ type Response struct {
 	ErrorCode      int                      `json:"error_code"`
 	Request        Request                  `json:"request"`
 
+	// Whether the request failed, i.e. had an error or a status that wasn't expected, the same
+	// way it's counted in http_req_failed
+	Failed bool `json:"-" js:"-"`
+
 	cachedJSON    interface{}
 	validatedJSON bool
+	jsonNumbers   string // how JSON numbers are parsed, one of the lib.JSONNumbers* values
 }
 
 func (res *Response) setTLSInfo(tlsState *tls.ConnectionState) {
@@ -151,10 +160,19 @@ func (res *Response) JSON(selector ...string) (interface{}, error) {
 			if !result.Exists() {
 				return nil, nil
 			}
-			return result.Value(), nil
+			if res.parsesNumbersAsFloats() {
+				return result.Value(), nil
+			}
+			return decodeJSON([]byte(result.Raw), res.jsonNumbers)
 		}
 
-		if err := json.Unmarshal(body, &v); err != nil {
+		var err error
+		if res.parsesNumbersAsFloats() {
+			err = json.Unmarshal(body, &v)
+		} else {
+			v, err = decodeJSON(body, res.jsonNumbers)
+		}
+		if err != nil {
 			if syntaxError, ok := err.(*json.SyntaxError); ok {
 				err = checkErrorInJSON(body, int(syntaxError.Offset), err)
 			}
@@ -166,6 +184,62 @@ func (res *Response) JSON(selector ...string) (interface{}, error) {
 	return res.cachedJSON, nil
 }
 
+func (res *Response) parsesNumbersAsFloats() bool {
+	return res.jsonNumbers == "" || res.jsonNumbers == lib.JSONNumbersFloat
+}
+
+// decodeJSON decodes a JSON document like json.Unmarshal does, except that its numbers are
+// returned as strings or float64s, according to the jsonNumbers option.
+func decodeJSON(data []byte, jsonNumbers string) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("invalid data after top-level value")
+		}
+		return nil, err
+	}
+	return convertJSONNumbers(v, jsonNumbers), nil
+}
+
+// The largest integer up to which all integers can be exactly represented by a float64
+const maxExactFloatInt = 1 << 53
+
+func convertJSONNumbers(v interface{}, jsonNumbers string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = convertJSONNumbers(item, jsonNumbers)
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = convertJSONNumbers(item, jsonNumbers)
+		}
+	case json.Number:
+		if jsonNumbers == lib.JSONNumbersString {
+			return val.String()
+		}
+		if !strings.ContainsAny(val.String(), ".eE") {
+			if i, err := val.Int64(); err != nil || i > maxExactFloatInt || i < -maxExactFloatInt {
+				return val.String()
+			}
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return val.String()
+		}
+		return f
+	}
+	return v
+}
+
 func checkErrorInJSON(input []byte, offset int, err error) error {
 	lf := '\n'
 	str := string(input)
@@ -0,0 +1,105 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The header in which servers describe their own timings, as defined in https://www.w3.org/TR/server-timing/
+const serverTimingHeader = "Server-Timing"
+
+// ServerTiming is one of the metrics from a Server-Timing header, e.g. `db;dur=53.2;desc="DB"`
+type ServerTiming struct {
+	Name        string
+	Description string
+	Duration    float64 // in milliseconds
+	HasDuration bool
+}
+
+// ParseServerTiming parses the metrics from the values of the Server-Timing headers. Malformed
+// parameters are ignored, as the spec requires, and so are metrics without a name.
+func ParseServerTiming(values []string) []ServerTiming {
+	var timings []ServerTiming
+	for _, value := range values {
+		for _, metric := range splitQuoted(value, ',') {
+			params := splitQuoted(metric, ';')
+			timing := ServerTiming{Name: strings.TrimSpace(params[0])}
+			if timing.Name == "" {
+				continue
+			}
+			seen := make(map[string]bool, len(params)-1)
+			for _, param := range params[1:] {
+				kv := strings.SplitN(param, "=", 2)
+				key := strings.ToLower(strings.TrimSpace(kv[0]))
+				if len(kv) != 2 || seen[key] {
+					continue // only the first occurrence of every parameter counts
+				}
+				seen[key] = true
+				switch val := unquote(strings.TrimSpace(kv[1])); key {
+				case "dur":
+					d, err := strconv.ParseFloat(val, 64)
+					timing.Duration, timing.HasDuration = d, err == nil
+				case "desc":
+					timing.Description = val
+				}
+			}
+			timings = append(timings, timing)
+		}
+	}
+	return timings
+}
+
+// splitQuoted splits s by sep, except where sep is inside of a quoted string
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case escaped:
+			escaped = false
+		case quoted && c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the contents of a quoted string, or the string itself if it isn't quoted
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	for i := 1; i < len(s)-1; i++ {
+		if s[i] == '\\' && i+1 < len(s)-1 {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
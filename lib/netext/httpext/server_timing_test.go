@@ -0,0 +1,137 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	null "gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	testCases := map[string][]ServerTiming{
+		"":     nil,
+		"miss": {{Name: "miss"}},
+		"db;dur=53, app;dur=47.2": {
+			{Name: "db", Duration: 53, HasDuration: true},
+			{Name: "app", Duration: 47.2, HasDuration: true},
+		},
+		`cache;desc="Cache Read";dur=23.2`: {
+			{Name: "cache", Description: "Cache Read", Duration: 23.2, HasDuration: true},
+		},
+		`db; DUR = 1.5 ; desc=Database`: {
+			{Name: "db", Description: "Database", Duration: 1.5, HasDuration: true},
+		},
+		`db;dur=1;dur=2, ;dur=3`: {
+			{Name: "db", Duration: 1, HasDuration: true},
+		},
+		`sql;desc="SELECT a, b; \"quoted\"";dur="12"`: {
+			{Name: "sql", Description: `SELECT a, b; "quoted"`, Duration: 12, HasDuration: true},
+		},
+		`db;dur=abc;desc;total;dur=7`: {
+			{Name: "db"},
+		},
+	}
+	for header, expected := range testCases {
+		header, expected := header, expected
+		t.Run(header, func(t *testing.T) {
+			assert.Equal(t, expected, ParseServerTiming([]string{header}))
+		})
+	}
+
+	t.Run("multiple headers", func(t *testing.T) {
+		assert.Equal(t, []ServerTiming{
+			{Name: "db", Duration: 1, HasDuration: true},
+			{Name: "app", Duration: 2, HasDuration: true},
+		}, ParseServerTiming([]string{"db;dur=1", "app;dur=2"}))
+	})
+}
+
+func TestServerTimingSamples(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Server-Timing", `db;dur=53.5;desc="Database", miss`)
+		w.Header().Add("Server-Timing", "app;dur=47")
+		w.Header().Set("Trailer", "Server-Timing")
+		_, _ = w.Write([]byte("ok"))
+		w.Header().Set("Server-Timing", "render;dur=12")
+	}))
+	defer srv.Close()
+
+	root, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	getSamples := func(t *testing.T, serverTiming bool) map[string]float64 {
+		samples := make(chan stats.SampleContainer, 10)
+		state := &lib.State{
+			Options: lib.Options{
+				RunTags:      &stats.SampleTags{},
+				SystemTags:   &stats.DefaultSystemTagSet,
+				ServerTiming: null.BoolFrom(serverTiming),
+			},
+			Transport: srv.Client().Transport,
+			Samples:   samples,
+			Logger:    logrus.New(),
+			Group:     root,
+		}
+		ctx := lib.WithState(context.Background(), state)
+		req, _ := http.NewRequest("GET", srv.URL, nil)
+		preq := &ParsedHTTPRequest{
+			Req: req, URL: &URL{u: req.URL}, Body: new(bytes.Buffer), Timeout: 10 * time.Second,
+			ResponseType: ResponseTypeNone,
+		}
+		_, err := MakeRequest(ctx, preq)
+		require.NoError(t, err)
+
+		timings := make(map[string]float64)
+		for _, sc := range stats.GetBufferedSamples(samples) {
+			for _, sample := range sc.GetSamples() {
+				if sample.Metric != metrics.HTTPReqServerTiming {
+					continue
+				}
+				name, ok := sample.Tags.Get("server_timing")
+				require.True(t, ok)
+				status, _ := sample.Tags.Get("status")
+				assert.Equal(t, "200", status)
+				timings[name] = sample.Value
+			}
+		}
+		return timings
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		assert.Equal(t, map[string]float64{"db": 53.5, "app": 47, "render": 12}, getSamples(t, true))
+	})
+	t.Run("disabled", func(t *testing.T) {
+		assert.Empty(t, getSamples(t, false))
+	})
+}
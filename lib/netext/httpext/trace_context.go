@@ -0,0 +1,42 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// The header used for propagating the trace context, as defined in https://www.w3.org/TR/trace-context/
+const traceParentHeader = "traceparent"
+
+// newTraceParent generates random trace and parent IDs and returns the trace ID, as well as the
+// traceparent header value for a new, sampled trace.
+func newTraceParent() (traceID string, traceParent string, err error) {
+	// The first 16 bytes are the trace ID and the last 8 are the parent (span) ID. All-zero IDs
+	// are invalid, but the chance of getting one is negligible.
+	var ids [24]byte
+	if _, err := rand.Read(ids[:]); err != nil {
+		return "", "", err
+	}
+	traceID = hex.EncodeToString(ids[:16])
+	return traceID, "00-" + traceID + "-" + hex.EncodeToString(ids[16:]) + "-01", nil
+}
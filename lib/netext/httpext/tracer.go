@@ -28,7 +28,11 @@ import (
 	"sync/atomic"
 	"time"
 
+	null "gopkg.in/guregu/null.v3"
+
 	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/lib/netext"
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 )
 
@@ -56,6 +60,32 @@ type Trail struct {
 	ConnRemoteAddr net.Addr
 	Errors         []error
 
+	// The number of requests that were made over the connection so far, including this one, or 0
+	// if that's unknown. An http_conn_requests sample is only emitted if this is set.
+	ConnRequests int64
+
+	// The part of Blocked spent waiting for a free connection, when the number of connections
+	// per host is limited, before either reusing one or dialing a new one. An http_req_queued
+	// sample is only emitted if this is valid.
+	Queued types.NullDuration
+
+	// Whether all of the connections to the host were in use when the request was made, so it
+	// had to wait for one. A conn_pool_exhausted sample is only emitted if this is set.
+	ConnPoolExhausted null.Bool
+
+	// Whether the request failed, i.e. had an error or an unexpected response status. An
+	// http_req_failed sample is only emitted if this is set.
+	Failed null.Bool
+
+	// The category of the request's error, or an empty string if there was none. If this is
+	// set, a sample of every http_req_failed_<category> metric is emitted, with a value of 1
+	// only for the category of the error.
+	ErrorCategory null.String
+
+	// The metrics from the Server-Timing response headers. An http_req_server_timing sample,
+	// tagged with the name of the metric, is emitted for every one of them that has a duration.
+	ServerTimings []ServerTiming
+
 	// Populated by SaveSamples()
 	Tags    *stats.SampleTags
 	Samples []stats.Sample
@@ -75,6 +105,51 @@ func (tr *Trail) SaveSamples(tags *stats.SampleTags) {
 		{Metric: metrics.HTTPReqWaiting, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.Waiting)},
 		{Metric: metrics.HTTPReqReceiving, Time: tr.EndTime, Tags: tags, Value: stats.D(tr.Receiving)},
 	}
+	if tr.ConnRequests > 0 {
+		tr.Samples = append(tr.Samples,
+			stats.Sample{Metric: metrics.HTTPConnRequests, Time: tr.EndTime, Tags: tags, Value: float64(tr.ConnRequests)})
+	}
+	if tr.Queued.Valid {
+		tr.Samples = append(tr.Samples,
+			stats.Sample{Metric: metrics.HTTPReqQueued, Time: tr.EndTime, Tags: tags, Value: stats.D(time.Duration(tr.Queued.Duration))})
+	}
+	if tr.ConnPoolExhausted.Valid {
+		exhausted := 0.0
+		if tr.ConnPoolExhausted.Bool {
+			exhausted = 1
+		}
+		tr.Samples = append(tr.Samples,
+			stats.Sample{Metric: metrics.ConnPoolExhausted, Time: tr.EndTime, Tags: tags, Value: exhausted})
+	}
+	if tr.Failed.Valid {
+		failed := 0.0
+		if tr.Failed.Bool {
+			failed = 1
+		}
+		tr.Samples = append(tr.Samples,
+			stats.Sample{Metric: metrics.HTTPReqFailed, Time: tr.EndTime, Tags: tags, Value: failed})
+	}
+	if tr.ErrorCategory.Valid {
+		for category, metric := range metrics.HTTPReqFailedCategories {
+			failed := 0.0
+			if category == tr.ErrorCategory.String {
+				failed = 1
+			}
+			tr.Samples = append(tr.Samples,
+				stats.Sample{Metric: metric, Time: tr.EndTime, Tags: tags, Value: failed})
+		}
+	}
+	for _, timing := range tr.ServerTimings {
+		if !timing.HasDuration {
+			continue
+		}
+		timingTags := tags.CloneTags()
+		timingTags["server_timing"] = timing.Name
+		tr.Samples = append(tr.Samples, stats.Sample{
+			Metric: metrics.HTTPReqServerTiming, Time: tr.EndTime,
+			Tags: stats.IntoSampleTags(&timingTags), Value: timing.Duration,
+		})
+	}
 }
 
 // GetSamples implements the stats.SampleContainer interface.
@@ -107,11 +182,15 @@ type Tracer struct {
 	tlsHandshakeStart    int64
 	tlsHandshakeDone     int64
 	gotConn              int64
+	queuedUntil          int64
 	wroteRequest         int64
 	gotFirstResponseByte int64
 
 	connReused     bool
 	connRemoteAddr net.Addr
+	conn           *netext.Conn   // only set for connections made by the k6 dialer
+	dialer         *netext.Dialer // if set, used to find the k6 connections under TLS ones
+	connRequests   int64
 
 	protoErrorsMutex sync.Mutex
 	protoErrors      []error
@@ -229,12 +308,31 @@ func (t *Tracer) GotConn(info httptrace.GotConnInfo) {
 	t.connReused = info.Reused
 	t.connRemoteAddr = info.Conn.RemoteAddr()
 
+	// The request waited in the queue either until it got a reused connection, or until the
+	// dial of the new one started, so the connecting and handshaking times aren't included.
+	t.queuedUntil = now
+	if connectStart := atomic.LoadInt64(&t.connectStart); !info.Reused && connectStart != 0 {
+		t.queuedUntil = connectStart
+	}
+
 	// The Go stdlib's http module can start connecting to a remote server, only
 	// to abandon that connection even before it was fully established and reuse
 	// a recently freed already existing connection.
 	// We overwrite the different timestamps here, so the other callbacks don't
 	// put incorrect values in them (they use CompareAndSwap)
 	_, isConnTLS := info.Conn.(*tls.Conn)
+	conn, _ := info.Conn.(*netext.Conn)
+	if conn == nil && t.dialer != nil {
+		conn = t.dialer.GetConn(info.Conn)
+	}
+	if conn != nil {
+		t.conn = conn
+		t.connRequests = conn.AddRequest()
+		if info.Reused {
+			conn.Reused(info.IdleTime)
+		}
+	}
+
 	if info.Reused {
 		atomic.SwapInt64(&t.connectStart, now)
 		atomic.SwapInt64(&t.connectDone, now)
@@ -286,11 +384,15 @@ func (t *Tracer) Done() *Trail {
 	trail := Trail{
 		ConnReused:     t.connReused,
 		ConnRemoteAddr: t.connRemoteAddr,
+		ConnRequests:   t.connRequests,
 	}
 
 	if t.gotConn != 0 && t.getConn != 0 && t.gotConn > t.getConn {
 		trail.Blocked = time.Duration(t.gotConn - t.getConn)
 	}
+	if t.queuedUntil != 0 && t.getConn != 0 && t.queuedUntil > t.getConn {
+		trail.Queued.Duration = types.Duration(t.queuedUntil - t.getConn)
+	}
 
 	// It's possible for some of the methods of httptrace.ClientTrace to
 	// actually be called after the http.Client or http.RoundTripper have
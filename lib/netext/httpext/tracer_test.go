@@ -54,7 +54,8 @@ func TestTracer(t *testing.T) {
 
 	transport, ok := srv.Client().Transport.(*http.Transport)
 	assert.True(t, ok)
-	transport.DialContext = netext.NewDialer(net.Dialer{}).DialContext
+	dialer := netext.NewDialer(net.Dialer{})
+	transport.DialContext = dialer.DialContext
 
 	var prev int64
 	assertLaterOrZero := func(t *testing.T, val int64, canBeZero bool) {
@@ -72,7 +73,7 @@ func TestTracer(t *testing.T) {
 	for tnum, isReuse := range []bool{false, true, true} {
 		t.Run(fmt.Sprintf("Test #%d", tnum), func(t *testing.T) {
 			// Do not enable parallel testing, test relies on sequential execution
-			tracer := &Tracer{}
+			tracer := &Tracer{dialer: dialer}
 			req, err := http.NewRequest("GET", srv.URL+"/get", nil)
 			require.NoError(t, err)
 
@@ -99,7 +100,7 @@ func TestTracer(t *testing.T) {
 
 			assert.Equal(t, strings.TrimPrefix(srv.URL, "https://"), trail.ConnRemoteAddr.String())
 
-			assert.Len(t, samples, 8)
+			assert.Len(t, samples, 9)
 			seenMetrics := map[*stats.Metric]bool{}
 			for i, s := range samples {
 				assert.NotContains(t, seenMetrics, s.Metric)
@@ -112,6 +113,8 @@ func TestTracer(t *testing.T) {
 				case metrics.HTTPReqs:
 					assert.Equal(t, 1.0, s.Value)
 					assert.Equal(t, 0, i, "`HTTPReqs` is reported before the other HTTP metrics")
+				case metrics.HTTPConnRequests:
+					assert.Equal(t, float64(tnum+1), s.Value, "all requests are made over the same connection")
 				case metrics.HTTPReqConnecting, metrics.HTTPReqTLSHandshaking:
 					if isReuse {
 						assert.Equal(t, 0.0, s.Value)
@@ -22,12 +22,16 @@ package httpext
 
 import (
 	"context"
+	"fmt"
 	"net"
 	"net/http"
 	"net/http/httptrace"
 	"strconv"
+	"strings"
 	"sync"
 
+	null "gopkg.in/guregu/null.v3"
+
 	"github.com/loadimpact/k6/lib"
 	"github.com/loadimpact/k6/lib/netext"
 	"github.com/loadimpact/k6/stats"
@@ -40,6 +44,9 @@ type transport struct {
 	state *lib.State
 	tags  map[string]string
 
+	// The response statuses that aren't considered failures; see isExpectedStatus()
+	expectedStatuses []int
+
 	lastRequest     *unfinishedRequest
 	lastRequestLock *sync.Mutex
 }
@@ -52,6 +59,11 @@ type unfinishedRequest struct {
 	request  *http.Request
 	response *http.Response
 	err      error
+
+	// The key of the request in the VU's connection pool tracker and whether the pool was
+	// exhausted when the request was made
+	connPoolKey       string
+	connPoolExhausted bool
 }
 
 // finishedRequest is produced once the request has been finalized; it is
@@ -76,19 +88,42 @@ func newTransport(
 	ctx context.Context,
 	state *lib.State,
 	tags map[string]string,
+	expectedStatuses []int,
 ) *transport {
 	return &transport{
-		ctx:             ctx,
-		state:           state,
-		tags:            tags,
-		lastRequestLock: new(sync.Mutex),
+		ctx:              ctx,
+		state:            state,
+		tags:             tags,
+		expectedStatuses: expectedStatuses,
+		lastRequestLock:  new(sync.Mutex),
+	}
+}
+
+// isExpectedStatus checks whether the given response status should be considered a success. By
+// default, that's any status below 400, unless a specific list of expected statuses was given.
+func isExpectedStatus(status int, expectedStatuses []int) bool {
+	if len(expectedStatuses) == 0 {
+		return status < 400
+	}
+	for _, s := range expectedStatuses {
+		if s == status {
+			return true
+		}
 	}
+	return false
 }
 
 // Helper method to finish the tracer trail, assemble the tag values and emits
 // the metric samples for the supplied unfinished request.
 func (t *transport) measureAndEmitMetrics(unfReq *unfinishedRequest) *finishedRequest {
 	trail := unfReq.tracer.Done()
+	if t.state.ConnPool != nil {
+		t.state.ConnPool.Release(unfReq.connPoolKey)
+		// HTTP/2 requests share the connections, so they only wait for new ones to be dialed
+		trail.ConnPoolExhausted = null.BoolFrom(
+			unfReq.connPoolExhausted && (unfReq.response == nil || unfReq.response.ProtoMajor < 2),
+		)
+	}
 
 	tags := map[string]string{}
 	for k, v := range t.tags {
@@ -110,6 +145,10 @@ func (t *transport) measureAndEmitMetrics(unfReq *unfinishedRequest) *finishedRe
 		if enabledTags.Has(stats.TagErrorCode) {
 			tags["error_code"] = strconv.Itoa(int(result.errorCode))
 		}
+		if enabledTags.Has(stats.TagErrorCategory) {
+			trail.ErrorCategory = null.StringFrom(errorCategory(result.errorCode))
+			tags["error_category"] = trail.ErrorCategory.String
+		}
 
 		if enabledTags.Has(stats.TagStatus) {
 			tags["status"] = "0"
@@ -118,15 +157,28 @@ func (t *transport) measureAndEmitMetrics(unfReq *unfinishedRequest) *finishedRe
 		if enabledTags.Has(stats.TagURL) {
 			u := URL{u: unfReq.request.URL, URL: unfReq.request.URL.String()}
 			tags["url"] = u.Clean()
+			if t.state.Options.BucketURLs.Bool {
+				tags["url"] = bucketURL(tags["url"], t.state.Options.URLBucketPatterns)
+			}
 		}
 		if enabledTags.Has(stats.TagStatus) {
 			tags["status"] = strconv.Itoa(unfReq.response.StatusCode)
 		}
+		if enabledTags.Has(stats.TagStatusClass) {
+			tags["status_class"] = statusClass(unfReq.response.StatusCode)
+		}
 		if unfReq.response.StatusCode >= 400 {
 			if enabledTags.Has(stats.TagErrorCode) {
 				result.errorCode = errCode(1000 + unfReq.response.StatusCode)
 				tags["error_code"] = strconv.Itoa(int(result.errorCode))
 			}
+			if enabledTags.Has(stats.TagErrorCategory) {
+				trail.ErrorCategory = null.StringFrom(errorCategory(errCode(1000 + unfReq.response.StatusCode)))
+				tags["error_category"] = trail.ErrorCategory.String
+			}
+		} else if enabledTags.Has(stats.TagErrorCategory) {
+			// Successful requests count towards the error rates of all categories
+			trail.ErrorCategory = null.StringFrom("")
 		}
 		if enabledTags.Has(stats.TagProto) {
 			tags["proto"] = unfReq.response.Proto
@@ -149,12 +201,39 @@ func (t *transport) measureAndEmitMetrics(unfReq *unfinishedRequest) *finishedRe
 		}
 	}
 
+	// The queuing time is only meaningful when the connections per host are limited
+	trail.Queued.Valid = t.state.Options.MaxConnsPerHost.Int64 > 0
+	trail.Failed = null.BoolFrom(
+		unfReq.err != nil || !isExpectedStatus(unfReq.response.StatusCode, t.expectedStatuses),
+	)
+	if t.state.Options.ServerTiming.Bool && unfReq.response != nil {
+		// The body has been read by now, so the timings can also be sent as trailers
+		trail.ServerTimings = append(
+			ParseServerTiming(unfReq.response.Header[serverTimingHeader]),
+			ParseServerTiming(unfReq.response.Trailer[serverTimingHeader])...,
+		)
+	}
 	trail.SaveSamples(stats.IntoSampleTags(&tags))
 	stats.PushIfNotDone(t.ctx, t.state.Samples, trail)
 
+	// Retire the connection once it has served the maximum number of requests. The response has
+	// been fully read by now, so the http.Transport will just see a closed idle connection. That's
+	// not true for HTTP/2, where other requests can still be streamed over the same connection,
+	// so those connections are left alone.
+	maxRequests := t.state.Options.MaxRequestsPerConnection.Int64
+	if conn := unfReq.tracer.conn; conn != nil && maxRequests > 0 && trail.ConnRequests >= maxRequests &&
+		unfReq.response != nil && unfReq.response.ProtoMajor == 1 {
+		_ = conn.Close()
+	}
+
 	return result
 }
 
+// statusClass returns the class of the given HTTP status code, e.g. "2xx" for 204
+func statusClass(statusCode int) string {
+	return strconv.Itoa(statusCode/100) + "xx"
+}
+
 func (t *transport) saveCurrentRequest(currentRequest *unfinishedRequest) {
 	t.lastRequestLock.Lock()
 	unprocessedRequest := t.lastRequest
@@ -193,8 +272,14 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 
 	ctx := req.Context()
 	tracer := &Tracer{}
+	if dialer, ok := t.state.Dialer.(*netext.Dialer); ok {
+		tracer.dialer = dialer
+	}
 	reqWithTracer := req.WithContext(httptrace.WithClientTrace(ctx, tracer.Trace()))
+	connPoolKey := req.URL.Scheme + "://" + req.URL.Host
+	connPoolExhausted := t.state.ConnPool.Acquire(connPoolKey)
 	resp, err := t.state.Transport.RoundTrip(reqWithTracer)
+	resp, err = t.checkResponseHeaders(resp, err)
 
 	t.saveCurrentRequest(&unfinishedRequest{
 		ctx:      ctx,
@@ -202,7 +287,41 @@ func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 		request:  req,
 		response: resp,
 		err:      err,
+
+		connPoolKey:       connPoolKey,
+		connPoolExhausted: connPoolExhausted,
 	})
 
 	return resp, err
 }
+
+// checkResponseHeaders replaces the response with an error if it has more headers than the
+// maxResponseHeaders limit, and gives a clear error for headers larger than the size limit.
+func (t *transport) checkResponseHeaders(resp *http.Response, err error) (*http.Response, error) {
+	if err != nil {
+		// The size is checked by the http.Transport itself, so there's only an error message
+		if strings.Contains(err.Error(), "server response headers exceeded") {
+			return nil, NewK6Error(responseHeadersTooLargeErrorCode, fmt.Sprintf(
+				"the response headers are larger than the maxResponseHeaderBytes limit of %d bytes",
+				t.state.Options.MaxResponseHeaderBytes.Int64,
+			), err)
+		}
+		return resp, err
+	}
+
+	maxHeaders := int(t.state.Options.MaxResponseHeaders.Int64)
+	if maxHeaders <= 0 {
+		return resp, nil
+	}
+	count := 0
+	for _, values := range resp.Header {
+		count += len(values)
+	}
+	if count <= maxHeaders {
+		return resp, nil
+	}
+	_ = resp.Body.Close()
+	return nil, NewK6Error(tooManyResponseHeadersErrorCode, fmt.Sprintf(
+		"the response has %d headers, more than the maxResponseHeaders limit of %d", count, maxHeaders,
+	), nil)
+}
@@ -0,0 +1,96 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/loadimpact/k6/lib/metrics"
+	"github.com/loadimpact/k6/stats"
+)
+
+// uploadProgressReader wraps a request body and emits the number of bytes that the transport
+// read from it as http_upload_bytes samples, at most once per interval, and once more for the
+// remaining bytes when the whole body is read or the body is closed.
+type uploadProgressReader struct {
+	io.ReadCloser
+
+	ctx      context.Context
+	samples  chan<- stats.SampleContainer
+	interval time.Duration
+	tags     *stats.SampleTags
+
+	// The transport can close the body in a different goroutine than the one reading it
+	mu       sync.Mutex
+	pending  int64
+	lastEmit time.Time
+}
+
+func newUploadProgressReader(
+	ctx context.Context, samples chan<- stats.SampleContainer, body io.ReadCloser,
+	interval time.Duration, tags *stats.SampleTags,
+) *uploadProgressReader {
+	return &uploadProgressReader{
+		ReadCloser: body,
+		ctx:        ctx,
+		samples:    samples,
+		interval:   interval,
+		tags:       tags,
+		lastEmit:   time.Now(),
+	}
+}
+
+func (r *uploadProgressReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pending += int64(n)
+	if now := time.Now(); err != nil || now.Sub(r.lastEmit) >= r.interval {
+		r.emit(now)
+	}
+	return n, err
+}
+
+// Close emits the bytes that were read since the last sample and closes the body
+func (r *uploadProgressReader) Close() error {
+	r.mu.Lock()
+	r.emit(time.Now())
+	r.mu.Unlock()
+	return r.ReadCloser.Close()
+}
+
+func (r *uploadProgressReader) emit(now time.Time) {
+	r.lastEmit = now
+	if r.pending == 0 {
+		return
+	}
+	stats.PushIfNotDone(r.ctx, r.samples, stats.Sample{
+		Metric: metrics.HTTPUploadBytes,
+		Time:   now,
+		Tags:   r.tags,
+		Value:  float64(r.pending),
+	})
+	r.pending = 0
+}
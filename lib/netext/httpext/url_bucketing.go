@@ -0,0 +1,80 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpext
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/loadimpact/k6/lib"
+)
+
+// The placeholder that bucketed URL path segments are replaced with
+const urlBucketPlaceholder = ":id"
+
+// The patterns that are used when URL bucketing is enabled without any custom ones: decimal
+// numbers and UUIDs.
+//nolint:gochecknoglobals
+var defaultURLBucketPatterns = []*lib.URLBucketPattern{
+	{Regexp: regexp.MustCompile(`^\d+$`)},
+	{Regexp: regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)},
+}
+
+// bucketURL replaces every path segment of the given URL that matches any of the patterns with
+// a placeholder, e.g. http://example.com/users/123 becomes http://example.com/users/:id. The
+// scheme, host, query string and fragment are left as they are.
+func bucketURL(u string, patterns []*lib.URLBucketPattern) string {
+	if patterns == nil {
+		patterns = defaultURLBucketPatterns
+	}
+
+	start := 0
+	if i := strings.Index(u, "://"); i >= 0 {
+		j := strings.IndexByte(u[i+3:], '/')
+		if j < 0 {
+			return u // no path
+		}
+		start = i + 3 + j
+	}
+	end := len(u)
+	if i := strings.IndexAny(u[start:], "?#"); i >= 0 {
+		end = start + i
+	}
+
+	segments := strings.Split(u[start:end], "/")
+	changed := false
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		for _, p := range patterns {
+			if p.MatchString(segment) {
+				segments[i] = urlBucketPlaceholder
+				changed = true
+				break
+			}
+		}
+	}
+	if !changed {
+		return u
+	}
+	return u[:start] + strings.Join(segments, "/") + u[end:]
+}
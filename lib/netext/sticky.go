@@ -0,0 +1,86 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// hashRingReplicas is the number of points every backend has on a HashRing; more points spread
+// the keys more evenly between the backends.
+const hashRingReplicas = 100
+
+// HashRing picks one of a list of backends for every key with consistent hashing, so the same
+// key always gets the same backend, and adding or removing a backend only moves the keys of
+// that backend.
+type HashRing struct {
+	points   []uint64
+	backends map[uint64]string
+}
+
+// NewHashRing returns a new HashRing for the given backends
+func NewHashRing(backends []string) *HashRing {
+	r := &HashRing{
+		points:   make([]uint64, 0, len(backends)*hashRingReplicas),
+		backends: make(map[uint64]string, len(backends)*hashRingReplicas),
+	}
+	for _, backend := range backends {
+		for i := 0; i < hashRingReplicas; i++ {
+			point := hashKey(backend + "#" + strconv.Itoa(i))
+			if _, ok := r.backends[point]; ok {
+				continue // a collision, the first backend keeps the point
+			}
+			r.points = append(r.points, point)
+			r.backends[point] = backend
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool { return r.points[i] < r.points[j] })
+	return r
+}
+
+// Get returns the backend for the key, i.e. the one with the first point after the key's hash
+func (r *HashRing) Get(key string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i] >= h })
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.backends[r.points[i]]
+}
+
+// hashKey returns the 64-bit FNV-1a hash of the key, with the bits mixed, since FNV alone
+// doesn't spread similar short keys, e.g. sequential VU IDs, well enough.
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
@@ -0,0 +1,61 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netext
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashRing(t *testing.T) {
+	t.Parallel()
+
+	backends := []string{"192.0.2.1", "192.0.2.2", "192.0.2.3:8080"}
+	ring := NewHashRing(backends)
+	assert.Equal(t, "", NewHashRing(nil).Get("1"))
+
+	picked := make(map[string]string, 100)
+	counts := make(map[string]int, len(backends))
+	for i := 1; i <= 100; i++ {
+		key := strconv.Itoa(i)
+		picked[key] = ring.Get(key)
+		counts[picked[key]]++
+		assert.Equal(t, picked[key], ring.Get(key))
+		assert.Equal(t, picked[key], NewHashRing(backends).Get(key))
+	}
+	for _, backend := range backends {
+		assert.NotZero(t, counts[backend], backend)
+	}
+
+	// Only the keys that move to the added backend change
+	biggerRing := NewHashRing(append(backends, "192.0.2.4"))
+	moved := 0
+	for key, backend := range picked {
+		if newBackend := biggerRing.Get(key); newBackend != backend {
+			assert.Equal(t, "192.0.2.4", newBackend)
+			moved++
+		}
+	}
+	assert.NotZero(t, moved)
+	assert.True(t, moved < 50, moved)
+}
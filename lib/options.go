@@ -22,10 +22,12 @@ package lib
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"net"
 	"reflect"
+	"regexp"
 
 	"github.com/loadimpact/k6/lib/scheduler"
 	"github.com/loadimpact/k6/lib/types"
@@ -39,6 +41,18 @@ import (
 // iterations+vus, or stages)
 const DefaultSchedulerName = "default"
 
+// The ways in which the numbers in JSON response bodies can be parsed
+const (
+	// JSONNumbersFloat parses all numbers as float64, the default, which loses the precision of
+	// integers above 2^53
+	JSONNumbersFloat = "float"
+	// JSONNumbersString returns all numbers as strings, exactly as they're written in the JSON
+	JSONNumbersString = "string"
+	// JSONNumbersSafe returns the integers that can't be exactly represented as a float64 as
+	// strings, and all of the other numbers as float64
+	JSONNumbersSafe = "safe"
+)
+
 // DefaultSummaryTrendStats are the default trend columns shown in the test summary output
 // nolint: gochecknoglobals
 var DefaultSummaryTrendStats = []string{"avg", "min", "med", "max", "p(90)", "p(95)"}
@@ -156,6 +170,44 @@ func (c *TLSAuth) Certificate() (*tls.Certificate, error) {
 	return c.certificate, nil
 }
 
+// TLSCACerts is a bundle of PEM-encoded CA certificates, which are trusted in addition to the
+// system ones. It's unmarshalled from the PEM content itself, not from a file path, so it can be
+// passed directly through an environment variable, e.g. from a CI secret.
+type TLSCACerts struct {
+	PEM  string
+	pool *x509.CertPool
+}
+
+// UnmarshalText parses the PEM-encoded certificates. An empty bundle is valid and means that
+// only the system certificates are trusted.
+func (c *TLSCACerts) UnmarshalText(b []byte) error {
+	if len(b) == 0 {
+		*c = TLSCACerts{}
+		return nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(b) {
+		return errors.New("Failed to parse the CA certificates, expected PEM-encoded certificates")
+	}
+	c.PEM = string(b)
+	c.pool = pool
+	return nil
+}
+
+// MarshalText returns the PEM-encoded certificates
+func (c TLSCACerts) MarshalText() ([]byte, error) {
+	return []byte(c.PEM), nil
+}
+
+// CertPool returns a pool with the system CA certificates and the ones from the bundle, or nil
+// if the bundle is empty
+func (c *TLSCACerts) CertPool() *x509.CertPool {
+	return c.pool
+}
+
 // IPNet is a wrapper around net.IPNet for JSON unmarshalling
 type IPNet net.IPNet
 
@@ -187,6 +239,30 @@ func ParseCIDR(s string) (*IPNet, error) {
 	return &parsedIPNet, nil
 }
 
+// URLBucketPattern is a regular expression that matches URL path segments which should be
+// replaced by a placeholder in the url and name tags, to limit their cardinality.
+type URLBucketPattern struct {
+	*regexp.Regexp
+}
+
+// UnmarshalText compiles the pattern
+func (p *URLBucketPattern) UnmarshalText(b []byte) error {
+	re, err := regexp.Compile(string(b))
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse URL bucket pattern")
+	}
+	p.Regexp = re
+	return nil
+}
+
+// MarshalText returns the source text of the pattern
+func (p URLBucketPattern) MarshalText() ([]byte, error) {
+	if p.Regexp == nil {
+		return []byte{}, nil
+	}
+	return []byte(p.Regexp.String()), nil
+}
+
 type Options struct {
 	// Should the test start in a paused state?
 	Paused null.Bool `json:"paused" envconfig:"K6_PAUSED"`
@@ -207,6 +283,10 @@ type Options struct {
 	SetupTimeout    types.NullDuration `json:"setupTimeout" envconfig:"K6_SETUP_TIMEOUT"`
 	TeardownTimeout types.NullDuration `json:"teardownTimeout" envconfig:"K6_TEARDOWN_TIMEOUT"`
 
+	// A hard cap on the wall-clock time of the whole run, regardless of the duration, stages or
+	// iterations, after which the run is stopped like an interrupted one. Disabled when unset or 0.
+	MaxDuration types.NullDuration `json:"maxDuration" envconfig:"K6_MAX_DURATION"`
+
 	// Limit HTTP requests per second.
 	RPS null.Int `json:"rps" envconfig:"K6_RPS"`
 
@@ -223,17 +303,44 @@ type Options struct {
 	// Should all HTTP requests and responses be logged (excluding body)?
 	HTTPDebug null.String `json:"httpDebug" envconfig:"K6_HTTP_DEBUG"`
 
+	// Add a W3C Trace Context `traceparent` header with a new trace ID to every HTTP request. The
+	// request metrics are only tagged with the trace ID if the trace_id system tag is enabled.
+	TraceContext null.Bool `json:"traceContext" envconfig:"K6_TRACE_CONTEXT"`
+
+	// Emit the durations from the Server-Timing response headers as http_req_server_timing
+	// samples, tagged with the names of the server's metrics.
+	ServerTiming null.Bool `json:"serverTiming" envconfig:"K6_SERVER_TIMING"`
+
+	// The HTTP response statuses that shouldn't be counted as failed in http_req_failed. If
+	// empty, any 1xx, 2xx or 3xx response is considered a success.
+	ExpectedStatuses []int `json:"expectedStatuses" envconfig:"K6_EXPECTED_STATUSES"`
+
+	// Replace URL path segments that look like IDs with a placeholder in the url and name tags, to
+	// limit their cardinality. If no patterns are specified, numbers and UUIDs are bucketed.
+	BucketURLs        null.Bool           `json:"bucketURLs" envconfig:"K6_BUCKET_URLS"`
+	URLBucketPatterns []*URLBucketPattern `json:"urlBucketPatterns" envconfig:"K6_URL_BUCKET_PATTERNS"`
+
 	// Accept invalid or untrusted TLS certificates.
 	InsecureSkipTLSVerify null.Bool `json:"insecureSkipTLSVerify" envconfig:"K6_INSECURE_SKIP_TLS_VERIFY"`
 
-	// Specify TLS versions and cipher suites, and present client certificates.
+	// Specify TLS versions and cipher suites, present client certificates and trust extra CAs.
 	TLSCipherSuites *TLSCipherSuites `json:"tlsCipherSuites" envconfig:"K6_TLS_CIPHER_SUITES"`
 	TLSVersion      *TLSVersions     `json:"tlsVersion" envconfig:"K6_TLS_VERSION"`
 	TLSAuth         []*TLSAuth       `json:"tlsAuth" envconfig:"K6_TLSAUTH"`
+	TLSCACerts      *TLSCACerts      `json:"tlsCACerts" envconfig:"K6_TLS_CA_CERTS"`
+
+	// The protocols offered with ALPN in the TLS handshakes, in order of preference. HTTP/2 is
+	// only used if "h2" is in the list, e.g. ["http/1.1"] forces HTTP/1.1 even with h2 servers.
+	TLSALPN []string `json:"tlsALPN" envconfig:"K6_TLS_ALPN"`
 
 	// Throw warnings (eg. failed HTTP requests) as errors instead of simply logging them.
 	Throw null.Bool `json:"throw" envconfig:"K6_THROW"`
 
+	// The seed of the random choices in the run, i.e. of Math.random() and of the seeded generators
+	// that aren't given a seed of their own. A random seed is picked if it's not set, and printed
+	// when the run fails, so the run can be replayed with the same random choices.
+	Seed null.Int `json:"seed" envconfig:"K6_SEED"`
+
 	// Define thresholds; these take the form of 'metric=["snippet1", "snippet2"]'.
 	// To create a threshold on a derived metric based on tag queries ("submetrics"), create a
 	// metric on a nonexistent metric named 'real_metric{tagA:valueA,tagB:valueB}'.
@@ -245,9 +352,43 @@ type Options struct {
 	// Hosts overrides dns entries for given hosts
 	Hosts map[string]net.IP `json:"hosts" envconfig:"K6_HOSTS"`
 
+	// Backends that the connections to the given hosts are spread between with consistent
+	// hashing of the VU IDs, so every VU always connects to the same backend of a host. The
+	// backends are IP addresses with optional ports.
+	StickyBackends map[string][]string `json:"stickyBackends" ignored:"true"`
+
 	// Disable keep-alive connections
 	NoConnectionReuse null.Bool `json:"noConnectionReuse" envconfig:"K6_NO_CONNECTION_REUSE"`
 
+	// Proxies that HTTP requests are distributed between in a round-robin fashion, instead of
+	// the ones from the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables.
+	Proxies []string `json:"proxies" envconfig:"K6_PROXIES"`
+
+	// Limit the number of connections per host, requests wait for a free one when it's reached;
+	// 0 means unlimited. The waiting time is measured by the http_req_queued metric, and the
+	// rate of requests that had to wait is tracked by the conn_pool_exhausted metric.
+	MaxConnsPerHost null.Int `json:"maxConnsPerHost" envconfig:"K6_MAX_CONNS_PER_HOST"`
+
+	// Which time the timestamps of the samples passed to the outputs reflect: "measurement", the
+	// default, for when the measurement occurred, or "write" for when the sample was written.
+	SampleTimestamps null.String `json:"sampleTimestamps" envconfig:"K6_SAMPLE_TIMESTAMPS"`
+
+	// Keep the tags of the samples with the min and max values of every trend metric, so e.g. the
+	// URL of the slowest request is known. They are included in the exported summary.
+	TrendExtremeTags null.Bool `json:"trendExtremeTags" envconfig:"K6_TREND_EXTREME_TAGS"`
+
+	// Limit the total size of the response headers, the default is 10MB. Larger responses fail
+	// with an error instead of the headers being buffered.
+	MaxResponseHeaderBytes null.Int `json:"maxResponseHeaderBytes" envconfig:"K6_MAX_RESPONSE_HEADER_BYTES"`
+
+	// Limit the number of response headers, counting every value of a repeated header; 0 means
+	// unlimited. Responses with more headers fail with an error.
+	MaxResponseHeaders null.Int `json:"maxResponseHeaders" envconfig:"K6_MAX_RESPONSE_HEADERS"`
+
+	// Close connections after they have been used for this many HTTP requests; 0 means unlimited.
+	// HTTP/2 connections aren't closed, since they can have other requests in flight.
+	MaxRequestsPerConnection null.Int `json:"maxRequestsPerConnection" envconfig:"K6_MAX_REQUESTS_PER_CONNECTION"`
+
 	// Do not reuse connections between VU iterations. This gives more realistic results (depending
 	// on what you're looking for), but you need to raise various kernel limits or you'll get
 	// errors about running out of file handles or sockets, or being unable to bind addresses.
@@ -257,6 +398,37 @@ type Options struct {
 	// iteration is shorter than the specified value.
 	MinIterationDuration types.NullDuration `json:"minIterationDuration" envconfig:"K6_MIN_ITERATION_DURATION"`
 
+	// Delay the first iteration of every VU by a random duration below this value, so VUs that
+	// are started together don't all hit the system under test at the exact same moment.
+	VUStartJitter types.NullDuration `json:"vuStartJitter" envconfig:"K6_VU_START_JITTER"`
+
+	// The maximum number of iterations that each VU starts per minute, e.g. to model per-user
+	// rate limits. The VUs that are held back leave the iterations to the others; 0 is unlimited.
+	VUIterationRate null.Int `json:"vuIterationRate" envconfig:"K6_VU_ITERATION_RATE"`
+
+	// Emit coarse Go runtime metrics (goroutines and memory allocations) for every iteration.
+	// Reading the memory stats briefly stops the world, so this is disabled by default.
+	RuntimeStats null.Bool `json:"runtimeStats" envconfig:"K6_RUNTIME_STATS"`
+
+	// Count iterations with at least one failed check in the iterations_failed metric, so
+	// that thresholds can be set on it, instead of only on the overall checks rate.
+	CheckFailsIteration null.Bool `json:"checkFailsIteration" envconfig:"K6_CHECK_FAILS_ITERATION"`
+
+	// Emit connection_open, connection_reuse and connection_close events, with their timings, for
+	// the connections made by VUs. It's meant for debugging, so it's disabled by default.
+	ConnectionEvents null.Bool `json:"connectionEvents" envconfig:"K6_CONNECTION_EVENTS"`
+
+	// Periodically emit a k6_heartbeat gauge, regardless of whether the test generates any
+	// traffic, so external monitors can detect stalled runs. Disabled when unset or 0.
+	HeartbeatInterval types.NullDuration `json:"heartbeatInterval" envconfig:"K6_HEARTBEAT_INTERVAL"`
+
+	// Limit the number of distinct custom metrics, to guard against scripts that accidentally
+	// create metrics with dynamic names. Samples of the metrics over the limit are dropped.
+	MaxCustomMetrics null.Int `json:"maxCustomMetrics" envconfig:"K6_MAX_CUSTOM_METRICS"`
+
+	// Deliberately inject connection errors and delays. Can't be set through env vars.
+	Faults *FaultInjection `json:"faults" ignored:"true"`
+
 	// These values are for third party collectors' benefit.
 	// Can't be set through env vars.
 	External map[string]json.RawMessage `json:"ext" ignored:"true"`
@@ -274,6 +446,11 @@ type Options struct {
 	// Tags to be applied to all samples for this running
 	RunTags *stats.SampleTags `json:"tags" envconfig:"K6_TAGS"`
 
+	// Tags to be applied to all samples for this run, whose values are taken from the specified
+	// environment variables, e.g. {"commit": "GIT_COMMIT"}. They're resolved into RunTags when
+	// the configuration is consolidated, from the same environment the script sees in __ENV.
+	TagsFromEnv map[string]string `json:"tagsFromEnv" envconfig:"K6_TAGS_FROM_ENV"`
+
 	// Buffer size of the channel for metric samples; 0 means unbuffered
 	MetricSamplesBufferSize null.Int `json:"metricSamplesBufferSize" envconfig:"K6_METRIC_SAMPLES_BUFFER_SIZE"`
 
@@ -283,6 +460,15 @@ type Options struct {
 	// Discard Http Responses Body
 	DiscardResponseBodies null.Bool `json:"discardResponseBodies" envconfig:"K6_DISCARD_RESPONSE_BODIES"`
 
+	// Emit the number of bytes of the request bodies that were uploaded as http_upload_bytes
+	// samples with this interval while the bodies are being sent, to show the upload throughput
+	// of large requests during the test; it's disabled if it isn't set.
+	UploadProgressInterval types.NullDuration `json:"uploadProgressInterval" envconfig:"K6_UPLOAD_PROGRESS_INTERVAL"`
+
+	// How the numbers in JSON response bodies are parsed by response.json(), see JSONNumbersFloat,
+	// JSONNumbersString and JSONNumbersSafe
+	JSONNumbers null.String `json:"jsonNumbers" envconfig:"K6_JSON_NUMBERS"`
+
 	// Redirect console logging to a file
 	ConsoleOutput null.String `json:"-" envconfig:"K6_CONSOLE_OUTPUT"`
 }
@@ -347,6 +533,9 @@ func (o Options) Apply(opts Options) Options {
 	if opts.TeardownTimeout.Valid {
 		o.TeardownTimeout = opts.TeardownTimeout
 	}
+	if opts.MaxDuration.Valid {
+		o.MaxDuration = opts.MaxDuration
+	}
 	if opts.RPS.Valid {
 		o.RPS = opts.RPS
 	}
@@ -365,6 +554,21 @@ func (o Options) Apply(opts Options) Options {
 	if opts.HTTPDebug.Valid {
 		o.HTTPDebug = opts.HTTPDebug
 	}
+	if opts.TraceContext.Valid {
+		o.TraceContext = opts.TraceContext
+	}
+	if opts.ServerTiming.Valid {
+		o.ServerTiming = opts.ServerTiming
+	}
+	if opts.ExpectedStatuses != nil {
+		o.ExpectedStatuses = opts.ExpectedStatuses
+	}
+	if opts.BucketURLs.Valid {
+		o.BucketURLs = opts.BucketURLs
+	}
+	if opts.URLBucketPatterns != nil {
+		o.URLBucketPatterns = opts.URLBucketPatterns
+	}
 	if opts.InsecureSkipTLSVerify.Valid {
 		o.InsecureSkipTLSVerify = opts.InsecureSkipTLSVerify
 	}
@@ -380,9 +584,20 @@ func (o Options) Apply(opts Options) Options {
 	if opts.TLSAuth != nil {
 		o.TLSAuth = opts.TLSAuth
 	}
+	// envconfig allocates pointers to structs even if the env var isn't set, so empty
+	// bundles shouldn't override the ones from other sources
+	if opts.TLSCACerts != nil && opts.TLSCACerts.PEM != "" {
+		o.TLSCACerts = opts.TLSCACerts
+	}
+	if opts.TLSALPN != nil {
+		o.TLSALPN = opts.TLSALPN
+	}
 	if opts.Throw.Valid {
 		o.Throw = opts.Throw
 	}
+	if opts.Seed.Valid {
+		o.Seed = opts.Seed
+	}
 	if opts.Thresholds != nil {
 		o.Thresholds = opts.Thresholds
 	}
@@ -392,15 +607,63 @@ func (o Options) Apply(opts Options) Options {
 	if opts.Hosts != nil {
 		o.Hosts = opts.Hosts
 	}
+	if opts.StickyBackends != nil {
+		o.StickyBackends = opts.StickyBackends
+	}
 	if opts.NoConnectionReuse.Valid {
 		o.NoConnectionReuse = opts.NoConnectionReuse
 	}
+	if opts.Proxies != nil {
+		o.Proxies = opts.Proxies
+	}
+	if opts.MaxConnsPerHost.Valid {
+		o.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.SampleTimestamps.Valid {
+		o.SampleTimestamps = opts.SampleTimestamps
+	}
+	if opts.TrendExtremeTags.Valid {
+		o.TrendExtremeTags = opts.TrendExtremeTags
+	}
+	if opts.MaxResponseHeaderBytes.Valid {
+		o.MaxResponseHeaderBytes = opts.MaxResponseHeaderBytes
+	}
+	if opts.MaxResponseHeaders.Valid {
+		o.MaxResponseHeaders = opts.MaxResponseHeaders
+	}
+	if opts.MaxRequestsPerConnection.Valid {
+		o.MaxRequestsPerConnection = opts.MaxRequestsPerConnection
+	}
 	if opts.NoVUConnectionReuse.Valid {
 		o.NoVUConnectionReuse = opts.NoVUConnectionReuse
 	}
 	if opts.MinIterationDuration.Valid {
 		o.MinIterationDuration = opts.MinIterationDuration
 	}
+	if opts.VUStartJitter.Valid {
+		o.VUStartJitter = opts.VUStartJitter
+	}
+	if opts.VUIterationRate.Valid {
+		o.VUIterationRate = opts.VUIterationRate
+	}
+	if opts.RuntimeStats.Valid {
+		o.RuntimeStats = opts.RuntimeStats
+	}
+	if opts.CheckFailsIteration.Valid {
+		o.CheckFailsIteration = opts.CheckFailsIteration
+	}
+	if opts.ConnectionEvents.Valid {
+		o.ConnectionEvents = opts.ConnectionEvents
+	}
+	if opts.HeartbeatInterval.Valid {
+		o.HeartbeatInterval = opts.HeartbeatInterval
+	}
+	if opts.MaxCustomMetrics.Valid {
+		o.MaxCustomMetrics = opts.MaxCustomMetrics
+	}
+	if opts.Faults != nil {
+		o.Faults = opts.Faults
+	}
 	if opts.NoCookiesReset.Valid {
 		o.NoCookiesReset = opts.NoCookiesReset
 	}
@@ -419,12 +682,21 @@ func (o Options) Apply(opts Options) Options {
 	if !opts.RunTags.IsEmpty() {
 		o.RunTags = opts.RunTags
 	}
+	if opts.TagsFromEnv != nil {
+		o.TagsFromEnv = opts.TagsFromEnv
+	}
 	if opts.MetricSamplesBufferSize.Valid {
 		o.MetricSamplesBufferSize = opts.MetricSamplesBufferSize
 	}
 	if opts.DiscardResponseBodies.Valid {
 		o.DiscardResponseBodies = opts.DiscardResponseBodies
 	}
+	if opts.UploadProgressInterval.Valid {
+		o.UploadProgressInterval = opts.UploadProgressInterval
+	}
+	if opts.JSONNumbers.Valid {
+		o.JSONNumbers = opts.JSONNumbers
+	}
 	if opts.ConsoleOutput.Valid {
 		o.ConsoleOutput = opts.ConsoleOutput
 	}
@@ -432,11 +704,96 @@ func (o Options) Apply(opts Options) Options {
 	return o
 }
 
+// GetRunTagsWithEnv returns the run tags, extended with the tags from TagsFromEnv, whose values
+// are looked up in the supplied environment. Tags whose environment variables aren't set are
+// skipped, and explicitly specified run tags take precedence.
+func (o Options) GetRunTagsWithEnv(env map[string]string) *stats.SampleTags {
+	if len(o.TagsFromEnv) == 0 {
+		return o.RunTags
+	}
+	tags := o.RunTags.CloneTags()
+	for name, envVar := range o.TagsFromEnv {
+		if _, ok := tags[name]; ok {
+			continue
+		}
+		if value, ok := env[envVar]; ok {
+			tags[name] = value
+		}
+	}
+	return stats.IntoSampleTags(&tags)
+}
+
 // Validate checks if all of the specified options make sense
 func (o Options) Validate() []error {
 	//TODO: validate all of the other options... that we should have already been validating...
 	//TODO: maybe integrate an external validation lib: https://github.com/avelino/awesome-go#validation
-	return o.Execution.Validate()
+	errs := o.Execution.Validate()
+	if o.Faults != nil {
+		errs = append(errs, o.Faults.Validate()...)
+	}
+	if o.MaxDuration.Valid && o.MaxDuration.Duration < 0 {
+		errs = append(errs, errors.New("maxDuration can't be negative"))
+	}
+	if o.HeartbeatInterval.Valid && o.HeartbeatInterval.Duration < 0 {
+		errs = append(errs, errors.New("heartbeatInterval can't be negative"))
+	}
+	if o.MaxRequestsPerConnection.Valid && o.MaxRequestsPerConnection.Int64 < 0 {
+		errs = append(errs, errors.New("maxRequestsPerConnection can't be negative"))
+	}
+	if o.VUStartJitter.Valid && o.VUStartJitter.Duration < 0 {
+		errs = append(errs, errors.New("vuStartJitter can't be negative"))
+	}
+	if o.VUIterationRate.Valid && o.VUIterationRate.Int64 < 0 {
+		errs = append(errs, errors.New("vuIterationRate can't be negative"))
+	}
+	if o.SampleTimestamps.Valid {
+		switch o.SampleTimestamps.String {
+		case stats.TimestampMeasurement, stats.TimestampWrite:
+		default:
+			errs = append(errs, fmt.Errorf(
+				"invalid sampleTimestamps value '%s', it should be '%s' or '%s'",
+				o.SampleTimestamps.String, stats.TimestampMeasurement, stats.TimestampWrite,
+			))
+		}
+	}
+	if o.UploadProgressInterval.Valid && o.UploadProgressInterval.Duration < 0 {
+		errs = append(errs, errors.New("uploadProgressInterval can't be negative"))
+	}
+	if o.JSONNumbers.Valid {
+		switch o.JSONNumbers.String {
+		case JSONNumbersFloat, JSONNumbersString, JSONNumbersSafe:
+		default:
+			errs = append(errs, fmt.Errorf(
+				"invalid jsonNumbers value '%s', it should be '%s', '%s' or '%s'",
+				o.JSONNumbers.String, JSONNumbersFloat, JSONNumbersString, JSONNumbersSafe,
+			))
+		}
+	}
+	if o.MaxResponseHeaderBytes.Valid && o.MaxResponseHeaderBytes.Int64 < 0 {
+		errs = append(errs, errors.New("maxResponseHeaderBytes can't be negative"))
+	}
+	if o.MaxResponseHeaders.Valid && o.MaxResponseHeaders.Int64 < 0 {
+		errs = append(errs, errors.New("maxResponseHeaders can't be negative"))
+	}
+	if o.MaxCustomMetrics.Valid && o.MaxCustomMetrics.Int64 < 0 {
+		errs = append(errs, errors.New("maxCustomMetrics can't be negative"))
+	}
+	for _, proto := range o.TLSALPN {
+		if len(proto) == 0 || len(proto) > 255 {
+			errs = append(errs, fmt.Errorf("invalid tlsALPN protocol '%s', it should be 1 to 255 bytes long", proto))
+		}
+	}
+	for host, backends := range o.StickyBackends {
+		if len(backends) == 0 {
+			errs = append(errs, fmt.Errorf("stickyBackends has no backends for host '%s'", host))
+		}
+		for _, backend := range backends {
+			if _, _, err := ParseBackend(backend); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errs
 }
 
 // ForEachSpecified enumerates all struct fields and calls the supplied function with each
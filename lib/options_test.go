@@ -129,6 +129,78 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.HTTPDebug.Valid)
 		assert.Equal(t, "foo", opts.HTTPDebug.String)
 	})
+	t.Run("TraceContext", func(t *testing.T) {
+		opts := Options{}.Apply(Options{TraceContext: null.BoolFrom(true)})
+		assert.True(t, opts.TraceContext.Valid)
+		assert.True(t, opts.TraceContext.Bool)
+	})
+	t.Run("ServerTiming", func(t *testing.T) {
+		opts := Options{}.Apply(Options{ServerTiming: null.BoolFrom(true)})
+		assert.True(t, opts.ServerTiming.Valid)
+		assert.True(t, opts.ServerTiming.Bool)
+	})
+	t.Run("ExpectedStatuses", func(t *testing.T) {
+		opts := Options{}.Apply(Options{ExpectedStatuses: []int{200, 404}})
+		assert.Equal(t, []int{200, 404}, opts.ExpectedStatuses)
+	})
+	t.Run("BucketURLs", func(t *testing.T) {
+		opts := Options{}.Apply(Options{BucketURLs: null.BoolFrom(true)})
+		assert.True(t, opts.BucketURLs.Valid)
+		assert.True(t, opts.BucketURLs.Bool)
+	})
+	t.Run("URLBucketPatterns", func(t *testing.T) {
+		pattern := &URLBucketPattern{}
+		require.NoError(t, pattern.UnmarshalText([]byte(`^\d+$`)))
+		opts := Options{}.Apply(Options{URLBucketPatterns: []*URLBucketPattern{pattern}})
+		assert.Equal(t, []*URLBucketPattern{pattern}, opts.URLBucketPatterns)
+	})
+	t.Run("Proxies", func(t *testing.T) {
+		proxies := []string{"http://proxy1:3128", "socks5://proxy2:1080"}
+		opts := Options{}.Apply(Options{Proxies: proxies})
+		assert.Equal(t, proxies, opts.Proxies)
+	})
+	t.Run("MaxConnsPerHost", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxConnsPerHost: null.IntFrom(4)})
+		assert.True(t, opts.MaxConnsPerHost.Valid)
+		assert.Equal(t, int64(4), opts.MaxConnsPerHost.Int64)
+	})
+	t.Run("SampleTimestamps", func(t *testing.T) {
+		opts := Options{}.Apply(Options{SampleTimestamps: null.StringFrom("write")})
+		assert.True(t, opts.SampleTimestamps.Valid)
+		assert.Equal(t, "write", opts.SampleTimestamps.String)
+		assert.Empty(t, opts.Validate())
+
+		opts = Options{}.Apply(Options{SampleTimestamps: null.StringFrom("ingest")})
+		errs := opts.Validate()
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "invalid sampleTimestamps value 'ingest', it should be 'measurement' or 'write'")
+	})
+	t.Run("TrendExtremeTags", func(t *testing.T) {
+		opts := Options{}.Apply(Options{TrendExtremeTags: null.BoolFrom(true)})
+		assert.True(t, opts.TrendExtremeTags.Valid)
+		assert.True(t, opts.TrendExtremeTags.Bool)
+	})
+	t.Run("MaxResponseHeaderBytes", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxResponseHeaderBytes: null.IntFrom(4096)})
+		assert.True(t, opts.MaxResponseHeaderBytes.Valid)
+		assert.Equal(t, int64(4096), opts.MaxResponseHeaderBytes.Int64)
+	})
+	t.Run("MaxResponseHeaders", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxResponseHeaders: null.IntFrom(50)})
+		assert.True(t, opts.MaxResponseHeaders.Valid)
+		assert.Equal(t, int64(50), opts.MaxResponseHeaders.Int64)
+	})
+	t.Run("MaxRequestsPerConnection", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxRequestsPerConnection: null.IntFrom(10)})
+		assert.True(t, opts.MaxRequestsPerConnection.Valid)
+		assert.Equal(t, int64(10), opts.MaxRequestsPerConnection.Int64)
+		assert.Empty(t, opts.Validate())
+
+		opts = Options{}.Apply(Options{MaxRequestsPerConnection: null.IntFrom(-1)})
+		errs := opts.Validate()
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "maxRequestsPerConnection can't be negative")
+	})
 	t.Run("InsecureSkipTLSVerify", func(t *testing.T) {
 		opts := Options{}.Apply(Options{InsecureSkipTLSVerify: null.BoolFrom(true)})
 		assert.True(t, opts.InsecureSkipTLSVerify.Valid)
@@ -301,6 +373,60 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.NoVUConnectionReuse.Valid)
 		assert.True(t, opts.NoVUConnectionReuse.Bool)
 	})
+	t.Run("RuntimeStats", func(t *testing.T) {
+		opts := Options{}.Apply(Options{RuntimeStats: null.BoolFrom(true)})
+		assert.True(t, opts.RuntimeStats.Valid)
+		assert.True(t, opts.RuntimeStats.Bool)
+	})
+	t.Run("CheckFailsIteration", func(t *testing.T) {
+		opts := Options{}.Apply(Options{CheckFailsIteration: null.BoolFrom(true)})
+		assert.True(t, opts.CheckFailsIteration.Valid)
+		assert.True(t, opts.CheckFailsIteration.Bool)
+	})
+	t.Run("MaxDuration", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxDuration: types.NullDurationFrom(time.Hour)})
+		assert.True(t, opts.MaxDuration.Valid)
+		assert.Equal(t, types.Duration(time.Hour), opts.MaxDuration.Duration)
+		assert.Empty(t, opts.Validate())
+
+		// It isn't reset along with the other end conditions
+		opts = opts.Apply(Options{Duration: types.NullDurationFrom(time.Minute)})
+		assert.Equal(t, types.NullDurationFrom(time.Hour), opts.MaxDuration)
+
+		opts = Options{}.Apply(Options{MaxDuration: types.NullDurationFrom(-time.Second)})
+		assert.Len(t, opts.Validate(), 1)
+	})
+	t.Run("ConnectionEvents", func(t *testing.T) {
+		opts := Options{}.Apply(Options{ConnectionEvents: null.BoolFrom(true)})
+		assert.True(t, opts.ConnectionEvents.Valid)
+		assert.True(t, opts.ConnectionEvents.Bool)
+	})
+	t.Run("HeartbeatInterval", func(t *testing.T) {
+		opts := Options{}.Apply(Options{HeartbeatInterval: types.NullDurationFrom(5 * time.Second)})
+		assert.True(t, opts.HeartbeatInterval.Valid)
+		assert.Equal(t, types.Duration(5*time.Second), opts.HeartbeatInterval.Duration)
+	})
+	t.Run("VUStartJitter", func(t *testing.T) {
+		opts := Options{}.Apply(Options{VUStartJitter: types.NullDurationFrom(time.Second)})
+		assert.True(t, opts.VUStartJitter.Valid)
+		assert.Equal(t, types.Duration(time.Second), opts.VUStartJitter.Duration)
+
+		opts = Options{}.Apply(Options{VUStartJitter: types.NullDurationFrom(-time.Second)})
+		assert.Len(t, opts.Validate(), 1)
+	})
+	t.Run("VUIterationRate", func(t *testing.T) {
+		opts := Options{}.Apply(Options{VUIterationRate: null.IntFrom(30)})
+		assert.True(t, opts.VUIterationRate.Valid)
+		assert.Equal(t, int64(30), opts.VUIterationRate.Int64)
+
+		opts = Options{}.Apply(Options{VUIterationRate: null.IntFrom(-1)})
+		assert.Len(t, opts.Validate(), 1)
+	})
+	t.Run("MaxCustomMetrics", func(t *testing.T) {
+		opts := Options{}.Apply(Options{MaxCustomMetrics: null.IntFrom(100)})
+		assert.True(t, opts.MaxCustomMetrics.Valid)
+		assert.Equal(t, int64(100), opts.MaxCustomMetrics.Int64)
+	})
 	t.Run("NoCookiesReset", func(t *testing.T) {
 		opts := Options{}.Apply(Options{NoCookiesReset: null.BoolFrom(true)})
 		assert.True(t, opts.NoCookiesReset.Valid)
@@ -328,6 +454,35 @@ func TestOptions(t *testing.T) {
 		assert.Equal(t, "192.0.2.1", opts.Hosts["test.loadimpact.com"].String())
 	})
 
+	t.Run("TLSALPN", func(t *testing.T) {
+		opts := Options{}.Apply(Options{TLSALPN: []string{"h2", "http/1.1"}})
+		assert.Equal(t, []string{"h2", "http/1.1"}, opts.TLSALPN)
+		assert.Empty(t, opts.Validate())
+
+		opts = Options{}.Apply(Options{TLSALPN: []string{"http/1.1", ""}})
+		assert.Len(t, opts.Validate(), 1)
+	})
+
+	t.Run("StickyBackends", func(t *testing.T) {
+		backends := map[string][]string{"test.loadimpact.com": {"192.0.2.1", "192.0.2.2:8080"}}
+		opts := Options{}.Apply(Options{StickyBackends: backends})
+		assert.Equal(t, backends, opts.StickyBackends)
+		assert.Empty(t, opts.Validate())
+
+		opts = Options{}.Apply(Options{StickyBackends: map[string][]string{
+			"test.loadimpact.com": {"test.k6.io:80"},
+			"test.k6.io":          {},
+		}})
+		assert.Len(t, opts.Validate(), 2)
+	})
+
+	t.Run("Seed", func(t *testing.T) {
+		opts := Options{}.Apply(Options{Seed: null.IntFrom(1234)})
+		assert.Equal(t, null.IntFrom(1234), opts.Seed)
+		opts = opts.Apply(Options{})
+		assert.Equal(t, null.IntFrom(1234), opts.Seed)
+	})
+
 	t.Run("Throws", func(t *testing.T) {
 		opts := Options{}.Apply(Options{Throw: null.BoolFrom(true)})
 		assert.True(t, opts.Throw.Valid)
@@ -391,6 +546,11 @@ func TestOptions(t *testing.T) {
 		opts := Options{}.Apply(Options{SummaryTrendStats: stats})
 		assert.Equal(t, stats, opts.SummaryTrendStats)
 	})
+	t.Run("TagsFromEnv", func(t *testing.T) {
+		tagsFromEnv := map[string]string{"commit": "GIT_COMMIT"}
+		opts := Options{}.Apply(Options{TagsFromEnv: tagsFromEnv})
+		assert.Equal(t, tagsFromEnv, opts.TagsFromEnv)
+	})
 	t.Run("RunTags", func(t *testing.T) {
 		tags := stats.IntoSampleTags(&map[string]string{"myTag": "hello"})
 		opts := Options{}.Apply(Options{RunTags: tags})
@@ -401,6 +561,25 @@ func TestOptions(t *testing.T) {
 		assert.True(t, opts.DiscardResponseBodies.Valid)
 		assert.True(t, opts.DiscardResponseBodies.Bool)
 	})
+	t.Run("UploadProgressInterval", func(t *testing.T) {
+		opts := Options{}.Apply(Options{UploadProgressInterval: types.NullDurationFrom(time.Second)})
+		assert.True(t, opts.UploadProgressInterval.Valid)
+		assert.Equal(t, types.Duration(time.Second), opts.UploadProgressInterval.Duration)
+
+		opts = Options{}.Apply(Options{UploadProgressInterval: types.NullDurationFrom(-time.Second)})
+		assert.Len(t, opts.Validate(), 1)
+	})
+	t.Run("JSONNumbers", func(t *testing.T) {
+		opts := Options{}.Apply(Options{JSONNumbers: null.StringFrom("safe")})
+		assert.True(t, opts.JSONNumbers.Valid)
+		assert.Equal(t, "safe", opts.JSONNumbers.String)
+		assert.Empty(t, opts.Validate())
+
+		opts = Options{}.Apply(Options{JSONNumbers: null.StringFrom("bigint")})
+		errs := opts.Validate()
+		require.Len(t, errs, 1)
+		assert.EqualError(t, errs[0], "invalid jsonNumbers value 'bigint', it should be 'float', 'string' or 'safe'")
+	})
 
 }
 
@@ -471,6 +650,104 @@ func TestOptionsEnv(t *testing.T) {
 			"true":  null.BoolFrom(true),
 			"false": null.BoolFrom(false),
 		},
+		{"TraceContext", "K6_TRACE_CONTEXT"}: {
+			"":      null.Bool{},
+			"true":  null.BoolFrom(true),
+			"false": null.BoolFrom(false),
+		},
+		{"ServerTiming", "K6_SERVER_TIMING"}: {
+			"":      null.Bool{},
+			"true":  null.BoolFrom(true),
+			"false": null.BoolFrom(false),
+		},
+		{"ExpectedStatuses", "K6_EXPECTED_STATUSES"}: {
+			"":        []int{},
+			"200,404": []int{200, 404},
+		},
+		{"Seed", "K6_SEED"}: {
+			"":     null.Int{},
+			"1234": null.IntFrom(1234),
+		},
+		{"TLSALPN", "K6_TLS_ALPN"}: {
+			"":            []string{},
+			"h2,http/1.1": []string{"h2", "http/1.1"},
+		},
+		{"Proxies", "K6_PROXIES"}: {
+			"":                                    []string{},
+			"http://proxy1:3128,http://proxy2:80": []string{"http://proxy1:3128", "http://proxy2:80"},
+		},
+		{"MaxConnsPerHost", "K6_MAX_CONNS_PER_HOST"}: {
+			"":  null.Int{},
+			"4": null.IntFrom(4),
+		},
+		{"UploadProgressInterval", "K6_UPLOAD_PROGRESS_INTERVAL"}: {
+			"":      types.NullDuration{},
+			"500ms": types.NullDurationFrom(500 * time.Millisecond),
+		},
+		{"JSONNumbers", "K6_JSON_NUMBERS"}: {
+			"":       null.String{},
+			"string": null.StringFrom("string"),
+		},
+		{"SampleTimestamps", "K6_SAMPLE_TIMESTAMPS"}: {
+			"":      null.String{},
+			"write": null.StringFrom("write"),
+		},
+		{"TrendExtremeTags", "K6_TREND_EXTREME_TAGS"}: {
+			"":      null.Bool{},
+			"true":  null.BoolFrom(true),
+			"false": null.BoolFrom(false),
+		},
+		{"MaxResponseHeaderBytes", "K6_MAX_RESPONSE_HEADER_BYTES"}: {
+			"":     null.Int{},
+			"4096": null.IntFrom(4096),
+		},
+		{"MaxResponseHeaders", "K6_MAX_RESPONSE_HEADERS"}: {
+			"":   null.Int{},
+			"50": null.IntFrom(50),
+		},
+		{"MaxRequestsPerConnection", "K6_MAX_REQUESTS_PER_CONNECTION"}: {
+			"":   null.Int{},
+			"10": null.IntFrom(10),
+		},
+		{"RuntimeStats", "K6_RUNTIME_STATS"}: {
+			"":      null.Bool{},
+			"true":  null.BoolFrom(true),
+			"false": null.BoolFrom(false),
+		},
+		{"CheckFailsIteration", "K6_CHECK_FAILS_ITERATION"}: {
+			"":      null.Bool{},
+			"true":  null.BoolFrom(true),
+			"false": null.BoolFrom(false),
+		},
+		{"TagsFromEnv", "K6_TAGS_FROM_ENV"}: {
+			"":                                 map[string]string{},
+			"commit:GIT_COMMIT,build:BUILD_ID": map[string]string{"commit": "GIT_COMMIT", "build": "BUILD_ID"},
+		},
+		{"MaxDuration", "K6_MAX_DURATION"}: {
+			"":   types.NullDuration{},
+			"1h": types.NullDurationFrom(time.Hour),
+		},
+		{"ConnectionEvents", "K6_CONNECTION_EVENTS"}: {
+			"":      null.Bool{},
+			"true":  null.BoolFrom(true),
+			"false": null.BoolFrom(false),
+		},
+		{"HeartbeatInterval", "K6_HEARTBEAT_INTERVAL"}: {
+			"":    types.NullDuration{},
+			"10s": types.NullDurationFrom(10 * time.Second),
+		},
+		{"VUStartJitter", "K6_VU_START_JITTER"}: {
+			"":   types.NullDuration{},
+			"5s": types.NullDurationFrom(5 * time.Second),
+		},
+		{"VUIterationRate", "K6_VU_ITERATION_RATE"}: {
+			"":   null.Int{},
+			"60": null.IntFrom(60),
+		},
+		{"MaxCustomMetrics", "K6_MAX_CUSTOM_METRICS"}: {
+			"":    null.Int{},
+			"100": null.IntFrom(100),
+		},
 		{"NoCookiesReset", "K6_NO_COOKIES_RESET"}: {
 			"":      null.Bool{},
 			"true":  null.BoolFrom(true),
@@ -537,3 +814,125 @@ func TestCIDRUnmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestURLBucketPatternJSON(t *testing.T) {
+	var opts Options
+	require.NoError(t, json.Unmarshal([]byte(`{"urlBucketPatterns": ["^\\d+$", "^[a-f]+$"]}`), &opts))
+	require.Len(t, opts.URLBucketPatterns, 2)
+	assert.True(t, opts.URLBucketPatterns[0].MatchString("123"))
+	assert.False(t, opts.URLBucketPatterns[0].MatchString("abc"))
+	assert.True(t, opts.URLBucketPatterns[1].MatchString("abc"))
+
+	data, err := json.Marshal(opts.URLBucketPatterns)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["^\\d+$", "^[a-f]+$"]`, string(data))
+
+	err = json.Unmarshal([]byte(`{"urlBucketPatterns": ["(unclosed"]}`), &opts)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed to parse URL bucket pattern")
+}
+
+func TestTLSCACerts(t *testing.T) {
+	caPEM := "-----BEGIN CERTIFICATE-----\n" +
+		"MIIBYzCCAQqgAwIBAgIUMYw1pqZ1XhXdFG0S2ITXhfHBsWgwCgYIKoZIzj0EAwIw\n" +
+		"EDEOMAwGA1UEAxMFTXkgQ0EwHhcNMTcwODE1MTYxODAwWhcNMjIwODE0MTYxODAw\n" +
+		"WjAQMQ4wDAYDVQQDEwVNeSBDQTBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABFWO\n" +
+		"fg4dgL8cdvjoSWDQFLBJxlbQFlZfOSyUR277a4g91BD07KWX+9ny+Q8WuUODog06\n" +
+		"xH1g8fc6zuaejllfzM6jQjBAMA4GA1UdDwEB/wQEAwIBBjAPBgNVHRMBAf8EBTAD\n" +
+		"AQH/MB0GA1UdDgQWBBTeoSFylGCmyqj1X4sWez1r6hkhjDAKBggqhkjOPQQDAgNH\n" +
+		"ADBEAiAfuKi6u/BVXenCkgnU2sfXsYjel6rACuXEcx01yaaWuQIgXAtjrDisdlf4\n" +
+		"0ZdoIoYjNhDAXUtnyRBt+V6+rIklv/8=\n" +
+		"-----END CERTIFICATE-----\n"
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(map[string]string{"tlsCACerts": caPEM})
+		require.NoError(t, err)
+		var opts Options
+		require.NoError(t, json.Unmarshal(data, &opts))
+		require.NotNil(t, opts.TLSCACerts)
+		assert.Equal(t, caPEM, opts.TLSCACerts.PEM)
+		assert.NotNil(t, opts.TLSCACerts.CertPool())
+
+		applied := Options{}.Apply(opts)
+		assert.Equal(t, opts.TLSCACerts, applied.TLSCACerts)
+
+		data, err = json.Marshal(opts)
+		require.NoError(t, err)
+		var roundTrip Options
+		require.NoError(t, json.Unmarshal(data, &roundTrip))
+		assert.Equal(t, caPEM, roundTrip.TLSCACerts.PEM)
+	})
+
+	t.Run("env", func(t *testing.T) {
+		os.Clearenv()
+		defer os.Clearenv()
+		require.NoError(t, os.Setenv("K6_TLS_CA_CERTS", caPEM))
+		var opts Options
+		require.NoError(t, envconfig.Process("k6", &opts))
+		require.NotNil(t, opts.TLSCACerts)
+		assert.Equal(t, caPEM, opts.TLSCACerts.PEM)
+	})
+
+	t.Run("unset env", func(t *testing.T) {
+		os.Clearenv()
+		defer os.Clearenv()
+		var envOpts Options
+		require.NoError(t, envconfig.Process("k6", &envOpts))
+
+		caCerts := &TLSCACerts{}
+		require.NoError(t, caCerts.UnmarshalText([]byte(caPEM)))
+		opts := Options{TLSCACerts: caCerts}.Apply(envOpts)
+		assert.Equal(t, caCerts, opts.TLSCACerts)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var opts Options
+		require.NoError(t, json.Unmarshal([]byte(`{"tlsCACerts": ""}`), &opts))
+		require.NotNil(t, opts.TLSCACerts)
+		assert.Equal(t, "", opts.TLSCACerts.PEM)
+		assert.Nil(t, opts.TLSCACerts.CertPool())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var caCerts TLSCACerts
+		err := caCerts.UnmarshalText([]byte("/path/to/ca.pem"))
+		assert.EqualError(t, err, "Failed to parse the CA certificates, expected PEM-encoded certificates")
+	})
+}
+
+func TestGetRunTagsWithEnv(t *testing.T) {
+	env := map[string]string{"GIT_COMMIT": "abc123", "BUILD_ID": "42", "EMPTY": ""}
+	testdata := map[string]struct {
+		runTags     map[string]string
+		tagsFromEnv map[string]string
+		expected    map[string]string
+	}{
+		"none": {nil, nil, map[string]string{}},
+		"only run tags": {
+			map[string]string{"foo": "bar"}, nil,
+			map[string]string{"foo": "bar"},
+		},
+		"from env": {
+			nil, map[string]string{"commit": "GIT_COMMIT", "build": "BUILD_ID", "empty": "EMPTY"},
+			map[string]string{"commit": "abc123", "build": "42", "empty": ""},
+		},
+		"unset env vars are skipped": {
+			nil, map[string]string{"commit": "GIT_COMMIT", "branch": "GIT_BRANCH"},
+			map[string]string{"commit": "abc123"},
+		},
+		"run tags take precedence": {
+			map[string]string{"foo": "bar", "build": "manual"}, map[string]string{"build": "BUILD_ID"},
+			map[string]string{"foo": "bar", "build": "manual"},
+		},
+	}
+	for name, data := range testdata {
+		data := data
+		t.Run(name, func(t *testing.T) {
+			opts := Options{TagsFromEnv: data.tagsFromEnv}
+			if data.runTags != nil {
+				opts.RunTags = stats.IntoSampleTags(&data.runTags)
+			}
+			assert.Equal(t, data.expected, opts.GetRunTagsWithEnv(env).CloneTags())
+		})
+	}
+}
@@ -22,6 +22,8 @@ package lib
 
 import (
 	"context"
+	"sync/atomic"
+	"time"
 
 	"github.com/loadimpact/k6/stats"
 )
@@ -68,6 +70,26 @@ type Runner interface {
 	SetOptions(opts Options) error
 }
 
+// MetricsReader gives thread-safe, read-only access to the aggregated values of the metrics of a
+// running test, as they are seen by the thresholds.
+type MetricsReader interface {
+	// Returns the current values (e.g. "count" and "rate" for counters) of the metric with the
+	// given name, or false if no samples for it have been processed yet.
+	GetMetricSnapshot(name string) (map[string]float64, bool)
+}
+
+// MetricsReaderSetter can optionally be implemented by Runners whose VUs can query the current
+// metric values mid-run. The Engine will hand itself to such runners when it's created.
+type MetricsReaderSetter interface {
+	SetMetricsReader(mr MetricsReader)
+}
+
+// ConnectionSampler can optionally be implemented by Runners that keep track of the connections
+// made by their VUs. The Engine will periodically emit the returned samples, if there are any.
+type ConnectionSampler interface {
+	GetConnectionSamples(t time.Time, tags *stats.SampleTags) stats.SampleContainer
+}
+
 // A VU is a Virtual User, that can be scheduled by an Executor.
 type VU interface {
 	// Runs the VU once. The VU is responsible for handling the Halting Problem, eg. making sure
@@ -143,6 +165,29 @@ func (r *MiniRunner) SetOptions(opts Options) error {
 	return nil
 }
 
+// NewReplayRunner returns a MiniRunner whose iterations simply sleep for the given durations, in
+// the order the iterations are started and regardless of which VU runs them. Once all of the
+// durations are replayed, it starts again from the first one. It's useful for reproducing a
+// specific timing profile, e.g. when testing the scheduling of executors.
+func NewReplayRunner(durations []time.Duration) *MiniRunner {
+	var iteration int64 = -1
+	return &MiniRunner{
+		Fn: func(ctx context.Context, out chan<- stats.SampleContainer) error {
+			if len(durations) == 0 {
+				return nil
+			}
+			i := atomic.AddInt64(&iteration, 1)
+			t := time.NewTimer(durations[i%int64(len(durations))])
+			defer t.Stop()
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	}
+}
+
 // A VU spawned by a MiniRunner.
 type MiniRunnerVU struct {
 	R   MiniRunner
@@ -30,6 +30,7 @@ import (
 	"github.com/oxtoacart/bpool"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/time/rate"
+	"gopkg.in/guregu/null.v3"
 
 	"github.com/loadimpact/k6/stats"
 )
@@ -53,6 +54,7 @@ type State struct {
 	// Networking equipment.
 	Transport http.RoundTripper
 	Dialer    DialContexter
+	ConnPool  *ConnPoolTracker // Only set if the connections per host are limited
 	CookieJar *cookiejar.Jar
 	TLSConfig *tls.Config
 
@@ -62,9 +64,35 @@ type State struct {
 	// Sample channel, possibly buffered
 	Samples chan<- stats.SampleContainer
 
+	// Read-only access to the aggregated metrics of the test; may be nil.
+	Metrics MetricsReader
+
 	// Buffer pool; use instead of allocating fresh buffers when possible.
 	// TODO: maybe use https://golang.org/pkg/sync/#Pool ?
 	BPool *bpool.BufferPool
 
 	Vu, Iteration int64
+
+	// The number of checks that have failed in the current iteration.
+	FailedChecks int64
+
+	// The outcome of the current iteration, if the script marked it with markIteration().
+	IterationOutcome null.Bool
+
+	// Tags set by the script for the current iteration, they're applied to all metrics that
+	// are emitted after they were set, until the end of the iteration.
+	Tags map[string]string
+
+	// Values that were captured from responses with the extract param of the HTTP requests, for
+	// the following requests of the current iteration.
+	Vars map[string]string
+}
+
+// CloneTags returns a copy of the run tags, together with the tags of the current iteration.
+func (s *State) CloneTags() map[string]string {
+	tags := s.Options.RunTags.CloneTags()
+	for k, v := range s.Tags {
+		tags[k] = v
+	}
+	return tags
 }
@@ -21,6 +21,8 @@
 package lib
 
 import (
+	"fmt"
+	"net"
 	"strings"
 
 	"github.com/loadimpact/k6/lib/types"
@@ -79,3 +81,17 @@ func Min(a, b int64) int64 {
 	}
 	return b
 }
+
+// ParseBackend splits a backend of the stickyBackends option, an IP address with an optional
+// port, into its parts. The port is empty if it isn't specified.
+func ParseBackend(backend string) (net.IP, string, error) {
+	host, port := backend, ""
+	if h, p, err := net.SplitHostPort(backend); err == nil {
+		host, port = h, p
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, "", fmt.Errorf("invalid backend '%s', it should be an IP address with an optional port", backend)
+	}
+	return ip, port, nil
+}
@@ -0,0 +1,141 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// SampleBatcher buffers the samples of an output until there are enough of them for a batch, so
+// that outputs don't make lots of tiny writes when the sample rate is low. So samples aren't held
+// back indefinitely, a batch is also flushed once its oldest sample has been buffered for maxAge.
+type SampleBatcher struct {
+	minSize int
+	maxAge  time.Duration
+
+	mu      sync.Mutex
+	buffer  []Sample
+	started time.Time // when the first sample of the current batch was buffered
+
+	// wake notifies Run that a new batch was started or that the batch became big enough
+	wake chan struct{}
+}
+
+// NewSampleBatcher returns a SampleBatcher that flushes a batch once it has at least minSize
+// samples or once its oldest sample is maxAge old, whichever comes first. A minSize that isn't
+// positive means that the batches are only flushed on age.
+func NewSampleBatcher(minSize int, maxAge time.Duration) *SampleBatcher {
+	return &SampleBatcher{
+		minSize: minSize,
+		maxAge:  maxAge,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Add buffers the samples of the given sample containers
+func (b *SampleBatcher) Add(scs []SampleContainer) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasEmpty := len(b.buffer) == 0
+	for _, sc := range scs {
+		b.buffer = append(b.buffer, sc.GetSamples()...)
+	}
+	if len(b.buffer) == 0 {
+		return
+	}
+	if wasEmpty {
+		b.started = time.Now()
+	}
+	if wasEmpty || b.isFull() {
+		select {
+		case b.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Take returns all of the buffered samples, regardless of whether a batch is due, and empties
+// the buffer.
+func (b *SampleBatcher) Take() []Sample {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	samples := b.buffer
+	b.buffer = nil
+	return samples
+}
+
+// Run calls flush with every batch that becomes due, until the context is done. Then it flushes
+// whatever is left in the buffer one final time.
+func (b *SampleBatcher) Run(ctx context.Context, flush func([]Sample)) {
+	timer := time.NewTimer(b.maxAge)
+	defer timer.Stop()
+	for {
+		samples, wait := b.due(time.Now())
+		if samples != nil {
+			flush(samples)
+			continue
+		}
+
+		var timerC <-chan time.Time
+		if wait > 0 {
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(wait)
+			timerC = timer.C
+		}
+		select {
+		case <-b.wake:
+		case <-timerC:
+		case <-ctx.Done():
+			if samples := b.Take(); len(samples) > 0 {
+				flush(samples)
+			}
+			return
+		}
+	}
+}
+
+// due returns the buffered samples if a batch is due, or how long it will take for the current
+// batch to become due otherwise. A zero wait means that there's no batch in progress.
+func (b *SampleBatcher) due(now time.Time) ([]Sample, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.buffer) == 0 {
+		return nil, 0
+	}
+	wait := b.started.Add(b.maxAge).Sub(now)
+	if !b.isFull() && wait > 0 {
+		return nil, wait
+	}
+	samples := b.buffer
+	b.buffer = nil
+	return samples, 0
+}
+
+func (b *SampleBatcher) isFull() bool {
+	return b.minSize > 0 && len(b.buffer) >= b.minSize
+}
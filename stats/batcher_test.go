@@ -0,0 +1,125 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSampleBatcher(t *testing.T) {
+	t.Parallel()
+	metric := New("my_metric", Counter)
+	sample := func(v float64) Sample {
+		return Sample{Metric: metric, Time: time.Now(), Value: v}
+	}
+
+	runBatcher := func(b *SampleBatcher) (chan []Sample, context.CancelFunc, chan struct{}) {
+		flushes := make(chan []Sample, 10)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			b.Run(ctx, func(samples []Sample) { flushes <- samples })
+			close(done)
+		}()
+		return flushes, cancel, done
+	}
+
+	t.Run("batch size", func(t *testing.T) {
+		t.Parallel()
+		b := NewSampleBatcher(3, time.Hour)
+		flushes, cancel, done := runBatcher(b)
+		defer func() { cancel(); <-done }()
+
+		b.Add([]SampleContainer{sample(1), Samples{sample(2)}})
+		select {
+		case <-flushes:
+			t.Fatal("a batch was flushed before reaching the minimum size")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		b.Add([]SampleContainer{Samples{sample(3), sample(4)}})
+		select {
+		case samples := <-flushes:
+			require.Len(t, samples, 4)
+			for i, s := range samples {
+				assert.Equal(t, float64(i+1), s.Value)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("the batch wasn't flushed after reaching the minimum size")
+		}
+	})
+
+	t.Run("max age", func(t *testing.T) {
+		t.Parallel()
+		b := NewSampleBatcher(100, 100*time.Millisecond)
+		flushes, cancel, done := runBatcher(b)
+		defer func() { cancel(); <-done }()
+
+		start := time.Now()
+		b.Add([]SampleContainer{sample(1)})
+		time.Sleep(20 * time.Millisecond)
+		b.Add([]SampleContainer{sample(2)})
+		select {
+		case samples := <-flushes:
+			assert.Len(t, samples, 2)
+			// the age is counted from the first sample of the batch, not from the last one
+			elapsed := time.Since(start)
+			assert.True(t, elapsed >= 100*time.Millisecond, "flushed too early: %s", elapsed)
+			assert.True(t, elapsed < time.Second, "flushed too late: %s", elapsed)
+		case <-time.After(2 * time.Second):
+			t.Fatal("the batch wasn't flushed after reaching the max age")
+		}
+
+		// the next batch gets its own max age
+		b.Add([]SampleContainer{sample(3)})
+		select {
+		case samples := <-flushes:
+			require.Len(t, samples, 1)
+			assert.Equal(t, float64(3), samples[0].Value)
+		case <-time.After(2 * time.Second):
+			t.Fatal("the second batch wasn't flushed after reaching the max age")
+		}
+	})
+
+	t.Run("final flush", func(t *testing.T) {
+		t.Parallel()
+		b := NewSampleBatcher(100, time.Hour)
+		flushes, cancel, done := runBatcher(b)
+		b.Add([]SampleContainer{sample(1)})
+		cancel()
+		<-done
+		require.Len(t, flushes, 1)
+		assert.Len(t, <-flushes, 1)
+	})
+
+	t.Run("take", func(t *testing.T) {
+		t.Parallel()
+		b := NewSampleBatcher(100, time.Hour)
+		b.Add([]SampleContainer{sample(1), sample(2)})
+		assert.Len(t, b.Take(), 2)
+		assert.Empty(t, b.Take())
+	})
+}
@@ -133,6 +133,11 @@ func New(conf Config, src *loader.SourceData, opts lib.Options, version string)
 		return nil, errors.New("Tests with unspecified duration are not allowed when using Load Impact Insights")
 	}
 
+	if conf.MetricPushConcurrency.Int64 < 1 {
+		return nil, errors.Errorf("metrics push concurrency must be a positive number but is %d",
+			conf.MetricPushConcurrency.Int64)
+	}
+
 	if !conf.Token.Valid && conf.DeprecatedToken.Valid {
 		logrus.Warn("K6CLOUD_TOKEN is deprecated and will be removed. Use K6_CLOUD_TOKEN instead.")
 		conf.Token = conf.DeprecatedToken
@@ -510,22 +515,47 @@ func (c *Collector) pushMetrics() {
 		"samples": len(buffer),
 	}).Debug("Pushing metrics to cloud")
 
+	// The buffer is split into packages of at most MaxMetricSamplesPerPackage samples, which are
+	// pushed by MetricPushConcurrency workers.
+	packages := make(chan []*Sample)
+	var stopOnce sync.Once
+	var wg sync.WaitGroup
+	for i := int64(0); i < c.config.MetricPushConcurrency.Int64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pkg := range packages {
+				err := c.client.PushMetric(c.referenceID, c.config.NoCompress.Bool, pkg)
+				if err == nil {
+					continue
+				}
+				if c.shouldStopSendingMetrics(err) {
+					stopOnce.Do(func() {
+						logrus.WithError(err).Warn("Stopped sending metrics to cloud due to an error")
+						close(c.stopSendingMetricsCh)
+					})
+					continue
+				}
+				logrus.WithError(err).Warn("Failed to send metrics to cloud")
+			}
+		}()
+	}
+
+pushLoop:
 	for len(buffer) > 0 {
 		var size = len(buffer)
 		if size > int(c.config.MaxMetricSamplesPerPackage.Int64) {
 			size = int(c.config.MaxMetricSamplesPerPackage.Int64)
 		}
-		err := c.client.PushMetric(c.referenceID, c.config.NoCompress.Bool, buffer[:size])
-		if err != nil {
-			if c.shouldStopSendingMetrics(err) {
-				logrus.WithError(err).Warn("Stopped sending metrics to cloud due to an error")
-				close(c.stopSendingMetricsCh)
-				break
-			}
-			logrus.WithError(err).Warn("Failed to send metrics to cloud")
+		select {
+		case packages <- buffer[:size]:
+			buffer = buffer[size:]
+		case <-c.stopSendingMetricsCh:
+			break pushLoop
 		}
-		buffer = buffer[size:]
 	}
+	close(packages)
+	wg.Wait()
 }
 
 func (c *Collector) testFinished() {
@@ -404,6 +404,101 @@ func TestCloudCollectorMaxPerPacket(t *testing.T) {
 	require.True(t, gotTheLimit)
 }
 
+func TestCloudCollectorPushConcurrency(t *testing.T) {
+	t.Parallel()
+	tb := httpmultibin.NewHTTPMultiBin(t)
+	tb.Mux.HandleFunc("/v1/tests", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := fmt.Fprint(w, `{"reference_id": "13"}`)
+		require.NoError(t, err)
+	}))
+	defer tb.Cleanup()
+
+	script := &loader.SourceData{
+		Data: []byte(""),
+		URL:  &url.URL{Path: "/script.js"},
+	}
+
+	options := lib.Options{
+		Duration: types.NullDurationFrom(1 * time.Second),
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		config := NewConfig().Apply(Config{
+			Host:                  null.StringFrom(tb.ServerHTTP.URL),
+			MetricPushConcurrency: null.IntFrom(0),
+		})
+		_, err := New(config, script, options, "1.0")
+		require.Error(t, err)
+	})
+
+	config := NewConfig().Apply(Config{
+		Host:                       null.StringFrom(tb.ServerHTTP.URL),
+		NoCompress:                 null.BoolFrom(true),
+		MetricPushInterval:         types.NullDurationFrom(1 * time.Hour),
+		MaxMetricSamplesPerPackage: null.IntFrom(10),
+		MetricPushConcurrency:      null.IntFrom(4),
+	})
+	collector, err := New(config, script, options, "1.0")
+	require.NoError(t, err)
+
+	var m sync.Mutex
+	inFlight, maxInFlight, received, requests := 0, 0, 0, 0
+	tb.Mux.HandleFunc(fmt.Sprintf("/v1/metrics/%s", collector.referenceID),
+		func(_ http.ResponseWriter, r *http.Request) {
+			m.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			m.Unlock()
+
+			body, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			receivedSamples := []Sample{}
+			assert.NoError(t, json.Unmarshal(body, &receivedSamples))
+			assert.True(t, len(receivedSamples) <= 10)
+			time.Sleep(50 * time.Millisecond)
+
+			m.Lock()
+			inFlight--
+			requests++
+			received += len(receivedSamples)
+			m.Unlock()
+		})
+
+	require.NoError(t, collector.Init())
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		collector.Run(ctx)
+		wg.Done()
+	}()
+
+	now := time.Now()
+	tags := stats.IntoSampleTags(&map[string]string{"test": "mest", "a": "b"})
+	container := make([]stats.SampleContainer, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		container = append(container, stats.Sample{
+			Time:   now.Add(time.Duration(i)),
+			Metric: metrics.VUs,
+			Tags:   tags,
+			Value:  float64(i),
+		})
+	}
+	collector.Collect(container)
+
+	start := time.Now()
+	cancel()
+	wg.Wait()
+
+	assert.Equal(t, 1000, received, "samples were lost")
+	assert.Equal(t, 100, requests)
+	assert.Equal(t, 4, maxInFlight)
+	// 100 requests that take 50ms each would take 5s if they were sent one after the other
+	assert.True(t, time.Since(start) < 4*time.Second, "pushing took %s", time.Since(start))
+}
+
 func TestCloudCollectorStopSendingMetric(t *testing.T) {
 	t.Parallel()
 	tb := httpmultibin.NewHTTPMultiBin(t)
@@ -45,6 +45,10 @@ type Config struct {
 	// The time interval between periodic API calls for sending samples to the cloud ingest service.
 	MetricPushInterval types.NullDuration `json:"metricPushInterval" envconfig:"K6_CLOUD_METRIC_PUSH_INTERVAL"`
 
+	// The maximum number of concurrent API calls for sending the packages of samples that were
+	// buffered since the last push to the cloud ingest service.
+	MetricPushConcurrency null.Int `json:"metricPushConcurrency" envconfig:"K6_CLOUD_METRIC_PUSH_CONCURRENCY"`
+
 	// Aggregation docs:
 	//
 	// If AggregationPeriod is specified and if it is greater than 0, HTTP metric aggregation
@@ -154,6 +158,7 @@ func NewConfig() Config {
 		WebAppURL:                  null.NewString("https://app.k6.io", false),
 		MetricPushInterval:         types.NewNullDuration(1*time.Second, false),
 		MaxMetricSamplesPerPackage: null.NewInt(100000, false),
+		MetricPushConcurrency:      null.NewInt(1, false),
 		// Aggregation is disabled by default, since AggregationPeriod has no default value
 		// but if it's enabled manually or from the cloud service, those are the default values it will use:
 		AggregationCalcInterval:         types.NewNullDuration(3*time.Second, false),
@@ -199,6 +204,9 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.MaxMetricSamplesPerPackage.Valid {
 		c.MaxMetricSamplesPerPackage = cfg.MaxMetricSamplesPerPackage
 	}
+	if cfg.MetricPushConcurrency.Valid {
+		c.MetricPushConcurrency = cfg.MetricPushConcurrency
+	}
 	if cfg.AggregationPeriod.Valid {
 		c.AggregationPeriod = cfg.AggregationPeriod
 	}
@@ -24,6 +24,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -39,16 +40,15 @@ import (
 
 // Collector saving output to csv implements the lib.Collector interface
 type Collector struct {
-	outfile      io.WriteCloser
-	fname        string
-	resTags      []string
-	ignoredTags  []string
-	csvWriter    *csv.Writer
-	csvLock      sync.Mutex
-	buffer       []stats.Sample
-	bufferLock   sync.Mutex
-	row          []string
-	saveInterval time.Duration
+	outfile     io.WriteCloser
+	fname       string
+	resTags     []string
+	ignoredTags []string
+	csvWriter   *csv.Writer
+	csvLock     sync.Mutex
+	batcher     *stats.SampleBatcher
+	row         []string
+	format      stats.OutputFormat
 }
 
 // Verify that Collector implements lib.Collector
@@ -75,19 +75,27 @@ func New(fs afero.Fs, tags stats.TagSet, config Config) (*Collector, error) {
 	sort.Strings(resTags)
 	sort.Strings(ignoredTags)
 
-	saveInterval := time.Duration(config.SaveInterval.Duration)
+	if err := config.Format.Validate(); err != nil {
+		return nil, err
+	}
+	if config.MinBatchSize.Int64 < 0 {
+		return nil, errors.New("csv output min batch size can't be negative")
+	}
+
+	batcher := stats.NewSampleBatcher(int(config.MinBatchSize.Int64), time.Duration(config.SaveInterval.Duration))
 	fname := config.FileName.String
 
 	if fname == "" || fname == "-" {
 		logfile := nopCloser{os.Stdout}
 		return &Collector{
-			outfile:      logfile,
-			fname:        "-",
-			resTags:      resTags,
-			ignoredTags:  ignoredTags,
-			csvWriter:    csv.NewWriter(logfile),
-			row:          make([]string, 3+len(resTags)+1),
-			saveInterval: saveInterval,
+			outfile:     logfile,
+			fname:       "-",
+			resTags:     resTags,
+			ignoredTags: ignoredTags,
+			csvWriter:   csv.NewWriter(logfile),
+			batcher:     batcher,
+			row:         make([]string, 3+len(resTags)+1),
+			format:      config.Format,
 		}, nil
 	}
 
@@ -97,13 +105,14 @@ func New(fs afero.Fs, tags stats.TagSet, config Config) (*Collector, error) {
 	}
 
 	return &Collector{
-		outfile:      logfile,
-		fname:        fname,
-		resTags:      resTags,
-		ignoredTags:  ignoredTags,
-		csvWriter:    csv.NewWriter(logfile),
-		row:          make([]string, 3+len(resTags)+1),
-		saveInterval: saveInterval,
+		outfile:     logfile,
+		fname:       fname,
+		resTags:     resTags,
+		ignoredTags: ignoredTags,
+		csvWriter:   csv.NewWriter(logfile),
+		batcher:     batcher,
+		row:         make([]string, 3+len(resTags)+1),
+		format:      config.Format,
 	}, nil
 }
 
@@ -121,40 +130,27 @@ func (c *Collector) Init() error {
 // SetRunStatus does nothing
 func (c *Collector) SetRunStatus(status lib.RunStatus) {}
 
-// Run just blocks until the context is done
+// Run writes the samples once there are at least min_batch_size of them, or once the oldest of
+// them has been buffered for the save interval, until the context is done
 func (c *Collector) Run(ctx context.Context) {
-	ticker := time.NewTicker(c.saveInterval)
-	for {
-		select {
-		case <-ticker.C:
-			c.WriteToFile()
-		case <-ctx.Done():
-			c.WriteToFile()
-			err := c.outfile.Close()
-			if err != nil {
-				logrus.WithField("filename", c.fname).Error("CSV: Error closing the file")
-			}
-			return
-		}
+	c.batcher.Run(ctx, c.writeSamples)
+	err := c.outfile.Close()
+	if err != nil {
+		logrus.WithField("filename", c.fname).Error("CSV: Error closing the file")
 	}
 }
 
 // Collect Saves samples to buffer
 func (c *Collector) Collect(scs []stats.SampleContainer) {
-	c.bufferLock.Lock()
-	defer c.bufferLock.Unlock()
-	for _, sc := range scs {
-		c.buffer = append(c.buffer, sc.GetSamples()...)
-	}
+	c.batcher.Add(scs)
 }
 
-// WriteToFile Writes samples to the csv file
+// WriteToFile Writes all of the buffered samples to the csv file
 func (c *Collector) WriteToFile() {
-	c.bufferLock.Lock()
-	samples := c.buffer
-	c.buffer = nil
-	c.bufferLock.Unlock()
+	c.writeSamples(c.batcher.Take())
+}
 
+func (c *Collector) writeSamples(samples []stats.Sample) {
 	if len(samples) > 0 {
 		c.csvLock.Lock()
 		defer c.csvLock.Unlock()
@@ -162,6 +158,9 @@ func (c *Collector) WriteToFile() {
 			for _, sample := range sc.GetSamples() {
 				sample := sample
 				row := SampleToRow(&sample, c.resTags, c.ignoredTags, c.row)
+				if c.format != (stats.OutputFormat{}) {
+					row[2] = c.format.For(sample.Metric.Type).FormatString(sample.Metric.Contains, sample.Value)
+				}
 				err := c.csvWriter.Write(row)
 				if err != nil {
 					logrus.WithField("filename", c.fname).Error("CSV: Error writing to file")
@@ -21,6 +21,7 @@
 package csv
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"sort"
@@ -35,6 +36,7 @@ import (
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMakeHeader(t *testing.T) {
@@ -215,7 +217,7 @@ func TestCollect(t *testing.T) {
 
 	collector.Collect(testSamples)
 
-	assert.Equal(t, len(testSamples), len(collector.buffer))
+	assert.Equal(t, len(testSamples), len(collector.batcher.Take()))
 }
 
 func TestRun(t *testing.T) {
@@ -296,6 +298,86 @@ func TestRunCollect(t *testing.T) {
 		csvstr)
 }
 
+func TestRunMinBatchSize(t *testing.T) {
+	_, err := New(afero.NewMemMapFs(), stats.TagSet{}, Config{FileName: null.StringFrom("path"), MinBatchSize: null.IntFrom(-1)})
+	assert.EqualError(t, err, "csv output min batch size can't be negative")
+
+	mem := afero.NewMemMapFs()
+	collector, err := New(mem, stats.TagSet{}, Config{
+		FileName:     null.StringFrom("path"),
+		SaveInterval: types.NullDurationFrom(time.Hour),
+		MinBatchSize: null.IntFrom(2),
+	})
+	require.NoError(t, err)
+	require.NoError(t, collector.Init())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		collector.Run(ctx)
+		close(done)
+	}()
+	lines := func() int {
+		csvbytes, _ := afero.ReadFile(mem, "path")
+		return bytes.Count(csvbytes, []byte("\n"))
+	}
+
+	sample := stats.Sample{Time: time.Unix(1562324643, 0), Metric: stats.New("my_metric", stats.Gauge), Value: 1}
+	collector.Collect([]stats.SampleContainer{sample})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 1, lines(), "a single sample shouldn't be written before the save interval")
+
+	// Reaching the min batch size writes the samples long before the save interval
+	collector.Collect([]stats.SampleContainer{sample})
+	for start := time.Now(); lines() < 3 && time.Since(start) < 5*time.Second; {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 3, lines())
+
+	collector.Collect([]stats.SampleContainer{sample})
+	cancel()
+	<-done
+	assert.Equal(t, 4, lines())
+}
+
+func TestWriteToFileFormat(t *testing.T) {
+	duration := stats.New("my_duration", stats.Trend, stats.Time)
+	counter := stats.New("my_counter", stats.Counter)
+	testSamples := []stats.SampleContainer{
+		stats.Sample{Time: time.Unix(1562324643, 0), Metric: duration, Value: 1234.5678},
+		stats.Sample{Time: time.Unix(1562324643, 0), Metric: counter, Value: 2.71828},
+		stats.Sample{Time: time.Unix(1562324644, 0), Metric: stats.New("my_gauge", stats.Gauge), Value: 1},
+	}
+
+	mem := afero.NewMemMapFs()
+	collector, err := New(mem, stats.TagSet{}, Config{
+		FileName:     null.StringFrom("path"),
+		SaveInterval: types.NewNullDuration(time.Duration(1), true),
+		Format: stats.OutputFormat{
+			Default: stats.ValueFormat{Precision: null.IntFrom(2)},
+			Trend:   stats.ValueFormat{Precision: null.IntFrom(3), TimeUnit: null.StringFrom("s")},
+		},
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, collector.Init())
+	collector.Collect(testSamples)
+	collector.WriteToFile()
+
+	csvbytes, _ := afero.ReadFile(mem, "path")
+	assert.Equal(t,
+		"metric_name,timestamp,metric_value,extra_tags\n"+
+			"my_duration,1562324643,1.235,\n"+
+			"my_counter,1562324643,2.72,\n"+
+			"my_gauge,1562324644,1.00,\n",
+		string(csvbytes))
+
+	_, err = New(mem, stats.TagSet{}, Config{
+		FileName: null.StringFrom("path"),
+		Format:   stats.OutputFormat{Default: stats.ValueFormat{Precision: null.IntFrom(-1)}},
+	})
+	assert.Error(t, err)
+}
+
 func TestNew(t *testing.T) {
 	configs := []struct {
 		cfg  Config
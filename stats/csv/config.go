@@ -26,6 +26,7 @@ import (
 	"time"
 
 	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
 	"gopkg.in/guregu/null.v3"
 )
 
@@ -34,6 +35,13 @@ type Config struct {
 	// Samples.
 	FileName     null.String        `json:"file_name" envconfig:"K6_CSV_FILENAME"`
 	SaveInterval types.NullDuration `json:"save_interval" envconfig:"K6_CSV_SAVE_INTERVAL"`
+
+	// The samples are written once there are at least MinBatchSize of them, or once the oldest
+	// of them was collected SaveInterval ago. Zero means that they're only written on age.
+	MinBatchSize null.Int `json:"min_batch_size" envconfig:"K6_CSV_MIN_BATCH_SIZE"`
+
+	// How the metric values are formatted, e.g. {"trend": {"precision": 2, "timeUnit": "s"}}.
+	Format stats.OutputFormat `json:"format" ignored:"true"`
 }
 
 // NewConfig creates a new Config instance with default values for some fields.
@@ -52,6 +60,10 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.SaveInterval.Valid {
 		c.SaveInterval = cfg.SaveInterval
 	}
+	if cfg.MinBatchSize.Valid {
+		c.MinBatchSize = cfg.MinBatchSize
+	}
+	c.Format = c.Format.Apply(cfg.Format)
 	return c
 }
 
@@ -79,6 +91,15 @@ func ParseArg(arg string) (Config, error) {
 			}
 		case "file_name":
 			c.FileName = null.StringFrom(r[1])
+		case "min_batch_size":
+			if err := c.MinBatchSize.UnmarshalText([]byte(r[1])); err != nil {
+				return c, err
+			}
+		case "precision", "time_unit", "counter.precision", "counter.time_unit", "gauge.precision",
+			"gauge.time_unit", "rate.precision", "rate.time_unit", "trend.precision", "trend.time_unit":
+			if err := c.Format.Set(r[0], r[1]); err != nil {
+				return c, err
+			}
 		default:
 			return c, fmt.Errorf("unknown key %q as argument for csv output", r[0])
 		}
@@ -27,6 +27,7 @@ import (
 	"gopkg.in/guregu/null.v3"
 
 	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -99,6 +100,28 @@ func TestParseArg(t *testing.T) {
 		"filename=test.csv,save_interval=5s": {
 			expectedErr: true,
 		},
+		"file_name=test.csv,save_interval=5s,min_batch_size=1000": {
+			config: Config{
+				FileName:     null.StringFrom("test.csv"),
+				SaveInterval: types.NullDurationFrom(5 * time.Second),
+				MinBatchSize: null.IntFrom(1000),
+			},
+		},
+		"min_batch_size=many": {
+			expectedErr: true,
+		},
+		"file_name=test.csv,precision=2,trend.time_unit=s": {
+			config: Config{
+				FileName: null.StringFrom("test.csv"),
+				Format: stats.OutputFormat{
+					Default: stats.ValueFormat{Precision: null.IntFrom(2)},
+					Trend:   stats.ValueFormat{TimeUnit: null.StringFrom("s")},
+				},
+			},
+		},
+		"trend.precision=two": {
+			expectedErr: true,
+		},
 	}
 
 	for arg, testCase := range cases {
@@ -115,6 +138,8 @@ func TestParseArg(t *testing.T) {
 			}
 			assert.Equal(t, testCase.config.FileName.String, config.FileName.String)
 			assert.Equal(t, testCase.config.SaveInterval.String(), config.SaveInterval.String())
+			assert.Equal(t, testCase.config.MinBatchSize.Int64, config.MinBatchSize.Int64)
+			assert.Equal(t, testCase.config.Format, config.Format)
 		})
 	}
 }
@@ -35,10 +35,11 @@ type Collector struct {
 
 	SampleContainers []stats.SampleContainer
 	Samples          []stats.Sample
+	Events           []*stats.Event
 }
 
-// Verify that Collector implements lib.Collector
-var _ lib.Collector = &Collector{}
+// Verify that Collector implements lib.EventCollector
+var _ lib.EventCollector = &Collector{}
 
 // Init does nothing, it's only included to satisfy the lib.Collector interface
 func (c *Collector) Init() error { return nil }
@@ -65,6 +66,11 @@ func (c *Collector) Collect(scs []stats.SampleContainer) {
 	}
 }
 
+// CollectEvents just appends all of the events passed to it to the internal event slice
+func (c *Collector) CollectEvents(events []*stats.Event) {
+	c.Events = append(c.Events, events...)
+}
+
 // Link returns a dummy string, it's only included to satisfy the lib.Collector interface
 func (c *Collector) Link() string {
 	return "http://example.com/"
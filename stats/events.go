@@ -0,0 +1,65 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import "time"
+
+// Event is a discrete, annotated occurrence, e.g. the deployment of a new version, that scripts
+// emit so that it can be shown along with the metrics, e.g. as a dashboard annotation. It's a
+// sample container without any samples, so it can be sent over the same channels as the samples,
+// but it's only passed to the outputs that support events.
+type Event struct {
+	Time time.Time              `json:"time"`
+	Name string                 `json:"name"`
+	Tags *SampleTags            `json:"tags"`
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// Ensure that Event implements SampleContainer
+var _ SampleContainer = &Event{}
+
+// GetSamples implements the SampleContainer interface, events don't have any samples
+func (e *Event) GetSamples() []Sample {
+	return nil
+}
+
+// SplitEvents separates the events from the other sample containers. The original slice is
+// returned as it is if there are no events in it, which is the most common case.
+func SplitEvents(sampleContainers []SampleContainer) ([]SampleContainer, []*Event) {
+	var containers []SampleContainer
+	var events []*Event
+	for i, sc := range sampleContainers {
+		event, ok := sc.(*Event)
+		switch {
+		case ok && events == nil:
+			containers = append(make([]SampleContainer, 0, len(sampleContainers)), sampleContainers[:i]...)
+			events = append(events, event)
+		case ok:
+			events = append(events, event)
+		case events != nil:
+			containers = append(containers, sc)
+		}
+	}
+	if events == nil {
+		return sampleContainers, nil
+	}
+	return containers, events
+}
@@ -0,0 +1,49 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitEvents(t *testing.T) {
+	t.Parallel()
+
+	metric := New("my_metric", Counter)
+	sample := Sample{Metric: metric, Value: 1}
+	samples := Samples{sample, sample}
+	event1, event2 := &Event{Name: "first"}, &Event{Name: "second"}
+
+	containers := []SampleContainer{sample, samples}
+	split, events := SplitEvents(containers)
+	assert.Equal(t, containers, split)
+	assert.Nil(t, events)
+
+	split, events = SplitEvents([]SampleContainer{sample, event1, samples, event2})
+	assert.Equal(t, []SampleContainer{sample, samples}, split)
+	assert.Equal(t, []*Event{event1, event2}, events)
+
+	split, events = SplitEvents([]SampleContainer{event1})
+	assert.Empty(t, split)
+	assert.Equal(t, []*Event{event1}, events)
+}
@@ -0,0 +1,182 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// The units in which outputs can emit time values. k6 itself measures time in milliseconds.
+var timeUnits = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// The largest number of decimal places that a float64 can meaningfully represent
+const maxPrecision = 15
+
+// ValueFormat describes how an output formats the values of a metric.
+type ValueFormat struct {
+	// Round values to this many decimal places; values are not rounded if it's not set.
+	Precision null.Int `json:"precision"`
+	// Convert time values to this unit (ns, us, ms or s); it doesn't affect non-time values.
+	TimeUnit null.String `json:"timeUnit"`
+}
+
+// Apply merges two formats by overwriting the properties that are set in the other one
+func (f ValueFormat) Apply(other ValueFormat) ValueFormat {
+	if other.Precision.Valid {
+		f.Precision = other.Precision
+	}
+	if other.TimeUnit.Valid {
+		f.TimeUnit = other.TimeUnit
+	}
+	return f
+}
+
+// Validate checks that the precision and the time unit make sense
+func (f ValueFormat) Validate() error {
+	if f.Precision.Valid && (f.Precision.Int64 < 0 || f.Precision.Int64 > maxPrecision) {
+		return fmt.Errorf("precision should be between 0 and %d, not %d", maxPrecision, f.Precision.Int64)
+	}
+	if f.TimeUnit.Valid {
+		if _, ok := timeUnits[f.TimeUnit.String]; !ok {
+			return fmt.Errorf("invalid time unit %q, it should be one of ns, us, ms or s", f.TimeUnit.String)
+		}
+	}
+	return nil
+}
+
+// Format converts a value that contains the given type of data to the configured unit and
+// rounds it to the configured precision.
+func (f ValueFormat) Format(contains ValueType, v float64) float64 {
+	if contains == Time && f.TimeUnit.Valid {
+		if unit, ok := timeUnits[f.TimeUnit.String]; ok {
+			v = v * float64(timeUnit) / float64(unit)
+		}
+	}
+	if f.Precision.Valid {
+		scale := math.Pow10(int(f.Precision.Int64))
+		v = math.Round(v*scale) / scale
+	}
+	return v
+}
+
+// FormatString formats a value like Format does, and then returns it as a string with exactly
+// the configured number of decimal places, or with as many as needed if there's no precision.
+func (f ValueFormat) FormatString(contains ValueType, v float64) string {
+	v = f.Format(contains, v)
+	if f.Precision.Valid {
+		return strconv.FormatFloat(v, 'f', int(f.Precision.Int64), 64)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// OutputFormat holds the value formats that the CSV output uses for the different metric types. The
+// format for a metric type is the default format, overwritten by whatever is set for the type.
+type OutputFormat struct {
+	Default ValueFormat `json:"default"`
+	Counter ValueFormat `json:"counter"`
+	Gauge   ValueFormat `json:"gauge"`
+	Rate    ValueFormat `json:"rate"`
+	Trend   ValueFormat `json:"trend"`
+}
+
+// Apply merges two output formats by overwriting the properties that are set in the other one
+func (f OutputFormat) Apply(other OutputFormat) OutputFormat {
+	f.Default = f.Default.Apply(other.Default)
+	f.Counter = f.Counter.Apply(other.Counter)
+	f.Gauge = f.Gauge.Apply(other.Gauge)
+	f.Rate = f.Rate.Apply(other.Rate)
+	f.Trend = f.Trend.Apply(other.Trend)
+	return f
+}
+
+// Validate checks all of the value formats
+func (f OutputFormat) Validate() error {
+	names := []string{"default", "counter", "gauge", "rate", "trend"}
+	for i, vf := range []ValueFormat{f.Default, f.Counter, f.Gauge, f.Rate, f.Trend} {
+		if err := vf.Validate(); err != nil {
+			return fmt.Errorf("invalid %s format: %s", names[i], err)
+		}
+	}
+	return nil
+}
+
+// For returns the value format for metrics of the given type
+func (f OutputFormat) For(t MetricType) ValueFormat {
+	switch t {
+	case Counter:
+		return f.Default.Apply(f.Counter)
+	case Gauge:
+		return f.Default.Apply(f.Gauge)
+	case Rate:
+		return f.Default.Apply(f.Rate)
+	case Trend:
+		return f.Default.Apply(f.Trend)
+	default:
+		return f.Default
+	}
+}
+
+// Set sets a single property from a key=value output argument. The precision and time_unit keys
+// set the default format, and prefixing them with a metric type, e.g. trend.precision, sets them
+// only for that type.
+func (f *OutputFormat) Set(key, value string) error {
+	vf := &f.Default
+	if i := strings.IndexByte(key, '.'); i >= 0 {
+		switch key[:i] {
+		case "counter":
+			vf = &f.Counter
+		case "gauge":
+			vf = &f.Gauge
+		case "rate":
+			vf = &f.Rate
+		case "trend":
+			vf = &f.Trend
+		default:
+			return fmt.Errorf("unknown metric type %q", key[:i])
+		}
+		key = key[i+1:]
+	}
+
+	switch key {
+	case "precision":
+		precision, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		vf.Precision = null.IntFrom(precision)
+	case "time_unit":
+		vf.TimeUnit = null.StringFrom(value)
+	default:
+		return fmt.Errorf("unknown format key %q", key)
+	}
+	return vf.Validate()
+}
@@ -0,0 +1,125 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestValueFormat(t *testing.T) {
+	t.Run("none", func(t *testing.T) {
+		assert.Equal(t, 1.23456789, ValueFormat{}.Format(Time, 1.23456789))
+		assert.Equal(t, "1.23456789", ValueFormat{}.FormatString(Time, 1.23456789))
+	})
+	t.Run("precision", func(t *testing.T) {
+		f := ValueFormat{Precision: null.IntFrom(2)}
+		assert.Equal(t, 1.23, f.Format(Time, 1.23456789))
+		assert.Equal(t, 1.24, f.Format(Default, 1.2356))
+		assert.Equal(t, "1.20", f.FormatString(Default, 1.2))
+		assert.Equal(t, "3", ValueFormat{Precision: null.IntFrom(0)}.FormatString(Default, 2.5))
+	})
+	t.Run("time unit", func(t *testing.T) {
+		f := ValueFormat{TimeUnit: null.StringFrom("s")}
+		assert.Equal(t, 1.5, f.Format(Time, 1500))
+		assert.Equal(t, 1500.0, f.Format(Data, 1500), "only time values should be converted")
+		assert.Equal(t, 1500.0, ValueFormat{TimeUnit: null.StringFrom("us")}.Format(Time, 1.5))
+	})
+	t.Run("precision and time unit", func(t *testing.T) {
+		f := ValueFormat{Precision: null.IntFrom(3), TimeUnit: null.StringFrom("s")}
+		assert.Equal(t, 1.235, f.Format(Time, 1234.5678))
+		assert.Equal(t, "0.001", f.FormatString(Time, 1.2345))
+	})
+	t.Run("validate", func(t *testing.T) {
+		assert.NoError(t, ValueFormat{}.Validate())
+		assert.NoError(t, ValueFormat{Precision: null.IntFrom(15), TimeUnit: null.StringFrom("ns")}.Validate())
+		assert.Error(t, ValueFormat{Precision: null.IntFrom(-1)}.Validate())
+		assert.Error(t, ValueFormat{Precision: null.IntFrom(16)}.Validate())
+		assert.Error(t, ValueFormat{TimeUnit: null.StringFrom("h")}.Validate())
+	})
+}
+
+func TestOutputFormat(t *testing.T) {
+	f := OutputFormat{
+		Default: ValueFormat{Precision: null.IntFrom(1)},
+		Rate:    ValueFormat{Precision: null.IntFrom(3)},
+		Trend:   ValueFormat{Precision: null.IntFrom(4), TimeUnit: null.StringFrom("s")},
+	}
+	require.NoError(t, f.Validate())
+
+	t.Run("For", func(t *testing.T) {
+		assert.Equal(t, f.Default, f.For(Counter))
+		assert.Equal(t, f.Default, f.For(Gauge))
+		assert.Equal(t, f.Rate, f.For(Rate))
+		assert.Equal(t, f.Trend, f.For(Trend))
+
+		partial := OutputFormat{
+			Default: ValueFormat{Precision: null.IntFrom(1), TimeUnit: null.StringFrom("us")},
+			Trend:   ValueFormat{Precision: null.IntFrom(4)},
+		}
+		assert.Equal(t, ValueFormat{Precision: null.IntFrom(4), TimeUnit: null.StringFrom("us")}, partial.For(Trend))
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		applied := f.Apply(OutputFormat{
+			Default: ValueFormat{TimeUnit: null.StringFrom("ms")},
+			Trend:   ValueFormat{Precision: null.IntFrom(2)},
+		})
+		assert.Equal(t, ValueFormat{Precision: null.IntFrom(1), TimeUnit: null.StringFrom("ms")}, applied.Default)
+		assert.Equal(t, ValueFormat{Precision: null.IntFrom(2), TimeUnit: null.StringFrom("s")}, applied.Trend)
+		assert.Equal(t, f.Rate, applied.Rate)
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		var set OutputFormat
+		require.NoError(t, set.Set("precision", "2"))
+		require.NoError(t, set.Set("trend.time_unit", "s"))
+		require.NoError(t, set.Set("trend.precision", "5"))
+		assert.Equal(t, OutputFormat{
+			Default: ValueFormat{Precision: null.IntFrom(2)},
+			Trend:   ValueFormat{Precision: null.IntFrom(5), TimeUnit: null.StringFrom("s")},
+		}, set)
+
+		assert.Error(t, set.Set("precision", "two"))
+		assert.Error(t, set.Set("precision", "-1"))
+		assert.Error(t, set.Set("time_unit", "m"))
+		assert.Error(t, set.Set("histogram.precision", "2"))
+		assert.Error(t, set.Set("trend.decimals", "2"))
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		var parsed OutputFormat
+		require.NoError(t, json.Unmarshal(
+			[]byte(`{"default": {"precision": 1}, "rate": {"precision": 3}, "trend": {"precision": 4, "timeUnit": "s"}}`),
+			&parsed,
+		))
+		assert.Equal(t, f, parsed)
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		err := OutputFormat{Gauge: ValueFormat{TimeUnit: null.StringFrom("h")}}.Validate()
+		assert.EqualError(t, err, `invalid gauge format: invalid time unit "h", it should be one of ns, us, ms or s`)
+	})
+}
@@ -0,0 +1,171 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/consts"
+	"github.com/loadimpact/k6/stats"
+	jsonc "github.com/loadimpact/k6/stats/json"
+)
+
+// Collector periodically POSTs the collected samples to an arbitrary HTTP endpoint, as JSON
+// arrays of the same envelopes that the JSON output writes. The configured headers are added
+// to every request, so it can be used with any service that accepts JSON, e.g. New Relic.
+type Collector struct {
+	config  Config
+	client  *http.Client
+	batcher *stats.SampleBatcher
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new instance of the generic HTTP collector
+func New(config Config) (*Collector, error) {
+	if config.URL.String == "" {
+		return nil, errors.New("http output needs a URL")
+	}
+	if config.PushInterval.Duration <= 0 {
+		return nil, errors.New("http output push interval should be positive")
+	}
+	if config.BatchSize.Int64 <= 0 {
+		return nil, errors.New("http output batch size should be positive")
+	}
+	if config.MinBatchSize.Int64 < 0 {
+		return nil, errors.New("http output min batch size shouldn't be negative")
+	}
+	return &Collector{
+		config: config,
+		client: &http.Client{Timeout: time.Duration(config.Timeout.Duration)},
+		batcher: stats.NewSampleBatcher(
+			int(config.MinBatchSize.Int64), time.Duration(config.PushInterval.Duration),
+		),
+	}, nil
+}
+
+// Init does nothing, it's only included to satisfy the lib.Collector interface
+func (c *Collector) Init() error {
+	return nil
+}
+
+// SetRunStatus does nothing
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run pushes the buffered samples once there are at least min_batch_size of them, or once the
+// oldest of them has been buffered for the push interval, until the context is done
+func (c *Collector) Run(ctx context.Context) {
+	c.batcher.Run(ctx, func(samples []stats.Sample) {
+		c.pushSamples(ctx, samples)
+	})
+}
+
+// Collect saves samples to the buffer
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.batcher.Add(scs)
+}
+
+// Link returns the URL the samples are sent to
+func (c *Collector) Link() string {
+	return c.config.URL.String
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() stats.SystemTagSet {
+	return stats.SystemTagSet(0) // There are no required tags for this collector
+}
+
+func (c *Collector) pushSamples(ctx context.Context, samples []stats.Sample) {
+	batchSize := int(c.config.BatchSize.Int64)
+	for start := 0; start < len(samples); start += batchSize {
+		end := start + batchSize
+		if end > len(samples) {
+			end = len(samples)
+		}
+		if err := c.pushBatch(ctx, samples[start:end]); err != nil {
+			logrus.WithField("url", c.config.URL.String).WithError(err).Error("HTTP: Couldn't push the samples")
+		}
+	}
+}
+
+// pushBatch sends a single batch of samples, retrying if the request fails because of a
+// network error or a server error, which are likely to be temporary. There are no retries
+// once the context is done, so the final push at the end of the test doesn't hold it up.
+func (c *Collector) pushBatch(ctx context.Context, samples []stats.Sample) error {
+	envelopes := make([]*jsonc.Envelope, len(samples))
+	for i := range samples {
+		envelopes[i] = jsonc.WrapSample(&samples[i])
+	}
+	body, err := json.Marshal(envelopes)
+	if err != nil {
+		return err
+	}
+
+	for attempt := int64(0); ; attempt++ {
+		retry, err := c.post(body)
+		if err == nil || !retry || attempt >= c.config.Retries.Int64 {
+			return err
+		}
+		select {
+		case <-time.After(time.Duration(c.config.RetryInterval.Duration)):
+			logrus.WithError(err).Debugf("HTTP: Retrying the push of %d samples", len(samples))
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (c *Collector) post(body []byte) (retry bool, err error) {
+	req, err := http.NewRequest("POST", c.config.URL.String, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "k6/"+consts.Version)
+	for k, v := range c.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("the server responded with status %d", resp.StatusCode)
+	}
+	return false, nil
+}
@@ -0,0 +1,279 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpsink
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+)
+
+type receivedSample struct {
+	Type   string `json:"type"`
+	Metric string `json:"metric"`
+	Data   struct {
+		Time  time.Time         `json:"time"`
+		Value float64           `json:"value"`
+		Tags  map[string]string `json:"tags"`
+	} `json:"data"`
+}
+
+// receiver is a mock HTTP endpoint that records the received batches
+type receiver struct {
+	mu       sync.Mutex
+	batches  [][]receivedSample
+	headers  []http.Header
+	statuses []int // the statuses of the next responses, 200 after they run out
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	var batch []receivedSample
+	if err := json.Unmarshal(body, &batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.headers = append(r.headers, req.Header)
+
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	if status == http.StatusOK {
+		r.batches = append(r.batches, batch)
+	}
+	w.WriteHeader(status)
+}
+
+func newTestCollector(t *testing.T, url string, config Config) *Collector {
+	c, err := New(NewConfig().Apply(Config{
+		URL:           null.StringFrom(url),
+		RetryInterval: types.NullDurationFrom(time.Millisecond),
+	}).Apply(config))
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+	return c
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(NewConfig())
+	assert.EqualError(t, err, "http output needs a URL")
+
+	_, err = New(NewConfig().Apply(Config{
+		URL:       null.StringFrom("http://localhost"),
+		BatchSize: null.IntFrom(0),
+	}))
+	assert.EqualError(t, err, "http output batch size should be positive")
+
+	_, err = New(NewConfig().Apply(Config{
+		URL:          null.StringFrom("http://localhost"),
+		MinBatchSize: null.IntFrom(-1),
+	}))
+	assert.EqualError(t, err, "http output min batch size shouldn't be negative")
+
+	c, err := New(NewConfig().Apply(Config{URL: null.StringFrom("http://localhost")}))
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost", c.Link())
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+	metric := stats.New("my_metric", stats.Counter)
+	now := time.Now()
+	tags := stats.IntoSampleTags(&map[string]string{"tag": "value"})
+	samples := []stats.SampleContainer{
+		stats.Sample{Metric: metric, Time: now, Value: 1, Tags: tags},
+		stats.Samples{
+			{Metric: metric, Time: now, Value: 2, Tags: tags},
+			{Metric: metric, Time: now, Value: 3, Tags: tags},
+		},
+		stats.Sample{Metric: metric, Time: now, Value: 4, Tags: tags},
+		stats.Sample{Metric: metric, Time: now, Value: 5, Tags: tags},
+	}
+
+	t.Run("batches and headers", func(t *testing.T) {
+		t.Parallel()
+		recv := &receiver{}
+		srv := httptest.NewServer(recv)
+		defer srv.Close()
+
+		c := newTestCollector(t, srv.URL, Config{
+			Headers:      map[string]string{"Api-Key": "secret", "X-Source": "k6"},
+			PushInterval: types.NullDurationFrom(time.Hour),
+			BatchSize:    null.IntFrom(2),
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			c.Run(ctx)
+			close(done)
+		}()
+		c.Collect(samples)
+		cancel() // the samples are pushed one final time when the collector is stopped
+		<-done
+
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		require.Len(t, recv.batches, 3)
+		values := []float64{}
+		for i, batch := range recv.batches {
+			assert.Len(t, batch, []int{2, 2, 1}[i])
+			for _, s := range batch {
+				assert.Equal(t, "Point", s.Type)
+				assert.Equal(t, "my_metric", s.Metric)
+				assert.Equal(t, map[string]string{"tag": "value"}, s.Data.Tags)
+				assert.True(t, now.Equal(s.Data.Time))
+				values = append(values, s.Data.Value)
+			}
+		}
+		assert.Equal(t, []float64{1, 2, 3, 4, 5}, values)
+
+		for _, header := range recv.headers {
+			assert.Equal(t, "application/json", header.Get("Content-Type"))
+			assert.Equal(t, "secret", header.Get("Api-Key"))
+			assert.Equal(t, "k6", header.Get("X-Source"))
+			assert.Contains(t, header.Get("User-Agent"), "k6/")
+		}
+	})
+
+	t.Run("periodic pushes", func(t *testing.T) {
+		t.Parallel()
+		recv := &receiver{}
+		srv := httptest.NewServer(recv)
+		defer srv.Close()
+
+		c := newTestCollector(t, srv.URL, Config{PushInterval: types.NullDurationFrom(10 * time.Millisecond)})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.Run(ctx)
+
+		c.Collect(samples[:1])
+		received := func() int {
+			recv.mu.Lock()
+			defer recv.mu.Unlock()
+			return len(recv.batches)
+		}
+		for deadline := time.Now().Add(2 * time.Second); received() == 0 && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+		}
+		assert.Equal(t, 1, received())
+	})
+
+	t.Run("min batch size", func(t *testing.T) {
+		t.Parallel()
+		recv := &receiver{}
+		srv := httptest.NewServer(recv)
+		defer srv.Close()
+
+		c := newTestCollector(t, srv.URL, Config{
+			PushInterval: types.NullDurationFrom(time.Hour),
+			MinBatchSize: null.IntFrom(5),
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go c.Run(ctx)
+
+		received := func() int {
+			recv.mu.Lock()
+			defer recv.mu.Unlock()
+			return len(recv.batches)
+		}
+		c.Collect(samples[:2]) // 3 samples
+		time.Sleep(50 * time.Millisecond)
+		assert.Equal(t, 0, received())
+
+		c.Collect(samples[2:]) // 5 samples in total
+		for deadline := time.Now().Add(2 * time.Second); received() == 0 && time.Now().Before(deadline); {
+			time.Sleep(5 * time.Millisecond)
+		}
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		require.Len(t, recv.batches, 1)
+		assert.Len(t, recv.batches[0], 5)
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		t.Parallel()
+		// Server errors and 429s are retried, but other client errors aren't
+		recv := &receiver{statuses: []int{
+			http.StatusInternalServerError, http.StatusTooManyRequests,
+			http.StatusBadRequest,
+			http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway,
+		}}
+		srv := httptest.NewServer(recv)
+		defer srv.Close()
+
+		c := newTestCollector(t, srv.URL, Config{Retries: null.IntFrom(2)})
+		ctx := context.Background()
+		batch := samples[0].GetSamples()
+		c.pushSamples(ctx, batch) // succeeds on the 3rd attempt
+		c.pushSamples(ctx, batch) // fails without retries
+		c.pushSamples(ctx, batch) // fails after 2 retries
+		c.pushSamples(ctx, batch) // succeeds
+
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		assert.Len(t, recv.batches, 2)
+		assert.Len(t, recv.headers, 8)
+	})
+
+	t.Run("no retries after the context is done", func(t *testing.T) {
+		t.Parallel()
+		recv := &receiver{statuses: []int{
+			http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError,
+		}}
+		srv := httptest.NewServer(recv)
+		defer srv.Close()
+
+		c := newTestCollector(t, srv.URL, Config{
+			Retries:       null.IntFrom(2),
+			RetryInterval: types.NullDurationFrom(time.Hour),
+		})
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		c.pushSamples(ctx, samples[0].GetSamples())
+
+		recv.mu.Lock()
+		defer recv.mu.Unlock()
+		assert.Empty(t, recv.batches)
+		assert.Len(t, recv.headers, 1)
+	})
+}
@@ -0,0 +1,140 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpsink
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+)
+
+// Config is the config for the generic HTTP collector
+type Config struct {
+	URL     null.String       `json:"url" envconfig:"K6_HTTP_URL"`
+	Headers map[string]string `json:"headers" envconfig:"K6_HTTP_HEADERS"`
+
+	PushInterval  types.NullDuration `json:"push_interval" envconfig:"K6_HTTP_PUSH_INTERVAL"`
+	BatchSize     null.Int           `json:"batch_size" envconfig:"K6_HTTP_BATCH_SIZE"`
+	MinBatchSize  null.Int           `json:"min_batch_size" envconfig:"K6_HTTP_MIN_BATCH_SIZE"`
+	Timeout       types.NullDuration `json:"timeout" envconfig:"K6_HTTP_TIMEOUT"`
+	Retries       null.Int           `json:"retries" envconfig:"K6_HTTP_RETRIES"`
+	RetryInterval types.NullDuration `json:"retry_interval" envconfig:"K6_HTTP_RETRY_INTERVAL"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		PushInterval:  types.NullDurationFrom(1 * time.Second),
+		BatchSize:     null.IntFrom(1000),
+		Timeout:       types.NullDurationFrom(10 * time.Second),
+		Retries:       null.IntFrom(3),
+		RetryInterval: types.NullDurationFrom(1 * time.Second),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.URL.Valid {
+		c.URL = cfg.URL
+	}
+	if len(cfg.Headers) > 0 {
+		// Headers are merged, so e.g. an auth header can be supplied separately through an env var
+		headers := make(map[string]string, len(c.Headers)+len(cfg.Headers))
+		for k, v := range c.Headers {
+			headers[k] = v
+		}
+		for k, v := range cfg.Headers {
+			headers[k] = v
+		}
+		c.Headers = headers
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	if cfg.BatchSize.Valid {
+		c.BatchSize = cfg.BatchSize
+	}
+	if cfg.MinBatchSize.Valid {
+		c.MinBatchSize = cfg.MinBatchSize
+	}
+	if cfg.Timeout.Valid {
+		c.Timeout = cfg.Timeout
+	}
+	if cfg.Retries.Valid {
+		c.Retries = cfg.Retries
+	}
+	if cfg.RetryInterval.Valid {
+		c.RetryInterval = cfg.RetryInterval
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config. The arg can either be just the URL
+// or a comma-separated list of key=value pairs, where headers are specified as header.Name=value.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	if strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://") {
+		c.URL = null.StringFrom(arg)
+		return c, nil
+	}
+
+	pairs := strings.Split(arg, ",")
+	for _, pair := range pairs {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for http output", arg)
+		}
+		var err error
+		switch key := r[0]; {
+		case key == "url":
+			c.URL = null.StringFrom(r[1])
+		case strings.HasPrefix(key, "header.") && len(key) > len("header."):
+			if c.Headers == nil {
+				c.Headers = make(map[string]string)
+			}
+			c.Headers[strings.TrimPrefix(key, "header.")] = r[1]
+		case key == "push_interval":
+			err = c.PushInterval.UnmarshalText([]byte(r[1]))
+		case key == "batch_size":
+			err = c.BatchSize.UnmarshalText([]byte(r[1]))
+		case key == "min_batch_size":
+			err = c.MinBatchSize.UnmarshalText([]byte(r[1]))
+		case key == "timeout":
+			err = c.Timeout.UnmarshalText([]byte(r[1]))
+		case key == "retries":
+			err = c.Retries.UnmarshalText([]byte(r[1]))
+		case key == "retry_interval":
+			err = c.RetryInterval.UnmarshalText([]byte(r[1]))
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for http output", key)
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,111 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package httpsink
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+)
+
+func TestNewConfig(t *testing.T) {
+	config := NewConfig()
+	assert.False(t, config.URL.Valid)
+	assert.Equal(t, "1s", config.PushInterval.String())
+	assert.Equal(t, int64(1000), config.BatchSize.Int64)
+	assert.False(t, config.MinBatchSize.Valid)
+	assert.Equal(t, "10s", config.Timeout.String())
+	assert.Equal(t, int64(3), config.Retries.Int64)
+	assert.Equal(t, "1s", config.RetryInterval.String())
+}
+
+func TestApply(t *testing.T) {
+	config := NewConfig().Apply(Config{
+		URL:     null.StringFrom("https://example.com/metrics"),
+		Headers: map[string]string{"X-Source": "k6"},
+	}).Apply(Config{
+		Headers:      map[string]string{"Api-Key": "secret"},
+		PushInterval: types.NewNullDuration(5*time.Second, false),
+		Retries:      null.IntFrom(0),
+		MinBatchSize: null.IntFrom(50),
+	})
+	assert.Equal(t, "https://example.com/metrics", config.URL.String)
+	assert.Equal(t, map[string]string{"X-Source": "k6", "Api-Key": "secret"}, config.Headers)
+	assert.Equal(t, "1s", config.PushInterval.String())
+	assert.True(t, config.Retries.Valid)
+	assert.Equal(t, int64(0), config.Retries.Int64)
+	assert.Equal(t, int64(50), config.MinBatchSize.Int64)
+}
+
+func TestParseArg(t *testing.T) {
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"https://example.com/metrics?key=value": {
+			config: Config{URL: null.StringFrom("https://example.com/metrics?key=value")},
+		},
+		"url=http://localhost:8080,header.Api-Key=secret,header.X-Source=k6": {
+			config: Config{
+				URL:     null.StringFrom("http://localhost:8080"),
+				Headers: map[string]string{"Api-Key": "secret", "X-Source": "k6"},
+			},
+		},
+		"push_interval=5s,batch_size=100,min_batch_size=10,timeout=2s,retries=5,retry_interval=100ms": {
+			config: Config{
+				PushInterval:  types.NullDurationFrom(5 * time.Second),
+				BatchSize:     null.IntFrom(100),
+				MinBatchSize:  null.IntFrom(10),
+				Timeout:       types.NullDurationFrom(2 * time.Second),
+				Retries:       null.IntFrom(5),
+				RetryInterval: types.NullDurationFrom(100 * time.Millisecond),
+			},
+		},
+		"localhost:8080": {
+			expectedErr: true,
+		},
+		"header.=value": {
+			expectedErr: true,
+		},
+		"batch_size=many": {
+			expectedErr: true,
+		},
+	}
+
+	for arg, testCase := range cases {
+		arg := arg
+		testCase := testCase
+
+		t.Run(arg, func(t *testing.T) {
+			config, err := ParseArg(arg)
+			if testCase.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.config, config)
+		})
+	}
+}
@@ -40,8 +40,7 @@ type Collector struct {
 	Config    Config
 	BatchConf client.BatchPointsConfig
 
-	buffer      []stats.Sample
-	bufferLock  sync.Mutex
+	batcher     *stats.SampleBatcher
 	wg          sync.WaitGroup
 	semaphoreCh chan struct{}
 }
@@ -55,10 +54,14 @@ func New(conf Config) (*Collector, error) {
 	if conf.ConcurrentWrites.Int64 <= 0 {
 		return nil, errors.New("influxdb's ConcurrentWrites must be a positive number")
 	}
+	if conf.MinBatchSize.Int64 < 0 {
+		return nil, errors.New("influxdb's MinBatchSize can't be negative")
+	}
 	return &Collector{
 		Client:      cl,
 		Config:      conf,
 		BatchConf:   batchConf,
+		batcher:     stats.NewSampleBatcher(int(conf.MinBatchSize.Int64), time.Duration(conf.PushInterval.Duration)),
 		semaphoreCh: make(chan struct{}, conf.ConcurrentWrites.Int64),
 	}, nil
 }
@@ -76,40 +79,24 @@ func (c *Collector) Init() error {
 
 func (c *Collector) Run(ctx context.Context) {
 	logrus.Debug("InfluxDB: Running!")
-	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
-	for {
-		select {
-		case <-ticker.C:
-			c.wg.Add(1)
-			go c.commit()
-		case <-ctx.Done():
-			c.wg.Add(1)
-			go c.commit()
-			c.wg.Wait()
-			return
-		}
-	}
+	c.batcher.Run(ctx, func(samples []stats.Sample) {
+		c.wg.Add(1)
+		go c.commit(samples)
+	})
+	c.wg.Wait()
 }
 
 func (c *Collector) Collect(scs []stats.SampleContainer) {
-	c.bufferLock.Lock()
-	defer c.bufferLock.Unlock()
-	for _, sc := range scs {
-		c.buffer = append(c.buffer, sc.GetSamples()...)
-	}
+	c.batcher.Add(scs)
 }
 
 func (c *Collector) Link() string {
 	return c.Config.Addr.String
 }
 
-func (c *Collector) commit() {
+func (c *Collector) commit(samples []stats.Sample) {
 	defer c.wg.Done()
-	c.bufferLock.Lock()
-	samples := c.buffer
-	c.buffer = nil
-	c.bufferLock.Unlock()
-	// let first get the data and then wait our turn
+	// wait our turn
 	c.semaphoreCh <- struct{}{}
 	defer func() {
 		<-c.semaphoreCh
@@ -4,12 +4,15 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 	"github.com/stretchr/testify/require"
 	null "gopkg.in/guregu/null.v3"
@@ -38,6 +41,54 @@ func TestBadConcurrentWrites(t *testing.T) {
 	})
 }
 
+func TestMinBatchSize(t *testing.T) {
+	c := NewConfig()
+	c.MinBatchSize = null.IntFrom(-1)
+	_, err := New(*c)
+	require.EqualError(t, err, "influxdb's MinBatchSize can't be negative")
+
+	written := make(chan int, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		written <- len(bytes.Split(bytes.TrimSpace(b), []byte("\n")))
+		rw.WriteHeader(204)
+	}))
+	defer srv.Close()
+
+	// The batch is written as soon as it's big enough, long before the push interval
+	c.Addr = null.StringFrom(srv.URL)
+	c.MinBatchSize = null.IntFrom(5)
+	c.PushInterval = types.NullDurationFrom(time.Hour)
+	collector, err := New(*c)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		collector.Run(ctx)
+		close(done)
+	}()
+
+	sample := stats.Sample{Metric: stats.New("testGauge", stats.Gauge), Time: time.Now(), Value: 1}
+	collector.Collect([]stats.SampleContainer{stats.Samples{sample, sample, sample}})
+	select {
+	case <-written:
+		t.Fatal("the batch was written before it was big enough")
+	case <-time.After(200 * time.Millisecond):
+	}
+	collector.Collect([]stats.SampleContainer{stats.Samples{sample, sample}})
+	select {
+	case n := <-written:
+		require.Equal(t, 5, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the batch wasn't written")
+	}
+
+	collector.Collect([]stats.SampleContainer{sample})
+	cancel()
+	<-done
+	require.Equal(t, 1, <-written, "the rest should be written at the end")
+}
+
 func testCollectorCycle(t testing.TB, handler http.HandlerFunc, body func(testing.TB, *Collector)) {
 	s := &http.Server{
 		Addr:           ":",
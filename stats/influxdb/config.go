@@ -43,6 +43,10 @@ type Config struct {
 	PushInterval     types.NullDuration `json:"pushInterval,omitempty" envconfig:"K6_INFLUXDB_PUSH_INTERVAL"`
 	ConcurrentWrites null.Int           `json:"concurrentWrites,omitempty" envconfig:"K6_INFLUXDB_CONCURRENT_WRITES"`
 
+	// The samples are written once there are at least MinBatchSize of them, or once the oldest
+	// of them was collected PushInterval ago. Zero means that they're only written on age.
+	MinBatchSize null.Int `json:"minBatchSize,omitempty" envconfig:"K6_INFLUXDB_MIN_BATCH_SIZE"`
+
 	// Samples.
 	DB           null.String `json:"db" envconfig:"K6_INFLUXDB_DB"`
 	Precision    null.String `json:"precision,omitempty" envconfig:"K6_INFLUXDB_PRECISION"`
@@ -100,6 +104,9 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.ConcurrentWrites.Valid {
 		c.ConcurrentWrites = cfg.ConcurrentWrites
 	}
+	if cfg.MinBatchSize.Valid {
+		c.MinBatchSize = cfg.MinBatchSize
+	}
 	return c
 }
 
@@ -189,6 +196,13 @@ func ParseURL(text string) (Config, error) {
 				return c, err
 			}
 			c.ConcurrentWrites = null.IntFrom(int64(writes))
+		case "minBatchSize":
+			var size int
+			size, err = strconv.Atoi(vs[0])
+			if err != nil {
+				return c, err
+			}
+			c.MinBatchSize = null.IntFrom(int64(size))
 		case "tagsAsFields":
 			c.TagsAsFields = vs
 		default:
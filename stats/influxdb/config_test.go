@@ -59,12 +59,13 @@ func TestParseURL(t *testing.T) {
 		Config Config
 		Err    string
 	}{
-		"?":                {Config{}, ""},
-		"?insecure=false":  {Config{Insecure: null.BoolFrom(false)}, ""},
-		"?insecure=true":   {Config{Insecure: null.BoolFrom(true)}, ""},
-		"?insecure=ture":   {Config{}, "insecure must be true or false, not ture"},
-		"?payload_size=69": {Config{PayloadSize: null.IntFrom(69)}, ""},
-		"?payload_size=a":  {Config{}, "strconv.Atoi: parsing \"a\": invalid syntax"},
+		"?":                 {Config{}, ""},
+		"?insecure=false":   {Config{Insecure: null.BoolFrom(false)}, ""},
+		"?insecure=true":    {Config{Insecure: null.BoolFrom(true)}, ""},
+		"?insecure=ture":    {Config{}, "insecure must be true or false, not ture"},
+		"?payload_size=69":  {Config{PayloadSize: null.IntFrom(69)}, ""},
+		"?payload_size=a":   {Config{}, "strconv.Atoi: parsing \"a\": invalid syntax"},
+		"?minBatchSize=500": {Config{MinBatchSize: null.IntFrom(500)}, ""},
 	}
 	for str, data := range testdata {
 		t.Run(str, func(t *testing.T) {
@@ -24,6 +24,7 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"strings"
 	"sync"
@@ -43,12 +44,13 @@ type Collector struct {
 
 	encoder *json.Encoder
 
-	buffer     []stats.Sample
-	bufferLock sync.Mutex
+	batcher    *stats.SampleBatcher
+	events     []*stats.Event
+	eventsLock sync.Mutex
 }
 
-// Verify that Collector implements lib.Collector
-var _ lib.Collector = &Collector{}
+// Verify that Collector implements lib.EventCollector
+var _ lib.EventCollector = &Collector{}
 
 func (c *Collector) HasSeenMetric(str string) bool {
 	for _, n := range c.seenMetrics {
@@ -59,9 +61,14 @@ func (c *Collector) HasSeenMetric(str string) bool {
 	return false
 }
 
-func New(fs afero.Fs, fname string) (*Collector, error) {
+func New(fs afero.Fs, config Config) (*Collector, error) {
+	if config.MinBatchSize.Int64 < 0 {
+		return nil, errors.New("json output min batch size can't be negative")
+	}
+	fname := config.FileName.String
 	var c = &Collector{
-		fname: fname,
+		fname:   fname,
+		batcher: stats.NewSampleBatcher(int(config.MinBatchSize.Int64), time.Duration(config.PushInterval.Duration)),
 	}
 	if fname == "" || fname == "-" {
 		c.encoder = json.NewEncoder(os.Stdout)
@@ -99,19 +106,11 @@ func (c *Collector) SetRunStatus(status lib.RunStatus) {}
 
 func (c *Collector) Run(ctx context.Context) {
 	logrus.Debug("JSON output: Running!")
-	ticker := time.NewTicker(time.Millisecond * 100)
 	defer func() {
 		_ = c.closeFn()
 	}()
-	for {
-		select {
-		case <-ticker.C:
-			c.commit()
-		case <-ctx.Done():
-			c.commit()
-			return
-		}
-	}
+	c.batcher.Run(ctx, c.commit)
+	c.commit(nil) // events that were collected after the last samples
 }
 
 func (c *Collector) HandleMetric(m *stats.Metric) {
@@ -130,18 +129,27 @@ func (c *Collector) HandleMetric(m *stats.Metric) {
 }
 
 func (c *Collector) Collect(scs []stats.SampleContainer) {
-	c.bufferLock.Lock()
-	defer c.bufferLock.Unlock()
-	for _, sc := range scs {
-		c.buffer = append(c.buffer, sc.GetSamples()...)
-	}
+	c.batcher.Add(scs)
 }
 
-func (c *Collector) commit() {
-	c.bufferLock.Lock()
-	samples := c.buffer
-	c.buffer = nil
-	c.bufferLock.Unlock()
+// CollectEvents buffers the events, which are written along with the samples
+func (c *Collector) CollectEvents(events []*stats.Event) {
+	c.eventsLock.Lock()
+	defer c.eventsLock.Unlock()
+	c.events = append(c.events, events...)
+}
+
+func (c *Collector) commit(samples []stats.Sample) {
+	c.eventsLock.Lock()
+	events := c.events
+	c.events = nil
+	c.eventsLock.Unlock()
+	for _, event := range events {
+		if err := c.encoder.Encode(WrapEvent(event)); err != nil {
+			logrus.WithField("filename", c.fname).WithError(err).Warning(
+				"JSON: Event couldn't be marshalled to JSON")
+		}
+	}
 	logrus.WithField("filename", c.fname).Debug("JSON: Writing JSON metrics")
 	var start = time.Now()
 	var count int
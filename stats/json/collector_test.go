@@ -21,11 +21,19 @@
 package json
 
 import (
+	"bytes"
+	"context"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
 )
 
 func TestNew(t *testing.T) {
@@ -39,7 +47,7 @@ func TestNew(t *testing.T) {
 		t.Run("path="+path, func(t *testing.T) {
 			defer func() { _ = os.Remove(path) }()
 
-			collector, err := New(afero.NewOsFs(), path)
+			collector, err := New(afero.NewOsFs(), Config{FileName: null.StringFrom(path)})
 			if succ {
 				assert.NoError(t, err)
 				assert.NotNil(t, collector)
@@ -50,3 +58,70 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+func TestMinBatchSize(t *testing.T) {
+	_, err := New(afero.NewMemMapFs(), Config{FileName: null.StringFrom("out.json"), MinBatchSize: null.IntFrom(-1)})
+	assert.EqualError(t, err, "json output min batch size can't be negative")
+
+	fs := afero.NewMemMapFs()
+	collector, err := New(fs, Config{
+		FileName:     null.StringFrom("out.json"),
+		PushInterval: types.NullDurationFrom(time.Hour),
+		MinBatchSize: null.IntFrom(2),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		collector.Run(ctx)
+		close(done)
+	}()
+	countPoints := func() int {
+		data, _ := afero.ReadFile(fs, "out.json")
+		return bytes.Count(data, []byte(`"type":"Point"`))
+	}
+
+	sample := stats.Sample{Metric: stats.New("my_metric", stats.Gauge), Time: time.Now(), Value: 1}
+	collector.Collect([]stats.SampleContainer{sample})
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, countPoints(), "a single sample shouldn't be written before the push interval")
+
+	// Reaching the min batch size writes the samples long before the push interval
+	collector.Collect([]stats.SampleContainer{sample})
+	for start := time.Now(); countPoints() < 2 && time.Since(start) < 5*time.Second; {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 2, countPoints())
+
+	collector.CollectEvents([]*stats.Event{{Name: "deployment"}})
+	collector.Collect([]stats.SampleContainer{sample})
+	cancel()
+	<-done
+	data, err := afero.ReadFile(fs, "out.json")
+	require.NoError(t, err)
+	assert.Equal(t, 3, bytes.Count(data, []byte(`"type":"Point"`)))
+	assert.Equal(t, 1, bytes.Count(data, []byte(`"type":"Event"`)))
+}
+
+func TestParseArg(t *testing.T) {
+	c, err := ParseArg("results.json")
+	require.NoError(t, err)
+	assert.Equal(t, Config{FileName: null.StringFrom("results.json")}, c)
+
+	c, err = ParseArg("")
+	require.NoError(t, err)
+	assert.Equal(t, Config{}, c)
+
+	c, err = ParseArg("file_name=results.json,push_interval=2s,min_batch_size=1000")
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		FileName:     null.StringFrom("results.json"),
+		PushInterval: types.NullDurationFrom(2 * time.Second),
+		MinBatchSize: null.IntFrom(1000),
+	}, c)
+
+	_, err = ParseArg("file_name=results.json,batch=1000")
+	assert.EqualError(t, err, `unknown key "batch" as argument for json output`)
+	_, err = ParseArg("min_batch_size=many")
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package json
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+)
+
+// Config is the config for the JSON output
+type Config struct {
+	// The file the samples are written to, or stdout if it's empty or "-"
+	FileName null.String `json:"file_name" envconfig:"K6_JSON_FILENAME"`
+
+	// The samples are written once there are at least MinBatchSize of them, or once the oldest
+	// of them was collected PushInterval ago. Zero means that they're only written on age.
+	PushInterval types.NullDuration `json:"push_interval" envconfig:"K6_JSON_PUSH_INTERVAL"`
+	MinBatchSize null.Int           `json:"min_batch_size" envconfig:"K6_JSON_MIN_BATCH_SIZE"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		PushInterval: types.NullDurationFrom(100 * time.Millisecond),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.FileName.Valid {
+		c.FileName = cfg.FileName
+	}
+	if cfg.PushInterval.Valid {
+		c.PushInterval = cfg.PushInterval
+	}
+	if cfg.MinBatchSize.Valid {
+		c.MinBatchSize = cfg.MinBatchSize
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config. An argument without any keys, e.g.
+// `-o json=results.json`, is just the file name.
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+	if !strings.Contains(arg, "=") {
+		if arg != "" {
+			c.FileName = null.StringFrom(arg)
+		}
+		return c, nil
+	}
+
+	for _, pair := range strings.Split(arg, ",") {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for json output", arg)
+		}
+		var err error
+		switch r[0] {
+		case "file_name":
+			c.FileName = null.StringFrom(r[1])
+		case "push_interval":
+			err = c.PushInterval.UnmarshalText([]byte(r[1]))
+		case "min_batch_size":
+			err = c.MinBatchSize.UnmarshalText([]byte(r[1]))
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for json output", r[0])
+		}
+		if err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
@@ -68,3 +68,14 @@ func WrapMetric(metric *stats.Metric) *Envelope {
 		Data:   metric,
 	}
 }
+
+// WrapEvent returns an envelope for the event; it has no metric, the name is in the data
+func WrapEvent(event *stats.Event) *Envelope {
+	if event == nil {
+		return nil
+	}
+	return &Envelope{
+		Type: "Event",
+		Data: event,
+	}
+}
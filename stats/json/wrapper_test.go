@@ -21,6 +21,7 @@
 package json
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/loadimpact/k6/stats"
@@ -32,6 +33,8 @@ func TestWrapersWithNilArg(t *testing.T) {
 	assert.Equal(t, out, (*Envelope)(nil))
 	out = WrapMetric(nil)
 	assert.Equal(t, out, (*Envelope)(nil))
+	out = WrapEvent(nil)
+	assert.Equal(t, out, (*Envelope)(nil))
 }
 
 func TestWrapSampleWithSamplePointer(t *testing.T) {
@@ -45,3 +48,13 @@ func TestWrapMetricWithMetricPointer(t *testing.T) {
 	out := WrapMetric(&stats.Metric{})
 	assert.NotEqual(t, out, (*Envelope)(nil))
 }
+
+func TestWrapEvent(t *testing.T) {
+	out := WrapEvent(&stats.Event{Name: "deployment", Data: map[string]interface{}{"version": "1.2"}})
+	data, err := json.Marshal(out)
+	assert.NoError(t, err)
+	assert.JSONEq(t,
+		`{"type":"Event","data":{"time":"0001-01-01T00:00:00Z","name":"deployment","tags":null,"data":{"version":"1.2"}}}`,
+		string(data),
+	)
+}
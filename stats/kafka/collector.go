@@ -23,7 +23,7 @@ package kafka
 import (
 	"context"
 	"encoding/json"
-	"sync"
+	"errors"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -40,12 +40,14 @@ type Collector struct {
 	Producer sarama.SyncProducer
 	Config   Config
 
-	Samples []stats.Sample
-	lock    sync.Mutex
+	batcher *stats.SampleBatcher
 }
 
 // New creates an instance of the collector
 func New(conf Config) (*Collector, error) {
+	if conf.MinBatchSize.Int64 < 0 {
+		return nil, errors.New("kafka output min batch size can't be negative")
+	}
 	producer, err := sarama.NewSyncProducer(conf.Brokers, nil)
 	if err != nil {
 		return nil, err
@@ -54,44 +56,30 @@ func New(conf Config) (*Collector, error) {
 	return &Collector{
 		Producer: producer,
 		Config:   conf,
+		batcher:  stats.NewSampleBatcher(int(conf.MinBatchSize.Int64), time.Duration(conf.PushInterval.Duration)),
 	}, nil
 }
 
 // Init does nothing, it's only included to satisfy the lib.Collector interface
 func (c *Collector) Init() error { return nil }
 
-// Run just blocks until the context is done
+// Run sends the samples once there are at least min_batch_size of them, or once the oldest of
+// them has been buffered for the push interval, until the context is done
 func (c *Collector) Run(ctx context.Context) {
 	logrus.Debug("Kafka: Running!")
-	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
-	for {
-		select {
-		case <-ticker.C:
-			c.pushMetrics()
-		case <-ctx.Done():
-			c.pushMetrics()
-
-			err := c.Producer.Close()
-			if err != nil {
-				logrus.WithError(err).Error("Kafka: Failed to close producer.")
-			}
-			return
-		}
+	c.batcher.Run(ctx, c.pushMetrics)
+
+	err := c.Producer.Close()
+	if err != nil {
+		logrus.WithError(err).Error("Kafka: Failed to close producer.")
 	}
 }
 
-// Collect just appends all of the samples passed to it to the internal sample slice.
-// According to the the lib.Collector interface, it should never be called concurrently,
-// so there's no locking on purpose - that way Go's race condition detector can actually
-// detect incorrect usage.
-// Also, theoretically the collector doesn't have to actually Run() before samples start
+// Collect buffers the samples until they're sent.
+// Theoretically the collector doesn't have to actually Run() before samples start
 // being collected, it only has to be initialized.
 func (c *Collector) Collect(scs []stats.SampleContainer) {
-	c.lock.Lock()
-	for _, sc := range scs {
-		c.Samples = append(c.Samples, sc.GetSamples()...)
-	}
-	c.lock.Unlock()
+	c.batcher.Add(scs)
 }
 
 // Link returns a dummy string, it's only included to satisfy the lib.Collector interface
@@ -136,14 +124,9 @@ func (c *Collector) formatSamples(samples stats.Samples) ([]string, error) {
 	return metrics, nil
 }
 
-func (c *Collector) pushMetrics() {
+func (c *Collector) pushMetrics(samples []stats.Sample) {
 	startTime := time.Now()
 
-	c.lock.Lock()
-	samples := c.Samples
-	c.Samples = nil
-	c.lock.Unlock()
-
 	// Format the samples
 	formattedSamples, err := c.formatSamples(samples)
 	if err != nil {
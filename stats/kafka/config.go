@@ -21,6 +21,7 @@
 package kafka
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/kubernetes/helm/pkg/strvals"
@@ -40,6 +41,10 @@ type Config struct {
 	Format       null.String        `json:"format" envconfig:"K6_KAFKA_FORMAT"`
 	PushInterval types.NullDuration `json:"push_interval" envconfig:"K6_KAFKA_PUSH_INTERVAL"`
 
+	// The samples are sent once there are at least MinBatchSize of them, or once the oldest of
+	// them was collected PushInterval ago. Zero means that they're only sent on age.
+	MinBatchSize null.Int `json:"min_batch_size" envconfig:"K6_KAFKA_MIN_BATCH_SIZE"`
+
 	InfluxDBConfig influxdb.Config `json:"influxdb"`
 }
 
@@ -75,6 +80,9 @@ func (c Config) Apply(cfg Config) Config {
 	if cfg.PushInterval.Valid {
 		c.PushInterval = cfg.PushInterval
 	}
+	if cfg.MinBatchSize.Valid {
+		c.MinBatchSize = cfg.MinBatchSize
+	}
 	return c
 }
 
@@ -107,6 +115,14 @@ func ParseArg(arg string) (Config, error) {
 		}
 	}
 
+	if v, ok := params["min_batch_size"]; ok {
+		err := c.MinBatchSize.UnmarshalText([]byte(fmt.Sprint(v)))
+		if err != nil {
+			return c, err
+		}
+	}
+	delete(params, "min_batch_size")
+
 	var cfg config
 	err = mapstructure.Decode(params, &cfg)
 	if err != nil {
@@ -42,6 +42,12 @@ func TestConfigParseArg(t *testing.T) {
 	assert.Equal(t, []string{"broker2", "broker3:9092"}, c.Brokers)
 	assert.Equal(t, null.StringFrom("someTopic2"), c.Topic)
 	assert.Equal(t, null.StringFrom("json"), c.Format)
+	assert.False(t, c.MinBatchSize.Valid)
+
+	c, err = ParseArg("brokers=broker1,topic=someTopic,min_batch_size=500")
+	assert.Nil(t, err)
+	assert.Equal(t, null.IntFrom(500), c.MinBatchSize)
+	assert.Equal(t, null.StringFrom("someTopic"), c.Topic)
 
 	c, err = ParseArg("brokers={broker2,broker3:9092},topic=someTopic,format=influxdb,influxdb.tagsAsFields=fake")
 	expInfluxConfig = influxdb.Config{
@@ -0,0 +1,98 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// MannWhitneyResult is the result of a Mann-Whitney U test of a current sample of values, e.g.
+// request durations, against a baseline one.
+type MannWhitneyResult struct {
+	// U is the U statistic of the current sample, i.e. the number of (baseline, current) pairs
+	// in which the current value is higher, with ties counting as half.
+	U float64
+	Z float64
+
+	// PValue is the two-sided p-value, i.e. the probability of seeing a difference at least as
+	// big if both samples came from the same distribution.
+	PValue float64
+
+	// EffectSize is the rank-biserial correlation, from -1 to 1. It's positive if the current
+	// values tend to be higher than the baseline ones, and negative if they tend to be lower.
+	EffectSize float64
+}
+
+// MannWhitneyU runs a Mann-Whitney U test, which checks whether the values of one sample tend to
+// be higher or lower than the values of another one, without assuming anything about their
+// distributions. The p-value is calculated with the normal approximation, corrected for ties and
+// continuity, so it's only accurate for samples of more than a few values each.
+func MannWhitneyU(baseline, current []float64) (MannWhitneyResult, error) {
+	n1, n2 := float64(len(baseline)), float64(len(current))
+	if n1 == 0 || n2 == 0 {
+		return MannWhitneyResult{}, errors.New("both samples need at least one value")
+	}
+
+	type rankedValue struct {
+		value     float64
+		isCurrent bool
+	}
+	values := make([]rankedValue, 0, len(baseline)+len(current))
+	for _, v := range baseline {
+		values = append(values, rankedValue{v, false})
+	}
+	for _, v := range current {
+		values = append(values, rankedValue{v, true})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].value < values[j].value })
+
+	// Tied values get the average of their ranks, and the ties reduce the variance of U
+	var currentRanks, tieCorrection float64
+	for i := 0; i < len(values); {
+		j := i + 1
+		for j < len(values) && values[j].value == values[i].value {
+			j++
+		}
+		rank := float64(i+j+1) / 2 // the average of the 1-based ranks i+1 to j
+		for _, v := range values[i:j] {
+			if v.isCurrent {
+				currentRanks += rank
+			}
+		}
+		t := float64(j - i)
+		tieCorrection += t*t*t - t
+		i = j
+	}
+
+	n := n1 + n2
+	res := MannWhitneyResult{U: currentRanks - n2*(n2+1)/2, PValue: 1}
+	res.EffectSize = 2*res.U/(n1*n2) - 1
+
+	mean := n1 * n2 / 2
+	stddev := math.Sqrt(n1 * n2 / 12 * (n + 1 - tieCorrection/(n*(n-1))))
+	if diff := res.U - mean; diff != 0 && stddev > 0 {
+		res.Z = (diff - math.Copysign(0.5, diff)) / stddev
+		res.PValue = math.Erfc(math.Abs(res.Z) / math.Sqrt2)
+	}
+	return res, nil
+}
@@ -0,0 +1,105 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMannWhitneyU(t *testing.T) {
+	t.Run("no values", func(t *testing.T) {
+		_, err := MannWhitneyU(nil, []float64{1})
+		assert.Error(t, err)
+		_, err = MannWhitneyU([]float64{1}, []float64{})
+		assert.Error(t, err)
+	})
+
+	// The expected values are those of the normal approximation, with the tie and continuity corrections
+	t.Run("higher", func(t *testing.T) {
+		res, err := MannWhitneyU([]float64{1, 2, 3, 4, 5}, []float64{6, 7, 8, 9, 10})
+		require.NoError(t, err)
+		assert.Equal(t, 25.0, res.U)
+		assert.InDelta(t, 2.5067, res.Z, 0.0001)
+		assert.InDelta(t, 0.012186, res.PValue, 0.000001)
+		assert.Equal(t, 1.0, res.EffectSize)
+	})
+	t.Run("lower", func(t *testing.T) {
+		res, err := MannWhitneyU([]float64{6, 7, 8, 9, 10}, []float64{1, 2, 3, 4, 5})
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, res.U)
+		assert.InDelta(t, -2.5067, res.Z, 0.0001)
+		assert.InDelta(t, 0.012186, res.PValue, 0.000001)
+		assert.Equal(t, -1.0, res.EffectSize)
+	})
+	t.Run("ties", func(t *testing.T) {
+		res, err := MannWhitneyU([]float64{1, 2, 2, 3, 4}, []float64{2, 3, 3, 4, 5, 6})
+		require.NoError(t, err)
+		assert.Equal(t, 23.5, res.U)
+		assert.InDelta(t, 1.4914, res.Z, 0.0001)
+		assert.InDelta(t, 0.135852, res.PValue, 0.000001)
+		assert.InDelta(t, 0.5667, res.EffectSize, 0.0001)
+	})
+	t.Run("identical", func(t *testing.T) {
+		res, err := MannWhitneyU([]float64{10, 20, 30}, []float64{30, 20, 10})
+		require.NoError(t, err)
+		assert.Equal(t, 4.5, res.U)
+		assert.Equal(t, 0.0, res.Z)
+		assert.Equal(t, 1.0, res.PValue)
+		assert.Equal(t, 0.0, res.EffectSize)
+	})
+	t.Run("all tied", func(t *testing.T) {
+		res, err := MannWhitneyU([]float64{5}, []float64{5, 5})
+		require.NoError(t, err)
+		assert.Equal(t, 1.0, res.PValue)
+		assert.Equal(t, 0.0, res.EffectSize)
+	})
+
+	t.Run("distributions", func(t *testing.T) {
+		r := rand.New(rand.NewSource(1))
+		sample := func(mean float64) []float64 {
+			values := make([]float64, 500)
+			for i := range values {
+				values[i] = mean + r.NormFloat64()*10
+			}
+			return values
+		}
+		baseline := sample(100)
+
+		res, err := MannWhitneyU(baseline, sample(100))
+		require.NoError(t, err)
+		assert.True(t, res.PValue > 0.05, "same distribution, p=%f", res.PValue)
+		assert.InDelta(t, 0, res.EffectSize, 0.1)
+
+		res, err = MannWhitneyU(baseline, sample(105))
+		require.NoError(t, err)
+		assert.True(t, res.PValue < 0.001, "slower distribution, p=%f", res.PValue)
+		assert.True(t, res.EffectSize > 0)
+
+		res, err = MannWhitneyU(baseline, sample(95))
+		require.NoError(t, err)
+		assert.True(t, res.PValue < 0.001, "faster distribution, p=%f", res.PValue)
+		assert.True(t, res.EffectSize < 0)
+	})
+}
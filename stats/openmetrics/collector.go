@@ -0,0 +1,268 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package openmetrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+)
+
+// The quantiles that are exposed for trend metrics
+var summaryQuantiles = []float64{0.5, 0.9, 0.95, 0.99}
+
+// Collector periodically writes the aggregated values of all metrics to a file, in the OpenMetrics
+// text exposition format. The file is replaced atomically, so it can be scraped at any time, e.g.
+// by the textfile collector of the Prometheus node_exporter. Metrics are aggregated over the whole
+// test run and without their tags, the same way they're shown in the end-of-test summary.
+type Collector struct {
+	fs            afero.Fs
+	fname         string
+	namespace     string
+	writeInterval time.Duration
+
+	buffer     []stats.SampleContainer
+	bufferLock sync.Mutex
+
+	metrics map[string]*stats.Metric
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new instance of the OpenMetrics file collector
+func New(fs afero.Fs, config Config) (*Collector, error) {
+	if config.FileName.String == "" {
+		return nil, errors.New("openmetrics output needs a file name")
+	}
+	if config.WriteInterval.Duration <= 0 {
+		return nil, errors.New("openmetrics write interval should be positive")
+	}
+	return &Collector{
+		fs:            fs,
+		fname:         config.FileName.String,
+		namespace:     config.Namespace.String,
+		writeInterval: time.Duration(config.WriteInterval.Duration),
+		metrics:       make(map[string]*stats.Metric),
+	}, nil
+}
+
+// Init writes an empty metrics file, to make sure that it can be written at all
+func (c *Collector) Init() error {
+	return c.writeFile()
+}
+
+// SetRunStatus does nothing
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run periodically aggregates the collected samples and rewrites the metrics file
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.writeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.aggregateAndWrite()
+		case <-ctx.Done():
+			c.aggregateAndWrite()
+			return
+		}
+	}
+}
+
+// Collect saves samples to the buffer
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	c.buffer = append(c.buffer, scs...)
+}
+
+// Link returns the path of the metrics file
+func (c *Collector) Link() string {
+	return c.fname
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() stats.SystemTagSet {
+	return stats.SystemTagSet(0) // There are no required tags for this collector
+}
+
+func (c *Collector) aggregateAndWrite() {
+	c.bufferLock.Lock()
+	buffer := c.buffer
+	c.buffer = nil
+	c.bufferLock.Unlock()
+
+	for _, sc := range buffer {
+		for _, sample := range sc.GetSamples() {
+			m, ok := c.metrics[sample.Metric.Name]
+			if !ok {
+				m = stats.New(sample.Metric.Name, sample.Metric.Type, sample.Metric.Contains)
+				if m.Type == stats.Trend {
+					// The trends are aggregated over the whole run, so their percentiles are
+					// estimated instead of keeping all of their values in memory
+					m.Sink = stats.NewTDigestSink(stats.DefaultTDigestCompression)
+				}
+				c.metrics[m.Name] = m
+			}
+			m.Sink.Add(sample)
+		}
+	}
+
+	if err := c.writeFile(); err != nil {
+		logrus.WithField("filename", c.fname).WithError(err).Error("OpenMetrics: Error writing the file")
+	}
+}
+
+// writeFile writes the metrics to a temporary file first and then renames it, so that scrapers
+// never see a partially written file.
+func (c *Collector) writeFile() error {
+	metrics := make([]*stats.Metric, 0, len(c.metrics))
+	for _, m := range c.metrics {
+		metrics = append(metrics, m)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteMetrics(&buf, c.namespace, metrics); err != nil {
+		return err
+	}
+
+	tmpName := c.fname + ".tmp"
+	if err := afero.WriteFile(c.fs, tmpName, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return c.fs.Rename(tmpName, c.fname)
+}
+
+// WriteMetrics writes the current values of the given metrics in the OpenMetrics text format,
+// prefixing their names with the namespace. Counters are exposed as counters, gauges and rates
+// as gauges and trends as summaries. Time values are converted to seconds, as is customary.
+func WriteMetrics(w io.Writer, namespace string, metrics []*stats.Metric) error {
+	sorted := make([]*stats.Metric, len(metrics))
+	copy(sorted, metrics)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var buf bytes.Buffer
+	for _, m := range sorted {
+		writeMetric(&buf, namespace, m)
+	}
+	buf.WriteString("# EOF\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeMetric(buf *bytes.Buffer, namespace string, m *stats.Metric) {
+	name := sanitizeName(namespace + m.Name)
+	unit, divisor := "", 1.0
+	switch m.Contains {
+	case stats.Time:
+		unit, divisor = "seconds", 1000 // k6 measures time in milliseconds
+	case stats.Data:
+		unit = "bytes"
+	}
+	if unit != "" {
+		name += "_" + unit
+	}
+
+	var typ, kind string
+	var lines []string
+	switch sink := m.Sink.(type) {
+	case *stats.CounterSink:
+		typ, kind = "counter", "counter"
+		lines = append(lines, name+"_total "+formatValue(sink.Value/divisor))
+	case *stats.GaugeSink:
+		typ, kind = "gauge", "gauge"
+		lines = append(lines, name+" "+formatValue(sink.Value/divisor))
+	case *stats.RateSink:
+		typ, kind = "gauge", "rate"
+		rate := 0.0
+		if sink.Total > 0 {
+			rate = float64(sink.Trues) / float64(sink.Total)
+		}
+		lines = append(lines, name+" "+formatValue(rate))
+	case *stats.TrendSink:
+		typ, kind = "summary", "trend"
+		lines = summaryLines(name, divisor, sink.P, sink.Sum, sink.Count)
+	case *stats.TDigestSink:
+		typ, kind = "summary", "trend"
+		lines = summaryLines(name, divisor, sink.P, sink.Sum, sink.Count)
+	default:
+		return
+	}
+
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, typ)
+	if unit != "" {
+		fmt.Fprintf(buf, "# UNIT %s %s\n", name, unit)
+	}
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, escapeHelp(fmt.Sprintf("k6 %s metric %s", kind, m.Name)))
+	for _, line := range lines {
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+}
+
+// summaryLines returns the quantile, sum and count lines of a trend, with the percentiles
+// from the given function.
+func summaryLines(name string, divisor float64, p func(float64) float64, sum float64, count uint64) []string {
+	lines := make([]string, 0, len(summaryQuantiles)+2)
+	for _, q := range summaryQuantiles {
+		lines = append(lines, fmt.Sprintf(`%s{quantile="%s"} %s`, name, formatValue(q), formatValue(p(q)/divisor)))
+	}
+	return append(lines,
+		name+"_sum "+formatValue(sum/divisor),
+		name+"_count "+strconv.FormatUint(count, 10),
+	)
+}
+
+// sanitizeName replaces all characters that aren't allowed in OpenMetrics metric names
+func sanitizeName(name string) string {
+	sanitized := []byte(name)
+	for i, ch := range sanitized {
+		valid := ch == '_' || ch == ':' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
+			(i > 0 && ch >= '0' && ch <= '9')
+		if !valid {
+			sanitized[i] = '_'
+		}
+	}
+	return string(sanitized)
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
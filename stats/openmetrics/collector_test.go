@@ -0,0 +1,183 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package openmetrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+)
+
+func addSamples(m *stats.Metric, values ...float64) *stats.Metric {
+	for _, v := range values {
+		m.Sink.Add(stats.Sample{Metric: m, Value: v})
+	}
+	return m
+}
+
+func TestWriteMetrics(t *testing.T) {
+	t.Run("counter", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteMetrics(&buf, "k6_", []*stats.Metric{
+			addSamples(stats.New("http_reqs", stats.Counter), 1, 1, 1),
+			addSamples(stats.New("data_sent", stats.Counter, stats.Data), 100, 28),
+		}))
+		assert.Equal(t, ""+
+			"# TYPE k6_data_sent_bytes counter\n"+
+			"# UNIT k6_data_sent_bytes bytes\n"+
+			"# HELP k6_data_sent_bytes k6 counter metric data_sent\n"+
+			"k6_data_sent_bytes_total 128\n"+
+			"# TYPE k6_http_reqs counter\n"+
+			"# HELP k6_http_reqs k6 counter metric http_reqs\n"+
+			"k6_http_reqs_total 3\n"+
+			"# EOF\n",
+			buf.String())
+	})
+
+	t.Run("trend", func(t *testing.T) {
+		values := make([]float64, 101) // 0ms, 10ms, ..., 1000ms
+		for i := range values {
+			values[i] = float64(i * 10)
+		}
+		var buf bytes.Buffer
+		require.NoError(t, WriteMetrics(&buf, "k6_", []*stats.Metric{
+			addSamples(stats.New("http_req_duration", stats.Trend, stats.Time), values...),
+			addSamples(stats.New("my_trend", stats.Trend), 1, 2, 3),
+		}))
+		assert.Equal(t, ""+
+			"# TYPE k6_http_req_duration_seconds summary\n"+
+			"# UNIT k6_http_req_duration_seconds seconds\n"+
+			"# HELP k6_http_req_duration_seconds k6 trend metric http_req_duration\n"+
+			"k6_http_req_duration_seconds{quantile=\"0.5\"} 0.5\n"+
+			"k6_http_req_duration_seconds{quantile=\"0.9\"} 0.9\n"+
+			"k6_http_req_duration_seconds{quantile=\"0.95\"} 0.95\n"+
+			"k6_http_req_duration_seconds{quantile=\"0.99\"} 0.99\n"+
+			"k6_http_req_duration_seconds_sum 50.5\n"+
+			"k6_http_req_duration_seconds_count 101\n"+
+			"# TYPE k6_my_trend summary\n"+
+			"# HELP k6_my_trend k6 trend metric my_trend\n"+
+			"k6_my_trend{quantile=\"0.5\"} 2\n"+
+			"k6_my_trend{quantile=\"0.9\"} 2.8\n"+
+			"k6_my_trend{quantile=\"0.95\"} 2.9\n"+
+			"k6_my_trend{quantile=\"0.99\"} 2.98\n"+
+			"k6_my_trend_sum 6\n"+
+			"k6_my_trend_count 3\n"+
+			"# EOF\n",
+			buf.String())
+	})
+
+	t.Run("gauge and rate", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteMetrics(&buf, "", []*stats.Metric{
+			addSamples(stats.New("vus", stats.Gauge), 10, 5),
+			addSamples(stats.New("checks", stats.Rate), 1, 1, 1, 0),
+			stats.New("empty_rate", stats.Rate),
+		}))
+		assert.Equal(t, ""+
+			"# TYPE checks gauge\n"+
+			"# HELP checks k6 rate metric checks\n"+
+			"checks 0.75\n"+
+			"# TYPE empty_rate gauge\n"+
+			"# HELP empty_rate k6 rate metric empty_rate\n"+
+			"empty_rate 0\n"+
+			"# TYPE vus gauge\n"+
+			"# HELP vus k6 gauge metric vus\n"+
+			"vus 5\n"+
+			"# EOF\n",
+			buf.String())
+	})
+
+	t.Run("names", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteMetrics(&buf, "", []*stats.Metric{
+			addSamples(stats.New("1st-custom.metric", stats.Counter), 1),
+		}))
+		assert.Equal(t, ""+
+			"# TYPE _st_custom_metric counter\n"+
+			"# HELP _st_custom_metric k6 counter metric 1st-custom.metric\n"+
+			"_st_custom_metric_total 1\n"+
+			"# EOF\n",
+			buf.String())
+	})
+}
+
+func TestCollector(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	_, err := New(fs, NewConfig().Apply(Config{FileName: null.StringFrom("")}))
+	assert.EqualError(t, err, "openmetrics output needs a file name")
+
+	c, err := New(fs, NewConfig().Apply(Config{
+		FileName:      null.StringFrom("/metrics/k6.prom"),
+		WriteInterval: types.NullDurationFrom(10 * time.Millisecond),
+	}))
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+	assert.Equal(t, "/metrics/k6.prom", c.Link())
+
+	readFile := func() string {
+		data, err := afero.ReadFile(fs, "/metrics/k6.prom")
+		require.NoError(t, err)
+		return string(data)
+	}
+	assert.Equal(t, "# EOF\n", readFile())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	reqs := stats.New("http_reqs", stats.Counter)
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{Metric: reqs, Value: 1},
+		stats.Samples{{Metric: reqs, Value: 1}, {Metric: reqs, Value: 1}},
+	})
+	time.Sleep(50 * time.Millisecond)
+	assert.Contains(t, readFile(), "k6_http_reqs_total 3\n")
+
+	// The trends are estimated with a t-digest, which is exact for so few values
+	duration := stats.New("my_trend", stats.Trend)
+	c.Collect([]stats.SampleContainer{
+		stats.Sample{Metric: reqs, Value: 1},
+		stats.Sample{Metric: duration, Value: 1},
+		stats.Sample{Metric: duration, Value: 3},
+	})
+	cancel()
+	<-done
+	assert.Contains(t, readFile(), "k6_http_reqs_total 4\n")
+	assert.Contains(t, readFile(), "k6_my_trend{quantile=\"0.5\"} 2\n")
+	assert.Contains(t, readFile(), "k6_my_trend_count 2\n")
+	assert.IsType(t, &stats.TDigestSink{}, c.metrics["my_trend"].Sink)
+
+	exists, err := afero.Exists(fs, "/metrics/k6.prom.tmp")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
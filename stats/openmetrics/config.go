@@ -0,0 +1,94 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package openmetrics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+)
+
+// Config is the config for the OpenMetrics file collector
+type Config struct {
+	FileName      null.String        `json:"file_name" envconfig:"K6_OPENMETRICS_FILENAME"`
+	WriteInterval types.NullDuration `json:"write_interval" envconfig:"K6_OPENMETRICS_WRITE_INTERVAL"`
+	Namespace     null.String        `json:"namespace" envconfig:"K6_OPENMETRICS_NAMESPACE"`
+}
+
+// NewConfig creates a new Config instance with default values for some fields.
+func NewConfig() Config {
+	return Config{
+		FileName:      null.StringFrom("k6.prom"),
+		WriteInterval: types.NullDurationFrom(10 * time.Second),
+		Namespace:     null.StringFrom("k6_"),
+	}
+}
+
+// Apply merges two configs by overwriting properties in the old config
+func (c Config) Apply(cfg Config) Config {
+	if cfg.FileName.Valid {
+		c.FileName = cfg.FileName
+	}
+	if cfg.WriteInterval.Valid {
+		c.WriteInterval = cfg.WriteInterval
+	}
+	if cfg.Namespace.Valid {
+		c.Namespace = cfg.Namespace
+	}
+	return c
+}
+
+// ParseArg takes an arg string and converts it to a config
+func ParseArg(arg string) (Config, error) {
+	c := Config{}
+
+	if !strings.Contains(arg, "=") {
+		c.FileName = null.StringFrom(arg)
+		return c, nil
+	}
+
+	pairs := strings.Split(arg, ",")
+	for _, pair := range pairs {
+		r := strings.SplitN(pair, "=", 2)
+		if len(r) != 2 {
+			return c, fmt.Errorf("couldn't parse %q as argument for openmetrics output", arg)
+		}
+		switch r[0] {
+		case "write_interval":
+			err := c.WriteInterval.UnmarshalText([]byte(r[1]))
+			if err != nil {
+				return c, err
+			}
+		case "file_name":
+			c.FileName = null.StringFrom(r[1])
+		case "namespace":
+			c.Namespace = null.StringFrom(r[1])
+		default:
+			return c, fmt.Errorf("unknown key %q as argument for openmetrics output", r[0])
+		}
+	}
+
+	return c, nil
+}
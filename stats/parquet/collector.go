@@ -0,0 +1,147 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package parquet
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+)
+
+// Collector writes the raw samples to a Parquet file, for analyzing them with data tools. The
+// samples that were collected since the last save are written as a new row group every save
+// interval, and the file becomes readable once the test is done and its metadata is written.
+type Collector struct {
+	fs           afero.Fs
+	fname        string
+	tags         []string
+	saveInterval time.Duration
+
+	outfile io.WriteCloser
+	writer  *Writer
+
+	buffer     []stats.Sample
+	bufferLock sync.Mutex
+}
+
+// Verify that Collector implements lib.Collector
+var _ lib.Collector = &Collector{}
+
+// New creates a new instance of the Parquet collector, with a column for each enabled system tag
+func New(fs afero.Fs, tags stats.TagSet, config Config) (*Collector, error) {
+	if config.FileName.String == "" {
+		return nil, errors.New("parquet output needs a file name")
+	}
+	if config.SaveInterval.Duration <= 0 {
+		return nil, errors.New("parquet save interval should be positive")
+	}
+
+	resTags := []string{}
+	for tag, enabled := range tags {
+		if enabled {
+			resTags = append(resTags, tag)
+		}
+	}
+	return &Collector{
+		fs:           fs,
+		fname:        config.FileName.String,
+		tags:         resTags,
+		saveInterval: time.Duration(config.SaveInterval.Duration),
+	}, nil
+}
+
+// Init creates the file and writes the Parquet header to it
+func (c *Collector) Init() error {
+	outfile, err := c.fs.Create(c.fname)
+	if err != nil {
+		return err
+	}
+	writer, err := NewWriter(outfile, c.tags)
+	if err != nil {
+		_ = outfile.Close()
+		return err
+	}
+	c.outfile, c.writer = outfile, writer
+	return nil
+}
+
+// SetRunStatus does nothing
+func (c *Collector) SetRunStatus(status lib.RunStatus) {}
+
+// Run writes the collected samples every save interval and finishes the file once the context
+// is done
+func (c *Collector) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.saveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.writeSamples()
+		case <-ctx.Done():
+			c.writeSamples()
+			if err := c.writer.Close(); err != nil {
+				logrus.WithField("filename", c.fname).WithError(err).Error("Parquet: Error writing the file metadata")
+			}
+			if err := c.outfile.Close(); err != nil {
+				logrus.WithField("filename", c.fname).WithError(err).Error("Parquet: Error closing the file")
+			}
+			return
+		}
+	}
+}
+
+// Collect saves samples to the buffer
+func (c *Collector) Collect(scs []stats.SampleContainer) {
+	c.bufferLock.Lock()
+	defer c.bufferLock.Unlock()
+	for _, sc := range scs {
+		c.buffer = append(c.buffer, sc.GetSamples()...)
+	}
+}
+
+func (c *Collector) writeSamples() {
+	c.bufferLock.Lock()
+	samples := c.buffer
+	c.buffer = nil
+	c.bufferLock.Unlock()
+
+	if err := c.writer.WriteRowGroup(samples); err != nil {
+		logrus.WithField("filename", c.fname).WithError(err).Error("Parquet: Error writing to the file")
+	}
+}
+
+// Link returns the path of the Parquet file
+func (c *Collector) Link() string {
+	return c.fname
+}
+
+// GetRequiredSystemTags returns which sample tags are needed by this collector
+func (c *Collector) GetRequiredSystemTags() stats.SystemTagSet {
+	return stats.SystemTagSet(0) // There are no required tags for this collector
+}
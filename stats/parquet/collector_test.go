@@ -0,0 +1,79 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package parquet
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+	"github.com/loadimpact/k6/stats"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New(afero.NewMemMapFs(), nil, Config{SaveInterval: types.NullDurationFrom(time.Second)})
+	assert.EqualError(t, err, "parquet output needs a file name")
+
+	_, err = New(afero.NewMemMapFs(), nil, Config{FileName: null.StringFrom("k6.parquet")})
+	assert.EqualError(t, err, "parquet save interval should be positive")
+}
+
+func TestCollector(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	c, err := New(fs, stats.TagSet{"status": true, "url": false}, Config{
+		FileName:     null.StringFrom("results.parquet"),
+		SaveInterval: types.NullDurationFrom(10 * time.Millisecond),
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Init())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		c.Run(ctx)
+		close(done)
+	}()
+
+	metric := stats.New("my_metric", stats.Counter)
+	tags := stats.IntoSampleTags(&map[string]string{"status": "200", "url": "http://example.com"})
+	c.Collect([]stats.SampleContainer{stats.Sample{Metric: metric, Time: time.Now(), Value: 1, Tags: tags}})
+	time.Sleep(50 * time.Millisecond) // written in its own row group
+	c.Collect([]stats.SampleContainer{stats.Samples{
+		{Metric: metric, Time: time.Now(), Value: 2, Tags: tags},
+		{Metric: metric, Time: time.Now(), Value: 3},
+	}})
+	cancel()
+	<-done
+
+	data, err := afero.ReadFile(fs, "results.parquet")
+	require.NoError(t, err)
+	names, columns := readFile(t, data)
+	assert.Equal(t, []string{"metric_name", "timestamp", "metric_value", "status", "extra_tags"}, names)
+	assert.Equal(t, []interface{}{1.0, 2.0, 3.0}, columns["metric_value"])
+	assert.Equal(t, []interface{}{"200", "200", nil}, columns["status"])
+	assert.Equal(t, []interface{}{`{"url":"http://example.com"}`, `{"url":"http://example.com"}`, nil}, columns["extra_tags"])
+}
@@ -0,0 +1,87 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package parquet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/guregu/null.v3"
+
+	"github.com/loadimpact/k6/lib/types"
+)
+
+func TestNewConfig(t *testing.T) {
+	config := NewConfig()
+	assert.Equal(t, "k6.parquet", config.FileName.String)
+	assert.Equal(t, "10s", config.SaveInterval.String())
+}
+
+func TestApply(t *testing.T) {
+	config := NewConfig().Apply(Config{
+		FileName:     null.StringFrom("results.parquet"),
+		SaveInterval: types.NewNullDuration(time.Second, false),
+	})
+	assert.Equal(t, "results.parquet", config.FileName.String)
+	assert.Equal(t, "10s", config.SaveInterval.String())
+}
+
+func TestParseArg(t *testing.T) {
+	cases := map[string]struct {
+		config      Config
+		expectedErr bool
+	}{
+		"results.parquet": {
+			config: Config{FileName: null.StringFrom("results.parquet")},
+		},
+		"save_interval=5s": {
+			config: Config{SaveInterval: types.NullDurationFrom(5 * time.Second)},
+		},
+		"file_name=results.parquet,save_interval=1m": {
+			config: Config{
+				FileName:     null.StringFrom("results.parquet"),
+				SaveInterval: types.NullDurationFrom(time.Minute),
+			},
+		},
+		"filename=results.parquet": {
+			expectedErr: true,
+		},
+		"save_interval=5": {
+			expectedErr: true,
+		},
+	}
+
+	for arg, testCase := range cases {
+		arg := arg
+		testCase := testCase
+
+		t.Run(arg, func(t *testing.T) {
+			config, err := ParseArg(arg)
+			if testCase.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.config, config)
+		})
+	}
+}
@@ -0,0 +1,323 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/loadimpact/k6/lib/consts"
+	"github.com/loadimpact/k6/stats"
+)
+
+const magic = "PAR1"
+
+// The Parquet physical and converted types, encodings and page types that are used
+const (
+	typeInt64     = 2
+	typeDouble    = 5
+	typeByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	convertedNone            = -1
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	pageTypeData = 0
+)
+
+// column describes a column of the written files, and how its values are taken from the samples
+type column struct {
+	name     string
+	optional bool
+
+	typ       int32
+	converted int32
+	value     func(s *stats.Sample, tags map[string]string) (interface{}, bool)
+}
+
+type columnChunk struct {
+	offset    int64
+	numValues int64
+	size      int64
+}
+
+type rowGroup struct {
+	numRows int64
+	size    int64
+	chunks  []columnChunk
+}
+
+// Writer writes samples to a Parquet file, with one row per sample. The schema is stable: the
+// metric_name, timestamp (in microseconds) and metric_value columns, followed by a column for each
+// of the given tags, in alphabetical order, and an extra_tags column with the other tags of the
+// samples as a JSON object. The tag columns are null for samples that don't have the tag.
+//
+// Every WriteRowGroup() call writes a separate row group, so the samples can be written as they
+// come. To keep the writer simple, every column chunk is a single uncompressed data page (v1) with
+// PLAIN-encoded values and RLE-encoded definition levels. There are no dictionary pages, no
+// statistics and no compression codecs, so readers that need any of those won't be able to
+// make use of them.
+type Writer struct {
+	w         io.Writer
+	offset    int64
+	createdBy string
+
+	columns   []column
+	rowGroups []rowGroup
+}
+
+// NewWriter returns a new Writer with a column for each of the given tags, and writes the header
+// of the Parquet file.
+func NewWriter(w io.Writer, tags []string) (*Writer, error) {
+	tags = append([]string{}, tags...)
+	sort.Strings(tags)
+	pw := &Writer{w: w, columns: getColumns(tags), createdBy: "k6 version " + consts.Version}
+	if err := pw.write([]byte(magic)); err != nil {
+		return nil, err
+	}
+	return pw, nil
+}
+
+// getColumns returns the columns of the files written with the given tags, which have to be
+// sorted. The tag columns remove their tags from the tags map of the sample, so the extra_tags
+// column, which has to be the last one, only gets the remaining ones.
+func getColumns(tags []string) []column {
+	columns := []column{
+		{
+			name: "metric_name", typ: typeByteArray, converted: convertedUTF8,
+			value: func(s *stats.Sample, _ map[string]string) (interface{}, bool) { return s.Metric.Name, true },
+		},
+		{
+			name: "timestamp", typ: typeInt64, converted: convertedTimestampMicros,
+			value: func(s *stats.Sample, _ map[string]string) (interface{}, bool) {
+				return s.Time.UnixNano() / 1000, true
+			},
+		},
+		{
+			name: "metric_value", typ: typeDouble, converted: convertedNone,
+			value: func(s *stats.Sample, _ map[string]string) (interface{}, bool) { return s.Value, true },
+		},
+	}
+	for _, tag := range tags {
+		tag := tag
+		columns = append(columns, column{
+			name: tag, optional: true, typ: typeByteArray, converted: convertedUTF8,
+			value: func(_ *stats.Sample, tags map[string]string) (interface{}, bool) {
+				v, ok := tags[tag]
+				delete(tags, tag)
+				return v, ok
+			},
+		})
+	}
+	return append(columns, column{
+		name: "extra_tags", optional: true, typ: typeByteArray, converted: convertedUTF8,
+		value: func(_ *stats.Sample, tags map[string]string) (interface{}, bool) {
+			if len(tags) == 0 {
+				return nil, false
+			}
+			data, err := json.Marshal(tags) // the keys are sorted, so the output is stable
+			return string(data), err == nil
+		},
+	})
+}
+
+func (pw *Writer) write(b []byte) error {
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	return err
+}
+
+// WriteRowGroup writes the given samples as a new row group. Nothing is written for no samples.
+func (pw *Writer) WriteRowGroup(samples []stats.Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	values := make([][]interface{}, len(pw.columns))
+	present := make([][]bool, len(pw.columns))
+	for i := range pw.columns {
+		values[i] = make([]interface{}, 0, len(samples))
+		present[i] = make([]bool, len(samples))
+	}
+	for j := range samples {
+		tags := samples[j].Tags.CloneTags()
+		for i, col := range pw.columns {
+			if v, ok := col.value(&samples[j], tags); ok {
+				values[i] = append(values[i], v)
+				present[i][j] = true
+			}
+		}
+	}
+
+	rg := rowGroup{numRows: int64(len(samples)), chunks: make([]columnChunk, len(pw.columns))}
+	for i, col := range pw.columns {
+		page := encodePage(col, values[i], present[i])
+
+		var header thriftWriter
+		header.beginStruct()
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(len(page)))
+		header.i32(3, int32(len(page)))
+		header.structField(5) // data_page_header
+		header.i32(1, int32(len(samples)))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+		header.endStruct()
+
+		chunk := columnChunk{
+			offset:    pw.offset,
+			numValues: int64(len(samples)),
+			size:      int64(len(header.Bytes()) + len(page)),
+		}
+		if err := pw.write(header.Bytes()); err != nil {
+			return err
+		}
+		if err := pw.write(page); err != nil {
+			return err
+		}
+		rg.chunks[i] = chunk
+		rg.size += chunk.size
+	}
+	pw.rowGroups = append(pw.rowGroups, rg)
+	return nil
+}
+
+// encodePage encodes the values of a column as a data page. Optional columns start with the
+// definition levels, i.e. whether each value is present, and only the present values are encoded.
+func encodePage(col column, values []interface{}, present []bool) []byte {
+	var page bytes.Buffer
+	if col.optional {
+		levels := encodeLevels(present)
+		_ = binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+		page.Write(levels)
+	}
+	for _, v := range values {
+		switch v := v.(type) {
+		case string:
+			_ = binary.Write(&page, binary.LittleEndian, uint32(len(v)))
+			page.WriteString(v)
+		case int64:
+			_ = binary.Write(&page, binary.LittleEndian, v)
+		case float64:
+			_ = binary.Write(&page, binary.LittleEndian, math.Float64bits(v))
+		}
+	}
+	return page.Bytes()
+}
+
+// encodeLevels encodes definition levels with a bit width of 1 as runs of the RLE/bit-packing
+// hybrid encoding, which is the most compact option when the same tags are mostly present.
+func encodeLevels(present []bool) []byte {
+	var buf bytes.Buffer
+	var b [binary.MaxVarintLen64]byte
+	for i := 0; i < len(present); {
+		j := i + 1
+		for j < len(present) && present[j] == present[i] {
+			j++
+		}
+		buf.Write(b[:binary.PutUvarint(b[:], uint64(j-i)<<1)])
+		if present[i] {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		i = j
+	}
+	return buf.Bytes()
+}
+
+// Close writes the file metadata that makes the written data readable. It doesn't close the
+// underlying writer.
+func (pw *Writer) Close() error {
+	var numRows int64
+	for _, rg := range pw.rowGroups {
+		numRows += rg.numRows
+	}
+
+	var meta thriftWriter
+	meta.beginStruct()
+	meta.i32(1, 1) // version
+	meta.list(2, thriftStruct, len(pw.columns)+1)
+	meta.beginStruct()
+	meta.string(4, "k6")
+	meta.i32(5, int32(len(pw.columns)))
+	meta.endStruct()
+	for _, col := range pw.columns {
+		meta.beginStruct()
+		meta.i32(1, col.typ)
+		if col.optional {
+			meta.i32(3, repetitionOptional)
+		} else {
+			meta.i32(3, repetitionRequired)
+		}
+		meta.string(4, col.name)
+		if col.converted != convertedNone {
+			meta.i32(6, col.converted)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, numRows)
+	meta.list(4, thriftStruct, len(pw.rowGroups))
+	for _, rg := range pw.rowGroups {
+		meta.beginStruct()
+		meta.list(1, thriftStruct, len(rg.chunks))
+		for i, chunk := range rg.chunks {
+			meta.beginStruct()
+			meta.i64(2, chunk.offset)
+			meta.structField(3) // meta_data
+			meta.i32(1, pw.columns[i].typ)
+			meta.list(2, thriftI32, 2)
+			meta.i32Elem(encodingPlain)
+			meta.i32Elem(encodingRLE)
+			meta.list(3, thriftBinary, 1)
+			meta.stringElem(pw.columns[i].name)
+			meta.i32(4, 0) // uncompressed
+			meta.i64(5, chunk.numValues)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.endStruct()
+			meta.endStruct()
+		}
+		meta.i64(2, rg.size)
+		meta.i64(3, rg.numRows)
+		meta.endStruct()
+	}
+	meta.string(6, pw.createdBy)
+	meta.endStruct()
+
+	footer := meta.Bytes()
+	footer = append(footer, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(footer[len(footer)-4:], uint32(len(footer)-4))
+	return pw.write(append(footer, magic...))
+}
@@ -0,0 +1,330 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// thriftReader decodes the Thrift compact protocol into generic values, structs being maps of
+// field IDs to values, for checking the written files independently of the writer.
+type thriftReader struct {
+	*bytes.Reader
+}
+
+type thriftStructValue map[int16]interface{}
+
+func (r thriftReader) readStruct() (thriftStructValue, error) {
+	s := thriftStructValue{}
+	var lastID int16
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b == 0 {
+			return s, nil
+		}
+		typ := b & 0x0f
+		if delta := int16(b >> 4); delta != 0 {
+			lastID += delta
+		} else {
+			id, err := binary.ReadVarint(r)
+			if err != nil {
+				return nil, err
+			}
+			lastID = int16(id)
+		}
+		if s[lastID], err = r.readValue(typ); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (r thriftReader) readValue(typ byte) (interface{}, error) {
+	switch typ {
+	case 1, 2:
+		return typ == 1, nil
+	case thriftI32, thriftI64:
+		return binary.ReadVarint(r)
+	case thriftBinary:
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, n)
+		_, err = r.Read(b)
+		return string(b), err
+	case thriftList:
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(b >> 4)
+		if size == 15 {
+			if size, err = binary.ReadUvarint(r); err != nil {
+				return nil, err
+			}
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			if list[i], err = r.readValue(b & 0x0f); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case thriftStruct:
+		return r.readStruct()
+	default:
+		return nil, fmt.Errorf("unsupported thrift type %d", typ)
+	}
+}
+
+// readFile reads the columns of a Parquet file written by Writer, with nil for null values
+func readFile(t *testing.T, data []byte) (names []string, columns map[string][]interface{}) {
+	require.True(t, len(data) >= 12)
+	require.Equal(t, magic, string(data[:4]))
+	require.Equal(t, magic, string(data[len(data)-4:]))
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLen : len(data)-8]
+
+	meta, err := thriftReader{bytes.NewReader(footer)}.readStruct()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), meta[1])
+
+	schema := meta[2].([]interface{})
+	require.Equal(t, int64(len(schema)-1), schema[0].(thriftStructValue)[5])
+	types := make(map[string]int64)
+	optional := make(map[string]bool)
+	columns = make(map[string][]interface{})
+	for _, el := range schema[1:] {
+		el := el.(thriftStructValue)
+		name := el[4].(string)
+		names = append(names, name)
+		types[name] = el[1].(int64)
+		optional[name] = el[3].(int64) == repetitionOptional
+		columns[name] = []interface{}{}
+	}
+
+	var numRows int64
+	for _, rg := range meta[4].([]interface{}) {
+		rg := rg.(thriftStructValue)
+		rowGroupRows := rg[3].(int64)
+		numRows += rowGroupRows
+		for i, chunk := range rg[1].([]interface{}) {
+			colMeta := chunk.(thriftStructValue)[3].(thriftStructValue)
+			name := colMeta[3].([]interface{})[0].(string)
+			require.Equal(t, names[i], name)
+			require.Equal(t, types[name], colMeta[1])
+			require.Equal(t, rowGroupRows, colMeta[5])
+
+			r := thriftReader{bytes.NewReader(data[colMeta[9].(int64):])}
+			header, err := r.readStruct()
+			require.NoError(t, err)
+			require.Equal(t, int64(pageTypeData), header[1])
+			require.Equal(t, header[2], header[3])
+			page := make([]byte, header[3].(int64))
+			_, err = r.Read(page)
+			require.NoError(t, err)
+			require.Equal(t, rowGroupRows, header[5].(thriftStructValue)[1])
+
+			columns[name] = append(columns[name], decodePage(t, page, types[name], optional[name], rowGroupRows)...)
+		}
+	}
+	assert.Equal(t, numRows, meta[3])
+	return names, columns
+}
+
+func decodePage(t *testing.T, page []byte, typ int64, optional bool, numValues int64) []interface{} {
+	present := make([]bool, numValues)
+	if optional {
+		levelsLen := binary.LittleEndian.Uint32(page)
+		levels := bytes.NewReader(page[4 : 4+levelsLen])
+		page = page[4+levelsLen:]
+		var i int
+		for levels.Len() > 0 {
+			header, err := binary.ReadUvarint(levels)
+			require.NoError(t, err)
+			require.Zero(t, header&1, "only RLE runs are expected")
+			value, err := levels.ReadByte()
+			require.NoError(t, err)
+			for n := 0; n < int(header>>1); n++ {
+				present[i] = value == 1
+				i++
+			}
+		}
+		require.Equal(t, int(numValues), i)
+	} else {
+		for i := range present {
+			present[i] = true
+		}
+	}
+
+	values := make([]interface{}, numValues)
+	for i := range values {
+		if !present[i] {
+			continue
+		}
+		switch typ {
+		case typeInt64:
+			values[i] = int64(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case typeDouble:
+			values[i] = math.Float64frombits(binary.LittleEndian.Uint64(page))
+			page = page[8:]
+		case typeByteArray:
+			n := binary.LittleEndian.Uint32(page)
+			values[i] = string(page[4 : 4+n])
+			page = page[4+n:]
+		}
+	}
+	assert.Empty(t, page)
+	return values
+}
+
+func TestWriter(t *testing.T) {
+	metric := stats.New("my_metric", stats.Trend)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 678901000, time.UTC)
+	samples := []stats.Sample{
+		{
+			Metric: metric, Time: start, Value: 1.5,
+			Tags: stats.IntoSampleTags(&map[string]string{"method": "GET", "status": "200", "custom": "a"}),
+		},
+		{
+			Metric: metric, Time: start.Add(time.Millisecond), Value: -2,
+			Tags: stats.IntoSampleTags(&map[string]string{"status": "500"}),
+		},
+	}
+	// A second, bigger row group with none of the tag columns, but two extra tags
+	for i := 0; i < 20; i++ {
+		samples = append(samples, stats.Sample{
+			Metric: stats.New("other", stats.Counter), Time: start.Add(time.Duration(i) * time.Second), Value: float64(i),
+			Tags: stats.IntoSampleTags(&map[string]string{"z": "1", "a": fmt.Sprint(i)}),
+		})
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []string{"status", "method"})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteRowGroup(samples[:2]))
+	require.NoError(t, w.WriteRowGroup(nil))
+	require.NoError(t, w.WriteRowGroup(samples[2:]))
+	require.NoError(t, w.Close())
+
+	names, columns := readFile(t, buf.Bytes())
+	assert.Equal(t, []string{"metric_name", "timestamp", "metric_value", "method", "status", "extra_tags"}, names)
+	for _, name := range names {
+		require.Len(t, columns[name], len(samples), name)
+	}
+
+	assert.Equal(t, "my_metric", columns["metric_name"][0])
+	assert.Equal(t, start.UnixNano()/1000, columns["timestamp"][0])
+	assert.Equal(t, 1.5, columns["metric_value"][0])
+	assert.Equal(t, "GET", columns["method"][0])
+	assert.Equal(t, "200", columns["status"][0])
+	assert.Equal(t, `{"custom":"a"}`, columns["extra_tags"][0])
+
+	assert.Equal(t, "my_metric", columns["metric_name"][1])
+	assert.Equal(t, start.Add(time.Millisecond).UnixNano()/1000, columns["timestamp"][1])
+	assert.Equal(t, -2.0, columns["metric_value"][1])
+	assert.Nil(t, columns["method"][1])
+	assert.Equal(t, "500", columns["status"][1])
+	assert.Nil(t, columns["extra_tags"][1])
+
+	for i, s := range samples[2:] {
+		assert.Equal(t, "other", columns["metric_name"][i+2])
+		assert.Equal(t, s.Time.UnixNano()/1000, columns["timestamp"][i+2])
+		assert.Equal(t, s.Value, columns["metric_value"][i+2])
+		assert.Nil(t, columns["method"][i+2])
+		assert.Nil(t, columns["status"][i+2])
+		assert.Equal(t, fmt.Sprintf(`{"a":"%d","z":"1"}`, i), columns["extra_tags"][i+2])
+	}
+}
+
+func TestWriterNoSamples(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	names, columns := readFile(t, buf.Bytes())
+	assert.Equal(t, []string{"metric_name", "timestamp", "metric_value", "extra_tags"}, names)
+	assert.Empty(t, columns["metric_name"])
+}
+
+// The fixture is a complete file with a tag column, missing tags and extra tags, so the format
+// can be checked with a reference reader whenever the writer changes. With pyarrow:
+//
+//	python3 -c 'import pyarrow.parquet as pq; print(pq.read_table("testdata/samples.parquet").to_pydict())'
+//
+// has to print the metric_name, timestamp, metric_value, status and extra_tags columns with the
+// values of goldenSamples(), e.g. a timestamp column of 2020-01-02 03:04:05.678901 and the next
+// two milliseconds, and None for the missing statuses and extra tags.
+const goldenFile = "testdata/samples.parquet"
+
+func goldenSamples() []stats.Sample {
+	metric := stats.New("http_req_duration", stats.Trend, stats.Time)
+	start := time.Date(2020, 1, 2, 3, 4, 5, 678901000, time.UTC)
+	return []stats.Sample{
+		{
+			Metric: metric, Time: start, Value: 123.5,
+			Tags: stats.IntoSampleTags(&map[string]string{"status": "200", "method": "GET"}),
+		},
+		{
+			Metric: metric, Time: start.Add(time.Millisecond), Value: 0.25,
+			Tags: stats.IntoSampleTags(&map[string]string{}),
+		},
+		{
+			Metric: metric, Time: start.Add(2 * time.Millisecond), Value: 42,
+			Tags: stats.IntoSampleTags(&map[string]string{"status": "503"}),
+		},
+	}
+}
+
+func TestWriterGolden(t *testing.T) {
+	expected, err := ioutil.ReadFile(goldenFile)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, []string{"status"})
+	require.NoError(t, err)
+	w.createdBy = "k6" // so the fixture doesn't change with every version
+	require.NoError(t, w.WriteRowGroup(goldenSamples()))
+	require.NoError(t, w.Close())
+	assert.Equal(t, expected, buf.Bytes())
+
+	names, columns := readFile(t, expected)
+	assert.Equal(t, []string{"metric_name", "timestamp", "metric_value", "status", "extra_tags"}, names)
+	assert.Equal(t, []interface{}{"200", nil, "503"}, columns["status"])
+	assert.Equal(t, []interface{}{`{"method":"GET"}`, nil, nil}, columns["extra_tags"])
+	assert.Equal(t, []interface{}{123.5, 0.25, 42.0}, columns["metric_value"])
+}
@@ -0,0 +1,85 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Registry keeps the metrics that were defined, by name, so the same metric isn't defined with
+// conflicting types, e.g. as a counter in one script module and as a trend in another one.
+type Registry struct {
+	metrics map[string]*Metric
+	mu      sync.Mutex
+}
+
+// NewRegistry returns a new empty registry
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]*Metric)}
+}
+
+// NewMetric returns the metric with the given name, type and value type, creating it if it
+// wasn't defined yet. It returns an error if the metric was already defined with another type
+// or value type.
+func (r *Registry) NewMetric(name string, typ MetricType, t ...ValueType) (*Metric, error) {
+	vt := Default
+	if len(t) > 0 {
+		vt = t[0]
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.metrics[name]; ok {
+		if m.Type != typ || m.Contains != vt {
+			return nil, fmt.Errorf(
+				"metric '%s' is already defined as a %s with %s values, it can't be redefined as a %s with %s values",
+				name, unquote(m.Type.String()), unquote(m.Contains.String()),
+				unquote(typ.String()), unquote(vt.String()),
+			)
+		}
+		return m, nil
+	}
+	m := New(name, typ, vt)
+	if m == nil {
+		return nil, ErrInvalidMetricType
+	}
+	r.metrics[name] = m
+	return m, nil
+}
+
+// Register adds an already created metric, e.g. a built-in one, to the registry. It returns an
+// error if another metric with the same name was already registered.
+func (r *Registry) Register(m *Metric) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.metrics[m.Name]; ok && existing != m {
+		return fmt.Errorf("metric '%s' is already registered", m.Name)
+	}
+	r.metrics[m.Name] = m
+	return nil
+}
+
+// unquote removes the quotes from the JSON-like string representations of the metric types
+func unquote(s string) string {
+	return strings.Trim(s, `"`)
+}
@@ -0,0 +1,63 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	m, err := r.NewMetric("my_trend", Trend, Time)
+	require.NoError(t, err)
+	assert.Equal(t, "my_trend", m.Name)
+	assert.Equal(t, Trend, m.Type)
+	assert.Equal(t, Time, m.Contains)
+
+	again, err := r.NewMetric("my_trend", Trend, Time)
+	require.NoError(t, err)
+	assert.True(t, m == again, "the same definition should return the same metric")
+
+	_, err = r.NewMetric("my_trend", Counter)
+	assert.EqualError(t, err, "metric 'my_trend' is already defined as a trend with time values, "+
+		"it can't be redefined as a counter with default values")
+	_, err = r.NewMetric("my_trend", Trend)
+	assert.EqualError(t, err, "metric 'my_trend' is already defined as a trend with time values, "+
+		"it can't be redefined as a trend with default values")
+
+	_, err = r.NewMetric("invalid", MetricType(-1))
+	assert.Equal(t, ErrInvalidMetricType, err)
+
+	builtin := New("http_reqs", Counter)
+	require.NoError(t, r.Register(builtin))
+	require.NoError(t, r.Register(builtin))
+	assert.EqualError(t, r.Register(New("http_reqs", Counter)), "metric 'http_reqs' is already registered")
+	_, err = r.NewMetric("http_reqs", Rate)
+	assert.Error(t, err)
+	counter, err := r.NewMetric("http_reqs", Counter)
+	require.NoError(t, err)
+	assert.True(t, builtin == counter)
+}
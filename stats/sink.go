@@ -93,6 +93,12 @@ type TrendSink struct {
 	Min, Max float64
 	Sum, Avg float64
 	Med      float64
+
+	// If TrackTags is enabled, the tags of the samples with the min and max values are kept in
+	// MinTags and MaxTags, e.g. to know which URL had the slowest request. With multiple samples
+	// with the same extreme value, the tags of the first one are kept.
+	TrackTags        bool
+	MinTags, MaxTags *SampleTags
 }
 
 func (t *TrendSink) Add(s Sample) {
@@ -102,11 +108,17 @@ func (t *TrendSink) Add(s Sample) {
 	t.Sum += s.Value
 	t.Avg = t.Sum / float64(t.Count)
 
-	if s.Value > t.Max {
+	if s.Value > t.Max || t.Count == 1 {
 		t.Max = s.Value
+		if t.TrackTags {
+			t.MaxTags = s.Tags
+		}
 	}
 	if s.Value < t.Min || t.Count == 1 {
 		t.Min = s.Value
+		if t.TrackTags {
+			t.MinTags = s.Tags
+		}
 	}
 }
 
@@ -130,6 +130,38 @@ func TestTrendSink(t *testing.T) {
 			assert.Equal(t, 0.0, sink.Med) // calculated in Calc()
 		})
 	})
+	t.Run("tags", func(t *testing.T) {
+		tags := func(url string) *SampleTags { return IntoSampleTags(&map[string]string{"url": url}) }
+		samples := []Sample{
+			{Value: -5.0, Tags: tags("first")},
+			{Value: 30.0, Tags: tags("slow")},
+			{Value: -10.0, Tags: tags("fast")},
+			{Value: 30.0, Tags: tags("also slow")},
+			{Value: 0.0, Tags: tags("last")},
+		}
+
+		sink := TrendSink{}
+		for _, s := range samples {
+			sink.Add(s)
+		}
+		assert.Equal(t, -10.0, sink.Min)
+		assert.Equal(t, 30.0, sink.Max)
+		assert.Nil(t, sink.MinTags)
+		assert.Nil(t, sink.MaxTags)
+
+		sink = TrendSink{TrackTags: true}
+		sink.Add(samples[0])
+		assert.Equal(t, tags("first"), sink.MinTags)
+		assert.Equal(t, tags("first"), sink.MaxTags)
+		assert.Equal(t, -5.0, sink.Max)
+		for _, s := range samples[1:] {
+			sink.Add(s)
+		}
+		assert.Equal(t, -10.0, sink.Min)
+		assert.Equal(t, 30.0, sink.Max)
+		assert.Equal(t, tags("fast"), sink.MinTags)
+		assert.Equal(t, tags("slow"), sink.MaxTags)
+	})
 	t.Run("calc", func(t *testing.T) {
 		t.Run("no values", func(t *testing.T) {
 			sink := TrendSink{}
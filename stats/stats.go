@@ -27,6 +27,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/dustin/go-humanize"
@@ -169,8 +170,9 @@ func (t ValueType) String() string {
 // between maps, since the same tag set is often used for multiple samples.
 // All methods should not panic, even if they are called on a nil pointer.
 type SampleTags struct {
-	tags map[string]string
-	json []byte
+	tags     map[string]string
+	json     []byte
+	jsonLock sync.Mutex
 }
 
 // Get returns an empty string and false if the the requested key is not
@@ -223,13 +225,14 @@ func (st *SampleTags) Contains(other *SampleTags) bool {
 }
 
 // MarshalJSON serializes SampleTags to a JSON string and caches
-// the result. It is not thread safe in the sense that the Go race
-// detector will complain if it's used concurrently, but no data
-// should be corrupted.
+// the result. It's safe for concurrent use, since outputs may
+// serialize the same tags from multiple goroutines.
 func (st *SampleTags) MarshalJSON() ([]byte, error) {
 	if st.IsEmpty() {
 		return []byte("null"), nil
 	}
+	st.jsonLock.Lock()
+	defer st.jsonLock.Unlock()
 	if st.json != nil {
 		return st.json, nil
 	}
@@ -180,6 +180,8 @@ func TestNewSubmetric(t *testing.T) {
 		"my_metric{a,b}":            {"my_metric", map[string]string{"a": "", "b": ""}},
 		"my_metric{a:1,b:2}":        {"my_metric", map[string]string{"a": "1", "b": "2"}},
 		"my_metric{ a : 1, b : 2 }": {"my_metric", map[string]string{"a": "1", "b": "2"}},
+		"my_metric{group:::a::b}":   {"my_metric", map[string]string{"group": "::a::b"}},
+		"my_metric{url:http://x/}":  {"my_metric", map[string]string{"url": "http://x/"}},
 	}
 
 	for name, data := range testdata {
@@ -23,7 +23,6 @@ package common
 import (
 	"context"
 	"fmt"
-	"sync"
 	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
@@ -43,11 +42,10 @@ type Collector struct {
 	// of those tags that should be sent. No tags are send in case of ProcessTags being null
 	ProcessTags func(map[string]string) []string
 
-	logger     *logrus.Entry
-	client     *statsd.Client
-	startTime  time.Time
-	buffer     []*Sample
-	bufferLock sync.Mutex
+	logger    *logrus.Entry
+	client    *statsd.Client
+	startTime time.Time
+	batcher   *stats.SampleBatcher
 }
 
 // Init sets up the collector
@@ -62,6 +60,13 @@ func (c *Collector) Init() (err error) {
 
 		return err
 	}
+	if c.Config.MinBatchSize.Int64 < 0 {
+		err = fmt.Errorf("min batch size can't be negative, got %d", c.Config.MinBatchSize.Int64)
+		c.logger.Error(err)
+
+		return err
+	}
+	c.batcher = stats.NewSampleBatcher(int(c.Config.MinBatchSize.Int64), time.Duration(c.Config.PushInterval.Duration))
 
 	c.client, err = statsd.NewBuffered(c.Config.Addr.String, int(c.Config.BufferSize.Int64))
 
@@ -85,19 +90,10 @@ func (c *Collector) Link() string {
 // Run the collector
 func (c *Collector) Run(ctx context.Context) {
 	c.logger.Debugf("%s: Running!", c.Type)
-	ticker := time.NewTicker(time.Duration(c.Config.PushInterval.Duration))
 	c.startTime = time.Now()
 
-	for {
-		select {
-		case <-ticker.C:
-			c.pushMetrics()
-		case <-ctx.Done():
-			c.pushMetrics()
-			c.finish()
-			return
-		}
-	}
+	c.batcher.Run(ctx, c.pushMetrics)
+	c.finish()
 }
 
 // GetRequiredSystemTags Return the required system sample tags for the specific collector
@@ -110,30 +106,14 @@ func (c *Collector) SetRunStatus(status lib.RunStatus) {}
 
 // Collect metrics
 func (c *Collector) Collect(containers []stats.SampleContainer) {
-	var pointSamples []*Sample
-
-	for _, container := range containers {
-		for _, sample := range container.GetSamples() {
-			pointSamples = append(pointSamples, generateDataPoint(sample))
-		}
-	}
-
-	if len(pointSamples) > 0 {
-		c.bufferLock.Lock()
-		c.buffer = append(c.buffer, pointSamples...)
-		c.bufferLock.Unlock()
-	}
+	c.batcher.Add(containers)
 }
 
-func (c *Collector) pushMetrics() {
-	c.bufferLock.Lock()
-	if len(c.buffer) == 0 {
-		c.bufferLock.Unlock()
-		return
+func (c *Collector) pushMetrics(samples []stats.Sample) {
+	buffer := make([]*Sample, len(samples))
+	for i, sample := range samples {
+		buffer[i] = generateDataPoint(sample)
 	}
-	buffer := c.buffer
-	c.buffer = nil
-	c.bufferLock.Unlock()
 
 	c.logger.
 		WithField("samples", len(buffer)).
@@ -29,6 +29,17 @@ func TestInitWithBogusAddressErrors(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestInitWithNegativeMinBatchSizeErrors(t *testing.T) {
+	var c = &Collector{
+		Config: Config{
+			Addr:         null.StringFrom("localhost:8125"),
+			MinBatchSize: null.IntFrom(-1),
+		},
+		Type: "testtype",
+	}
+	require.EqualError(t, c.Init(), "min batch size can't be negative, got -1")
+}
+
 func TestLinkReturnAddress(t *testing.T) {
 	var bogusValue = "bogus value"
 	var c = &Collector{
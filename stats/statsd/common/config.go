@@ -33,6 +33,10 @@ type Config struct {
 	BufferSize   null.Int           `json:"bufferSize,omitempty" envconfig:"BUFFER_SIZE"`
 	Namespace    null.String        `json:"namespace,omitempty" envconfig:"NAMESPACE"`
 	PushInterval types.NullDuration `json:"pushInterval,omitempty" envconfig:"PUSH_INTERVAL"`
+
+	// The samples are sent once there are at least MinBatchSize of them, or once the oldest of
+	// them was collected PushInterval ago. Zero means that they're only sent on age.
+	MinBatchSize null.Int `json:"minBatchSize,omitempty" envconfig:"MIN_BATCH_SIZE"`
 }
 
 // NewConfig creates a new Config instance with default values for some fields.
@@ -63,5 +67,9 @@ func (c Config) Apply(cfg Config) Config {
 		c.PushInterval = cfg.PushInterval
 	}
 
+	if cfg.MinBatchSize.Valid {
+		c.MinBatchSize = cfg.MinBatchSize
+	}
+
 	return c
 }
@@ -29,19 +29,24 @@ const (
 	TagError
 	TagErrorCode
 	TagTLSVersion
+	TagStatusClass
+	TagMsgType
+	TagErrorCategory
 
 	// System tags not enabled by default.
 	TagIter
 	TagVU
 	TagOCSPStatus
 	TagIP
+	TagTraceID // only set if the traceContext option is enabled
 )
 
 // DefaultSystemTagSet includes all of the system tags emitted with metrics by default.
-// Other tags that are not enabled by default include: iter, vu, ocsp_status, ip
+// Other tags that are not enabled by default include: iter, vu, ocsp_status, ip, trace_id
 //nolint:gochecknoglobals
 var DefaultSystemTagSet = TagProto | TagSubproto | TagStatus | TagMethod | TagURL | TagName | TagGroup |
-	TagCheck | TagCheck | TagError | TagErrorCode | TagTLSVersion
+	TagCheck | TagCheck | TagError | TagErrorCode | TagTLSVersion | TagStatusClass |
+	TagMsgType | TagErrorCategory
 
 // Add adds a tag to tag set.
 func (i *SystemTagSet) Add(tag SystemTagSet) {
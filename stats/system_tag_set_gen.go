@@ -7,24 +7,28 @@ import (
 	"fmt"
 )
 
-const _SystemTagSetName = "protosubprotostatusmethodurlnamegroupcheckerrorerror_codetls_versionitervuocsp_statusip"
+const _SystemTagSetName = "protosubprotostatusmethodurlnamegroupcheckerrorerror_codetls_versionstatus_classmsg_typeerror_categoryitervuocsp_statusiptrace_id"
 
 var _SystemTagSetMap = map[SystemTagSet]string{
-	1:     _SystemTagSetName[0:5],
-	2:     _SystemTagSetName[5:13],
-	4:     _SystemTagSetName[13:19],
-	8:     _SystemTagSetName[19:25],
-	16:    _SystemTagSetName[25:28],
-	32:    _SystemTagSetName[28:32],
-	64:    _SystemTagSetName[32:37],
-	128:   _SystemTagSetName[37:42],
-	256:   _SystemTagSetName[42:47],
-	512:   _SystemTagSetName[47:57],
-	1024:  _SystemTagSetName[57:68],
-	2048:  _SystemTagSetName[68:72],
-	4096:  _SystemTagSetName[72:74],
-	8192:  _SystemTagSetName[74:85],
-	16384: _SystemTagSetName[85:87],
+	1:      _SystemTagSetName[0:5],
+	2:      _SystemTagSetName[5:13],
+	4:      _SystemTagSetName[13:19],
+	8:      _SystemTagSetName[19:25],
+	16:     _SystemTagSetName[25:28],
+	32:     _SystemTagSetName[28:32],
+	64:     _SystemTagSetName[32:37],
+	128:    _SystemTagSetName[37:42],
+	256:    _SystemTagSetName[42:47],
+	512:    _SystemTagSetName[47:57],
+	1024:   _SystemTagSetName[57:68],
+	2048:   _SystemTagSetName[68:80],
+	4096:   _SystemTagSetName[80:88],
+	8192:   _SystemTagSetName[88:102],
+	16384:  _SystemTagSetName[102:106],
+	32768:  _SystemTagSetName[106:108],
+	65536:  _SystemTagSetName[108:119],
+	131072: _SystemTagSetName[119:121],
+	262144: _SystemTagSetName[121:129],
 }
 
 func (i SystemTagSet) String() string {
@@ -34,24 +38,28 @@ func (i SystemTagSet) String() string {
 	return fmt.Sprintf("SystemTagSet(%d)", i)
 }
 
-var _SystemTagSetValues = []SystemTagSet{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384}
+var _SystemTagSetValues = []SystemTagSet{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096, 8192, 16384, 32768, 65536, 131072, 262144}
 
 var _SystemTagSetNameToValueMap = map[string]SystemTagSet{
-	_SystemTagSetName[0:5]:   1,
-	_SystemTagSetName[5:13]:  2,
-	_SystemTagSetName[13:19]: 4,
-	_SystemTagSetName[19:25]: 8,
-	_SystemTagSetName[25:28]: 16,
-	_SystemTagSetName[28:32]: 32,
-	_SystemTagSetName[32:37]: 64,
-	_SystemTagSetName[37:42]: 128,
-	_SystemTagSetName[42:47]: 256,
-	_SystemTagSetName[47:57]: 512,
-	_SystemTagSetName[57:68]: 1024,
-	_SystemTagSetName[68:72]: 2048,
-	_SystemTagSetName[72:74]: 4096,
-	_SystemTagSetName[74:85]: 8192,
-	_SystemTagSetName[85:87]: 16384,
+	_SystemTagSetName[0:5]:     1,
+	_SystemTagSetName[5:13]:    2,
+	_SystemTagSetName[13:19]:   4,
+	_SystemTagSetName[19:25]:   8,
+	_SystemTagSetName[25:28]:   16,
+	_SystemTagSetName[28:32]:   32,
+	_SystemTagSetName[32:37]:   64,
+	_SystemTagSetName[37:42]:   128,
+	_SystemTagSetName[42:47]:   256,
+	_SystemTagSetName[47:57]:   512,
+	_SystemTagSetName[57:68]:   1024,
+	_SystemTagSetName[68:80]:   2048,
+	_SystemTagSetName[80:88]:   4096,
+	_SystemTagSetName[88:102]:  8192,
+	_SystemTagSetName[102:106]: 16384,
+	_SystemTagSetName[106:108]: 32768,
+	_SystemTagSetName[108:119]: 65536,
+	_SystemTagSetName[119:121]: 131072,
+	_SystemTagSetName[121:129]: 262144,
 }
 
 // SystemTagSetString retrieves an enum value from the enum constants string name.
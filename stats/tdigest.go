@@ -0,0 +1,192 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// DefaultTDigestCompression is the compression used by TDigestSink when none is specified. It
+// keeps at most a few hundred centroids, regardless of how many samples are added.
+const DefaultTDigestCompression = 100
+
+var _ Sink = &TDigestSink{}
+
+type centroid struct {
+	mean, weight float64
+}
+
+// TDigestSink is a trend sink that, unlike TrendSink, doesn't keep all of the sample values in
+// memory. Instead, it uses a merging t-digest (https://arxiv.org/abs/1902.04023) to estimate the
+// percentiles. The estimates are very accurate near the tails, which are usually the most
+// interesting percentiles, and less so around the median.
+type TDigestSink struct {
+	// Compression controls the trade-off between accuracy and memory use: higher values mean
+	// more centroids. It should be set before any samples are added.
+	Compression float64
+
+	Count    uint64
+	Min, Max float64
+	Sum, Avg float64
+	Med      float64
+
+	centroids  []centroid
+	cumulative []float64 // the weight up to the middle of every centroid
+	buffer     []float64 // values that haven't been merged into the centroids yet
+	scratch    []centroid
+}
+
+// NewTDigestSink returns a new t-digest sink with the given compression.
+func NewTDigestSink(compression float64) *TDigestSink {
+	return &TDigestSink{Compression: compression}
+}
+
+func (t *TDigestSink) compression() float64 {
+	if t.Compression <= 0 {
+		return DefaultTDigestCompression
+	}
+	return t.Compression
+}
+
+// Add buffers the sample value and merges the buffer into the digest when it gets full.
+func (t *TDigestSink) Add(s Sample) {
+	t.Count++
+	t.Sum += s.Value
+	t.Avg = t.Sum / float64(t.Count)
+
+	if s.Value > t.Max || t.Count == 1 {
+		t.Max = s.Value
+	}
+	if s.Value < t.Min || t.Count == 1 {
+		t.Min = s.Value
+	}
+
+	t.buffer = append(t.buffer, s.Value)
+	if len(t.buffer) >= int(5*t.compression()) {
+		t.merge()
+	}
+}
+
+// scale is the k1 scale function from the t-digest paper. Adjacent values may only be merged
+// into the same centroid if their scaled quantiles differ by at most 1, which keeps the
+// centroids near the tails small.
+func (t *TDigestSink) scale(q float64) float64 {
+	return t.compression() / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func (t *TDigestSink) merge() {
+	if len(t.buffer) == 0 {
+		return
+	}
+	sort.Float64s(t.buffer)
+
+	// Merge the sorted buffer and the (also sorted) existing centroids into a single list
+	all := t.scratch[:0]
+	i := 0
+	for _, v := range t.buffer {
+		for i < len(t.centroids) && t.centroids[i].mean <= v {
+			all = append(all, t.centroids[i])
+			i++
+		}
+		all = append(all, centroid{mean: v, weight: 1})
+	}
+	all = append(all, t.centroids[i:]...)
+	t.buffer = t.buffer[:0]
+
+	// Both slices are reused between merges, to avoid allocating in the hot path
+	total := float64(t.Count)
+	merged := t.centroids[:0]
+	curr, weightSoFar := all[0], 0.0
+	kLow := t.scale(0)
+	for _, c := range all[1:] {
+		if t.scale((weightSoFar+curr.weight+c.weight)/total)-kLow <= 1 {
+			curr.weight += c.weight
+			curr.mean += (c.mean - curr.mean) * c.weight / curr.weight
+			continue
+		}
+		weightSoFar += curr.weight
+		kLow = t.scale(weightSoFar / total)
+		merged = append(merged, curr)
+		curr = c
+	}
+	t.centroids = append(merged, curr)
+	t.scratch = all
+
+	t.cumulative = t.cumulative[:0]
+	weightSoFar = 0
+	for _, c := range t.centroids {
+		t.cumulative = append(t.cumulative, weightSoFar+c.weight/2)
+		weightSoFar += c.weight
+	}
+}
+
+// P estimates the given percentile, by interpolating between the centroids around it.
+func (t *TDigestSink) P(pct float64) float64 {
+	t.merge()
+	switch {
+	case t.Count == 0:
+		return 0
+	case pct <= 0:
+		return t.Min
+	case pct >= 1:
+		return t.Max
+	}
+
+	index := pct * float64(t.Count)
+	last := len(t.centroids) - 1
+	first, lastCentroid := t.centroids[0], t.centroids[last]
+	if index <= t.cumulative[0] {
+		return t.Min + (first.mean-t.Min)*index/t.cumulative[0]
+	}
+	if index >= t.cumulative[last] {
+		rest := float64(t.Count) - t.cumulative[last]
+		return lastCentroid.mean + (t.Max-lastCentroid.mean)*(index-t.cumulative[last])/rest
+	}
+
+	i := sort.SearchFloat64s(t.cumulative, index)
+	lo, hi := t.cumulative[i-1], t.cumulative[i]
+	f := (index - lo) / (hi - lo)
+	return t.centroids[i-1].mean + (t.centroids[i].mean-t.centroids[i-1].mean)*f
+}
+
+// Centroids returns the number of centroids that are currently in the digest.
+func (t *TDigestSink) Centroids() int {
+	t.merge()
+	return len(t.centroids)
+}
+
+func (t *TDigestSink) Calc() {
+	t.Med = t.P(0.5)
+}
+
+func (t *TDigestSink) Format(tt time.Duration) map[string]float64 {
+	t.Calc()
+	return map[string]float64{
+		"min":   t.Min,
+		"max":   t.Max,
+		"avg":   t.Avg,
+		"med":   t.Med,
+		"p(90)": t.P(0.90),
+		"p(95)": t.P(0.95),
+	}
+}
@@ -0,0 +1,116 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTDigestSink(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		sink := TDigestSink{}
+		assert.Equal(t, 0.0, sink.P(0.5))
+		assert.Equal(t, map[string]float64{
+			"min": 0, "max": 0, "avg": 0, "med": 0, "p(90)": 0, "p(95)": 0,
+		}, sink.Format(0))
+	})
+
+	t.Run("small", func(t *testing.T) {
+		sink := TDigestSink{}
+		for _, v := range []float64{-5, 1, 2, 3, 4, 5, 6, 7, 8, 100} {
+			sink.Add(Sample{Metric: &Metric{}, Value: v})
+		}
+		assert.Equal(t, uint64(10), sink.Count)
+		assert.Equal(t, -5.0, sink.Min)
+		assert.Equal(t, 100.0, sink.Max)
+		assert.Equal(t, 13.1, sink.Avg)
+		assert.Equal(t, -5.0, sink.P(0))
+		assert.Equal(t, 100.0, sink.P(1))
+		// With only a few values, every one of them is its own centroid
+		assert.Equal(t, 10, sink.Centroids())
+		assert.Equal(t, 4.5, sink.P(0.5))
+		sink.Calc()
+		assert.Equal(t, 4.5, sink.Med)
+	})
+
+	distributions := map[string]func(r *rand.Rand) float64{
+		"uniform":     func(r *rand.Rand) float64 { return r.Float64() * 1000 },
+		"normal":      func(r *rand.Rand) float64 { return 500 + r.NormFloat64()*100 },
+		"exponential": func(r *rand.Rand) float64 { return r.ExpFloat64() * 200 },
+	}
+	percentiles := []float64{0.001, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 0.999}
+
+	for name, gen := range distributions {
+		gen := gen
+		t.Run(name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(42))
+			values := make([]float64, 100000)
+			sink := NewTDigestSink(DefaultTDigestCompression)
+			for i := range values {
+				values[i] = gen(r)
+				sink.Add(Sample{Metric: &Metric{}, Value: values[i]})
+			}
+			sort.Float64s(values)
+
+			// The digest has a bounded size, no matter how many values it has seen
+			assert.True(t, sink.Centroids() <= 2*DefaultTDigestCompression, sink.Centroids())
+
+			for _, pct := range percentiles {
+				// The t-digest error is bounded in terms of ranks, not values, and is
+				// proportional to q*(1-q)/compression, so it's the smallest at the tails.
+				estimate := sink.P(pct)
+				rank := float64(sort.SearchFloat64s(values, estimate)) / float64(len(values))
+				bound := 4*pct*(1-pct)/DefaultTDigestCompression + 0.0005
+				assert.InDelta(t, pct, rank, bound, "p(%g) estimate %g", pct*100, estimate)
+			}
+		})
+	}
+}
+
+func benchmarkTrendSink(b *testing.B, newSink func() Sink) {
+	r := rand.New(rand.NewSource(42))
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = r.ExpFloat64() * 200
+	}
+	metric, sink := &Metric{}, newSink()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sink.Add(Sample{Metric: metric, Value: values[i%len(values)]})
+	}
+	sink.Format(0)
+}
+
+// Compares the memory use of the exact and t-digest trend sinks. The memory per sample of the
+// TrendSink is constant, so its total grows with the number of samples, while the t-digest stays flat.
+func BenchmarkTrendSinkMemory(b *testing.B) {
+	b.Run("exact", func(b *testing.B) {
+		benchmarkTrendSink(b, func() Sink { return &TrendSink{} })
+	})
+	b.Run("tdigest", func(b *testing.B) {
+		benchmarkTrendSink(b, func() Sink { return NewTDigestSink(DefaultTDigestCompression) })
+	})
+}
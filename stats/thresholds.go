@@ -31,6 +31,7 @@ import (
 )
 
 const jsEnvSrc = `
+var __ENV = {};
 function p(pct) {
 	return __sink__.P(pct/100.0);
 };
@@ -191,6 +192,35 @@ func (ts *Thresholds) Run(sink Sink, t time.Duration) (bool, error) {
 	return ts.runAll(t)
 }
 
+// Check is like Run, but it leaves the state of the thresholds alone: they aren't marked as
+// failed and can't abort the test. It's meant for evaluating the thresholds over other samples
+// than the ones of the whole test, e.g. over the ones of a single time window.
+func (ts *Thresholds) Check(sink Sink, t time.Duration) (bool, error) {
+	if err := ts.updateVM(sink, t); err != nil {
+		return false, err
+	}
+	for i, th := range ts.Thresholds {
+		b, err := th.runNoTaint()
+		if err != nil {
+			return false, errors.Wrapf(err, "%d", i)
+		}
+		if !b {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// SetEnv makes the given environment variables available to the threshold expressions through
+// the __ENV object, the same way they are available to the script. That way the values metrics
+// are compared to can be supplied at the start of the test, e.g. "p(95)<__ENV.P95_TARGET".
+func (ts *Thresholds) SetEnv(env map[string]string) {
+	if ts.Runtime == nil {
+		return
+	}
+	ts.Runtime.Set("__ENV", env)
+}
+
 // UnmarshalJSON is implementation of json.Unmarshaler
 func (ts *Thresholds) UnmarshalJSON(data []byte) error {
 	var configs []thresholdConfig
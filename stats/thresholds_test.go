@@ -28,6 +28,7 @@ import (
 	"github.com/dop251/goja"
 	"github.com/loadimpact/k6/lib/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewThreshold(t *testing.T) {
@@ -217,6 +218,60 @@ func TestThresholdsRun(t *testing.T) {
 	})
 }
 
+func TestThresholdsCheck(t *testing.T) {
+	ts, err := NewThresholds([]string{"a>0", "a<10"})
+	require.NoError(t, err)
+	ts.Thresholds[1].AbortOnFail = true
+
+	b, err := ts.Check(DummySink{"a": 5}, 0)
+	assert.NoError(t, err)
+	assert.True(t, b)
+
+	b, err = ts.Check(DummySink{"a": 20}, 0)
+	assert.NoError(t, err)
+	assert.False(t, b)
+	assert.False(t, ts.Thresholds[1].LastFailed)
+	assert.False(t, ts.Abort)
+
+	ts, err = NewThresholds([]string{"a>0"})
+	require.NoError(t, err)
+	b, err = ts.Check(DummySink{}, 0)
+	assert.Error(t, err)
+	assert.False(t, b)
+}
+
+func TestThresholdsRunWithEnv(t *testing.T) {
+	ts, err := NewThresholds([]string{"a<__ENV.A_TARGET", "a<=Number(__ENV.A_TARGET)+1"})
+	require.NoError(t, err)
+
+	t.Run("unset", func(t *testing.T) {
+		b, err := ts.Run(DummySink{"a": 100}, 0)
+		assert.NoError(t, err)
+		assert.False(t, b)
+	})
+
+	ts.SetEnv(map[string]string{"A_TARGET": "800"})
+
+	t.Run("pass", func(t *testing.T) {
+		b, err := ts.Run(DummySink{"a": 799}, 0)
+		assert.NoError(t, err)
+		assert.True(t, b)
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		b, err := ts.Run(DummySink{"a": 800.5}, 0)
+		assert.NoError(t, err)
+		assert.False(t, b)
+		assert.True(t, ts.Thresholds[0].LastFailed)
+		assert.False(t, ts.Thresholds[1].LastFailed)
+	})
+
+	t.Run("zero value", func(t *testing.T) {
+		var ts Thresholds
+		assert.NotPanics(t, func() { ts.SetEnv(map[string]string{"A_TARGET": "800"}) })
+	})
+}
+
 func TestThresholdsJSON(t *testing.T) {
 	var testdata = []struct {
 		JSON        string
@@ -0,0 +1,58 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import "time"
+
+// The possible values of the sampleTimestamps option, i.e. which time the timestamps of the
+// samples passed to the outputs reflect
+const (
+	// TimestampMeasurement is the time the measurement occurred, the default
+	TimestampMeasurement = "measurement"
+	// TimestampWrite is the time the sample was written to the outputs
+	TimestampWrite = "write"
+)
+
+// Restamp returns copies of the sample containers, in which the time of all of the samples and
+// of the containers themselves is t. The original containers aren't changed. Connected sample
+// containers, e.g. HTTP trails, are replaced with ConnectedSamples, since their other fields
+// are based on the original times.
+func Restamp(sampleContainers []SampleContainer, t time.Time) []SampleContainer {
+	restamped := make([]SampleContainer, len(sampleContainers))
+	for i, sc := range sampleContainers {
+		samples := sc.GetSamples()
+		newSamples := make([]Sample, len(samples))
+		for j, sample := range samples {
+			sample.Time = t
+			newSamples[j] = sample
+		}
+
+		switch sc := sc.(type) {
+		case Sample:
+			restamped[i] = newSamples[0]
+		case ConnectedSampleContainer:
+			restamped[i] = ConnectedSamples{Samples: newSamples, Tags: sc.GetTags(), Time: t}
+		default:
+			restamped[i] = Samples(newSamples)
+		}
+	}
+	return restamped
+}
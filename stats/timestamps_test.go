@@ -0,0 +1,64 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestamp(t *testing.T) {
+	metric := New("my_metric", Counter)
+	tags := IntoSampleTags(&map[string]string{"a": "1"})
+	measured := time.Unix(1000, 0)
+	written := measured.Add(5 * time.Second)
+
+	original := []SampleContainer{
+		Sample{Metric: metric, Time: measured, Value: 1, Tags: tags},
+		ConnectedSamples{
+			Samples: []Sample{{Metric: metric, Time: measured, Value: 2}, {Metric: metric, Time: measured, Value: 3}},
+			Tags:    tags,
+			Time:    measured,
+		},
+		Samples{{Metric: metric, Time: measured, Value: 4}},
+	}
+	restamped := Restamp(original, written)
+
+	require.Len(t, restamped, 3)
+	assert.Equal(t, Sample{Metric: metric, Time: written, Value: 1, Tags: tags}, restamped[0])
+	connected := restamped[1].(ConnectedSamples)
+	assert.Equal(t, written, connected.Time)
+	assert.Equal(t, tags, connected.Tags)
+	assert.Equal(t, []Sample{{Metric: metric, Time: written, Value: 2}, {Metric: metric, Time: written, Value: 3}},
+		connected.Samples)
+	assert.Equal(t, Samples{{Metric: metric, Time: written, Value: 4}}, restamped[2])
+
+	// The original samples keep the measurement time
+	for _, sc := range original {
+		for _, s := range sc.GetSamples() {
+			assert.Equal(t, measured, s.Time)
+		}
+	}
+	assert.Equal(t, measured, original[1].(ConnectedSamples).Time)
+}
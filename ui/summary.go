@@ -394,6 +394,33 @@ type SummaryData struct {
 	RootGroup *lib.Group
 	Time      time.Duration
 	TimeUnit  string
+
+	// The per-stage breakdown of the metrics, if enabled
+	Stages []SummaryStage
+
+	// If set, the JSON summary also contains all of the values of the trend metrics, e.g. for
+	// a Mann-Whitney U test of a later run against this one
+	TrendValues bool
+}
+
+// SummaryStage represents the metrics of a single stage of the test
+type SummaryStage struct {
+	Index    int
+	Stage    lib.Stage
+	Duration time.Duration
+	Metrics  map[string]*stats.Metric
+}
+
+// describeStage returns the configured duration and target of the stage, e.g. "30s, target 10"
+func describeStage(stage lib.Stage) string {
+	parts := []string{}
+	if stage.Duration.Valid {
+		parts = append(parts, stage.Duration.Duration.String())
+	}
+	if stage.Target.Valid {
+		parts = append(parts, "target "+strconv.FormatInt(stage.Target.Int64, 10))
+	}
+	return strings.Join(parts, ", ")
 }
 
 // SummarizeMetrics creates a summary of provided metrics and writes it to w.
@@ -403,18 +430,49 @@ func (s *Summary) SummarizeMetrics(w io.Writer, indent string, data SummaryData)
 	}
 
 	s.summarizeMetrics(w, indent+"  ", data.Time, data.TimeUnit, data.Metrics)
+
+	for _, stage := range data.Stages {
+		desc := describeStage(stage.Stage)
+		if desc != "" {
+			desc = " (" + desc + ")"
+		}
+		_, _ = fmt.Fprintf(w, "\n%s    stage #%d%s:\n\n", indent, stage.Index, desc)
+		s.summarizeMetrics(w, indent+"    ", stage.Duration, data.TimeUnit, stage.Metrics)
+	}
 }
 
 // SummarizeMetricsJSON summarizes a dataset in JSON format.
 func (s *Summary) SummarizeMetricsJSON(w io.Writer, data SummaryData) error {
 	m := make(map[string]interface{})
 	m["root_group"] = data.RootGroup
+	m["metrics"] = metricsJSON(data.Metrics, data.Time, data.TrendValues)
+	if len(data.Stages) > 0 {
+		stages := make([]map[string]interface{}, len(data.Stages))
+		for i, stage := range data.Stages {
+			stages[i] = map[string]interface{}{
+				"index":    stage.Index,
+				"stage":    stage.Stage,
+				"duration": stats.D(stage.Duration), // in milliseconds, like the time metrics
+				"metrics":  metricsJSON(stage.Metrics, stage.Duration, data.TrendValues),
+			}
+		}
+		m["stages"] = stages
+	}
 
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+
+	return encoder.Encode(m)
+}
+
+// metricsJSON returns the summary data of the metrics for the JSON summary
+func metricsJSON(metrics map[string]*stats.Metric, t time.Duration, trendValues bool) map[string]interface{} {
 	metricsData := make(map[string]interface{})
-	for name, m := range data.Metrics {
+	for name, m := range metrics {
 		m.Sink.Calc()
 
-		sinkData := m.Sink.Format(data.Time)
+		sinkData := m.Sink.Format(t)
 		metricsData[name] = sinkData
 
 		var thresholds map[string]interface{}
@@ -431,11 +489,28 @@ func (s *Summary) SummarizeMetricsJSON(w io.Writer, data SummaryData) error {
 			metricsData[name] = sinkDataWithThreshold
 		}
 
-		if _, ok := m.Sink.(*stats.TrendSink); ok {
+		if sink, ok := m.Sink.(*stats.TrendSink); ok {
+			if sink.TrackTags || trendValues {
+				trendData := make(map[string]interface{})
+				for k, v := range sinkData {
+					trendData[k] = v
+				}
+				if thresholds != nil {
+					trendData["thresholds"] = thresholds
+				}
+				if sink.TrackTags {
+					trendData["min_tags"] = sink.MinTags
+					trendData["max_tags"] = sink.MaxTags
+				}
+				if trendValues {
+					trendData["values"] = append([]float64{}, sink.Values...)
+				}
+				metricsData[name] = trendData
+			}
 			continue
 		}
 
-		extra := nonTrendMetricValueForSumJSON(data.Time, m)
+		extra := nonTrendMetricValueForSumJSON(t, m)
 		if len(extra) > 1 {
 			extraData := make(map[string]interface{})
 			extraData["value"] = sinkData["value"]
@@ -448,10 +523,5 @@ func (s *Summary) SummarizeMetricsJSON(w io.Writer, data SummaryData) error {
 			metricsData[name] = extraData
 		}
 	}
-	m["metrics"] = metricsData
-	encoder := json.NewEncoder(w)
-	encoder.SetEscapeHTML(false)
-	encoder.SetIndent("", "    ")
-
-	return encoder.Encode(m)
+	return metricsData
 }
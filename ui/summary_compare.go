@@ -0,0 +1,266 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/loadimpact/k6/stats"
+)
+
+// mannWhitneyStat is the pseudo-stat of the rules that compare all of the values of a trend metric
+// with a Mann-Whitney U test, instead of a single stat. Their tolerance is the significance level.
+const mannWhitneyStat = "mannwhitney"
+
+// SummaryComparisonRule specifies a single metric stat that should be compared between a
+// baseline and a current summary export, and how much worse it's allowed to get.
+type SummaryComparisonRule struct {
+	Metric string
+	Stat   string // e.g. "avg", "p(95)", "count", "passes" or "mannwhitney"
+
+	// Tolerance is the maximum allowed relative change for the worse, e.g. 0.1 for 10%. For the
+	// "mannwhitney" stat, it's the significance level that the p-value of the test is checked
+	// against instead, e.g. 0.05.
+	Tolerance float64
+
+	// HigherIsBetter should be set for stats like the check passes, where an increase is
+	// an improvement. By default, a higher value is considered a regression.
+	HigherIsBetter bool
+}
+
+// UsesValues returns whether the rule needs all of the values of a trend metric, i.e. the summaries
+// have to be exported with SummaryData.TrendValues.
+func (r SummaryComparisonRule) UsesValues() bool {
+	return r.Stat == mannWhitneyStat
+}
+
+// ParseSummaryComparisonRule parses a rule in the `[metric]:[stat]<[tolerance]` format, e.g.
+// `http_req_duration:p(95)<10%`, which regresses if the stat increases by more than 10%. With
+// `>` instead, e.g. `checks:passes>5%`, higher values are better, so the rule regresses if the
+// stat decreases by more than the tolerance. The tolerance is either a percentage or a fraction.
+func ParseSummaryComparisonRule(s string) (SummaryComparisonRule, error) {
+	opIdx := strings.LastIndexAny(s, "<>")
+	if opIdx == -1 {
+		return SummaryComparisonRule{}, errors.Errorf(
+			"invalid comparison rule '%s', it should be in the [metric]:[stat]<[tolerance] format", s)
+	}
+	statIdx := strings.LastIndex(s[:opIdx], ":")
+	if statIdx <= 0 || statIdx == opIdx-1 {
+		return SummaryComparisonRule{}, errors.Errorf(
+			"invalid comparison rule '%s', it should be in the [metric]:[stat]<[tolerance] format", s)
+	}
+
+	tolerance, scale := strings.TrimSpace(s[opIdx+1:]), 1.0
+	if strings.HasSuffix(tolerance, "%") {
+		tolerance, scale = strings.TrimSuffix(tolerance, "%"), 0.01
+	}
+	t, err := strconv.ParseFloat(tolerance, 64)
+	if err != nil {
+		return SummaryComparisonRule{}, errors.Errorf("invalid tolerance of the comparison rule '%s'", s)
+	}
+	if t < 0 {
+		return SummaryComparisonRule{}, errors.Errorf("negative tolerance of the comparison rule '%s'", s)
+	}
+	rule := SummaryComparisonRule{
+		Metric:         strings.TrimSpace(s[:statIdx]),
+		Stat:           strings.TrimSpace(s[statIdx+1 : opIdx]),
+		Tolerance:      t * scale,
+		HigherIsBetter: s[opIdx] == '>',
+	}
+	if rule.UsesValues() && rule.Tolerance > 1 {
+		return SummaryComparisonRule{}, errors.Errorf(
+			"the significance level of the comparison rule '%s' should be at most 1", s)
+	}
+	return rule, nil
+}
+
+// SummaryComparisonResult is the result of a single SummaryComparisonRule.
+type SummaryComparisonResult struct {
+	SummaryComparisonRule
+	Baseline float64
+	Current  float64
+
+	// Change is the relative change from the baseline value, e.g. 0.25 for a 25% increase.
+	Change    float64
+	Regressed bool
+
+	// The result of the Mann-Whitney U test of the "mannwhitney" rules, whose Baseline and
+	// Current values are the medians.
+	MannWhitney *stats.MannWhitneyResult
+}
+
+// String returns a single line, human-readable representation of the result.
+func (r SummaryComparisonResult) String() string {
+	mark := succMark
+	if r.Regressed {
+		mark = failMark
+	}
+	if r.MannWhitney != nil {
+		return fmt.Sprintf("%s %s{%s}: median %g -> %g, p-value %.4g, effect size %+.3f (significance level %g)",
+			mark, r.Metric, r.Stat, r.Baseline, r.Current, r.MannWhitney.PValue, r.MannWhitney.EffectSize, r.Tolerance)
+	}
+	return fmt.Sprintf("%s %s{%s}: %g -> %g (%+.2f%%, tolerance %.2f%%)",
+		mark, r.Metric, r.Stat, r.Baseline, r.Current, r.Change*100, r.Tolerance*100)
+}
+
+// summaryExport is the part of the --summary-export JSON that's needed for comparisons.
+type summaryExport struct {
+	Metrics map[string]map[string]interface{} `json:"metrics"`
+}
+
+func readSummaryExport(r io.Reader) (summaryExport, error) {
+	var s summaryExport
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return s, err
+	}
+	if s.Metrics == nil {
+		return s, errors.New("no metrics in the summary")
+	}
+	return s, nil
+}
+
+func (s summaryExport) getStat(metric, stat string) (float64, error) {
+	m, ok := s.Metrics[metric]
+	if !ok {
+		return 0, errors.Errorf("metric '%s' not found", metric)
+	}
+	v, ok := m[stat].(float64)
+	if !ok {
+		return 0, errors.Errorf("stat '%s' of metric '%s' not found", stat, metric)
+	}
+	return v, nil
+}
+
+func (s summaryExport) getValues(metric string) ([]float64, error) {
+	m, ok := s.Metrics[metric]
+	if !ok {
+		return nil, errors.Errorf("metric '%s' not found", metric)
+	}
+	raw, ok := m["values"].([]interface{})
+	if !ok {
+		return nil, errors.Errorf("values of metric '%s' not found, they're only exported for trend metrics "+
+			"with --summary-export-values", metric)
+	}
+	values := make([]float64, len(raw))
+	for i, v := range raw {
+		if values[i], ok = v.(float64); !ok {
+			return nil, errors.Errorf("invalid value of metric '%s'", metric)
+		}
+	}
+	return values, nil
+}
+
+// compareValues runs a Mann-Whitney U test of all of the values of the rule's metric. The rule
+// regresses if the difference is significant and the current values tend to be worse.
+func compareValues(baseSummary, currSummary summaryExport, rule SummaryComparisonRule) (SummaryComparisonResult, error) {
+	res := SummaryComparisonResult{SummaryComparisonRule: rule}
+	base, err := baseSummary.getValues(rule.Metric)
+	if err != nil {
+		return res, errors.Wrap(err, "baseline summary")
+	}
+	curr, err := currSummary.getValues(rule.Metric)
+	if err != nil {
+		return res, errors.Wrap(err, "current summary")
+	}
+	mw, err := stats.MannWhitneyU(base, curr)
+	if err != nil {
+		return res, errors.Wrapf(err, "%s{%s}", rule.Metric, rule.Stat)
+	}
+
+	res.MannWhitney = &mw
+	res.Baseline, _ = baseSummary.getStat(rule.Metric, "med")
+	res.Current, _ = currSummary.getStat(rule.Metric, "med")
+	if res.Baseline != 0 {
+		res.Change = (res.Current - res.Baseline) / math.Abs(res.Baseline)
+	}
+	worse := mw.EffectSize > 0
+	if rule.HigherIsBetter {
+		worse = mw.EffectSize < 0
+	}
+	res.Regressed = worse && mw.PValue < rule.Tolerance
+	return res, nil
+}
+
+// CompareSummaries reads a baseline and a current summary, in the format produced by
+// --summary-export, and checks all of the given rules against them. It returns the results for
+// every rule and whether any of them has regressed beyond its tolerance.
+func CompareSummaries(
+	baseline, current io.Reader, rules []SummaryComparisonRule,
+) ([]SummaryComparisonResult, bool, error) {
+	baseSummary, err := readSummaryExport(baseline)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "invalid baseline summary")
+	}
+	currSummary, err := readSummaryExport(current)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "invalid current summary")
+	}
+
+	results := make([]SummaryComparisonResult, len(rules))
+	regressed := false
+	for i, rule := range rules {
+		if rule.Tolerance < 0 {
+			return nil, false, errors.Errorf("negative tolerance for %s{%s}", rule.Metric, rule.Stat)
+		}
+		if rule.UsesValues() {
+			res, err := compareValues(baseSummary, currSummary, rule)
+			if err != nil {
+				return nil, false, err
+			}
+			regressed = regressed || res.Regressed
+			results[i] = res
+			continue
+		}
+		base, err := baseSummary.getStat(rule.Metric, rule.Stat)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "baseline summary")
+		}
+		curr, err := currSummary.getStat(rule.Metric, rule.Stat)
+		if err != nil {
+			return nil, false, errors.Wrap(err, "current summary")
+		}
+
+		res := SummaryComparisonResult{SummaryComparisonRule: rule, Baseline: base, Current: curr}
+		worsening := curr - base
+		if rule.HigherIsBetter {
+			worsening = base - curr
+		}
+		switch {
+		case base != 0:
+			res.Change = (curr - base) / math.Abs(base)
+			res.Regressed = worsening/math.Abs(base) > rule.Tolerance
+		case curr != 0:
+			// Any worsening from a zero baseline is an infinitely large relative change
+			res.Change = math.Copysign(math.Inf(1), curr)
+			res.Regressed = worsening > 0
+		}
+		regressed = regressed || res.Regressed
+		results[i] = res
+	}
+	return results, regressed, nil
+}
@@ -0,0 +1,237 @@
+/*
+ *
+ * k6 - a next-generation load testing tool
+ * Copyright (C) 2019 Load Impact
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Affero General Public License as
+ * published by the Free Software Foundation, either version 3 of the
+ * License, or (at your option) any later version.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Affero General Public License for more details.
+ *
+ * You should have received a copy of the GNU Affero General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ui
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/stats"
+)
+
+func makeSummaryExport(t *testing.T, durations []float64, checkPasses, checkFails int) string {
+	duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+	for _, d := range durations {
+		duration.Sink.Add(stats.Sample{Value: d})
+	}
+	checks := stats.New("checks", stats.Rate)
+	for i := 0; i < checkPasses; i++ {
+		checks.Sink.Add(stats.Sample{Value: 1})
+	}
+	for i := 0; i < checkFails; i++ {
+		checks.Sink.Add(stats.Sample{Value: 0})
+	}
+
+	rootG, err := lib.NewGroup("", nil)
+	require.NoError(t, err)
+
+	var w bytes.Buffer
+	s := NewSummary([]string{"avg", "p(95)"})
+	require.NoError(t, s.SummarizeMetricsJSON(&w, SummaryData{
+		Metrics:   map[string]*stats.Metric{duration.Name: duration, checks.Name: checks},
+		RootGroup: rootG,
+		Time:      time.Second,
+	}))
+	return w.String()
+}
+
+func TestCompareSummaries(t *testing.T) {
+	baseline := makeSummaryExport(t, []float64{100, 100, 100, 100}, 9, 1)
+	rules := []SummaryComparisonRule{
+		{Metric: "http_req_duration", Stat: "avg", Tolerance: 0.1},
+		{Metric: "checks", Stat: "passes", Tolerance: 0.05, HigherIsBetter: true},
+	}
+
+	t.Run("same", func(t *testing.T) {
+		results, regressed, err := CompareSummaries(
+			strings.NewReader(baseline), strings.NewReader(baseline), rules)
+		require.NoError(t, err)
+		assert.False(t, regressed)
+		require.Len(t, results, 2)
+		for _, res := range results {
+			assert.Equal(t, res.Baseline, res.Current)
+			assert.Equal(t, 0.0, res.Change)
+			assert.False(t, res.Regressed)
+		}
+	})
+
+	t.Run("within tolerance", func(t *testing.T) {
+		current := makeSummaryExport(t, []float64{105, 105, 105, 105}, 9, 1)
+		results, regressed, err := CompareSummaries(
+			strings.NewReader(baseline), strings.NewReader(current), rules)
+		require.NoError(t, err)
+		assert.False(t, regressed)
+		assert.InDelta(t, 0.05, results[0].Change, 0.0001)
+	})
+
+	t.Run("improvements", func(t *testing.T) {
+		current := makeSummaryExport(t, []float64{50, 50, 50, 50}, 10, 0)
+		results, regressed, err := CompareSummaries(
+			strings.NewReader(baseline), strings.NewReader(current), rules)
+		require.NoError(t, err)
+		assert.False(t, regressed)
+		assert.InDelta(t, -0.5, results[0].Change, 0.0001)
+		assert.True(t, results[1].Change > 0)
+		assert.False(t, results[0].Regressed)
+		assert.False(t, results[1].Regressed)
+	})
+
+	t.Run("regressions", func(t *testing.T) {
+		current := makeSummaryExport(t, []float64{120, 120, 120, 120}, 7, 3)
+		results, regressed, err := CompareSummaries(
+			strings.NewReader(baseline), strings.NewReader(current), rules)
+		require.NoError(t, err)
+		assert.True(t, regressed)
+		assert.True(t, results[0].Regressed)
+		assert.Equal(t, 100.0, results[0].Baseline)
+		assert.Equal(t, 120.0, results[0].Current)
+		assert.True(t, results[1].Regressed)
+		assert.Equal(t, 9.0, results[1].Baseline)
+		assert.Equal(t, 7.0, results[1].Current)
+		assert.Contains(t, results[0].String(), failMark+" http_req_duration{avg}: 100 -> 120 (+20.00%")
+	})
+
+	t.Run("zero baseline", func(t *testing.T) {
+		zeroRules := []SummaryComparisonRule{{Metric: "checks", Stat: "fails", Tolerance: 0.5}}
+		current := makeSummaryExport(t, []float64{100}, 9, 1)
+		_, regressed, err := CompareSummaries(
+			strings.NewReader(makeSummaryExport(t, []float64{100}, 10, 0)), strings.NewReader(current), zeroRules)
+		require.NoError(t, err)
+		assert.True(t, regressed)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, _, err := CompareSummaries(strings.NewReader("{"), strings.NewReader(baseline), rules)
+		assert.EqualError(t, err, "invalid baseline summary: unexpected EOF")
+
+		_, _, err = CompareSummaries(strings.NewReader(baseline), strings.NewReader(`{}`), rules)
+		assert.EqualError(t, err, "invalid current summary: no metrics in the summary")
+
+		_, _, err = CompareSummaries(strings.NewReader(baseline), strings.NewReader(baseline),
+			[]SummaryComparisonRule{{Metric: "http_req_duration", Stat: "p(99)"}})
+		assert.EqualError(t, err, "baseline summary: stat 'p(99)' of metric 'http_req_duration' not found")
+
+		_, _, err = CompareSummaries(strings.NewReader(baseline), strings.NewReader(baseline),
+			[]SummaryComparisonRule{{Metric: "vus", Stat: "max"}})
+		assert.EqualError(t, err, "baseline summary: metric 'vus' not found")
+
+		_, _, err = CompareSummaries(strings.NewReader(baseline), strings.NewReader(baseline),
+			[]SummaryComparisonRule{{Metric: "checks", Stat: "passes", Tolerance: -1}})
+		assert.EqualError(t, err, "negative tolerance for checks{passes}")
+	})
+}
+
+func TestParseSummaryComparisonRule(t *testing.T) {
+	testdata := map[string]SummaryComparisonRule{
+		"http_req_duration:p(95)<10%":        {Metric: "http_req_duration", Stat: "p(95)", Tolerance: 0.1},
+		"http_req_duration:avg<0.25":         {Metric: "http_req_duration", Stat: "avg", Tolerance: 0.25},
+		"checks:passes>5%":                   {Metric: "checks", Stat: "passes", Tolerance: 0.05, HigherIsBetter: true},
+		"http_req_duration:mannwhitney<0.05": {Metric: "http_req_duration", Stat: "mannwhitney", Tolerance: 0.05},
+		"http_req_duration{status:200}:max < 0": {
+			Metric: "http_req_duration{status:200}", Stat: "max", Tolerance: 0,
+		},
+	}
+	for s, expected := range testdata {
+		s, expected := s, expected
+		t.Run(s, func(t *testing.T) {
+			rule, err := ParseSummaryComparisonRule(s)
+			require.NoError(t, err)
+			assert.Equal(t, expected.Metric, rule.Metric)
+			assert.Equal(t, expected.Stat, rule.Stat)
+			assert.InDelta(t, expected.Tolerance, rule.Tolerance, 1e-9)
+			assert.Equal(t, expected.HigherIsBetter, rule.HigherIsBetter)
+		})
+	}
+
+	for _, s := range []string{"", "http_req_duration", "http_req_duration:avg", "avg<10%",
+		"http_req_duration:<10%", "http_req_duration:avg<", "http_req_duration:avg<ten", "http_req_duration:avg<-1%",
+		"http_req_duration:mannwhitney<5"} {
+		_, err := ParseSummaryComparisonRule(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestCompareSummariesMannWhitney(t *testing.T) {
+	makeExport := func(offset float64) string {
+		duration := stats.New("http_req_duration", stats.Trend, stats.Time)
+		for i := 0; i < 40; i++ {
+			duration.Sink.Add(stats.Sample{Value: 100 + offset + float64(i)})
+		}
+		rootG, err := lib.NewGroup("", nil)
+		require.NoError(t, err)
+		var w bytes.Buffer
+		require.NoError(t, NewSummary(nil).SummarizeMetricsJSON(&w, SummaryData{
+			Metrics:     map[string]*stats.Metric{duration.Name: duration},
+			RootGroup:   rootG,
+			Time:        time.Second,
+			TrendValues: true,
+		}))
+		return w.String()
+	}
+	baseline := makeExport(0)
+	rule := SummaryComparisonRule{Metric: "http_req_duration", Stat: "mannwhitney", Tolerance: 0.05}
+	require.True(t, rule.UsesValues())
+
+	compare := func(current string, rule SummaryComparisonRule) SummaryComparisonResult {
+		results, regressed, err := CompareSummaries(
+			strings.NewReader(baseline), strings.NewReader(current), []SummaryComparisonRule{rule})
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		require.NotNil(t, results[0].MannWhitney)
+		assert.Equal(t, results[0].Regressed, regressed)
+		return results[0]
+	}
+
+	res := compare(baseline, rule)
+	assert.False(t, res.Regressed)
+	assert.InDelta(t, 1, res.MannWhitney.PValue, 0.01)
+
+	// A small shift isn't significant
+	res = compare(makeExport(2), rule)
+	assert.False(t, res.Regressed)
+	assert.True(t, res.MannWhitney.PValue > 0.05)
+
+	res = compare(makeExport(30), rule)
+	assert.True(t, res.Regressed)
+	assert.True(t, res.MannWhitney.PValue < 0.05)
+	assert.True(t, res.MannWhitney.EffectSize > 0)
+	assert.Equal(t, 119.5, res.Baseline)
+	assert.Equal(t, 149.5, res.Current)
+	assert.Contains(t, res.String(), failMark+" http_req_duration{mannwhitney}: median 119.5 -> 149.5, p-value ")
+
+	// A significant improvement isn't a regression, unless higher values are better
+	res = compare(makeExport(-30), rule)
+	assert.False(t, res.Regressed)
+	assert.True(t, res.MannWhitney.PValue < 0.05)
+	rule.HigherIsBetter = true
+	assert.True(t, compare(makeExport(-30), rule).Regressed)
+
+	_, _, err := CompareSummaries(strings.NewReader(makeSummaryExport(t, []float64{100}, 1, 0)),
+		strings.NewReader(baseline), []SummaryComparisonRule{rule})
+	assert.EqualError(t, err, "baseline summary: values of metric 'http_req_duration' not found, "+
+		"they're only exported for trend metrics with --summary-export-values")
+}
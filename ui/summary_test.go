@@ -31,6 +31,7 @@ import (
 	"gopkg.in/guregu/null.v3"
 
 	"github.com/loadimpact/k6/lib"
+	"github.com/loadimpact/k6/lib/types"
 	"github.com/loadimpact/k6/stats"
 )
 
@@ -79,6 +80,29 @@ func TestSummary(t *testing.T) {
 		}
 	})
 
+	t.Run("Stages", func(t *testing.T) {
+		reqs := stats.New("http_reqs", stats.Counter)
+		reqs.Sink.Add(stats.Sample{Value: 4})
+		var w bytes.Buffer
+		NewSummary([]string{"avg"}).SummarizeMetrics(&w, " ", SummaryData{
+			Metrics: map[string]*stats.Metric{},
+			Time:    time.Second,
+			Stages: []SummaryStage{
+				{
+					Index:    0,
+					Stage:    lib.Stage{Duration: types.NullDurationFrom(2 * time.Second), Target: null.IntFrom(10)},
+					Duration: 2 * time.Second,
+					Metrics:  map[string]*stats.Metric{"http_reqs": reqs},
+				},
+				{Index: 1, Duration: time.Second, Metrics: map[string]*stats.Metric{}},
+			},
+		})
+		assert.Equal(t,
+			"\n     stage #0 (2s, target 10):\n\n       http_reqs...: 4 2/s\n"+
+				"\n     stage #1:\n\n",
+			w.String())
+	})
+
 	t.Run("generateCustomTrendValueResolvers", func(t *testing.T) {
 		var customResolversTests = []struct {
 			stats      []string
@@ -261,4 +285,54 @@ func TestSummarizeMetricsJSON(t *testing.T) {
 	require.Nil(t, err)
 	require.Contains(t, w.String(), "<")
 	require.JSONEq(t, expected, w.String())
+
+	t.Run("extreme tags", func(t *testing.T) {
+		metric := stats.New("my_trend", stats.Trend, stats.Time)
+		metric.Sink.(*stats.TrendSink).TrackTags = true
+		for i, url := range []string{"a", "c", "b"} {
+			metric.Sink.Add(stats.Sample{Value: float64(i), Tags: stats.IntoSampleTags(&map[string]string{"url": url})})
+		}
+
+		var w bytes.Buffer
+		err := s.SummarizeMetricsJSON(&w, SummaryData{Metrics: map[string]*stats.Metric{"my_trend": metric}})
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"root_group": null,
+			"metrics": {
+				"my_trend": {
+					"avg": 1, "max": 2, "med": 1, "min": 0, "p(90)": 1.8, "p(95)": 1.9,
+					"min_tags": {"url": "a"},
+					"max_tags": {"url": "b"}
+				}
+			}
+		}`, w.String())
+	})
+
+	t.Run("stages", func(t *testing.T) {
+		reqs := stats.New("http_reqs", stats.Counter)
+		reqs.Sink.Add(stats.Sample{Value: 4})
+		stage := lib.Stage{Duration: types.NullDurationFrom(2 * time.Second), Target: null.IntFrom(10)}
+
+		var w bytes.Buffer
+		err := s.SummarizeMetricsJSON(&w, SummaryData{
+			Metrics: map[string]*stats.Metric{},
+			Stages: []SummaryStage{{
+				Index:    0,
+				Stage:    stage,
+				Duration: 2 * time.Second,
+				Metrics:  map[string]*stats.Metric{"http_reqs": reqs},
+			}},
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{
+			"root_group": null,
+			"metrics": {},
+			"stages": [{
+				"index": 0,
+				"stage": {"duration": "2s", "target": 10},
+				"duration": 2000,
+				"metrics": {"http_reqs": {"count": 4, "rate": 2}}
+			}]
+		}`, w.String())
+	})
 }